@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/alist-org/alist/v3/internal/manifest"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// ApplyCmd represents the apply command
+var ApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Apply a manifest of storages, metas, roles, users and settings",
+	Long: `Apply a YAML or JSON manifest to the database.
+Entries are matched by mount_path, path, name and username, only the fields
+present in an entry are changed. With "prune: true" records missing from a
+section of the manifest are deleted. ${NAME} in a value is replaced by the environment
+variable NAME, use it for passwords and tokens.
+Storages are loaded on the next start of the server. A manifest.yaml placed
+in the data dir is applied every time the server starts.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		file, _ := cmd.Flags().GetString("file")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if file == "" {
+			utils.Log.Errorf("manifest file is required")
+			return
+		}
+		m, err := manifest.Load(file)
+		if err != nil {
+			utils.Log.Errorf("%+v", err)
+			return
+		}
+		Init()
+		defer Release()
+		plan, err := manifest.Diff(m)
+		if err != nil {
+			utils.Log.Errorf("failed to diff manifest: %+v", err)
+			return
+		}
+		if len(plan) == 0 {
+			fmt.Println("No changes")
+			return
+		}
+		fmt.Println(plan)
+		if dryRun {
+			return
+		}
+		if err = plan.Apply(); err != nil {
+			utils.Log.Errorf("%+v", err)
+			return
+		}
+		fmt.Printf("Applied %d changes\n", len(plan))
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(ApplyCmd)
+	ApplyCmd.Flags().StringP("file", "f", "", "manifest file")
+	ApplyCmd.Flags().Bool("dry-run", false, "only print the plan")
+}
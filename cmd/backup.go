@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/backup"
+	"github.com/alist-org/alist/v3/internal/bootstrap"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+const passphraseEnv = "ALIST_BACKUP_PASSPHRASE"
+
+func getPassphrase(cmd *cobra.Command) string {
+	passphrase, _ := cmd.Flags().GetString("passphrase")
+	if passphrase == "" {
+		passphrase = os.Getenv(passphraseEnv)
+	}
+	return passphrase
+}
+
+// BackupCmd represents the backup command
+var BackupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Back up storages, metas, users, roles, settings and other data to an archive",
+	Long: `Back up the data of the instance to a single archive.
+Sessions, tasks and the search index are not included. With a passphrase
+(--passphrase or ` + passphraseEnv + `) the confidential values are encrypted:
+storage fields and settings named like secrets, otp secrets of users, meta
+passwords and the secret keys of S3 credentials. Password and token hashes
+are stored as they are.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		output, _ := cmd.Flags().GetString("output")
+		if output == "" {
+			output = fmt.Sprintf("alist-backup-%s.tar.gz", time.Now().Format("20060102-150405"))
+		}
+		Init()
+		defer Release()
+		f, err := os.OpenFile(output, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err != nil {
+			utils.Log.Errorf("failed to create backup file: %+v", err)
+			return
+		}
+		h, err := backup.Create(f, getPassphrase(cmd))
+		if err == nil {
+			err = f.Close()
+		} else {
+			_ = f.Close()
+		}
+		if err != nil {
+			_ = os.Remove(output)
+			utils.Log.Errorf("failed to back up: %+v", err)
+			return
+		}
+		utils.Log.Infof("backup written to %s, encrypted: %t, counts: %v", output, h.Encrypted, h.Counts)
+	},
+}
+
+// RestoreCmd represents the restore command
+var RestoreCmd = &cobra.Command{
+	Use:   "restore [backup file]",
+	Short: "Restore data from a backup archive, replacing the current data",
+	Long: `Restore data from a backup archive created by [alist backup].
+All storages, metas, users, roles, settings and other backed up data are
+replaced. Stop the server before restoring. Backups of older versions are
+upgraded with the same patches that run when alist itself is upgraded.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			utils.Log.Errorf("backup file is required")
+			return
+		}
+		force, _ := cmd.Flags().GetBool("force")
+		f, err := os.Open(args[0])
+		if err != nil {
+			utils.Log.Errorf("failed to open backup file: %+v", err)
+			return
+		}
+		defer f.Close()
+		h, s, err := backup.Read(f, getPassphrase(cmd))
+		if err != nil {
+			utils.Log.Errorf("failed to read backup: %+v", err)
+			return
+		}
+		if bootstrap.IsNewerVersion(h.Version) && !force {
+			utils.Log.Errorf("backup was created by %s which is newer than this version, use --force to restore anyway", h.Version)
+			return
+		}
+		Init()
+		defer Release()
+		if err = backup.Restore(s); err != nil {
+			utils.Log.Errorf("failed to restore: %+v", err)
+			return
+		}
+		bootstrap.UpgradeFrom(h.Version)
+		utils.Log.Infof("restored backup of %s created at %s, counts: %v", h.Version, h.CreatedAt.Format(time.RFC3339), h.Counts)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(BackupCmd)
+	RootCmd.AddCommand(RestoreCmd)
+	BackupCmd.Flags().StringP("output", "o", "", "output file")
+	BackupCmd.Flags().String("passphrase", "", "encrypt confidential values with the passphrase")
+	RestoreCmd.Flags().String("passphrase", "", "passphrase of an encrypted backup")
+	RestoreCmd.Flags().Bool("force", false, "restore a backup created by a newer version")
+}
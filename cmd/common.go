@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
 	"github.com/alist-org/alist/v3/internal/bootstrap/patch/v3_46_0"
 	"os"
 	"path/filepath"
@@ -9,13 +11,16 @@ import (
 	"github.com/alist-org/alist/v3/internal/bootstrap"
 	"github.com/alist-org/alist/v3/internal/bootstrap/data"
 	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/tracing"
 	"github.com/alist-org/alist/v3/pkg/utils"
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
 )
 
 func Init() {
 	bootstrap.InitConfig()
 	bootstrap.Log()
+	tracing.Init()
 	bootstrap.InitDB()
 
 	if v3_46_0.IsLegacyRoleDetected() {
@@ -30,9 +35,34 @@ func Init() {
 }
 
 func Release() {
+	if err := tracing.Shutdown(context.Background()); err != nil {
+		utils.Log.Errorf("failed to flush spans: %+v", err)
+	}
 	db.Close()
 }
 
+// jsonOutput is set by the --json flag of the commands printing results
+var jsonOutput bool
+
+// runCmd runs fn between Init and Release, the process exits with 1 if fn fails
+func runCmd(fn func(cmd *cobra.Command, args []string) error) func(cmd *cobra.Command, args []string) {
+	return func(cmd *cobra.Command, args []string) {
+		Init()
+		err := fn(cmd, args)
+		Release()
+		if err != nil {
+			utils.Log.Errorf("%v", err)
+			os.Exit(1)
+		}
+	}
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
 var pid = -1
 var pidFile string
 
@@ -0,0 +1,607 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	stdpath "path"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alist-org/alist/v3/cmd/flags"
+	"github.com/alist-org/alist/v3/internal/bootstrap"
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/fs"
+	amodel "github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/stream"
+	"github.com/alist-org/alist/v3/pkg/http_range"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// fsCmd represents the fs command
+var fsCmd = &cobra.Command{
+	Use:   "fs",
+	Short: "Operate on the files of the storages without the server",
+	Long: `Operate on the files of the configured storages as the admin user.
+Paths are the same as in the web UI, alias, crypt and balance mounts included.
+The storages are loaded by the command itself, the server doesn't need to run.`,
+}
+
+type fsObj struct {
+	Name     string                     `json:"name"`
+	Path     string                     `json:"path"`
+	Size     int64                      `json:"size"`
+	IsDir    bool                       `json:"is_dir"`
+	Modified time.Time                  `json:"modified"`
+	HashInfo map[*utils.HashType]string `json:"hash_info,omitempty"`
+}
+
+type fsResult struct {
+	Path  string `json:"path"`
+	Dst   string `json:"dst,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// runFs loads the storages and runs fn with the admin user in ctx
+func runFs(fn func(ctx context.Context, cmd *cobra.Command, args []string) error) func(cmd *cobra.Command, args []string) {
+	return runCmd(func(cmd *cobra.Command, args []string) error {
+		if !flags.Debug && !flags.Dev {
+			// keep the output of the command readable
+			logrus.SetLevel(logrus.WarnLevel)
+			utils.Log.SetLevel(logrus.WarnLevel)
+		}
+		bootstrap.LoadStoragesSync()
+		admin, err := op.GetAdmin()
+		if err != nil {
+			return err
+		}
+		ctx, stop := signal.NotifyContext(context.WithValue(context.Background(), "user", admin), os.Interrupt)
+		defer stop()
+		return fn(ctx, cmd, args)
+	})
+}
+
+// fsPath resolves p the way the web UI does for the user in ctx
+func fsPath(ctx context.Context, p string) (string, error) {
+	user := ctx.Value("user").(*amodel.User)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return user.JoinPath(p)
+}
+
+// report prints the results in json mode and fails if any operation failed
+func report(results []fsResult) error {
+	failed := 0
+	for _, r := range results {
+		if r.Error != "" {
+			failed++
+			if !jsonOutput {
+				utils.Log.Errorf("failed %s: %s", r.Path, r.Error)
+			}
+		}
+	}
+	if jsonOutput {
+		if err := printJSON(results); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return errors.Errorf("%d of %d operations failed", failed, len(results))
+	}
+	return nil
+}
+
+func newResult(path, dst string, err error) fsResult {
+	r := fsResult{Path: path, Dst: dst}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+var fsLsCmd = &cobra.Command{
+	Use:   "ls [path...]",
+	Short: "List directories",
+	Run: runFs(func(ctx context.Context, cmd *cobra.Command, args []string) error {
+		refresh, _ := cmd.Flags().GetBool("refresh")
+		if len(args) == 0 {
+			args = []string{"/"}
+		}
+		var objs []fsObj
+		var failed []fsResult
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for i, arg := range args {
+			p, err := fsPath(ctx, arg)
+			if err != nil {
+				failed = append(failed, newResult(arg, "", err))
+				continue
+			}
+			list, err := listObjs(ctx, p, refresh)
+			if err != nil {
+				failed = append(failed, newResult(p, "", err))
+				continue
+			}
+			objs = append(objs, list...)
+			if jsonOutput {
+				continue
+			}
+			if len(args) > 1 {
+				if i > 0 {
+					fmt.Fprintln(w)
+				}
+				fmt.Fprintf(w, "%s:\n", p)
+			}
+			for _, obj := range list {
+				size, modified, name := formatSize(obj.Size), "-", obj.Name
+				if obj.IsDir {
+					size, name = "-", name+"/"
+				}
+				if !obj.Modified.IsZero() {
+					modified = obj.Modified.Local().Format("2006-01-02 15:04")
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", size, modified, name)
+			}
+		}
+		if jsonOutput {
+			if objs == nil {
+				objs = []fsObj{}
+			}
+			if err := printJSON(objs); err != nil {
+				return err
+			}
+		} else if err := w.Flush(); err != nil {
+			return err
+		}
+		for _, r := range failed {
+			utils.Log.Errorf("failed %s: %s", r.Path, r.Error)
+		}
+		if len(failed) > 0 {
+			return errors.Errorf("%d of %d paths failed", len(failed), len(args))
+		}
+		return nil
+	}),
+}
+
+// listObjs lists p if it's a directory, or returns the file itself
+func listObjs(ctx context.Context, p string, refresh bool) ([]fsObj, error) {
+	obj, err := fs.Get(ctx, p, &fs.GetArgs{NoLog: true})
+	if err != nil {
+		return nil, err
+	}
+	toObj := func(obj amodel.Obj, path string) fsObj {
+		return fsObj{
+			Name:     obj.GetName(),
+			Path:     path,
+			Size:     obj.GetSize(),
+			IsDir:    obj.IsDir(),
+			Modified: obj.ModTime(),
+			HashInfo: obj.GetHash().Export(),
+		}
+	}
+	if !obj.IsDir() {
+		return []fsObj{toObj(obj, p)}, nil
+	}
+	list, err := fs.List(ctx, p, &fs.ListArgs{Refresh: refresh, NoLog: true})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]fsObj, 0, len(list))
+	for _, obj := range list {
+		res = append(res, toObj(obj, stdpath.Join(p, obj.GetName())))
+	}
+	return res, nil
+}
+
+// resolveTarget returns the directory and the name to copy or move the
+// sources to, the name is empty to keep the name of the source
+func resolveTarget(ctx context.Context, args []string) (srcs []string, dstDir, name string, err error) {
+	if len(args) < 2 {
+		return nil, "", "", errors.New("source and target paths are required")
+	}
+	for _, arg := range args[:len(args)-1] {
+		p, err := fsPath(ctx, arg)
+		if err != nil {
+			return nil, "", "", err
+		}
+		srcs = append(srcs, p)
+	}
+	rawDst := args[len(args)-1]
+	dst, err := fsPath(ctx, rawDst)
+	if err != nil {
+		return nil, "", "", err
+	}
+	obj, err := fs.Get(ctx, dst, &fs.GetArgs{NoLog: true})
+	if (err == nil && obj.IsDir()) || strings.HasSuffix(rawDst, "/") {
+		return srcs, dst, "", nil
+	}
+	if len(srcs) > 1 {
+		return nil, "", "", errors.Errorf("target [%s] is not a directory", dst)
+	}
+	if err != nil && !errs.IsObjectNotFound(err) {
+		return nil, "", "", err
+	}
+	return srcs, stdpath.Dir(dst), stdpath.Base(dst), nil
+}
+
+// transfer copies or moves the sources of args to the target with fn
+func transfer(ctx context.Context, args []string, fn func(ctx context.Context, src, dstDir, name string) error) ([]fsResult, error) {
+	srcs, dstDir, name, err := resolveTarget(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	var results []fsResult
+	for _, src := range srcs {
+		n := name
+		if n == "" {
+			n = stdpath.Base(src)
+		}
+		err := fn(ctx, src, dstDir, n)
+		results = append(results, newResult(src, stdpath.Join(dstDir, n), err))
+	}
+	return results, nil
+}
+
+var fsCpCmd = &cobra.Command{
+	Use:   "cp <src>... <dst>",
+	Short: "Copy files and directories, also between storages",
+	Run: runFs(func(ctx context.Context, cmd *cobra.Command, args []string) error {
+		results, err := transfer(ctx, args, copyObj)
+		if err != nil {
+			return err
+		}
+		return report(results)
+	}),
+}
+
+// copyObj copies src as dstDir/name, with the copy of the driver if both are
+// in the same storage, or by streaming the files otherwise
+func copyObj(ctx context.Context, src, dstDir, name string) error {
+	// the copy would list and copy itself endlessly
+	if utils.IsSubPath(src, stdpath.Join(dstDir, name)) {
+		return errors.Errorf("can't copy [%s] into itself", src)
+	}
+	obj, err := fs.Get(ctx, src, &fs.GetArgs{NoLog: true})
+	if err != nil {
+		return err
+	}
+	if name == obj.GetName() {
+		srcStorage, srcActualPath, err := op.GetStorageAndActualPath(src)
+		if err != nil {
+			return errors.WithMessage(err, "failed get src storage")
+		}
+		dstStorage, dstDirActualPath, err := op.GetStorageAndActualPath(dstDir)
+		if err != nil {
+			return errors.WithMessage(err, "failed get dst storage")
+		}
+		if srcStorage.GetStorage() == dstStorage.GetStorage() {
+			err = op.Copy(ctx, srcStorage, srcActualPath, dstDirActualPath)
+			if !errors.Is(err, errs.NotImplement) && !errors.Is(err, errs.NotSupport) {
+				return err
+			}
+		}
+	}
+	if !obj.IsDir() {
+		return copyFile(ctx, src, obj, dstDir, name)
+	}
+	dst := stdpath.Join(dstDir, name)
+	if err = fs.MakeDir(ctx, dst); err != nil {
+		return err
+	}
+	objs, err := fs.List(ctx, src, &fs.ListArgs{NoLog: true})
+	if err != nil {
+		return err
+	}
+	for _, o := range objs {
+		if utils.IsCanceled(ctx) {
+			return ctx.Err()
+		}
+		if err = copyObj(ctx, stdpath.Join(src, o.GetName()), dst, o.GetName()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFile(ctx context.Context, src string, obj amodel.Obj, dstDir, name string) error {
+	link, _, err := fs.Link(ctx, src, amodel.LinkArgs{Header: http.Header{}})
+	if err != nil {
+		return errors.WithMessagef(err, "failed get [%s] link", src)
+	}
+	ss, err := stream.NewSeekableStream(stream.FileStream{Obj: obj, Ctx: ctx}, link)
+	if err != nil {
+		return errors.WithMessagef(err, "failed get [%s] stream", src)
+	}
+	if name != obj.GetName() {
+		ss.SetName(name)
+	}
+	bar := newFsProgress(name, obj.GetSize())
+	err = fs.PutDirectlyWithProgress(ctx, dstDir, ss, bar.Update)
+	bar.Done()
+	return err
+}
+
+var fsMvCmd = &cobra.Command{
+	Use:   "mv <src>... <dst>",
+	Short: "Move or rename files and directories within a storage",
+	Run: runFs(func(ctx context.Context, cmd *cobra.Command, args []string) error {
+		results, err := transfer(ctx, args, moveObj)
+		if err != nil {
+			return err
+		}
+		return report(results)
+	}),
+}
+
+func moveObj(ctx context.Context, src, dstDir, name string) error {
+	if stdpath.Dir(src) != dstDir {
+		if err := fs.Move(ctx, src, dstDir); err != nil {
+			return err
+		}
+		src = stdpath.Join(dstDir, stdpath.Base(src))
+	}
+	if stdpath.Base(src) == name {
+		return nil
+	}
+	return fs.Rename(ctx, src, name)
+}
+
+var fsRmCmd = &cobra.Command{
+	Use:   "rm <path>...",
+	Short: "Remove files and directories",
+	Run: runFs(func(ctx context.Context, cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("path is required")
+		}
+		return report(removePaths(ctx, args))
+	}),
+}
+
+func removePaths(ctx context.Context, args []string) []fsResult {
+	var results []fsResult
+	for _, arg := range args {
+		p, err := fsPath(ctx, arg)
+		if err != nil {
+			results = append(results, newResult(arg, "", err))
+			continue
+		}
+		results = append(results, newResult(p, "", fs.Remove(ctx, p)))
+	}
+	return results
+}
+
+var fsCatCmd = &cobra.Command{
+	Use:   "cat <path>...",
+	Short: "Write the content of files to stdout",
+	Run: runFs(func(ctx context.Context, cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("path is required")
+		}
+		for _, arg := range args {
+			p, err := fsPath(ctx, arg)
+			if err != nil {
+				return err
+			}
+			if err = catFile(ctx, p, os.Stdout); err != nil {
+				return errors.WithMessagef(err, "failed cat [%s]", p)
+			}
+		}
+		return nil
+	}),
+}
+
+func catFile(ctx context.Context, p string, w *os.File) error {
+	obj, err := fs.Get(ctx, p, &fs.GetArgs{NoLog: true})
+	if err != nil {
+		return err
+	}
+	if obj.IsDir() {
+		return errors.WithStack(errs.NotFile)
+	}
+	link, _, err := fs.Link(ctx, p, amodel.LinkArgs{Header: http.Header{}})
+	if err != nil {
+		return err
+	}
+	ss, err := stream.NewSeekableStream(stream.FileStream{Obj: obj, Ctx: ctx}, link)
+	if err != nil {
+		return err
+	}
+	defer ss.Close()
+	r, err := ss.RangeRead(http_range.Range{Length: -1})
+	if err != nil {
+		return err
+	}
+	var bar *fsProgress
+	// the bar would be mixed with the content on a terminal
+	if !isTerminal(w) {
+		bar = newFsProgress(obj.GetName(), obj.GetSize())
+	}
+	_, err = utils.CopyWithBuffer(&progressWriter{w: w, bar: bar, total: obj.GetSize()}, r)
+	bar.Done()
+	return err
+}
+
+var fsPutCmd = &cobra.Command{
+	Use:   "put <dst> [local file]",
+	Short: "Upload a local file, or stdin if no file or - is given",
+	Run: runFs(func(ctx context.Context, cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("dst path is required")
+		}
+		local := "-"
+		if len(args) > 1 {
+			local = args[1]
+		}
+		dst, err := fsPath(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		dstDir, name := stdpath.Dir(dst), stdpath.Base(dst)
+		obj, err := fs.Get(ctx, dst, &fs.GetArgs{NoLog: true})
+		if (err == nil && obj.IsDir()) || strings.HasSuffix(args[0], "/") {
+			if local == "-" {
+				return errors.New("dst must be a file path when reading stdin")
+			}
+			dstDir, name = dst, stdpath.Base(local)
+		}
+		file, err := openLocal(ctx, local, name)
+		if err != nil {
+			return err
+		}
+		bar := newFsProgress(name, file.GetSize())
+		err = fs.PutDirectlyWithProgress(ctx, dstDir, file, bar.Update)
+		bar.Done()
+		return report([]fsResult{newResult(local, stdpath.Join(dstDir, name), err)})
+	}),
+}
+
+// openLocal opens the local file to put, stdin is first saved to a temp
+// file since most drivers need the size before uploading
+func openLocal(ctx context.Context, local, name string) (*stream.FileStream, error) {
+	file := &stream.FileStream{
+		Ctx:      ctx,
+		Mimetype: utils.GetMimeType(name),
+		Closers:  utils.EmptyClosers(),
+	}
+	modified := time.Now()
+	if local == "-" {
+		tmp, err := os.CreateTemp(conf.Conf.TempDir, "file-*")
+		if err != nil {
+			return nil, err
+		}
+		file.SetTmpFile(tmp)
+		if _, err = utils.CopyWithBuffer(tmp, os.Stdin); err == nil {
+			_, err = tmp.Seek(0, io.SeekStart)
+		}
+		if err != nil {
+			_ = file.Close()
+			return nil, errors.WithMessage(err, "failed read stdin")
+		}
+	} else {
+		f, err := os.Open(local)
+		if err != nil {
+			return nil, err
+		}
+		file.Add(f)
+		file.Reader = f
+		if info, err := f.Stat(); err == nil {
+			modified = info.ModTime()
+		}
+	}
+	info, err := file.Reader.(*os.File).Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		_ = file.Close()
+		return nil, errors.WithStack(errs.NotFile)
+	}
+	file.Obj = &amodel.Object{
+		Name:     name,
+		Size:     info.Size(),
+		Modified: modified,
+	}
+	return file, nil
+}
+
+// fsProgress draws a progress bar of a transfer on stderr, it's nil and
+// draws nothing in json mode or if stderr isn't a terminal
+type fsProgress struct {
+	mu      sync.Mutex
+	name    string
+	total   int64
+	bar     progress.Model
+	start   time.Time
+	last    time.Time
+	printed bool
+}
+
+func newFsProgress(name string, total int64) *fsProgress {
+	if jsonOutput || !isTerminal(os.Stderr) {
+		return nil
+	}
+	return &fsProgress{
+		name:  name,
+		total: total,
+		bar:   progress.New(progress.WithDefaultGradient(), progress.WithWidth(30)),
+		start: time.Now(),
+	}
+}
+
+// Update takes the percentage done, as driver.UpdateProgress
+func (p *fsProgress) Update(percent float64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	if now.Sub(p.last) < 100*time.Millisecond && percent < 100 {
+		return
+	}
+	p.last = now
+	done := int64(percent / 100 * float64(p.total))
+	speed := int64(float64(done) / now.Sub(p.start).Seconds())
+	fmt.Fprintf(os.Stderr, "\r%s %s %s/%s %s/s\x1b[K", p.name, p.bar.ViewAs(percent/100),
+		formatSize(done), formatSize(p.total), formatSize(speed))
+	p.printed = true
+}
+
+func (p *fsProgress) Done() {
+	if p != nil && p.printed {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+type progressWriter struct {
+	w     io.Writer
+	bar   *fsProgress
+	total int64
+	n     int64
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	w.n += int64(n)
+	if w.total > 0 {
+		w.bar.Update(float64(w.n) / float64(w.total) * 100)
+	}
+	return n, err
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func init() {
+	RootCmd.AddCommand(fsCmd)
+	fsCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "print the result as json")
+	fsLsCmd.Flags().Bool("refresh", false, "list from the storage instead of the cache")
+	fsCmd.AddCommand(fsLsCmd, fsCpCmd, fsMvCmd, fsRmCmd, fsCatCmd, fsPutCmd)
+}
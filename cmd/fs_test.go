@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/bootstrap/data"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/testutil"
+)
+
+func init() {
+	testutil.InitDB()
+	data.InitData()
+}
+
+// fsCtx is the context runFs passes to the commands
+func fsCtx(t *testing.T) context.Context {
+	admin, err := op.GetAdmin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return context.WithValue(context.Background(), "user", admin)
+}
+
+func writeLocal(t *testing.T, name, content string) {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readLocal(t *testing.T, name string) string {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", name, err)
+	}
+	return string(data)
+}
+
+func failed(results []fsResult) []string {
+	var errs []string
+	for _, r := range results {
+		if r.Error != "" {
+			errs = append(errs, r.Path+": "+r.Error)
+		}
+	}
+	return errs
+}
+
+func TestFsCp(t *testing.T) {
+	ctx := fsCtx(t)
+	_, a := testutil.CreateLocal(t, "/cp_a")
+	_, b := testutil.CreateLocal(t, "/cp_b")
+	writeLocal(t, filepath.Join(a, "dir", "x.txt"), "x")
+	writeLocal(t, filepath.Join(a, "dir", "sub", "y.txt"), "y")
+	writeLocal(t, filepath.Join(b, "x.txt"), "old")
+
+	// into a directory of another storage, keeping the names
+	results, err := transfer(ctx, []string{"cp_a/dir", "/cp_b/"}, copyObj)
+	if err != nil || len(failed(results)) > 0 {
+		t.Fatalf("failed to copy: %v %v", err, failed(results))
+	}
+	if readLocal(t, filepath.Join(b, "dir", "sub", "y.txt")) != "y" {
+		t.Fatal("the tree was not copied")
+	}
+
+	// onto an existing file
+	results, err = transfer(ctx, []string{"/cp_a/dir/x.txt", "/cp_b/x.txt"}, copyObj)
+	if err != nil || len(failed(results)) > 0 {
+		t.Fatalf("failed to overwrite: %v %v", err, failed(results))
+	}
+	if got := readLocal(t, filepath.Join(b, "x.txt")); got != "x" {
+		t.Fatalf("expected the file to be overwritten, got %q", got)
+	}
+
+	// into its own subtree
+	results, err = transfer(ctx, []string{"/cp_a/dir", "/cp_a/dir/sub/"}, copyObj)
+	if err != nil || len(failed(results)) != 1 || !strings.Contains(results[0].Error, "into itself") {
+		t.Fatalf("expected the copy into the subtree to fail, got %v %+v", err, results)
+	}
+	if _, err = os.Stat(filepath.Join(a, "dir", "sub", "dir")); !os.IsNotExist(err) {
+		t.Fatalf("the subtree was modified: %v", err)
+	}
+
+	// several sources need a directory
+	if _, err = transfer(ctx, []string{"/cp_a/dir/x.txt", "/cp_a/dir/sub/y.txt", "/cp_b/z.txt"}, copyObj); err == nil {
+		t.Fatal("expected several sources onto a file to fail")
+	}
+}
+
+func TestFsMvRm(t *testing.T) {
+	ctx := fsCtx(t)
+	_, dir := testutil.CreateLocal(t, "/mv")
+	writeLocal(t, filepath.Join(dir, "a.txt"), "a")
+	writeLocal(t, filepath.Join(dir, "dir", "b.txt"), "b")
+
+	// move and rename at once
+	results, err := transfer(ctx, []string{"/mv/a.txt", "/mv/dir/c.txt"}, moveObj)
+	if err != nil || len(failed(results)) > 0 {
+		t.Fatalf("failed to move: %v %v", err, failed(results))
+	}
+	if readLocal(t, filepath.Join(dir, "dir", "c.txt")) != "a" {
+		t.Fatal("the file was not moved")
+	}
+	if _, err = os.Stat(filepath.Join(dir, "a.txt")); !os.IsNotExist(err) {
+		t.Fatalf("the source is left: %v", err)
+	}
+
+	// into its own subtree
+	writeLocal(t, filepath.Join(dir, "dir", "sub", "d.txt"), "d")
+	results, err = transfer(ctx, []string{"/mv/dir", "/mv/dir/sub/"}, moveObj)
+	if err != nil || len(failed(results)) != 1 {
+		t.Fatalf("expected the move into the subtree to fail, got %v %+v", err, results)
+	}
+	if readLocal(t, filepath.Join(dir, "dir", "sub", "d.txt")) != "d" {
+		t.Fatal("the subtree was modified")
+	}
+
+	results = removePaths(ctx, []string{"/mv/dir/c.txt", "mv/dir/sub", "/mv/../dir"})
+	if errs := failed(results); len(errs) != 1 || !strings.HasPrefix(errs[0], "/mv/../dir") {
+		t.Fatalf("expected only the relative path to fail, got %v", errs)
+	}
+	for _, p := range []string{"c.txt", "sub"} {
+		if _, err = os.Stat(filepath.Join(dir, "dir", p)); !os.IsNotExist(err) {
+			t.Fatalf("%s was not removed: %v", p, err)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	amodel "github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// roleCmd represents the role command
+var roleCmd = &cobra.Command{
+	Use:   "role",
+	Short: "Manage roles",
+}
+
+// permNames are the names of the permission bits of a role
+var permNames = map[string]uint{
+	"see_hides":               common.PermSeeHides,
+	"access_without_password": common.PermAccessWithoutPassword,
+	"offline_download":        common.PermAddOfflineDownload,
+	"write":                   common.PermWrite,
+	"rename":                  common.PermRename,
+	"move":                    common.PermMove,
+	"copy":                    common.PermCopy,
+	"remove":                  common.PermRemove,
+	"webdav_read":             common.PermWebdavRead,
+	"webdav_manage":           common.PermWebdavManage,
+	"ftp_access":              common.PermFTPAccess,
+	"ftp_manage":              common.PermFTPManage,
+	"read_archives":           common.PermReadArchives,
+	"decompress":              common.PermDecompress,
+	"path_limit":              common.PermPathLimit,
+	"mcp_access":              common.PermMCPAccess,
+	"mcp_manage":              common.PermMCPManage,
+}
+
+// parsePerm parses a permission given as a number or as names separated by ,
+func parsePerm(s string) (int32, error) {
+	if p, err := strconv.ParseInt(s, 0, 32); err == nil {
+		return int32(p), nil
+	}
+	var perm int32
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "all" {
+			for _, bit := range permNames {
+				perm |= 1 << bit
+			}
+			continue
+		}
+		bit, ok := permNames[name]
+		if !ok {
+			return 0, errors.Errorf("unknown permission: %s", name)
+		}
+		perm |= 1 << bit
+	}
+	return perm, nil
+}
+
+func formatPerm(perm int32) string {
+	var names []string
+	for _, name := range sortedPermNames() {
+		if common.HasPermission(perm, permNames[name]) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ",")
+}
+
+var listRoleCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List roles",
+	Run: runCmd(func(cmd *cobra.Command, args []string) error {
+		roles, _, err := op.GetRoles(1, -1)
+		if err != nil {
+			return err
+		}
+		if jsonOutput {
+			return printJSON(roles)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tDEFAULT\tPATH\tPERMISSION")
+		for _, r := range roles {
+			if len(r.PermissionScopes) == 0 {
+				fmt.Fprintf(w, "%d\t%s\t%t\t\t\n", r.ID, r.Name, r.Default)
+			}
+			for i, scope := range r.PermissionScopes {
+				if i == 0 {
+					fmt.Fprintf(w, "%d\t%s\t%t\t", r.ID, r.Name, r.Default)
+				} else {
+					fmt.Fprint(w, "\t\t\t")
+				}
+				fmt.Fprintf(w, "%s\t%s\n", scope.Path, formatPerm(scope.Permission))
+			}
+		}
+		return w.Flush()
+	}),
+}
+
+// getRole gets a role by name or id
+func getRole(s string) (*amodel.Role, error) {
+	if r, err := op.GetRoleByName(s); err == nil {
+		return r, nil
+	}
+	id, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, errors.Errorf("role [%s] not found", s)
+	}
+	return op.GetRole(uint(id))
+}
+
+var addRoleCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add a role",
+	Run: runCmd(func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("name is required")
+		}
+		r := &amodel.Role{Name: args[0]}
+		r.Description, _ = cmd.Flags().GetString("description")
+		r.Default, _ = cmd.Flags().GetBool("default")
+		grants, _ := cmd.Flags().GetStringArray("grant")
+		for _, g := range grants {
+			path, perm, ok := strings.Cut(g, "=")
+			if !ok {
+				return errors.Errorf("invalid grant [%s], should be path=permission", g)
+			}
+			p, err := parsePerm(perm)
+			if err != nil {
+				return err
+			}
+			r.PermissionScopes = append(r.PermissionScopes, amodel.PermissionEntry{Path: path, Permission: p})
+		}
+		if err := op.CreateRole(r); err != nil {
+			return err
+		}
+		return printRole(r)
+	}),
+}
+
+func printRole(r *amodel.Role) error {
+	if jsonOutput {
+		return printJSON(r)
+	}
+	utils.Log.Infof("role [%s] done, id: %d", r.Name, r.ID)
+	return nil
+}
+
+var delRoleCmd = &cobra.Command{
+	Use:   "del <name>",
+	Short: "Delete a role",
+	Run: runCmd(func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("name is required")
+		}
+		r, err := getRole(args[0])
+		if err != nil {
+			return err
+		}
+		if err = op.DeleteRole(r.ID); err != nil {
+			return err
+		}
+		return printRole(r)
+	}),
+}
+
+var grantRoleCmd = &cobra.Command{
+	Use:   "grant <name> <path> <permission>",
+	Short: "Set the permission of a role on a path",
+	Long: `Set the permission of a role on a path, replacing the one it had there.
+The permission is a number or a list of names separated by ,: all, ` + strings.Join(sortedPermNames(), ", ") + `.
+With --revoke the path is removed from the role instead.`,
+	Run: runCmd(func(cmd *cobra.Command, args []string) error {
+		revoke, _ := cmd.Flags().GetBool("revoke")
+		if len(args) < 2 || (!revoke && len(args) < 3) {
+			return errors.New("name, path and permission are required")
+		}
+		r, err := getRole(args[0])
+		if err != nil {
+			return err
+		}
+		// the role is cached, don't change it before saving
+		role := *r
+		role.PermissionScopes = nil
+		path := utils.FixAndCleanPath(args[1])
+		found := false
+		for _, scope := range r.PermissionScopes {
+			if utils.FixAndCleanPath(scope.Path) != path {
+				role.PermissionScopes = append(role.PermissionScopes, scope)
+				continue
+			}
+			found = true
+			if revoke {
+				continue
+			}
+			if scope.Permission, err = parsePerm(args[2]); err != nil {
+				return err
+			}
+			role.PermissionScopes = append(role.PermissionScopes, scope)
+		}
+		if !found {
+			if revoke {
+				return errors.Errorf("role [%s] has no permission on %s", role.Name, path)
+			}
+			perm, err := parsePerm(args[2])
+			if err != nil {
+				return err
+			}
+			role.PermissionScopes = append(role.PermissionScopes, amodel.PermissionEntry{Path: path, Permission: perm})
+		}
+		if err = op.UpdateRole(&role); err != nil {
+			return err
+		}
+		return printRole(&role)
+	}),
+}
+
+func sortedPermNames() []string {
+	names := make([]string, 0, len(permNames))
+	for name := range permNames {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return permNames[names[i]] < permNames[names[j]] })
+	return names
+}
+
+func init() {
+	RootCmd.AddCommand(roleCmd)
+	roleCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "print the result as json")
+	addRoleCmd.Flags().String("description", "", "description of the role")
+	addRoleCmd.Flags().Bool("default", false, "make it the default role of new users")
+	addRoleCmd.Flags().StringArray("grant", nil, "permission on a path as path=permission, can be repeated")
+	grantRoleCmd.Flags().Bool("revoke", false, "remove the permission of the role on the path")
+	roleCmd.AddCommand(listRoleCmd, addRoleCmd, delRoleCmd, grantRoleCmd)
+}
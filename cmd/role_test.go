@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alist-org/alist/v3/server/common"
+)
+
+func TestParsePerm(t *testing.T) {
+	perm, err := parsePerm("write, rename,move")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !common.HasPermission(perm, common.PermWrite) || !common.HasPermission(perm, common.PermMove) || common.HasPermission(perm, common.PermRemove) {
+		t.Fatalf("unexpected permission: %b", perm)
+	}
+	if s := formatPerm(perm); s != "write,rename,move" {
+		t.Fatalf("unexpected names: %s", s)
+	}
+	if again, err := parsePerm(formatPerm(perm)); err != nil || again != perm {
+		t.Fatalf("the names don't round trip: %b %v", again, err)
+	}
+	if perm, err = parsePerm("0x8"); err != nil || perm != 8 {
+		t.Fatalf("unexpected number: %d %v", perm, err)
+	}
+	if perm, err = parsePerm("all"); err != nil || len(strings.Split(formatPerm(perm), ",")) != len(permNames) {
+		t.Fatalf("expected all the permissions: %b %v", perm, err)
+	}
+	if _, err = parsePerm("write,fly"); err == nil {
+		t.Fatal("expected an unknown permission to fail")
+	}
+	if s := formatPerm(0); s != "none" {
+		t.Fatalf("unexpected names: %s", s)
+	}
+}
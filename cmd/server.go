@@ -22,7 +22,9 @@ import (
 	"github.com/alist-org/alist/v3/internal/fs"
 	"github.com/alist-org/alist/v3/pkg/utils"
 	"github.com/alist-org/alist/v3/server"
+	"github.com/alist-org/alist/v3/server/dlna"
 	mcpserver "github.com/alist-org/alist/v3/server/mcp"
+	"github.com/alist-org/alist/v3/server/nfs"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -42,9 +44,11 @@ the address is defined in config file`,
 			utils.Log.Infof("delayed start for %d seconds", conf.Conf.DelayedStart)
 			time.Sleep(time.Duration(conf.Conf.DelayedStart) * time.Second)
 		}
+		bootstrap.ApplyManifest()
 		bootstrap.InitOfflineDownloadTools()
 		bootstrap.LoadStorages()
 		bootstrap.InitTaskManager()
+		bootstrap.InitRSSSubscriptions()
 		bootstrap.InitFRP()
 		if !flags.Debug && !flags.Dev {
 			gin.SetMode(gin.ReleaseMode)
@@ -160,6 +164,53 @@ the address is defined in config file`,
 				}()
 			}
 		}
+		var nfsServer *nfs.Server
+		if conf.Conf.NFS.Listen != "" && conf.Conf.NFS.Enable {
+			nfsDriver, err := server.NewNfsDriver()
+			if err == nil {
+				nfsServer, err = server.NewNfsServer(nfsDriver)
+			}
+			if err != nil {
+				utils.Log.Fatalf("failed to start nfs driver: %s", err.Error())
+			} else {
+				utils.Log.Infof("start nfs server on %s", conf.Conf.NFS.Listen)
+				go func() {
+					if err := nfsServer.ListenAndServe(); err != nil {
+						utils.Log.Fatalf("problem nfs server listening: %s", err.Error())
+					}
+				}()
+			}
+		}
+		var dlnaServer *dlna.Server
+		if conf.Conf.DLNA.Listen != "" && conf.Conf.DLNA.Enable {
+			var err error
+			dlnaServer, err = dlna.NewServer()
+			if err != nil {
+				utils.Log.Fatalf("failed to start dlna server: %s", err.Error())
+			} else {
+				utils.Log.Infof("start dlna server on %s", conf.Conf.DLNA.Listen)
+				go func() {
+					if err := dlnaServer.ListenAndServe(); err != nil {
+						utils.Log.Fatalf("problem dlna server listening: %s", err.Error())
+					}
+				}()
+			}
+		}
+		var metricsSrv *http.Server
+		if conf.Conf.Metrics.Port != -1 && conf.Conf.Metrics.Enable {
+			metricsr := gin.New()
+			metricsr.Use(gin.RecoveryWithWriter(log.StandardLogger().Out))
+			server.InitMetrics(metricsr)
+			metricsBase := fmt.Sprintf("%s:%d", conf.Conf.Scheme.Address, conf.Conf.Metrics.Port)
+			utils.Log.Infof("start metrics server @ %s", metricsBase)
+			metricsSrv = &http.Server{Addr: metricsBase, Handler: metricsr}
+			go func() {
+				err := metricsSrv.ListenAndServe()
+				if err != nil && !errors.Is(err, http.ErrServerClosed) {
+					utils.Log.Fatalf("failed to start metrics server: %s", err.Error())
+				}
+			}()
+		}
 		var mcpHttpSrv *http.Server
 		if conf.Conf.MCP.Port != -1 && conf.Conf.MCP.Enable {
 			mcpHandler := mcpserver.NewHTTPHandler()
@@ -184,6 +235,17 @@ the address is defined in config file`,
 		utils.Log.Println("Shutdown server...")
 		fs.ArchiveContentUploadTaskManager.RemoveAll()
 		frp.Instance.Stop()
+		if nfsServer != nil {
+			// files not committed by the clients are uploaded before the database is closed
+			if err := nfsServer.Close(); err != nil {
+				utils.Log.Errorf("NFS server shutdown err: %s", err.Error())
+			}
+		}
+		if dlnaServer != nil {
+			if err := dlnaServer.Close(); err != nil {
+				utils.Log.Errorf("DLNA server shutdown err: %s", err.Error())
+			}
+		}
 		Release()
 		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 		defer cancel()
@@ -234,6 +296,15 @@ the address is defined in config file`,
 				}
 			}()
 		}
+		if metricsSrv != nil {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := metricsSrv.Shutdown(ctx); err != nil {
+					utils.Log.Fatal("metrics server shutdown err: ", err)
+				}
+			}()
+		}
 		if conf.Conf.MCP.Port != -1 && conf.Conf.MCP.Enable && mcpHttpSrv != nil {
 			wg.Add(1)
 			go func() {
@@ -2,14 +2,24 @@ package cmd
 
 import (
 	"crypto/tls"
+	"encoding/csv"
 	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/alist-org/alist/v3/internal/conf"
+	amodel "github.com/alist-org/alist/v3/internal/model"
 	"github.com/alist-org/alist/v3/internal/op"
 	"github.com/alist-org/alist/v3/internal/setting"
 	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/alist-org/alist/v3/pkg/utils/random"
 	"github.com/go-resty/resty/v2"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
 )
 
 func DelAdminCacheOnline() {
@@ -50,3 +60,422 @@ func DelUserCacheOnline(username string) {
 	}
 	utils.Log.Debugf("[del_user_cache_online] del user [%s] cache success", username)
 }
+
+// userCmd represents the user command
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage users",
+	Long: `Manage users directly in the database, e.g. when the web UI can't be
+reached after a misconfigured SSO. A running server is asked to drop its
+cache of the changed users.`,
+}
+
+type userInfo struct {
+	ID       uint     `json:"id"`
+	Username string   `json:"username"`
+	Roles    []string `json:"roles"`
+	BasePath string   `json:"base_path"`
+	Disabled bool     `json:"disabled"`
+	SsoID    string   `json:"sso_id,omitempty"`
+	OTP      bool     `json:"otp"`
+}
+
+type userResult struct {
+	Username string `json:"username"`
+	// Password is only set if it was generated
+	Password string `json:"password,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+var listUserCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List users",
+	Run: runCmd(func(cmd *cobra.Command, args []string) error {
+		users, _, err := op.GetUsers(1, -1)
+		if err != nil {
+			return err
+		}
+		infos := make([]userInfo, 0, len(users))
+		for _, u := range users {
+			infos = append(infos, userInfo{
+				ID:       u.ID,
+				Username: u.Username,
+				Roles:    roleNames(u.Role),
+				BasePath: u.BasePath,
+				Disabled: u.Disabled,
+				SsoID:    u.SsoID,
+				OTP:      u.OtpSecret != "",
+			})
+		}
+		if jsonOutput {
+			return printJSON(infos)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tUSERNAME\tROLES\tBASE PATH\tDISABLED\tOTP")
+		for _, u := range infos {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%t\t%t\n", u.ID, u.Username, strings.Join(u.Roles, ","), u.BasePath, u.Disabled, u.OTP)
+		}
+		return w.Flush()
+	}),
+}
+
+// roleNames returns the names of the roles, the id if a role is missing
+func roleNames(ids amodel.Roles) []string {
+	names := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if r, err := op.GetRole(uint(id)); err == nil {
+			names = append(names, r.Name)
+		} else {
+			names = append(names, strconv.Itoa(id))
+		}
+	}
+	return names
+}
+
+// parseRoles resolves a list of role names or ids separated by , or ;
+func parseRoles(s string) (amodel.Roles, error) {
+	var roles amodel.Roles
+	for _, name := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' }) {
+		name = strings.TrimSpace(name)
+		if r, err := op.GetRoleByName(name); err == nil {
+			roles = append(roles, int(r.ID))
+			continue
+		}
+		id, err := strconv.Atoi(name)
+		if err != nil {
+			return nil, errors.Errorf("role [%s] not found", name)
+		}
+		if _, err = op.GetRole(uint(id)); err != nil {
+			return nil, errors.Errorf("role [%s] not found", name)
+		}
+		roles = append(roles, id)
+	}
+	return roles, nil
+}
+
+var addUserCmd = &cobra.Command{
+	Use:   "add [username]",
+	Short: "Add a user, or import users from a csv file",
+	Long: `Add a user, a random password is generated if none is given.
+With --csv, users are imported from a csv file (- for stdin) with the header
+username,password,roles,base_path,disabled where only username is required.`,
+	Run: runCmd(func(cmd *cobra.Command, args []string) error {
+		csvFile, _ := cmd.Flags().GetString("csv")
+		var users []newUser
+		if csvFile != "" {
+			var err error
+			if users, err = readUsersCSV(csvFile); err != nil {
+				return err
+			}
+		} else {
+			if len(args) < 1 {
+				return errors.New("username is required")
+			}
+			u := newUser{username: args[0]}
+			u.password, _ = cmd.Flags().GetString("password")
+			u.roles, _ = cmd.Flags().GetString("role")
+			u.basePath, _ = cmd.Flags().GetString("base-path")
+			u.disabled, _ = cmd.Flags().GetBool("disabled")
+			users = append(users, u)
+		}
+		var results []userResult
+		for _, u := range users {
+			results = append(results, addUser(u))
+		}
+		return reportUsers(results)
+	}),
+}
+
+type newUser struct {
+	username, password, roles, basePath string
+	disabled                            bool
+}
+
+func readUsersCSV(name string) ([]newUser, error) {
+	var in io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		in = f
+	}
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed read csv")
+	}
+	if len(records) == 0 {
+		return nil, errors.New("csv is empty")
+	}
+	columns := make(map[string]int)
+	for i, c := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(c))] = i
+	}
+	if _, ok := columns["username"]; !ok {
+		return nil, errors.New("csv has no username column")
+	}
+	get := func(record []string, column string) string {
+		if i, ok := columns[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	var users []newUser
+	for _, record := range records[1:] {
+		u := newUser{
+			username: get(record, "username"),
+			password: get(record, "password"),
+			roles:    get(record, "roles"),
+			basePath: get(record, "base_path"),
+		}
+		if d := get(record, "disabled"); d != "" {
+			if u.disabled, err = strconv.ParseBool(d); err != nil {
+				return nil, errors.Errorf("invalid disabled value of user [%s]: %s", u.username, d)
+			}
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+func addUser(nu newUser) userResult {
+	res := userResult{Username: nu.username}
+	err := func() error {
+		if nu.username == "" {
+			return errors.New("username is required")
+		}
+		u := &amodel.User{Username: nu.username, BasePath: nu.basePath, Disabled: nu.disabled, Authn: "[]"}
+		var err error
+		if u.Role, err = parseRoles(nu.roles); err != nil {
+			return err
+		}
+		if len(u.Role) == 0 {
+			u.Role = amodel.Roles{op.GetDefaultRoleID()}
+		}
+		if u.IsAdmin() || u.IsGuest() {
+			return errors.New("admin or guest user can not be created")
+		}
+		if nu.password == "" {
+			nu.password = random.String(8)
+			res.Password = nu.password
+		}
+		u.SetPassword(nu.password)
+		if err = op.CreateUser(u); err != nil {
+			return err
+		}
+		// CreateUser takes the base path of the role
+		if nu.basePath != "" && u.BasePath != utils.FixAndCleanPath(nu.basePath) {
+			u.BasePath = nu.basePath
+			return op.UpdateUser(u)
+		}
+		return nil
+	}()
+	if err != nil {
+		res.Error = err.Error()
+		res.Password = ""
+	}
+	return res
+}
+
+// reportUsers prints the results and fails if any of them failed
+func reportUsers(results []userResult) error {
+	failed := 0
+	for _, r := range results {
+		if r.Error != "" {
+			failed++
+		}
+		if jsonOutput {
+			continue
+		}
+		if r.Error != "" {
+			utils.Log.Errorf("failed user [%s]: %s", r.Username, r.Error)
+		} else if r.Password != "" {
+			utils.Log.Infof("user [%s] done, password: %s", r.Username, r.Password)
+		} else {
+			utils.Log.Infof("user [%s] done", r.Username)
+		}
+	}
+	if jsonOutput {
+		if err := printJSON(results); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return errors.Errorf("%d of %d users failed", failed, len(results))
+	}
+	return nil
+}
+
+// updateUsers applies fn to the named users, saves them and drops their
+// cache on a running server
+func updateUsers(names []string, fn func(u *amodel.User, res *userResult) error) error {
+	if len(names) == 0 {
+		return errors.New("username is required")
+	}
+	var results []userResult
+	for _, name := range names {
+		res := userResult{Username: name}
+		u, err := op.GetUserByName(name)
+		if err == nil {
+			// the user is cached, don't change it before saving
+			user := *u
+			if err = fn(&user, &res); err == nil {
+				err = op.UpdateUser(&user)
+			}
+		}
+		if err != nil {
+			res.Error = err.Error()
+			res.Password = ""
+		} else {
+			DelUserCacheOnline(name)
+		}
+		results = append(results, res)
+	}
+	return reportUsers(results)
+}
+
+var delUserCmd = &cobra.Command{
+	Use:   "del <username>...",
+	Short: "Delete users",
+	Run: runCmd(func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("username is required")
+		}
+		var results []userResult
+		for _, name := range args {
+			res := userResult{Username: name}
+			u, err := op.GetUserByName(name)
+			if err == nil {
+				err = op.DeleteUserById(u.ID)
+			}
+			if err != nil {
+				res.Error = err.Error()
+			} else {
+				DelUserCacheOnline(name)
+			}
+			results = append(results, res)
+		}
+		return reportUsers(results)
+	}),
+}
+
+var passwdUserCmd = &cobra.Command{
+	Use:   "passwd <username> [password]",
+	Short: "Set the password of a user, a random one if none is given",
+	Run: runCmd(func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("username is required")
+		}
+		return updateUsers(args[:1], func(u *amodel.User, res *userResult) error {
+			if len(args) > 1 {
+				u.SetPassword(args[1])
+			} else {
+				res.Password = random.String(8)
+				u.SetPassword(res.Password)
+			}
+			return nil
+		})
+	}),
+}
+
+// keepEnabledAdmin fails if u is the last enabled admin
+func keepEnabledAdmin(u *amodel.User) error {
+	if !u.IsAdmin() {
+		return nil
+	}
+	count, err := op.CountEnabledAdminsExcluding(u.ID)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("at least one enabled admin must be kept")
+	}
+	return nil
+}
+
+var disableUserCmd = &cobra.Command{
+	Use:   "disable <username>...",
+	Short: "Disable users",
+	Run: runCmd(func(cmd *cobra.Command, args []string) error {
+		return updateUsers(args, disableUser)
+	}),
+}
+
+func disableUser(u *amodel.User, res *userResult) error {
+	u.Disabled = true
+	return keepEnabledAdmin(u)
+}
+
+var enableUserCmd = &cobra.Command{
+	Use:   "enable <username>...",
+	Short: "Enable users",
+	Run: runCmd(func(cmd *cobra.Command, args []string) error {
+		return updateUsers(args, func(u *amodel.User, res *userResult) error {
+			u.Disabled = false
+			return nil
+		})
+	}),
+}
+
+var setRoleUserCmd = &cobra.Command{
+	Use:   "set-role <username> <role>[,role...]",
+	Short: "Set the roles of a user, by name or id",
+	Run: runCmd(func(cmd *cobra.Command, args []string) error {
+		if len(args) < 2 {
+			return errors.New("username and roles are required")
+		}
+		roles, err := parseRoles(args[1])
+		if err != nil {
+			return err
+		}
+		if len(roles) == 0 {
+			return errors.New("roles are required")
+		}
+		return updateUsers(args[:1], setUserRoles(roles))
+	}),
+}
+
+// setUserRoles replaces the roles of a user, the admin and guest roles
+// can't be given or taken away
+func setUserRoles(roles amodel.Roles) func(u *amodel.User, res *userResult) error {
+	return func(u *amodel.User, res *userResult) error {
+		if u.IsAdmin() {
+			return errors.New("cannot change role of admin user")
+		}
+		u.Role = roles
+		if u.IsAdmin() || u.IsGuest() {
+			return errors.New("cannot assign admin or guest role to user")
+		}
+		return nil
+	}
+}
+
+var setBasePathUserCmd = &cobra.Command{
+	Use:   "set-base-path <username> <path>",
+	Short: "Set the base path of a user",
+	Run: runCmd(func(cmd *cobra.Command, args []string) error {
+		if len(args) < 2 {
+			return errors.New("username and path are required")
+		}
+		return updateUsers(args[:1], func(u *amodel.User, res *userResult) error {
+			u.BasePath = args[1]
+			return nil
+		})
+	}),
+}
+
+func init() {
+	RootCmd.AddCommand(userCmd)
+	userCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "print the result as json")
+	addUserCmd.Flags().String("password", "", "password of the user, random if empty")
+	addUserCmd.Flags().String("role", "", "roles of the user by name or id, the default role if empty")
+	addUserCmd.Flags().String("base-path", "", "base path of the user")
+	addUserCmd.Flags().Bool("disabled", false, "add the user disabled")
+	addUserCmd.Flags().String("csv", "", "import the users from a csv file, - for stdin")
+	userCmd.AddCommand(listUserCmd, addUserCmd, delUserCmd, passwdUserCmd, disableUserCmd,
+		enableUserCmd, setRoleUserCmd, setBasePathUserCmd)
+}
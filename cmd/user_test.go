@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	amodel "github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+)
+
+func TestAddUsersFromCSV(t *testing.T) {
+	if err := op.CreateRole(&amodel.Role{Name: "csv_editor", PermissionScopes: []amodel.PermissionEntry{{Path: "/team", Permission: 0}}}); err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(t.TempDir(), "users.csv")
+	csv := "Username, Password, Roles, Base_Path, Disabled\n" +
+		"csv_alice,secret,csv_editor,/team/alice,false\n" +
+		"csv_bob,,csv_editor,,true\n" +
+		"csv_admin,,admin,,\n" +
+		"csv_carol,,missing,,\n"
+	if err := os.WriteFile(name, []byte(csv), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	users, err := readUsersCSV(name)
+	if err != nil || len(users) != 4 {
+		t.Fatalf("failed to read csv: %d %v", len(users), err)
+	}
+	var results []userResult
+	for _, u := range users {
+		results = append(results, addUser(u))
+	}
+	if results[0].Error != "" || results[0].Password != "" {
+		t.Fatalf("unexpected result of alice: %+v", results[0])
+	}
+	if results[1].Error != "" || results[1].Password == "" {
+		t.Fatalf("expected bob to get a random password: %+v", results[1])
+	}
+	if !strings.Contains(results[2].Error, "admin or guest") || !strings.Contains(results[3].Error, "not found") {
+		t.Fatalf("expected the admin and the unknown role to fail: %+v %+v", results[2], results[3])
+	}
+	if reportUsers(results) == nil {
+		t.Fatal("expected the report to fail")
+	}
+
+	alice, err := op.GetUserByName("csv_alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alice.ValidateRawPassword("secret") != nil || alice.BasePath != "/team/alice" || alice.Disabled || len(alice.Role) != 1 {
+		t.Fatalf("unexpected alice: %+v", alice)
+	}
+	bob, err := op.GetUserByName("csv_bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bob.Disabled || bob.ValidateRawPassword(results[1].Password) != nil {
+		t.Fatalf("unexpected bob: %+v", bob)
+	}
+	if _, err = op.GetUserByName("csv_admin"); err == nil {
+		t.Fatal("the admin of the csv was created")
+	}
+
+	for _, bad := range []string{"name\ncsv_dave\n", "username,disabled\ncsv_dave,maybe\n", ""} {
+		if err = os.WriteFile(name, []byte(bad), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = readUsersCSV(name); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestKeepLastAdmin(t *testing.T) {
+	admin, err := op.GetAdmin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = updateUsers([]string{admin.Username}, disableUser); err == nil {
+		t.Fatal("expected disabling the last admin to fail")
+	}
+	if admin, err = op.GetAdmin(); err != nil || admin.Disabled {
+		t.Fatalf("the last admin was disabled: %v", err)
+	}
+	adminRole, err := op.GetRoleByName("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = updateUsers([]string{admin.Username}, setUserRoles(amodel.Roles{op.GetDefaultRoleID()})); err == nil {
+		t.Fatal("expected changing the roles of the admin to fail")
+	}
+	if admin, err = op.GetAdmin(); err != nil || !admin.IsAdmin() {
+		t.Fatalf("the admin lost its role: %v", err)
+	}
+
+	// nobody else becomes admin through set-role
+	user := &amodel.User{Username: "role_user", Role: amodel.Roles{op.GetDefaultRoleID()}, Authn: "[]"}
+	if err = op.CreateUser(user.SetPassword("pwd")); err != nil {
+		t.Fatal(err)
+	}
+	if err = updateUsers([]string{user.Username}, setUserRoles(amodel.Roles{int(adminRole.ID)})); err == nil {
+		t.Fatal("expected the admin role to be refused")
+	}
+	if u, err := op.GetUserByName(user.Username); err != nil || u.IsAdmin() {
+		t.Fatalf("the user became admin: %v", err)
+	}
+}
@@ -53,7 +53,7 @@ func (d *Pan115) List(ctx context.Context, dir model.Obj, args model.ListArgs) (
 	if err := d.WaitLimit(ctx); err != nil {
 		return nil, err
 	}
-	files, err := d.getFiles(dir.GetID())
+	files, err := d.getFiles(ctx, dir.GetID())
 	if err != nil && !errors.Is(err, driver115.ErrNotExist) {
 		return nil, err
 	}
@@ -102,7 +102,7 @@ func (d *Pan115) MakeDir(ctx context.Context, parentDir model.Obj, dirName strin
 	if err != nil {
 		return nil, err
 	}
-	f, err := d.getNewFile(result.FileID)
+	f, err := d.getNewFile(ctx, result.FileID)
 	if err != nil {
 		return nil, nil
 	}
@@ -116,7 +116,7 @@ func (d *Pan115) Move(ctx context.Context, srcObj, dstDir model.Obj) (model.Obj,
 	if err := d.client.Move(dstDir.GetID(), srcObj.GetID()); err != nil {
 		return nil, err
 	}
-	f, err := d.getNewFile(srcObj.GetID())
+	f, err := d.getNewFile(ctx, srcObj.GetID())
 	if err != nil {
 		return nil, nil
 	}
@@ -130,7 +130,7 @@ func (d *Pan115) Rename(ctx context.Context, srcObj model.Obj, newName string) (
 	if err := d.client.Rename(srcObj.GetID(), newName); err != nil {
 		return nil, err
 	}
-	f, err := d.getNewFile((srcObj.GetID()))
+	f, err := d.getNewFile(ctx, (srcObj.GetID()))
 	if err != nil {
 		return nil, nil
 	}
@@ -207,7 +207,7 @@ func (d *Pan115) Put(ctx context.Context, dstDir model.Obj, stream model.FileStr
 	if matched, err := fastInfo.Ok(); err != nil {
 		return nil, err
 	} else if matched {
-		f, err := d.getNewFileByPickCode(fastInfo.PickCode)
+		f, err := d.getNewFileByPickCode(ctx, fastInfo.PickCode)
 		if err != nil {
 			return nil, nil
 		}
@@ -227,7 +227,7 @@ func (d *Pan115) Put(ctx context.Context, dstDir model.Obj, stream model.FileStr
 		}
 	}
 
-	file, err := d.getNewFile(uploadResult.Data.FileID)
+	file, err := d.getNewFile(ctx, uploadResult.Data.FileID)
 	if err != nil {
 		return nil, nil
 	}
@@ -16,6 +16,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/alist-org/alist/v3/drivers/base"
 	"github.com/alist-org/alist/v3/internal/conf"
 	"github.com/alist-org/alist/v3/internal/driver"
 	"github.com/alist-org/alist/v3/internal/model"
@@ -53,6 +54,7 @@ func (d *Pan115) login() error {
 		driver115.UA(d.getUA()),
 		func(c *driver115.Pan115Client) {
 			c.Client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: conf.Conf.TlsInsecureSkipVerify})
+			base.WrapTransport(c.Client)
 		},
 	}
 	d.client = driver115.New(opts...)
@@ -77,7 +79,7 @@ func (d *Pan115) login() error {
 	return d.client.LoginCheck()
 }
 
-func (d *Pan115) getFiles(fileId string) ([]FileObj, error) {
+func (d *Pan115) getFiles(ctx context.Context, fileId string) ([]FileObj, error) {
 	res := make([]FileObj, 0)
 	if d.PageSize <= 0 {
 		d.PageSize = driver115.FileListLimit
@@ -95,7 +97,7 @@ func (d *Pan115) getFiles(fileId string) ([]FileObj, error) {
 
 	offset := int64(0)
 	for i := 0; ; i++ {
-		result, err := d.getFilesPageWithThumb(fileId, opts.ApiURLs[i%len(opts.ApiURLs)], limit, offset)
+		result, err := d.getFilesPageWithThumb(ctx, fileId, opts.ApiURLs[i%len(opts.ApiURLs)], limit, offset)
 		if err != nil {
 			return nil, err
 		}
@@ -111,8 +113,8 @@ func (d *Pan115) getFiles(fileId string) ([]FileObj, error) {
 	return res, nil
 }
 
-func (d *Pan115) getNewFile(fileId string) (*FileObj, error) {
-	fileInfo, err := d.getFileInfoWithThumb("file_id", fileId)
+func (d *Pan115) getNewFile(ctx context.Context, fileId string) (*FileObj, error) {
+	fileInfo, err := d.getFileInfoWithThumb(ctx, "file_id", fileId)
 	if err != nil {
 		return nil, err
 	}
@@ -120,8 +122,8 @@ func (d *Pan115) getNewFile(fileId string) (*FileObj, error) {
 	return &file, nil
 }
 
-func (d *Pan115) getNewFileByPickCode(pickCode string) (*FileObj, error) {
-	fileInfo, err := d.getFileInfoWithThumb("pick_code", pickCode)
+func (d *Pan115) getNewFileByPickCode(ctx context.Context, pickCode string) (*FileObj, error) {
+	fileInfo, err := d.getFileInfoWithThumb(ctx, "pick_code", pickCode)
 	if err != nil {
 		return nil, err
 	}
@@ -142,9 +144,10 @@ func fileObjFromInfo(fileInfo *fileInfoWithThumb) FileObj {
 	}
 }
 
-func (d *Pan115) getFileInfoWithThumb(queryKey, queryVal string) (*fileInfoWithThumb, error) {
+func (d *Pan115) getFileInfoWithThumb(ctx context.Context, queryKey, queryVal string) (*fileInfoWithThumb, error) {
 	result := getFileInfoResponseWithThumb{}
 	req := d.client.NewRequest().
+		SetContext(ctx).
 		SetQueryParam(queryKey, queryVal).
 		ForceContentType("application/json;charset=UTF-8").
 		SetResult(&result)
@@ -158,7 +161,7 @@ func (d *Pan115) getFileInfoWithThumb(queryKey, queryVal string) (*fileInfoWithT
 	return result.Files[0], nil
 }
 
-func (d *Pan115) getFilesPageWithThumb(dirID, apiURL string, limit, offset int64) (*fileListRespWithThumb, error) {
+func (d *Pan115) getFilesPageWithThumb(ctx context.Context, dirID, apiURL string, limit, offset int64) (*fileListRespWithThumb, error) {
 	if dirID == "" {
 		dirID = "0"
 	}
@@ -178,6 +181,7 @@ func (d *Pan115) getFilesPageWithThumb(dirID, apiURL string, limit, offset int64
 		"fc_mix":           "0",
 	}
 	req := d.client.NewRequest().
+		SetContext(ctx).
 		ForceContentType("application/json;charset=UTF-8").
 		SetQueryParams(params).
 		SetResult(&result)
@@ -5,11 +5,14 @@ import (
 	"errors"
 	stdpath "path"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/alist-org/alist/v3/internal/driver"
 	"github.com/alist-org/alist/v3/internal/errs"
 	"github.com/alist-org/alist/v3/internal/fs"
 	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/cron"
 	"github.com/alist-org/alist/v3/pkg/utils"
 )
 
@@ -19,6 +22,10 @@ type Alias struct {
 	pathMap     map[string][]string
 	autoFlatten bool
 	oneKey      string
+	rrMu        sync.Mutex
+	rr          map[string]int
+	tiering     atomic.Bool
+	cron        *cron.Cron
 }
 
 func (d *Alias) Config() driver.Config {
@@ -51,10 +58,19 @@ func (d *Alias) Init(ctx context.Context) error {
 		d.oneKey = ""
 		d.autoFlatten = false
 	}
+	d.rr = make(map[string]int)
+	if d.TierAfter > 0 && d.union() {
+		d.cron = cron.NewCron(tierInterval)
+		d.cron.Do(d.tier)
+	}
 	return nil
 }
 
 func (d *Alias) Drop(ctx context.Context) error {
+	if d.cron != nil {
+		d.cron.Stop()
+		d.cron = nil
+	}
 	d.pathMap = nil
 	return nil
 }
@@ -72,11 +88,21 @@ func (d *Alias) Get(ctx context.Context, path string) (model.Obj, error) {
 	if !ok {
 		return nil, errs.ObjectNotFound
 	}
+	var found model.Obj
 	for _, dst := range dsts {
 		obj, err := d.get(ctx, path, dst, sub)
-		if err == nil {
+		if err != nil {
+			continue
+		}
+		if d.SearchPolicy != policyNewest {
 			return obj, nil
 		}
+		if found == nil || obj.ModTime().After(found.ModTime()) {
+			found = obj
+		}
+	}
+	if found != nil {
+		return found, nil
 	}
 	return nil, errs.ObjectNotFound
 }
@@ -99,16 +125,15 @@ func (d *Alias) List(ctx context.Context, dir model.Obj, args model.ListArgs) ([
 			objs = append(objs, tmp...)
 		}
 	}
-	return objs, nil
+	return d.resolveConflicts(objs), nil
 }
 
 func (d *Alias) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
 	root, sub := d.getRootAndPath(file.GetPath())
-	dsts, ok := d.pathMap[root]
-	if !ok {
+	if _, ok := d.pathMap[root]; !ok {
 		return nil, errs.ObjectNotFound
 	}
-	for _, dst := range dsts {
+	for _, dst := range d.searchDsts(ctx, root, sub) {
 		link, err := d.link(ctx, dst, sub, args)
 		if err == nil {
 			if !args.Redirect && len(link.URL) > 0 {
@@ -131,6 +156,13 @@ func (d *Alias) MakeDir(ctx context.Context, parentDir model.Obj, dirName string
 	if !d.Writable {
 		return errs.PermissionDenied
 	}
+	if d.union() {
+		dirPath, err := d.createPath(ctx, parentDir)
+		if err != nil {
+			return err
+		}
+		return fs.MakeDir(ctx, stdpath.Join(dirPath, dirName))
+	}
 	reqPath, err := d.getReqPath(ctx, parentDir, true)
 	if err == nil {
 		return fs.MakeDir(ctx, stdpath.Join(*reqPath, dirName))
@@ -152,6 +184,16 @@ func (d *Alias) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
 	if err != nil {
 		return err
 	}
+	if d.union() {
+		dstPath, err := d.sameStoragePath(ctx, dstDir, *srcPath)
+		if err != nil {
+			return err
+		}
+		if err := fs.MakeDir(ctx, dstPath); err != nil {
+			return err
+		}
+		return fs.Move(ctx, *srcPath, dstPath)
+	}
 	dstPath, err := d.getReqPath(ctx, dstDir, true)
 	if errs.IsNotImplement(err) {
 		return errors.New("same-name dirs cannot be moved to")
@@ -166,6 +208,18 @@ func (d *Alias) Rename(ctx context.Context, srcObj model.Obj, newName string) er
 	if !d.Writable {
 		return errs.PermissionDenied
 	}
+	if d.union() && srcObj.IsDir() {
+		paths, err := d.existingPaths(ctx, srcObj)
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			if err := fs.Rename(ctx, path, newName); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 	reqPath, err := d.getReqPath(ctx, srcObj, false)
 	if err == nil {
 		return fs.Rename(ctx, *reqPath, newName)
@@ -187,6 +241,14 @@ func (d *Alias) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
 	if err != nil {
 		return err
 	}
+	if d.union() {
+		dstPath, err := d.createPath(ctx, dstDir)
+		if err != nil {
+			return err
+		}
+		_, err = fs.Copy(ctx, *srcPath, dstPath)
+		return err
+	}
 	dstPath, err := d.getReqPath(ctx, dstDir, true)
 	if errs.IsNotImplement(err) {
 		return errors.New("same-name dirs cannot be copied to")
@@ -202,6 +264,18 @@ func (d *Alias) Remove(ctx context.Context, obj model.Obj) error {
 	if !d.Writable {
 		return errs.PermissionDenied
 	}
+	if d.union() && obj.IsDir() {
+		paths, err := d.existingPaths(ctx, obj)
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			if err := fs.Remove(ctx, path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 	reqPath, err := d.getReqPath(ctx, obj, false)
 	if err == nil {
 		return fs.Remove(ctx, *reqPath)
@@ -216,6 +290,13 @@ func (d *Alias) Put(ctx context.Context, dstDir model.Obj, s model.FileStreamer,
 	if !d.Writable {
 		return errs.PermissionDenied
 	}
+	if d.union() {
+		dirPath, err := d.createPath(ctx, dstDir)
+		if err != nil {
+			return err
+		}
+		return fs.PutDirectly(ctx, dirPath, s)
+	}
 	reqPath, err := d.getReqPath(ctx, dstDir, true)
 	if err == nil {
 		return fs.PutDirectly(ctx, *reqPath, s)
@@ -230,6 +311,13 @@ func (d *Alias) PutURL(ctx context.Context, dstDir model.Obj, name, url string)
 	if !d.Writable {
 		return errs.PermissionDenied
 	}
+	if d.union() {
+		dirPath, err := d.createPath(ctx, dstDir)
+		if err != nil {
+			return err
+		}
+		return fs.PutURL(ctx, dirPath, name, url)
+	}
 	reqPath, err := d.getReqPath(ctx, dstDir, true)
 	if err == nil {
 		return fs.PutURL(ctx, *reqPath, name, url)
@@ -14,6 +14,11 @@ type Addition struct {
 	DownloadConcurrency int    `json:"download_concurrency" default:"0" required:"false" type:"number" help:"Need to enable proxy"`
 	DownloadPartSize    int    `json:"download_part_size" default:"0" type:"number" required:"false" help:"Need to enable proxy. Unit: KB"`
 	Writable            bool   `json:"writable" type:"bool" default:"false"`
+	CreatePolicy        string `json:"create_policy" type:"select" options:"off,first_found,most_free_space,least_used_space,round_robin" default:"off" help:"Need writable. Which of the paths of a name new files and dirs go to, off writes to the only path holding the parent dir. The space policies need targets reporting their space, e.g. local, onedrive, google drive, dropbox, aliyundrive open and baidu netdisk"`
+	PathPreserving      bool   `json:"path_preserving" type:"bool" default:"false" help:"Only create in paths already holding the parent dir"`
+	SearchPolicy        string `json:"search_policy" type:"select" options:"first_found,newest" default:"first_found" help:"Which path a file is read from when several of them hold it"`
+	ConflictStrategy    string `json:"conflict_strategy" type:"select" options:"keep_all,first_found,newest" default:"keep_all" help:"How same-name files of several paths are listed, same-name dirs are merged unless keep_all"`
+	TierAfter           int    `json:"tier_after" type:"number" default:"0" help:"Need a create policy. Move files not modified for this many days from the first path of a name to the others, 0 to disable"`
 }
 
 var config = driver.Config{
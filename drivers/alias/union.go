@@ -0,0 +1,274 @@
+package alias
+
+import (
+	"context"
+	"math"
+	"net/http"
+	stdpath "path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/stream"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	policyOff            = "off"
+	policyFirstFound     = "first_found"
+	policyMostFreeSpace  = "most_free_space"
+	policyLeastUsedSpace = "least_used_space"
+	policyRoundRobin     = "round_robin"
+	policyNewest         = "newest"
+	conflictKeepAll      = "keep_all"
+
+	tierInterval = time.Hour
+	tierMaxDepth = 64
+)
+
+func (d *Alias) union() bool {
+	return d.Writable && d.CreatePolicy != "" && d.CreatePolicy != policyOff
+}
+
+// createPath picks the target a new entry in dir goes to by the create
+// policy. With path preserving only the targets already holding dir count.
+func (d *Alias) createPath(ctx context.Context, dir model.Obj) (string, error) {
+	root, sub := d.getRootAndPath(dir.GetPath())
+	dsts, ok := d.pathMap[root]
+	if !ok {
+		return "", errs.ObjectNotFound
+	}
+	dst, err := d.pickDst(ctx, root, dsts, sub)
+	if err != nil {
+		return "", err
+	}
+	return stdpath.Join(dst, sub), nil
+}
+
+func (d *Alias) pickDst(ctx context.Context, root string, dsts []string, sub string) (string, error) {
+	candidates := dsts
+	if d.PathPreserving {
+		candidates = nil
+		for _, dst := range dsts {
+			obj, err := fs.Get(ctx, stdpath.Join(dst, sub), &fs.GetArgs{NoLog: true})
+			if err == nil && obj.IsDir() {
+				candidates = append(candidates, dst)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return "", errs.ObjectNotFound
+	}
+	switch d.CreatePolicy {
+	case policyMostFreeSpace, policyLeastUsedSpace:
+		best, bestScore := "", int64(math.MinInt64)
+		for _, dst := range candidates {
+			details, err := d.details(ctx, dst)
+			if err != nil {
+				log.Warnf("[alias] skip %s for create policy %s: %v", dst, d.CreatePolicy, err)
+				continue
+			}
+			score := details.FreeSpace
+			if d.CreatePolicy == policyLeastUsedSpace {
+				score = -details.UsedSpace()
+			}
+			if score > bestScore {
+				best, bestScore = dst, score
+			}
+		}
+		if best == "" {
+			// picking the first one would silently turn it into first_found
+			return "", errors.Errorf("create policy %s needs the space of the targets, none of %v reports it", d.CreatePolicy, candidates)
+		}
+		return best, nil
+	case policyRoundRobin:
+		d.rrMu.Lock()
+		defer d.rrMu.Unlock()
+		i := d.rr[root] % len(candidates)
+		d.rr[root] = i + 1
+		return candidates[i], nil
+	default:
+		return candidates[0], nil
+	}
+}
+
+func (d *Alias) details(ctx context.Context, dst string) (*model.StorageDetails, error) {
+	storage, err := fs.GetStorage(dst, &fs.GetStoragesArgs{})
+	if err != nil {
+		return nil, err
+	}
+	return op.GetStorageDetails(ctx, storage)
+}
+
+// sameStoragePath returns the path of dir in the target on the storage of
+// srcPath, as moves can not cross storages.
+func (d *Alias) sameStoragePath(ctx context.Context, dir model.Obj, srcPath string) (string, error) {
+	srcStorage, err := fs.GetStorage(srcPath, &fs.GetStoragesArgs{})
+	if err != nil {
+		return "", err
+	}
+	root, sub := d.getRootAndPath(dir.GetPath())
+	for _, dst := range d.pathMap[root] {
+		storage, err := fs.GetStorage(dst, &fs.GetStoragesArgs{})
+		if err == nil && storage.GetStorage() == srcStorage.GetStorage() {
+			return stdpath.Join(dst, sub), nil
+		}
+	}
+	return "", errs.MoveBetweenTwoStorages
+}
+
+// existingPaths returns the paths of obj in every target holding it.
+func (d *Alias) existingPaths(ctx context.Context, obj model.Obj) ([]string, error) {
+	root, sub := d.getRootAndPath(obj.GetPath())
+	if sub == "" {
+		return nil, errs.NotSupport
+	}
+	dsts, ok := d.pathMap[root]
+	if !ok {
+		return nil, errs.ObjectNotFound
+	}
+	var paths []string
+	for _, dst := range dsts {
+		path := stdpath.Join(dst, sub)
+		if _, err := fs.Get(ctx, path, &fs.GetArgs{NoLog: true}); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, errs.ObjectNotFound
+	}
+	return paths, nil
+}
+
+// searchDsts orders the targets of root by the search policy, with newest
+// only the targets holding sub are returned, the most recent first.
+func (d *Alias) searchDsts(ctx context.Context, root, sub string) []string {
+	dsts := d.pathMap[root]
+	if d.SearchPolicy != policyNewest || len(dsts) < 2 {
+		return dsts
+	}
+	type found struct {
+		dst      string
+		modified time.Time
+	}
+	var res []found
+	for _, dst := range dsts {
+		obj, err := fs.Get(ctx, stdpath.Join(dst, sub), &fs.GetArgs{NoLog: true})
+		if err == nil {
+			res = append(res, found{dst: dst, modified: obj.ModTime()})
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].modified.After(res[j].modified) })
+	ordered := make([]string, len(res))
+	for i, f := range res {
+		ordered[i] = f.dst
+	}
+	return ordered
+}
+
+// resolveConflicts keeps one entry per name unless the strategy is to keep
+// them all. Dirs are merged into the first one found.
+func (d *Alias) resolveConflicts(objs []model.Obj) []model.Obj {
+	if d.ConflictStrategy == "" || d.ConflictStrategy == conflictKeepAll {
+		return objs
+	}
+	index := make(map[string]int, len(objs))
+	res := make([]model.Obj, 0, len(objs))
+	for _, obj := range objs {
+		i, ok := index[obj.GetName()]
+		if !ok {
+			index[obj.GetName()] = len(res)
+			res = append(res, obj)
+			continue
+		}
+		if d.ConflictStrategy == policyNewest && !obj.IsDir() && !res[i].IsDir() &&
+			obj.ModTime().After(res[i].ModTime()) {
+			res[i] = obj
+		}
+	}
+	return res
+}
+
+// tier moves the files of the first target which were not modified for
+// TierAfter days to the other targets, picked by the create policy.
+func (d *Alias) tier() {
+	if !d.tiering.CompareAndSwap(false, true) {
+		return
+	}
+	defer d.tiering.Store(false)
+	ctx := context.Background()
+	cutoff := time.Now().AddDate(0, 0, -d.TierAfter)
+	for root, dsts := range d.pathMap {
+		if len(dsts) < 2 {
+			continue
+		}
+		first := dsts[0]
+		obj, err := fs.Get(ctx, first, &fs.GetArgs{NoLog: true})
+		if err != nil {
+			log.Warnf("[alias] failed get tier %s: %+v", first, err)
+			continue
+		}
+		err = fs.WalkFS(ctx, tierMaxDepth, first, obj, func(reqPath string, info model.Obj) error {
+			if info.IsDir() || info.ModTime().After(cutoff) {
+				return nil
+			}
+			sub := strings.TrimPrefix(reqPath, first)
+			dst, err := d.pickDst(ctx, root, dsts[1:], stdpath.Dir(sub))
+			if err != nil {
+				log.Debugf("[alias] no tier for %s: %v", reqPath, err)
+				return nil
+			}
+			dstDir := stdpath.Join(dst, stdpath.Dir(sub))
+			if err := moveFile(ctx, reqPath, dstDir); err != nil {
+				log.Errorf("[alias] failed move %s to tier %s: %+v", reqPath, dstDir, err)
+			} else {
+				log.Infof("[alias] moved %s to tier %s", reqPath, dstDir)
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, filepath.SkipDir) {
+			log.Warnf("[alias] failed walk tier %s: %+v", first, err)
+		}
+	}
+}
+
+// moveFile moves a file to a dir which may be on another storage, by copying
+// and removing it in that case.
+func moveFile(ctx context.Context, srcPath, dstDirPath string) error {
+	srcStorage, srcActualPath, err := op.GetStorageAndActualPath(srcPath)
+	if err != nil {
+		return err
+	}
+	dstStorage, dstDirActualPath, err := op.GetStorageAndActualPath(dstDirPath)
+	if err != nil {
+		return err
+	}
+	if srcStorage.GetStorage() == dstStorage.GetStorage() {
+		if err := op.MakeDir(ctx, dstStorage, dstDirActualPath); err != nil {
+			return err
+		}
+		return op.Move(ctx, srcStorage, srcActualPath, dstDirActualPath)
+	}
+	srcFile, err := op.Get(ctx, srcStorage, srcActualPath)
+	if err != nil {
+		return err
+	}
+	link, _, err := op.Link(ctx, srcStorage, srcActualPath, model.LinkArgs{Header: http.Header{}})
+	if err != nil {
+		return err
+	}
+	ss, err := stream.NewSeekableStream(stream.FileStream{Obj: srcFile, Ctx: ctx}, link)
+	if err != nil {
+		return err
+	}
+	if err := op.Put(ctx, dstStorage, dstDirActualPath, ss, nil, true); err != nil {
+		return err
+	}
+	return op.Remove(ctx, srcStorage, srcActualPath)
+}
@@ -0,0 +1,147 @@
+package alias
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/alist-org/alist/v3/drivers/local"
+	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/stream"
+	"github.com/alist-org/alist/v3/internal/testutil"
+)
+
+func init() {
+	testutil.InitDB()
+}
+
+func createUnion(t *testing.T, mountPath string, addition Addition) *Alias {
+	bs, _ := json.Marshal(addition)
+	if _, err := op.CreateStorage(context.Background(), model.Storage{Driver: "Alias", MountPath: mountPath, Addition: string(bs)}); err != nil {
+		t.Fatalf("failed to create storage: %+v", err)
+	}
+	storage, err := op.GetStorageByMountPath(mountPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return storage.(*Alias)
+}
+
+func put(t *testing.T, dir, name, content string) {
+	s := &stream.FileStream{
+		Obj:    &model.Object{Name: name, Size: int64(len(content)), Modified: time.Now()},
+		Reader: strings.NewReader(content),
+	}
+	if err := fs.PutDirectly(context.Background(), dir, s); err != nil {
+		t.Fatalf("failed to put %s: %+v", name, err)
+	}
+}
+
+func TestUnionRoundRobin(t *testing.T) {
+	_, a := testutil.CreateLocal(t, "/rr_a")
+	_, b := testutil.CreateLocal(t, "/rr_b")
+	createUnion(t, "/rr", Addition{
+		Paths:            "pool:/rr_a\npool:/rr_b",
+		Writable:         true,
+		CreatePolicy:     policyRoundRobin,
+		ConflictStrategy: policyNewest,
+	})
+	put(t, "/rr", "1.txt", "1")
+	put(t, "/rr", "2.txt", "2")
+	for _, f := range []string{filepath.Join(a, "1.txt"), filepath.Join(b, "2.txt")} {
+		if _, err := os.Stat(f); err != nil {
+			t.Errorf("%s should be written: %v", f, err)
+		}
+	}
+	// same-name files are listed once, the newest wins
+	old := time.Now().Add(-time.Hour)
+	if err := os.WriteFile(filepath.Join(a, "x.txt"), []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(a, "x.txt"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(b, "x.txt"), []byte("new!"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	objs, err := fs.List(context.Background(), "/rr", &fs.ListArgs{Refresh: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []model.Obj
+	for _, obj := range objs {
+		if obj.GetName() == "x.txt" {
+			found = append(found, obj)
+		}
+	}
+	if len(found) != 1 || found[0].GetSize() != 4 {
+		t.Errorf("expected the newest x.txt only, got %v", found)
+	}
+}
+
+func TestUnionSpacePolicy(t *testing.T) {
+	_, a := testutil.CreateLocal(t, "/space_a")
+	createUnion(t, "/space", Addition{
+		Paths:        "pool:/space_a",
+		Writable:     true,
+		CreatePolicy: policyMostFreeSpace,
+	})
+	put(t, "/space", "1.txt", "1")
+	if _, err := os.Stat(filepath.Join(a, "1.txt")); err != nil {
+		t.Errorf("1.txt should be written: %v", err)
+	}
+	// an alias doesn't report its space, the policy must not fall back to
+	// the first target
+	createUnion(t, "/nospace_x", Addition{Paths: "/space_a"})
+	createUnion(t, "/nospace", Addition{
+		Paths:        "pool:/nospace_x",
+		Writable:     true,
+		CreatePolicy: policyMostFreeSpace,
+	})
+	s := &stream.FileStream{
+		Obj:    &model.Object{Name: "2.txt", Size: 1, Modified: time.Now()},
+		Reader: strings.NewReader("2"),
+	}
+	if err := fs.PutDirectly(context.Background(), "/nospace", s); err == nil {
+		t.Errorf("put should fail without the space of the targets")
+	}
+}
+
+func TestUnionTier(t *testing.T) {
+	_, fast := testutil.CreateLocal(t, "/tier_fast")
+	_, slow := testutil.CreateLocal(t, "/tier_slow")
+	d := createUnion(t, "/tier", Addition{
+		Paths:        "pool:/tier_fast\npool:/tier_slow",
+		Writable:     true,
+		CreatePolicy: policyFirstFound,
+		TierAfter:    7,
+	})
+	put(t, "/tier", "new.txt", "new")
+	if err := os.MkdirAll(filepath.Join(fast, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	oldFile := filepath.Join(fast, "sub", "old.txt")
+	if err := os.WriteFile(oldFile, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().AddDate(0, 0, -30)
+	if err := os.Chtimes(oldFile, old, old); err != nil {
+		t.Fatal(err)
+	}
+	d.tier()
+	if _, err := os.Stat(filepath.Join(slow, "sub", "old.txt")); err != nil {
+		t.Errorf("old file should be moved to the slow tier: %v", err)
+	}
+	if _, err := os.Stat(oldFile); !os.IsNotExist(err) {
+		t.Errorf("old file should be removed from the fast tier: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(fast, "new.txt")); err != nil {
+		t.Errorf("new file should stay in the fast tier: %v", err)
+	}
+}
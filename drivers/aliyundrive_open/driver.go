@@ -295,7 +295,22 @@ func (d *AliyundriveOpen) Other(ctx context.Context, args model.OtherArgs) (inte
 	return resp, nil
 }
 
+func (d *AliyundriveOpen) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
+	var resp SpaceInfoResp
+	_, err := d.request(ctx, limiterOther, "/adrive/v1.0/user/getSpaceInfo", http.MethodPost, func(req *resty.Request) {
+		req.SetResult(&resp)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &model.StorageDetails{
+		TotalSpace: resp.PersonalSpaceInfo.TotalSize,
+		FreeSpace:  resp.PersonalSpaceInfo.TotalSize - resp.PersonalSpaceInfo.UsedSize,
+	}, nil
+}
+
 var _ driver.Driver = (*AliyundriveOpen)(nil)
+var _ driver.WithDetails = (*AliyundriveOpen)(nil)
 var _ driver.MkdirResult = (*AliyundriveOpen)(nil)
 var _ driver.MoveResult = (*AliyundriveOpen)(nil)
 var _ driver.RenameResult = (*AliyundriveOpen)(nil)
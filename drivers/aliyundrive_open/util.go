@@ -221,7 +221,7 @@ func (d *AliyundriveOpen) request(ctx context.Context, limitTy limiterType, uri,
 }
 
 func (d *AliyundriveOpen) requestReturnErrResp(ctx context.Context, limitTy limiterType, uri, method string, callback base.ReqCallback, retry ...bool) ([]byte, error, *ErrResp) {
-	req := base.RestyClient.R()
+	req := base.RestyClient.R().SetContext(ctx)
 	// TODO check whether access_token is expired
 	req.SetHeader("Authorization", "Bearer "+d.getAccessToken())
 	if method == http.MethodPost {
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"net/url"
 	"os"
 	stdpath "path"
@@ -69,7 +70,7 @@ func (d *BaiduNetdisk) Init(ctx context.Context) error {
 		d.UploadAPI = UPLOAD_FALLBACK_API
 	}
 
-	res, err := d.get("/xpan/nas", map[string]string{
+	res, err := d.get(ctx, "/xpan/nas", map[string]string{
 		"method": "uinfo",
 	}, nil)
 	log.Debugf("[baidu_netdisk] get uinfo: %s", string(res))
@@ -85,7 +86,7 @@ func (d *BaiduNetdisk) Drop(ctx context.Context) error {
 }
 
 func (d *BaiduNetdisk) List(ctx context.Context, dir model.Obj, args model.ListArgs) ([]model.Obj, error) {
-	files, err := d.getFiles(dir.GetPath())
+	files, err := d.getFiles(ctx, dir.GetPath())
 	if err != nil {
 		return nil, err
 	}
@@ -96,16 +97,16 @@ func (d *BaiduNetdisk) List(ctx context.Context, dir model.Obj, args model.ListA
 
 func (d *BaiduNetdisk) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
 	if d.DownloadAPI == "crack" {
-		return d.linkCrack(file, args)
+		return d.linkCrack(ctx, file, args)
 	} else if d.DownloadAPI == "crack_video" {
-		return d.linkCrackVideo(file, args)
+		return d.linkCrackVideo(ctx, file, args)
 	}
-	return d.linkOfficial(file, args)
+	return d.linkOfficial(ctx, file, args)
 }
 
 func (d *BaiduNetdisk) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) (model.Obj, error) {
 	var newDir File
-	_, err := d.create(stdpath.Join(parentDir.GetPath(), dirName), 0, 1, "", "", &newDir, 0, 0)
+	_, err := d.create(ctx, stdpath.Join(parentDir.GetPath(), dirName), 0, 1, "", "", &newDir, 0, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -120,7 +121,7 @@ func (d *BaiduNetdisk) Move(ctx context.Context, srcObj, dstDir model.Obj) (mode
 			"newname": srcObj.GetName(),
 		},
 	}
-	_, err := d.manage("move", data)
+	_, err := d.manage(ctx, "move", data)
 	if err != nil {
 		return nil, err
 	}
@@ -139,7 +140,7 @@ func (d *BaiduNetdisk) Rename(ctx context.Context, srcObj model.Obj, newName str
 			"newname": newName,
 		},
 	}
-	_, err := d.manage("rename", data)
+	_, err := d.manage(ctx, "rename", data)
 	if err != nil {
 		return nil, err
 	}
@@ -161,13 +162,13 @@ func (d *BaiduNetdisk) Copy(ctx context.Context, srcObj, dstDir model.Obj) error
 			"newname": srcObj.GetName(),
 		},
 	}
-	_, err := d.manage("copy", data)
+	_, err := d.manage(ctx, "copy", data)
 	return err
 }
 
 func (d *BaiduNetdisk) Remove(ctx context.Context, obj model.Obj) error {
 	data := []string{obj.GetPath()}
-	_, err := d.manage("delete", data)
+	_, err := d.manage(ctx, "delete", data)
 	return err
 }
 
@@ -184,7 +185,7 @@ func (d *BaiduNetdisk) PutRapid(ctx context.Context, dstDir model.Obj, stream mo
 	blockList, _ := utils.Json.MarshalToString([]string{contentMd5})
 
 	var newFile File
-	_, err := d.create(path, streamSize, 0, "", blockList, &newFile, mtime, ctime)
+	_, err := d.create(ctx, path, streamSize, 0, "", blockList, &newFile, mtime, ctime)
 	if err != nil {
 		return nil, err
 	}
@@ -301,7 +302,7 @@ func (d *BaiduNetdisk) Put(ctx context.Context, dstDir model.Obj, stream model.F
 uploadLoop:
 	for attempt := 0; attempt < 2; attempt++ {
 		// 获取上传域名
-		uploadUrl := d.getUploadUrl(path, precreateResp.Uploadid)
+		uploadUrl := d.getUploadUrl(ctx, path, precreateResp.Uploadid)
 		// 并发上传
 		threadG, upCtx := errgroup.NewGroupWithContext(ctx, d.uploadThread,
 			retry.Attempts(1),
@@ -379,7 +380,7 @@ uploadLoop:
 
 	// step.3 创建文件
 	var newFile File
-	_, err = d.create(path, streamSize, 0, precreateResp.Uploadid, blockListStr, &newFile, mtime, ctime)
+	_, err = d.create(ctx, path, streamSize, 0, precreateResp.Uploadid, blockListStr, &newFile, mtime, ctime)
 	if err != nil {
 		return nil, err
 	}
@@ -412,7 +413,7 @@ func (d *BaiduNetdisk) precreate(ctx context.Context, path string, streamSize in
 	joinTime(form, ctime, mtime)
 
 	var precreateResp PrecreateResp
-	_, err := d.postForm("/xpan/file", params, form, &precreateResp)
+	_, err := d.postForm(ctx, "/xpan/file", params, form, &precreateResp)
 	if err != nil {
 		return nil, err
 	}
@@ -451,4 +452,19 @@ func (d *BaiduNetdisk) uploadSlice(ctx context.Context, uploadUrl string, params
 	return nil
 }
 
+func (d *BaiduNetdisk) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
+	var quota QuotaResp
+	_, err := d.request(ctx, "https://pan.baidu.com/api/quota", http.MethodGet, func(req *resty.Request) {
+		req.SetContext(ctx).SetQueryParam("checkfree", "1")
+	}, &quota)
+	if err != nil {
+		return nil, err
+	}
+	return &model.StorageDetails{
+		TotalSpace: quota.Total,
+		FreeSpace:  quota.Total - quota.Used,
+	}, nil
+}
+
 var _ driver.Driver = (*BaiduNetdisk)(nil)
+var _ driver.WithDetails = (*BaiduNetdisk)(nil)
@@ -218,3 +218,10 @@ type UploadServerResp struct {
 	} `json:"servers"`
 	Sl int `json:"sl"`
 }
+
+type QuotaResp struct {
+	Errno int   `json:"errno"`
+	Total int64 `json:"total"`
+	Free  int64 `json:"free"`
+	Used  int64 `json:"used"`
+}
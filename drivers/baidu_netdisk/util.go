@@ -1,6 +1,7 @@
 package baidu_netdisk
 
 import (
+	"context"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -54,10 +55,10 @@ func (d *BaiduNetdisk) _refreshToken() error {
 	return nil
 }
 
-func (d *BaiduNetdisk) request(furl string, method string, callback base.ReqCallback, resp interface{}) ([]byte, error) {
+func (d *BaiduNetdisk) request(ctx context.Context, furl string, method string, callback base.ReqCallback, resp interface{}) ([]byte, error) {
 	var result []byte
 	err := retry.Do(func() error {
-		req := base.RestyClient.R()
+		req := base.RestyClient.R().SetContext(ctx)
 		req.SetQueryParam("access_token", d.AccessToken)
 		if callback != nil {
 			callback(req)
@@ -90,6 +91,7 @@ func (d *BaiduNetdisk) request(furl string, method string, callback base.ReqCall
 		result = res.Body()
 		return nil
 	},
+		retry.Context(ctx),
 		retry.LastErrorOnly(true),
 		retry.Attempts(3),
 		retry.Delay(time.Second),
@@ -97,20 +99,20 @@ func (d *BaiduNetdisk) request(furl string, method string, callback base.ReqCall
 	return result, err
 }
 
-func (d *BaiduNetdisk) get(pathname string, params map[string]string, resp interface{}) ([]byte, error) {
-	return d.request("https://pan.baidu.com/rest/2.0"+pathname, http.MethodGet, func(req *resty.Request) {
+func (d *BaiduNetdisk) get(ctx context.Context, pathname string, params map[string]string, resp interface{}) ([]byte, error) {
+	return d.request(ctx, "https://pan.baidu.com/rest/2.0"+pathname, http.MethodGet, func(req *resty.Request) {
 		req.SetQueryParams(params)
 	}, resp)
 }
 
-func (d *BaiduNetdisk) postForm(pathname string, params map[string]string, form map[string]string, resp interface{}) ([]byte, error) {
-	return d.request("https://pan.baidu.com/rest/2.0"+pathname, http.MethodPost, func(req *resty.Request) {
+func (d *BaiduNetdisk) postForm(ctx context.Context, pathname string, params map[string]string, form map[string]string, resp interface{}) ([]byte, error) {
+	return d.request(ctx, "https://pan.baidu.com/rest/2.0"+pathname, http.MethodPost, func(req *resty.Request) {
 		req.SetQueryParams(params)
 		req.SetFormData(form)
 	}, resp)
 }
 
-func (d *BaiduNetdisk) getFiles(dir string) ([]File, error) {
+func (d *BaiduNetdisk) getFiles(ctx context.Context, dir string) ([]File, error) {
 	start := 0
 	limit := 200
 	params := map[string]string{
@@ -130,7 +132,7 @@ func (d *BaiduNetdisk) getFiles(dir string) ([]File, error) {
 		params["limit"] = strconv.Itoa(limit)
 		start += limit
 		var resp ListResp
-		_, err := d.get("/xpan/file", params, &resp)
+		_, err := d.get(ctx, "/xpan/file", params, &resp)
 		if err != nil {
 			return nil, err
 		}
@@ -151,14 +153,14 @@ func (d *BaiduNetdisk) getFiles(dir string) ([]File, error) {
 	return res, nil
 }
 
-func (d *BaiduNetdisk) linkOfficial(file model.Obj, _ model.LinkArgs) (*model.Link, error) {
+func (d *BaiduNetdisk) linkOfficial(ctx context.Context, file model.Obj, _ model.LinkArgs) (*model.Link, error) {
 	var resp DownloadResp
 	params := map[string]string{
 		"method": "filemetas",
 		"fsids":  fmt.Sprintf("[%s]", file.GetID()),
 		"dlink":  "1",
 	}
-	_, err := d.get("/xpan/multimedia", params, &resp)
+	_, err := d.get(ctx, "/xpan/multimedia", params, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -179,7 +181,7 @@ func (d *BaiduNetdisk) linkOfficial(file model.Obj, _ model.LinkArgs) (*model.Li
 	}, nil
 }
 
-func (d *BaiduNetdisk) linkCrack(file model.Obj, _ model.LinkArgs) (*model.Link, error) {
+func (d *BaiduNetdisk) linkCrack(ctx context.Context, file model.Obj, _ model.LinkArgs) (*model.Link, error) {
 	var resp DownloadResp2
 	param := map[string]string{
 		"target": fmt.Sprintf("[\"%s\"]", file.GetPath()),
@@ -187,7 +189,7 @@ func (d *BaiduNetdisk) linkCrack(file model.Obj, _ model.LinkArgs) (*model.Link,
 		"web":    "5",
 		"origin": "dlna",
 	}
-	_, err := d.request("https://pan.baidu.com/api/filemetas", http.MethodGet, func(req *resty.Request) {
+	_, err := d.request(ctx, "https://pan.baidu.com/api/filemetas", http.MethodGet, func(req *resty.Request) {
 		req.SetQueryParams(param)
 	}, &resp)
 	if err != nil {
@@ -202,7 +204,7 @@ func (d *BaiduNetdisk) linkCrack(file model.Obj, _ model.LinkArgs) (*model.Link,
 	}, nil
 }
 
-func (d *BaiduNetdisk) linkCrackVideo(file model.Obj, _ model.LinkArgs) (*model.Link, error) {
+func (d *BaiduNetdisk) linkCrackVideo(ctx context.Context, file model.Obj, _ model.LinkArgs) (*model.Link, error) {
 	param := map[string]string{
 		"type":       "VideoURL",
 		"path":       fmt.Sprintf("%s", file.GetPath()),
@@ -215,7 +217,7 @@ func (d *BaiduNetdisk) linkCrackVideo(file model.Obj, _ model.LinkArgs) (*model.
 		"media":      "1",
 		"origin":     "dlna",
 	}
-	resp, err := d.request("https://pan.baidu.com/api/mediainfo", http.MethodGet, func(req *resty.Request) {
+	resp, err := d.request(ctx, "https://pan.baidu.com/api/mediainfo", http.MethodGet, func(req *resty.Request) {
 		req.SetQueryParams(param)
 	}, nil)
 	if err != nil {
@@ -230,20 +232,20 @@ func (d *BaiduNetdisk) linkCrackVideo(file model.Obj, _ model.LinkArgs) (*model.
 	}, nil
 }
 
-func (d *BaiduNetdisk) manage(opera string, filelist any) ([]byte, error) {
+func (d *BaiduNetdisk) manage(ctx context.Context, opera string, filelist any) ([]byte, error) {
 	params := map[string]string{
 		"method": "filemanager",
 		"opera":  opera,
 	}
 	marshal, _ := utils.Json.MarshalToString(filelist)
-	return d.postForm("/xpan/file", params, map[string]string{
+	return d.postForm(ctx, "/xpan/file", params, map[string]string{
 		"async":    "0",
 		"filelist": marshal,
 		"ondup":    "fail",
 	}, nil)
 }
 
-func (d *BaiduNetdisk) create(path string, size int64, isdir int, uploadid, block_list string, resp any, mtime, ctime int64) ([]byte, error) {
+func (d *BaiduNetdisk) create(ctx context.Context, path string, size int64, isdir int, uploadid, block_list string, resp any, mtime, ctime int64) ([]byte, error) {
 	params := map[string]string{
 		"method": "create",
 	}
@@ -263,7 +265,7 @@ func (d *BaiduNetdisk) create(path string, size int64, isdir int, uploadid, bloc
 	if block_list != "" {
 		form["block_list"] = block_list
 	}
-	return d.postForm("/xpan/file", params, form, resp)
+	return d.postForm(ctx, "/xpan/file", params, form, resp)
 }
 
 func joinTime(form map[string]string, ctime, mtime int64) {
@@ -343,7 +345,7 @@ func (d *BaiduNetdisk) getSliceSize(filesize int64) int64 {
 
 // getUploadUrl 从开放平台获取上传域名/地址，并发请求会被合并，结果会被缓存1h。
 // 如果获取失败，则返回 Upload API设置项。
-func (d *BaiduNetdisk) getUploadUrl(path, uploadId string) string {
+func (d *BaiduNetdisk) getUploadUrl(ctx context.Context, path, uploadId string) string {
 	if !d.UseDynamicUploadAPI {
 		return d.UploadAPI
 	}
@@ -366,7 +368,7 @@ func (d *BaiduNetdisk) getUploadUrl(path, uploadId string) string {
 			return uploadUrl, nil
 		}
 
-		uploadUrl, err := d.requestForUploadUrl(path, uploadId)
+		uploadUrl, err := d.requestForUploadUrl(ctx, path, uploadId)
 		if err != nil {
 			return "", err
 		}
@@ -390,7 +392,7 @@ func (d *BaiduNetdisk) getUploadUrl(path, uploadId string) string {
 // requestForUploadUrl 请求获取上传地址。
 // 实测此接口不需要认证，传method和upload_version就行，不过还是按文档规范调用。
 // https://pan.baidu.com/union/doc/Mlvw5hfnr
-func (d *BaiduNetdisk) requestForUploadUrl(path, uploadId string) (string, error) {
+func (d *BaiduNetdisk) requestForUploadUrl(ctx context.Context, path, uploadId string) (string, error) {
 	params := map[string]string{
 		"method":         "locateupload",
 		"appid":          "250528",
@@ -400,7 +402,7 @@ func (d *BaiduNetdisk) requestForUploadUrl(path, uploadId string) (string, error
 	}
 	apiUrl := "https://d.pcs.baidu.com/rest/2.0/pcs/file"
 	var resp UploadServerResp
-	_, err := d.request(apiUrl, http.MethodGet, func(req *resty.Request) {
+	_, err := d.request(ctx, apiUrl, http.MethodGet, func(req *resty.Request) {
 		req.SetQueryParams(params)
 	}, &resp)
 	if err != nil {
@@ -0,0 +1,40 @@
+package baidu_netdisk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alist-org/alist/v3/drivers/base"
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/ratelimit"
+	"github.com/go-resty/resty/v2"
+)
+
+func TestRequestPausesStorage(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"errno":0}`))
+	}))
+	defer upstream.Close()
+	conf.Conf = conf.DefaultConfig()
+	client := base.RestyClient
+	defer func() { base.RestyClient = client }()
+	base.RestyClient = resty.New()
+	base.WrapTransport(base.RestyClient)
+
+	l := ratelimit.New(0, 0)
+	d := &BaiduNetdisk{}
+	if _, err := d.request(ratelimit.WithLimiter(context.Background(), l), upstream.URL, http.MethodGet, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the storage to be paused, got %v", err)
+	}
+}
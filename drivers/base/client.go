@@ -7,6 +7,8 @@ import (
 
 	"github.com/alist-org/alist/v3/internal/conf"
 	"github.com/alist-org/alist/v3/internal/net"
+	"github.com/alist-org/alist/v3/internal/ratelimit"
+	"github.com/alist-org/alist/v3/internal/tracing"
 	"github.com/go-resty/resty/v2"
 )
 
@@ -25,6 +27,7 @@ func InitClient() {
 		}),
 	).SetTLSClientConfig(&tls.Config{InsecureSkipVerify: conf.Conf.TlsInsecureSkipVerify})
 	NoRedirectClient.SetHeader("user-agent", UserAgent)
+	WrapTransport(NoRedirectClient)
 
 	RestyClient = NewRestyClient()
 	HttpClient = net.NewHttpClient()
@@ -37,5 +40,17 @@ func NewRestyClient() *resty.Client {
 		SetRetryResetReaders(true).
 		SetTimeout(DefaultTimeout).
 		SetTLSClientConfig(&tls.Config{InsecureSkipVerify: conf.Conf.TlsInsecureSkipVerify})
+	WrapTransport(client)
 	return client
 }
+
+// WrapTransport lets the storage limiters see the 429 of client and
+// traces its requests, resty can't set the TLS config or the proxy of a
+// wrapped transport so this comes last
+func WrapTransport(client *resty.Client) {
+	transport := ratelimit.Transport(client.GetClient().Transport)
+	if conf.Conf.Tracing.Enable {
+		transport = tracing.Transport(transport)
+	}
+	client.SetTransport(transport)
+}
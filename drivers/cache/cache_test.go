@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/http_range"
+)
+
+func TestBlockStoreEvict(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newBlockStore(dir, 30, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"aa/a.0", "aa/a.1", "aa/a.2"} {
+		if err := s.put(name, make([]byte, 10)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a.0 becomes the most recently used, so a.1 is evicted
+	if _, ok := s.get("aa/a.0"); !ok {
+		t.Fatal("a.0 should be cached")
+	}
+	if err := s.put("aa/a.3", make([]byte, 10)); err != nil {
+		t.Fatal(err)
+	}
+	if s.has("aa/a.1") || !s.has("aa/a.0") || !s.has("aa/a.3") {
+		t.Errorf("unexpected blocks after eviction: %v", s.entries)
+	}
+	// blocks survive a restart
+	s, err = newBlockStore(dir, 30, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count, size := s.usage(); count != 3 || size != 30 {
+		t.Errorf("unexpected usage after reopen: %d blocks, %d bytes", count, size)
+	}
+	s.ttl = time.Nanosecond
+	s.expire()
+	if count, _ := s.usage(); count != 0 {
+		t.Errorf("expired blocks should be removed, %d left", count)
+	}
+}
+
+func TestBlockReader(t *testing.T) {
+	store, err := newBlockStore(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &Cache{store: store, blockSize: 1000, prefetching: make(chan struct{}, maxPrefetching)}
+	data := make([]byte, 4500)
+	rand.Read(data)
+	var fetches atomic.Int32
+	src := &source{
+		d:    d,
+		key:  "0123456789",
+		size: int64(len(data)),
+		open: func(ctx context.Context) (model.RangeReadCloserIF, error) {
+			return &model.RangeReadCloser{
+				RangeReader: func(ctx context.Context, r http_range.Range) (io.ReadCloser, error) {
+					fetches.Add(1)
+					return io.NopCloser(bytes.NewReader(data[r.Start : r.Start+r.Length])), nil
+				},
+			}, nil
+		},
+	}
+	read := func(start, length int64) []byte {
+		rc, err := src.rangeRead(context.Background(), http_range.Range{Start: start, Length: length})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rc.Close()
+		got, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	if got := read(1500, 2000); !bytes.Equal(got, data[1500:3500]) {
+		t.Fatal("content of the range mismatch")
+	}
+	if got := read(4000, -1); !bytes.Equal(got, data[4000:]) {
+		t.Fatal("content of the last block mismatch")
+	}
+	if got := read(0, -1); !bytes.Equal(got, data) {
+		t.Fatal("content of the whole file mismatch")
+	}
+	// every block is fetched once, the reads above hit the cache afterwards
+	n := fetches.Load()
+	if n != 5 {
+		t.Errorf("expected 5 fetches, got %d", n)
+	}
+	read(0, -1)
+	if fetches.Load() != n {
+		t.Errorf("cached blocks should not be fetched again")
+	}
+}
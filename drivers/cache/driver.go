@@ -0,0 +1,265 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	stdpath "path"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alist-org/alist/v3/cmd/flags"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/stream"
+	"github.com/alist-org/alist/v3/pkg/cron"
+	"github.com/alist-org/alist/v3/pkg/http_range"
+	"github.com/alist-org/alist/v3/pkg/singleflight"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// Cache serves the files of another mount path through a block cache on
+// local disk, so that repeated and seeking reads of slow or rate-limited
+// storages only fetch each block once.
+type Cache struct {
+	model.Storage
+	Addition
+	remoteStorage driver.Driver
+	cacheDir      string
+	blockSize     int64
+	store         *blockStore
+	fetchG        singleflight.Group[[]byte]
+	prefetching   chan struct{}
+	cron          *cron.Cron
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	// pending holds the uploads kept by write back, by path
+	pendingMu sync.Mutex
+	pending   map[string]*pendingUpload
+}
+
+func (d *Cache) Config() driver.Config {
+	return config
+}
+
+func (d *Cache) GetAddition() driver.Additional {
+	return &d.Addition
+}
+
+func (d *Cache) Init(ctx context.Context) error {
+	if d.BlockSize <= 0 {
+		d.BlockSize = defaultBlockSize
+	}
+	if d.MaxSize <= 0 {
+		d.MaxSize = defaultMaxSize
+	}
+	if d.PrefetchBlocks < 0 {
+		d.PrefetchBlocks = defaultPrefetchBlocks
+	}
+	if d.TTL < 0 {
+		d.TTL = 0
+	}
+	d.RemotePath = utils.FixAndCleanPath(d.RemotePath)
+	op.MustSaveDriverStorage(d)
+
+	storage, err := fs.GetStorage(d.RemotePath, &fs.GetStoragesArgs{})
+	if err != nil {
+		return fmt.Errorf("can't find remote storage: %w", err)
+	}
+	d.remoteStorage = storage
+
+	d.cacheDir = d.CacheDir
+	if d.cacheDir == "" {
+		d.cacheDir = filepath.Join(flags.DataDir, "cache", strconv.FormatUint(uint64(d.ID), 10))
+	}
+	d.blockSize = int64(d.BlockSize) * utils.MB
+	d.store, err = newBlockStore(filepath.Join(d.cacheDir, "blocks"), int64(d.MaxSize)*utils.MB, time.Duration(d.TTL)*time.Minute)
+	if err != nil {
+		return fmt.Errorf("failed to open cache dir: %w", err)
+	}
+	count, size := d.store.usage()
+	log.Infof("[cache] %s: %d blocks, %d MB cached", d.MountPath, count, size/utils.MB)
+	d.prefetching = make(chan struct{}, maxPrefetching)
+	d.cron = cron.NewCron(10 * time.Minute)
+	d.cron.Do(d.store.expire)
+
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	d.pending = make(map[string]*pendingUpload)
+	return d.loadPending()
+}
+
+func (d *Cache) Drop(ctx context.Context) error {
+	if d.cron != nil {
+		d.cron.Stop()
+	}
+	if d.cancel != nil {
+		d.cancel()
+	}
+	return nil
+}
+
+func (d *Cache) List(ctx context.Context, dir model.Obj, args model.ListArgs) ([]model.Obj, error) {
+	path := dir.GetPath()
+	objs, err := fs.List(ctx, d.remotePath(path), &fs.ListArgs{NoLog: true, Refresh: args.Refresh})
+	if err != nil {
+		return nil, err
+	}
+	pending := d.listPending(path)
+	result := make([]model.Obj, 0, len(objs)+len(pending))
+	seen := make(map[string]struct{}, len(pending))
+	for _, p := range pending {
+		result = append(result, p.obj())
+		seen[p.Name] = struct{}{}
+	}
+	for _, obj := range objs {
+		if _, ok := seen[obj.GetName()]; ok {
+			continue
+		}
+		result = append(result, wrapObj(stdpath.Join(path, obj.GetName()), obj))
+	}
+	return result, nil
+}
+
+func (d *Cache) Get(ctx context.Context, path string) (model.Obj, error) {
+	if utils.PathEqual(path, "/") {
+		return &model.Object{
+			Name:     "Root",
+			IsFolder: true,
+			Path:     "/",
+		}, nil
+	}
+	if p := d.getPending(path); p != nil {
+		return p.obj(), nil
+	}
+	obj, err := fs.Get(ctx, d.remotePath(path), &fs.GetArgs{NoLog: true})
+	if err != nil {
+		return nil, err
+	}
+	return wrapObj(path, obj), nil
+}
+
+func (d *Cache) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	if p := d.getPending(file.GetPath()); p != nil {
+		f, err := os.Open(d.pendingData(p.id))
+		if err != nil {
+			return nil, err
+		}
+		return &model.Link{MFile: f}, nil
+	}
+	actualPath, err := d.getActualPathForRemote(file.GetPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert path to remote path: %w", err)
+	}
+	src := &source{
+		d:    d,
+		key:  blockKey(d.remotePath(file.GetPath()), file),
+		size: file.GetSize(),
+		open: func(ctx context.Context) (model.RangeReadCloserIF, error) {
+			remoteLink, remoteFile, err := op.Link(ctx, d.remoteStorage, actualPath, args)
+			if err != nil {
+				return nil, err
+			}
+			if remoteLink.RangeReadCloser != nil {
+				return remoteLink.RangeReadCloser, nil
+			}
+			if remoteLink.MFile != nil {
+				mFile := remoteLink.MFile
+				return &model.RangeReadCloser{
+					RangeReader: func(ctx context.Context, httpRange http_range.Range) (io.ReadCloser, error) {
+						// prefetches read concurrently, ReadAt keeps them apart
+						return io.NopCloser(io.NewSectionReader(mFile, httpRange.Start, httpRange.Length)), nil
+					},
+					Closers: utils.NewClosers(mFile),
+				}, nil
+			}
+			if len(remoteLink.URL) > 0 {
+				return stream.GetRangeReadCloserFromLink(remoteFile.GetSize(), remoteLink)
+			}
+			return nil, errs.NotSupport
+		},
+	}
+	rrc := &model.RangeReadCloser{RangeReader: src.rangeRead}
+	rrc.Add(src)
+	return &model.Link{RangeReadCloser: rrc}, nil
+}
+
+func (d *Cache) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	dstDirActualPath, err := d.getActualPathForRemote(parentDir.GetPath())
+	if err != nil {
+		return fmt.Errorf("failed to convert path to remote path: %w", err)
+	}
+	return op.MakeDir(ctx, d.remoteStorage, stdpath.Join(dstDirActualPath, dirName))
+}
+
+func (d *Cache) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	if err := d.checkNotPending(srcObj.GetPath()); err != nil {
+		return err
+	}
+	srcRemoteActualPath, err := d.getActualPathForRemote(srcObj.GetPath())
+	if err != nil {
+		return fmt.Errorf("failed to convert path to remote path: %w", err)
+	}
+	dstRemoteActualPath, err := d.getActualPathForRemote(dstDir.GetPath())
+	if err != nil {
+		return fmt.Errorf("failed to convert path to remote path: %w", err)
+	}
+	return op.Move(ctx, d.remoteStorage, srcRemoteActualPath, dstRemoteActualPath)
+}
+
+func (d *Cache) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	if err := d.checkNotPending(srcObj.GetPath()); err != nil {
+		return err
+	}
+	remoteActualPath, err := d.getActualPathForRemote(srcObj.GetPath())
+	if err != nil {
+		return fmt.Errorf("failed to convert path to remote path: %w", err)
+	}
+	return op.Rename(ctx, d.remoteStorage, remoteActualPath, newName)
+}
+
+func (d *Cache) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	if err := d.checkNotPending(srcObj.GetPath()); err != nil {
+		return err
+	}
+	srcRemoteActualPath, err := d.getActualPathForRemote(srcObj.GetPath())
+	if err != nil {
+		return fmt.Errorf("failed to convert path to remote path: %w", err)
+	}
+	dstRemoteActualPath, err := d.getActualPathForRemote(dstDir.GetPath())
+	if err != nil {
+		return fmt.Errorf("failed to convert path to remote path: %w", err)
+	}
+	return op.Copy(ctx, d.remoteStorage, srcRemoteActualPath, dstRemoteActualPath)
+}
+
+func (d *Cache) Remove(ctx context.Context, obj model.Obj) error {
+	if err := d.checkNotPending(obj.GetPath()); err != nil {
+		return err
+	}
+	remoteActualPath, err := d.getActualPathForRemote(obj.GetPath())
+	if err != nil {
+		return fmt.Errorf("failed to convert path to remote path: %w", err)
+	}
+	return op.Remove(ctx, d.remoteStorage, remoteActualPath)
+}
+
+func (d *Cache) Put(ctx context.Context, dstDir model.Obj, s model.FileStreamer, up driver.UpdateProgress) error {
+	if d.WriteBack {
+		return d.writeBack(dstDir, s, up)
+	}
+	dstDirActualPath, err := d.getActualPathForRemote(dstDir.GetPath())
+	if err != nil {
+		return fmt.Errorf("failed to convert path to remote path: %w", err)
+	}
+	return op.Put(ctx, d.remoteStorage, dstDirActualPath, s, up, false)
+}
+
+var _ driver.Driver = (*Cache)(nil)
@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/op"
+)
+
+const (
+	defaultBlockSize      = 4     // MB
+	defaultMaxSize        = 10240 // MB
+	defaultPrefetchBlocks = 4
+	maxPrefetching        = 8
+)
+
+type Addition struct {
+	RemotePath     string `json:"remote_path" required:"true" help:"AList mounted folder path to cache, e.g. /115/videos"`
+	CacheDir       string `json:"cache_dir" help:"Local folder of the cached blocks, defaults to cache/<storage id> in the data folder"`
+	BlockSize      int    `json:"block_size" type:"number" default:"4" help:"Size of a cached block in MB"`
+	MaxSize        int    `json:"max_size" type:"number" default:"10240" help:"Disk space of the cached blocks in MB, the least recently used blocks are removed beyond it"`
+	TTL            int    `json:"ttl" type:"number" default:"1440" help:"Minutes a cached block is kept, 0 keeps it until it is removed for space"`
+	PrefetchBlocks int    `json:"prefetch_blocks" type:"number" default:"4" help:"Number of blocks fetched ahead of a sequential read"`
+	WriteBack      bool   `json:"write_back" type:"bool" default:"false" help:"Keep uploads in the cache folder and upload them in background, they are listed while pending"`
+}
+
+var config = driver.Config{
+	Name:              "Cache",
+	LocalSort:         true,
+	OnlyLocal:         false,
+	OnlyProxy:         true,
+	NoCache:           true,
+	NoUpload:          false,
+	NeedMs:            false,
+	DefaultRoot:       "/",
+	CheckStatus:       false,
+	Alert:             "",
+	NoOverwriteUpload: false,
+}
+
+func init() {
+	op.RegisterDriver(func() driver.Driver {
+		return &Cache{}
+	})
+}
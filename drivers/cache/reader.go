@@ -0,0 +1,174 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/http_range"
+	log "github.com/sirupsen/logrus"
+)
+
+const prefetchTimeout = 2 * time.Minute
+
+// source reads the blocks of one version of a remote file. The blocks are
+// named after the path, size and modification time of the file, so a change
+// of the file makes the old blocks unreachable until they are evicted.
+type source struct {
+	d    *Cache
+	key  string
+	size int64
+	// open links the remote file, it is only called on a cache miss
+	open func(ctx context.Context) (model.RangeReadCloserIF, error)
+
+	mu  sync.Mutex
+	rrc model.RangeReadCloserIF
+}
+
+func blockKey(remotePath string, obj model.Obj) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s\x00%d\x00%d", remotePath, obj.GetSize(), obj.ModTime().UnixNano())))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *source) blockName(idx int64) string {
+	return s.key[:2] + "/" + s.key + "." + strconv.FormatInt(idx, 10)
+}
+
+func (s *source) blockRange(idx int64) http_range.Range {
+	start := idx * s.d.blockSize
+	return http_range.Range{Start: start, Length: min(s.d.blockSize, s.size-start)}
+}
+
+func (s *source) remote(ctx context.Context) (model.RangeReadCloserIF, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.rrc != nil {
+		return s.rrc, nil
+	}
+	rrc, err := s.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.rrc = rrc
+	return rrc, nil
+}
+
+// Close releases the remote link once the request is done, prefetches still
+// running after it fail and are retried by the next reader.
+func (s *source) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.rrc == nil {
+		return nil
+	}
+	return s.rrc.Close()
+}
+
+// block returns the content of block idx, fetching it from the remote
+// storage on a miss. Concurrent misses of a block share one fetch.
+func (s *source) block(ctx context.Context, idx int64) ([]byte, error) {
+	name := s.blockName(idx)
+	if data, ok := s.d.store.get(name); ok {
+		return data, nil
+	}
+	data, err, _ := s.d.fetchG.Do(name, func() ([]byte, error) {
+		// a prefetch may have stored it in between
+		if data, ok := s.d.store.get(name); ok {
+			return data, nil
+		}
+		rrc, err := s.remote(ctx)
+		if err != nil {
+			return nil, err
+		}
+		r := s.blockRange(idx)
+		rc, err := rrc.RangeRead(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		data := make([]byte, r.Length)
+		if _, err := io.ReadFull(rc, data); err != nil {
+			return nil, fmt.Errorf("failed to read block %d: %w", idx, err)
+		}
+		if err := s.d.store.put(name, data); err != nil {
+			log.Warnf("[cache] failed store block %s: %+v", name, err)
+		}
+		return data, nil
+	})
+	return data, err
+}
+
+// prefetch fetches the blocks following idx in background, up to the
+// configured count and not beyond last.
+func (s *source) prefetch(idx, last int64) {
+	for i := idx + 1; i <= min(idx+int64(s.d.PrefetchBlocks), last); i++ {
+		if s.d.store.has(s.blockName(i)) {
+			continue
+		}
+		select {
+		case s.d.prefetching <- struct{}{}:
+		default:
+			// enough fetches are going on, the reader will get the block itself
+			return
+		}
+		go func(i int64) {
+			defer func() { <-s.d.prefetching }()
+			ctx, cancel := context.WithTimeout(context.Background(), prefetchTimeout)
+			defer cancel()
+			if _, err := s.block(ctx, i); err != nil {
+				log.Debugf("[cache] failed prefetch block %s: %+v", s.blockName(i), err)
+			}
+		}(i)
+	}
+}
+
+func (s *source) rangeRead(ctx context.Context, httpRange http_range.Range) (io.ReadCloser, error) {
+	end := s.size
+	if httpRange.Length >= 0 && httpRange.Start+httpRange.Length < s.size {
+		end = httpRange.Start + httpRange.Length
+	}
+	return &blockReader{ctx: ctx, s: s, pos: httpRange.Start, end: end, idx: -1}, nil
+}
+
+// blockReader reads a range of a file block by block.
+type blockReader struct {
+	ctx  context.Context
+	s    *source
+	pos  int64
+	end  int64
+	idx  int64
+	data []byte
+}
+
+func (r *blockReader) Read(p []byte) (int, error) {
+	if r.pos >= r.end {
+		return 0, io.EOF
+	}
+	bs := r.s.d.blockSize
+	if idx := r.pos / bs; idx != r.idx {
+		data, err := r.s.block(r.ctx, idx)
+		if err != nil {
+			return 0, err
+		}
+		r.idx, r.data = idx, data
+		r.s.prefetch(idx, (r.end-1)/bs)
+	}
+	off := r.pos - r.idx*bs
+	if off >= int64(len(r.data)) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	n := copy(p, r.data[off:min(int64(len(r.data)), r.end-r.idx*bs)])
+	r.pos += int64(n)
+	return n, nil
+}
+
+func (r *blockReader) Close() error {
+	r.data = nil
+	return nil
+}
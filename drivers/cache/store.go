@@ -0,0 +1,188 @@
+package cache
+
+import (
+	"container/list"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// blockStore keeps blocks as files under dir and removes the least recently
+// used ones beyond maxSize, as well as the ones older than ttl.
+type blockStore struct {
+	dir     string
+	maxSize int64
+	ttl     time.Duration
+
+	mu      sync.Mutex
+	lru     *list.List // of *blockEntry, the most recently used first
+	entries map[string]*list.Element
+	size    int64
+}
+
+type blockEntry struct {
+	name    string
+	size    int64
+	created time.Time
+}
+
+// newBlockStore indexes the blocks left in dir by a previous run, their
+// modification time is taken as both creation and last use.
+func newBlockStore(dir string, maxSize int64, ttl time.Duration) (*blockStore, error) {
+	if err := os.MkdirAll(dir, 0o777); err != nil {
+		return nil, err
+	}
+	s := &blockStore{
+		dir:     dir,
+		maxSize: maxSize,
+		ttl:     ttl,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+	var found []*blockEntry
+	err := filepath.WalkDir(dir, func(p string, de fs.DirEntry, err error) error {
+		if err != nil || de.IsDir() {
+			return err
+		}
+		if strings.HasSuffix(p, ".tmp") {
+			_ = os.Remove(p)
+			return nil
+		}
+		info, err := de.Info()
+		if err != nil {
+			return nil
+		}
+		name, _ := filepath.Rel(dir, p)
+		found = append(found, &blockEntry{name: filepath.ToSlash(name), size: info.Size(), created: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].created.After(found[j].created) })
+	for _, e := range found {
+		s.entries[e.name] = s.lru.PushBack(e)
+		s.size += e.size
+	}
+	s.mu.Lock()
+	s.evict()
+	s.mu.Unlock()
+	return s, nil
+}
+
+func (s *blockStore) path(name string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(name))
+}
+
+func (s *blockStore) expired(e *blockEntry) bool {
+	return s.ttl > 0 && time.Since(e.created) > s.ttl
+}
+
+// get returns the content of a cached block.
+func (s *blockStore) get(name string) ([]byte, bool) {
+	s.mu.Lock()
+	el, ok := s.entries[name]
+	if ok && s.expired(el.Value.(*blockEntry)) {
+		s.remove(el)
+		ok = false
+	}
+	if ok {
+		s.lru.MoveToFront(el)
+	}
+	s.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	data, err := os.ReadFile(s.path(name))
+	if err != nil {
+		log.Warnf("[cache] failed read block %s: %+v", name, err)
+		s.mu.Lock()
+		if el, ok := s.entries[name]; ok {
+			s.remove(el)
+		}
+		s.mu.Unlock()
+		return nil, false
+	}
+	return data, true
+}
+
+func (s *blockStore) has(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.entries[name]
+	return ok && !s.expired(el.Value.(*blockEntry))
+}
+
+// put stores a block, it is written to a temporary file first so that a
+// block is either complete or missing.
+func (s *blockStore) put(name string, data []byte) error {
+	p := s.path(name)
+	if err := os.MkdirAll(filepath.Dir(p), 0o777); err != nil {
+		return err
+	}
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o666); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, p); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[name]; ok {
+		s.size -= el.Value.(*blockEntry).size
+		s.lru.Remove(el)
+	}
+	s.entries[name] = s.lru.PushFront(&blockEntry{name: name, size: int64(len(data)), created: time.Now()})
+	s.size += int64(len(data))
+	s.evict()
+	return nil
+}
+
+// remove drops a block, the caller must hold s.mu.
+func (s *blockStore) remove(el *list.Element) {
+	e := s.lru.Remove(el).(*blockEntry)
+	delete(s.entries, e.name)
+	s.size -= e.size
+	if err := os.Remove(s.path(e.name)); err != nil && !os.IsNotExist(err) {
+		log.Warnf("[cache] failed remove block %s: %+v", e.name, err)
+	}
+}
+
+// evict removes the least recently used blocks until the store fits in
+// maxSize, the caller must hold s.mu.
+func (s *blockStore) evict() {
+	for s.maxSize > 0 && s.size > s.maxSize {
+		s.remove(s.lru.Back())
+	}
+}
+
+// expire removes the blocks older than ttl.
+func (s *blockStore) expire() {
+	if s.ttl <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for el := s.lru.Front(); el != nil; {
+		next := el.Next()
+		if s.expired(el.Value.(*blockEntry)) {
+			s.remove(el)
+		}
+		el = next
+	}
+}
+
+func (s *blockStore) usage() (count int, size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries), s.size
+}
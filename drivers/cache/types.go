@@ -0,0 +1,15 @@
+package cache
+
+import "time"
+
+// pendingUpload is a file kept on disk by write back until it is uploaded.
+// It is saved next to the data so that the upload resumes after a restart.
+type pendingUpload struct {
+	Dir      string    `json:"dir"` // parent dir in the storage
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	Mimetype string    `json:"mimetype"`
+	// id names the data and info files
+	id string
+}
@@ -0,0 +1,37 @@
+package cache
+
+import (
+	stdpath "path"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+)
+
+func (d *Cache) remotePath(path string) string {
+	return stdpath.Join(d.RemotePath, path)
+}
+
+// actual path is used for internal only. any link for user should come from remotePath
+func (d *Cache) getActualPathForRemote(path string) (string, error) {
+	_, remoteActualPath, err := op.GetStorageAndActualPath(d.remotePath(path))
+	return remoteActualPath, err
+}
+
+func wrapObj(path string, obj model.Obj) model.Obj {
+	o := model.Object{
+		Path:     path,
+		Name:     obj.GetName(),
+		Size:     obj.GetSize(),
+		Modified: obj.ModTime(),
+		Ctime:    obj.CreateTime(),
+		IsFolder: obj.IsDir(),
+		HashInfo: obj.GetHash(),
+	}
+	if thumb, ok := model.GetThumb(obj); ok {
+		return &model.ObjThumb{
+			Object:    o,
+			Thumbnail: model.Thumbnail{Thumbnail: thumb},
+		}
+	}
+	return &o
+}
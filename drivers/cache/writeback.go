@@ -0,0 +1,242 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	stdpath "path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/stream"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	minUploadRetry = time.Minute
+	maxUploadRetry = 30 * time.Minute
+)
+
+func (d *Cache) pendingDir() string {
+	return filepath.Join(d.cacheDir, "pending")
+}
+
+func (p *pendingUpload) path() string {
+	return stdpath.Join(p.Dir, p.Name)
+}
+
+func (p *pendingUpload) obj() *model.Object {
+	return &model.Object{
+		Path:     p.path(),
+		Name:     p.Name,
+		Size:     p.Size,
+		Modified: p.Modified,
+	}
+}
+
+func (d *Cache) getPending(path string) *pendingUpload {
+	d.pendingMu.Lock()
+	defer d.pendingMu.Unlock()
+	return d.pending[path]
+}
+
+func (d *Cache) listPending(dir string) []*pendingUpload {
+	d.pendingMu.Lock()
+	defer d.pendingMu.Unlock()
+	var res []*pendingUpload
+	for _, p := range d.pending {
+		if p.Dir == dir {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// checkNotPending refuses to change a file or a dir holding files which are
+// still to be uploaded.
+func (d *Cache) checkNotPending(path string) error {
+	d.pendingMu.Lock()
+	defer d.pendingMu.Unlock()
+	for p := range d.pending {
+		if p == path || strings.HasPrefix(p, strings.TrimSuffix(path, "/")+"/") {
+			return fmt.Errorf("%s is being uploaded", p)
+		}
+	}
+	return nil
+}
+
+// loadPending resumes the uploads left by a previous run.
+func (d *Cache) loadPending() error {
+	if err := os.MkdirAll(d.pendingDir(), 0o777); err != nil {
+		return err
+	}
+	infos, err := filepath.Glob(filepath.Join(d.pendingDir(), "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		var p pendingUpload
+		data, err := os.ReadFile(info)
+		if err == nil {
+			err = json.Unmarshal(data, &p)
+		}
+		p.id = strings.TrimSuffix(filepath.Base(info), ".json")
+		if _, statErr := os.Stat(d.pendingData(p.id)); err != nil || statErr != nil {
+			log.Warnf("[cache] drop broken pending upload %s: %v %v", p.id, err, statErr)
+			d.removePendingFiles(p.id)
+			continue
+		}
+		d.pending[p.path()] = &p
+		go d.upload(d.ctx, &p)
+	}
+	return nil
+}
+
+func (d *Cache) pendingData(id string) string {
+	return filepath.Join(d.pendingDir(), id)
+}
+
+func (d *Cache) removePendingFiles(id string) {
+	for _, p := range []string{d.pendingData(id), d.pendingData(id) + ".json"} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			log.Warnf("[cache] failed remove %s: %+v", p, err)
+		}
+	}
+}
+
+// writeBack keeps the upload on disk and uploads it in background.
+func (d *Cache) writeBack(dstDir model.Obj, s model.FileStreamer, up driver.UpdateProgress) error {
+	p := &pendingUpload{
+		Dir:      dstDir.GetPath(),
+		Name:     s.GetName(),
+		Size:     s.GetSize(),
+		Modified: s.ModTime(),
+		Mimetype: s.GetMimetype(),
+		id:       uuid.NewString(),
+	}
+	if err := d.checkNotPending(p.path()); err != nil {
+		return err
+	}
+	f, err := os.Create(d.pendingData(p.id))
+	if err != nil {
+		return err
+	}
+	n, err := utils.CopyWithBuffer(f, &stream.ReaderUpdatingProgress{
+		Reader:         s,
+		UpdateProgress: up,
+	})
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && p.Size > 0 && n != p.Size {
+		err = fmt.Errorf("got %d bytes of %d", n, p.Size)
+	}
+	p.Size = n
+	if err == nil {
+		var info []byte
+		info, err = json.Marshal(p)
+		if err == nil {
+			err = os.WriteFile(d.pendingData(p.id)+".json", info, 0o666)
+		}
+	}
+	if err != nil {
+		d.removePendingFiles(p.id)
+		return err
+	}
+	d.pendingMu.Lock()
+	d.pending[p.path()] = p
+	d.pendingMu.Unlock()
+	go d.upload(d.ctx, p)
+	return nil
+}
+
+// upload retries until the pending file is uploaded or the storage is
+// dropped, in which case the upload resumes with the next init.
+func (d *Cache) upload(ctx context.Context, p *pendingUpload) {
+	wait := minUploadRetry
+	for {
+		err := d.uploadOnce(ctx, p)
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Errorf("[cache] failed upload %s, retry in %s: %+v", p.path(), wait, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		wait = min(wait*2, maxUploadRetry)
+	}
+	d.seed(ctx, p)
+	d.pendingMu.Lock()
+	delete(d.pending, p.path())
+	d.pendingMu.Unlock()
+	d.removePendingFiles(p.id)
+}
+
+func (d *Cache) uploadOnce(ctx context.Context, p *pendingUpload) error {
+	dstDirActualPath, err := d.getActualPathForRemote(p.Dir)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(d.pendingData(p.id))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	s := &stream.FileStream{
+		Ctx: ctx,
+		Obj: &model.Object{
+			Name:     p.Name,
+			Size:     p.Size,
+			Modified: p.Modified,
+		},
+		Reader:   f,
+		Mimetype: p.Mimetype,
+	}
+	return op.Put(ctx, d.remoteStorage, dstDirActualPath, s, nil, false)
+}
+
+// seed fills the block cache with an uploaded file, it is likely to be read
+// soon. Files taking more than a quarter of the cache are skipped.
+func (d *Cache) seed(ctx context.Context, p *pendingUpload) {
+	if d.store.maxSize > 0 && p.Size > d.store.maxSize/4 {
+		return
+	}
+	actualPath, err := d.getActualPathForRemote(p.path())
+	if err != nil {
+		return
+	}
+	obj, err := op.Get(ctx, d.remoteStorage, actualPath)
+	if err != nil || obj.GetSize() != p.Size {
+		return
+	}
+	f, err := os.Open(d.pendingData(p.id))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	s := &source{d: d, key: blockKey(d.remotePath(p.path()), obj), size: p.Size}
+	for idx := int64(0); idx*d.blockSize < p.Size; idx++ {
+		r := s.blockRange(idx)
+		data := make([]byte, r.Length)
+		if _, err := io.ReadFull(f, data); err != nil {
+			return
+		}
+		if err := d.store.put(s.blockName(idx), data); err != nil {
+			log.Warnf("[cache] failed seed %s: %+v", p.path(), err)
+			return
+		}
+	}
+}
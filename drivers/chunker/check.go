@@ -0,0 +1,299 @@
+package chunker
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"path"
+	"sort"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/stream"
+	"github.com/alist-org/alist/v3/pkg/http_range"
+	"github.com/alist-org/alist/v3/pkg/utils"
+)
+
+// Problems found by Check.
+const (
+	ProblemMissingChunk = "missing_chunk"
+	ProblemSizeMismatch = "size_mismatch"
+	ProblemHashMismatch = "hash_mismatch"
+	ProblemBadMetadata  = "bad_metadata"
+	ProblemOrphanChunk  = "orphan_chunk"
+	ProblemUnreadable   = "unreadable"
+)
+
+type CheckOptions struct {
+	// VerifyHash reads every chunked file to compare it with the hash of
+	// its metadata
+	VerifyHash bool `json:"verify_hash"`
+	// RemoveOrphans removes the chunks that belong to no file
+	RemoveOrphans bool `json:"remove_orphans"`
+	// Rebalance moves chunks to the target they are placed on by the
+	// current remote paths, e.g. after a remote path is added
+	Rebalance bool `json:"rebalance"`
+}
+
+// CheckIssue is a problem of a file, or a chunk of it, found by Check.
+type CheckIssue struct {
+	Path    string `json:"path"`
+	Problem string `json:"problem"`
+	Detail  string `json:"detail"`
+	Fixed   bool   `json:"fixed"`
+}
+
+type CheckProgress struct {
+	Files      int `json:"files"`
+	TotalFiles int `json:"total_files"`
+	Issues     int `json:"issues"`
+	Moved      int `json:"moved"`
+}
+
+type checkEntry struct {
+	dir   string
+	name  string
+	group *groupInfo
+}
+
+type checker struct {
+	d        *Chunker
+	opts     CheckOptions
+	issue    func(CheckIssue)
+	report   func(CheckProgress)
+	progress CheckProgress
+}
+
+// Check verifies the chunked files under dirPath, reporting every problem
+// to issue and the progress after every file to report.
+func (d *Chunker) Check(ctx context.Context, dirPath string, opts CheckOptions, issue func(CheckIssue), report func(CheckProgress)) error {
+	c := &checker{d: d, opts: opts, issue: issue, report: report}
+	var entries []checkEntry
+	if err := c.collect(ctx, utils.FixAndCleanPath(dirPath), &entries); err != nil {
+		return err
+	}
+	c.progress.TotalFiles = len(entries)
+	report(c.progress)
+	for _, e := range entries {
+		if utils.IsCanceled(ctx) {
+			return ctx.Err()
+		}
+		if err := c.checkFile(ctx, e); err != nil {
+			return err
+		}
+		c.progress.Files++
+		report(c.progress)
+	}
+	return nil
+}
+
+func (c *checker) collect(ctx context.Context, dirPath string, entries *[]checkEntry) error {
+	dirs, groups, err := c.d.listDirGroups(ctx, dirPath, true)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		*entries = append(*entries, checkEntry{dir: dirPath, name: name, group: groups[name]})
+	}
+	for name := range dirs {
+		if err = c.collect(ctx, path.Join(dirPath, name), entries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *checker) add(ctx context.Context, filePath, problem, detail string, orphan *chunkPart) {
+	issue := CheckIssue{Path: filePath, Problem: problem, Detail: detail}
+	if orphan != nil && c.opts.RemoveOrphans {
+		if err := c.d.removeChunk(ctx, filePath, *orphan); err != nil {
+			issue.Detail += ", failed to remove: " + err.Error()
+		} else {
+			issue.Fixed = true
+		}
+	}
+	c.progress.Issues++
+	c.issue(issue)
+}
+
+func (c *checker) checkFile(ctx context.Context, e checkEntry) error {
+	filePath := path.Join(e.dir, e.name)
+	g := e.group
+	obj, ok, err := c.d.buildListedObject(ctx, e.dir, e.name, g)
+	if err != nil {
+		c.add(ctx, filePath, ProblemUnreadable, err.Error(), nil)
+		return nil
+	}
+	var file *Object
+	if ok {
+		file = obj.(*Object)
+	}
+	if file != nil && !file.Chunked && len(g.partsByXact) > 0 && g.base.Obj.GetSize() <= maxMetadataSizeRead {
+		// the chunks are kept, the metadata may be restored from elsewhere
+		c.add(ctx, filePath, ProblemBadMetadata, "the metadata of the chunks can't be read", nil)
+		return nil
+	}
+
+	selected := ""
+	if file != nil && file.Meta != nil {
+		selected = file.Meta.XactID
+	}
+	xacts := make([]string, 0, len(g.partsByXact))
+	for xact := range g.partsByXact {
+		xacts = append(xacts, xact)
+	}
+	sort.Strings(xacts)
+	for _, xact := range xacts {
+		if file != nil && file.Chunked && xact == selected {
+			continue
+		}
+		for _, part := range sortChunkParts(g.partsByXact[xact]) {
+			c.add(ctx, filePath, ProblemOrphanChunk, c.chunkDetail(filePath, part)+" belongs to no file", &part)
+		}
+	}
+	for _, part := range g.duplicates {
+		c.add(ctx, filePath, ProblemOrphanChunk, c.chunkDetail(filePath, part)+" is a duplicate", &part)
+	}
+	if file == nil || !file.Chunked {
+		return nil
+	}
+
+	nChunks := 0
+	if file.Meta != nil {
+		nChunks = file.Meta.NChunks
+	} else if len(file.Parts) > 0 {
+		nChunks = file.Parts[len(file.Parts)-1].No + 1
+	}
+	present := make(map[int]bool, len(file.Parts))
+	var size int64
+	var extra []chunkPart
+	for _, part := range file.Parts {
+		if part.No >= nChunks {
+			extra = append(extra, part)
+			continue
+		}
+		present[part.No] = true
+		size += part.Size
+	}
+	for _, part := range extra {
+		c.add(ctx, filePath, ProblemOrphanChunk, fmt.Sprintf("%s is beyond the %d chunks of the file", c.chunkDetail(filePath, part), nChunks), &part)
+	}
+	missing := 0
+	for no := 0; no < nChunks; no++ {
+		if !present[no] {
+			missing++
+			c.add(ctx, filePath, ProblemMissingChunk, fmt.Sprintf("chunk %d of %d is missing", no+c.d.StartFrom, nChunks), nil)
+		}
+	}
+	if missing > 0 {
+		return nil
+	}
+	if file.Meta != nil && size != file.Meta.Size {
+		c.add(ctx, filePath, ProblemSizeMismatch, fmt.Sprintf("the chunks have %d bytes, the metadata %d", size, file.Meta.Size), nil)
+		return nil
+	}
+	if c.opts.VerifyHash && file.Meta != nil && (file.Meta.MD5 != "" || file.Meta.SHA1 != "") {
+		if err = c.verifyHash(ctx, file); err != nil {
+			if utils.IsCanceled(ctx) {
+				return ctx.Err()
+			}
+			c.add(ctx, filePath, ProblemHashMismatch, err.Error(), nil)
+			return nil
+		}
+	}
+	if c.opts.Rebalance {
+		for _, part := range file.Parts {
+			target := c.d.chunkTargetIndex(part.No)
+			if part.RemoteIndex == target {
+				continue
+			}
+			if err = c.d.moveChunk(ctx, file, part, target); err != nil {
+				if utils.IsCanceled(ctx) {
+					return ctx.Err()
+				}
+				c.add(ctx, filePath, ProblemUnreadable, fmt.Sprintf("failed to move %s: %v", c.chunkDetail(filePath, part), err), nil)
+				continue
+			}
+			c.progress.Moved++
+		}
+	}
+	return nil
+}
+
+func (c *checker) chunkDetail(filePath string, part chunkPart) string {
+	return fmt.Sprintf("chunk %s on %s", path.Base(c.d.makeChunkName(filePath, part.No, part.XactID)), c.d.remoteTargets[part.RemoteIndex].MountPath)
+}
+
+func (c *checker) verifyHash(ctx context.Context, file *Object) error {
+	link, err := c.d.Link(ctx, file, model.LinkArgs{})
+	if err != nil {
+		return err
+	}
+	defer link.RangeReadCloser.Close()
+	// the reader is closed with the link
+	rc, err := link.RangeReadCloser.RangeRead(ctx, http_range.Range{Start: 0, Length: file.GetSize()})
+	if err != nil {
+		return err
+	}
+	var h hash.Hash
+	want := file.Meta.MD5
+	if want != "" {
+		h = md5.New()
+	} else {
+		h, want = sha1.New(), file.Meta.SHA1
+	}
+	if _, err = utils.CopyWithBuffer(h, rc); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != want {
+		return fmt.Errorf("the chunks hash to %s, the metadata has %s", got, want)
+	}
+	return nil
+}
+
+func (d *Chunker) removeChunk(ctx context.Context, filePath string, part chunkPart) error {
+	actualPath, err := d.getActualChunkPath(filePath, part.No, part.XactID, part.RemoteIndex)
+	if err != nil {
+		return err
+	}
+	return op.Remove(ctx, d.remoteTargets[part.RemoteIndex].Storage, actualPath)
+}
+
+// moveChunk moves a chunk of file to another target, copying it through
+// alist since the targets are different storages.
+func (d *Chunker) moveChunk(ctx context.Context, file *Object, part chunkPart, target int) error {
+	filePath := file.GetPath()
+	if err := d.ensureDirOnTarget(ctx, target, path.Dir(filePath)); err != nil {
+		return err
+	}
+	srcStorage := d.remoteTargets[part.RemoteIndex].Storage
+	srcActualPath, err := d.getActualChunkPath(filePath, part.No, part.XactID, part.RemoteIndex)
+	if err != nil {
+		return err
+	}
+	dstDirActualPath, err := d.getActualPathForRemoteOnTarget(path.Dir(filePath), target)
+	if err != nil {
+		return err
+	}
+	link, srcObj, err := op.Link(ctx, srcStorage, srcActualPath, model.LinkArgs{})
+	if err != nil {
+		return err
+	}
+	ss, err := stream.NewSeekableStream(stream.FileStream{Obj: srcObj, Ctx: ctx}, link)
+	if err != nil {
+		return err
+	}
+	err = op.Put(ctx, d.remoteTargets[target].Storage, dstDirActualPath, ss, nil, false)
+	if err != nil {
+		return err
+	}
+	return op.Remove(ctx, srcStorage, srcActualPath)
+}
@@ -0,0 +1,125 @@
+package chunker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/alist-org/alist/v3/drivers/local"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/stream"
+	"github.com/alist-org/alist/v3/internal/testutil"
+)
+
+func init() {
+	testutil.InitDB()
+}
+
+func check(t *testing.T, d *Chunker, opts CheckOptions) ([]CheckIssue, CheckProgress) {
+	var issues []CheckIssue
+	var progress CheckProgress
+	err := d.Check(context.Background(), "/", opts, func(issue CheckIssue) {
+		issues = append(issues, issue)
+	}, func(p CheckProgress) {
+		progress = p
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return issues, progress
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	_, a := testutil.CreateLocal(t, "/check_a")
+	_, b := testutil.CreateLocal(t, "/check_b")
+	addition := Addition{
+		RemotePath:           "/check_a",
+		StoreChunksInPrimary: true,
+		ChunkSize:            10,
+		NameFormat:           defaultChunkNameFmt,
+		StartFrom:            1,
+		MetaFormat:           "simplejson",
+		HashType:             "md5",
+	}
+	bs, _ := json.Marshal(addition)
+	id, err := op.CreateStorage(ctx, model.Storage{Driver: "Chunker", MountPath: "/check", Addition: string(bs)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"ok.txt", "lost.txt", "bad.txt"} {
+		data := bytes.Repeat([]byte(name), 10)
+		s := &stream.FileStream{
+			Obj:    &model.Object{Name: name, Size: int64(len(data)), Modified: time.Now()},
+			Reader: bytes.NewReader(data),
+		}
+		if err = fs.PutDirectly(ctx, "/check", s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	chunks, _ := filepath.Glob(filepath.Join(a, "*.rclone_chunk.*"))
+	for _, chunk := range chunks {
+		base := filepath.Base(chunk)
+		switch {
+		case strings.HasPrefix(base, "lost.txt.rclone_chunk.002"):
+			_ = os.Remove(chunk)
+		case strings.HasPrefix(base, "bad.txt.rclone_chunk.001"):
+			_ = os.WriteFile(chunk, []byte("0123456789"), 0o644)
+		}
+	}
+	if err = os.WriteFile(filepath.Join(a, "gone.txt.rclone_chunk.001_abcd"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	d := op.GetBalancedStorage("/check").(*Chunker)
+	issues, _ := check(t, d, CheckOptions{VerifyHash: true})
+	problems := map[string]string{}
+	for _, issue := range issues {
+		problems[issue.Path] = issue.Problem
+	}
+	want := map[string]string{
+		"/lost.txt": ProblemMissingChunk,
+		"/bad.txt":  ProblemHashMismatch,
+		"/gone.txt": ProblemOrphanChunk,
+	}
+	for p, problem := range want {
+		if problems[p] != problem {
+			t.Errorf("%s: got %q, want %q", p, problems[p], problem)
+		}
+	}
+	if len(issues) != len(want) {
+		t.Errorf("unexpected issues: %+v", issues)
+	}
+
+	// add a remote path, the chunks are spread over both then
+	addition.RemotePaths = "/check_b"
+	bs, _ = json.Marshal(addition)
+	storage, _ := db.GetStorageById(id)
+	storage.Addition = string(bs)
+	if err = op.UpdateStorage(ctx, *storage); err != nil {
+		t.Fatal(err)
+	}
+	d = op.GetBalancedStorage("/check").(*Chunker)
+	_, progress := check(t, d, CheckOptions{RemoveOrphans: true, Rebalance: true})
+	if _, err = os.Stat(filepath.Join(a, "gone.txt.rclone_chunk.001_abcd")); !os.IsNotExist(err) {
+		t.Errorf("orphan chunk should be removed: %v", err)
+	}
+	moved, _ := filepath.Glob(filepath.Join(b, "ok.txt.rclone_chunk.*"))
+	if len(moved) != 3 || progress.Moved < 3 {
+		t.Errorf("every other chunk of ok.txt should be moved, got %v", moved)
+	}
+	issues, _ = check(t, d, CheckOptions{VerifyHash: true})
+	for _, issue := range issues {
+		if issue.Path == "/ok.txt" {
+			t.Errorf("ok.txt should be intact after rebalance: %+v", issue)
+		}
+	}
+}
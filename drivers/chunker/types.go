@@ -73,6 +73,8 @@ type chunkPart struct {
 type groupInfo struct {
 	base        *locatedObj
 	partsByXact map[string]map[int]chunkPart
+	// duplicates are the copies of chunks found on more than one target
+	duplicates []chunkPart
 }
 
 type Object struct {
@@ -335,6 +335,33 @@ func (d *Chunker) chunkLocation(filePath string, part chunkPart) objectLocation
 }
 
 func (d *Chunker) listDirObjects(ctx context.Context, dirPath string, refresh bool) ([]model.Obj, error) {
+	dirMap, groups, err := d.listDirGroups(ctx, dirPath, refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make([]model.Obj, 0, len(dirMap))
+	for _, obj := range dirMap {
+		dirs = append(dirs, obj)
+	}
+
+	result := make([]model.Obj, 0, len(dirs)+len(groups))
+	result = append(result, dirs...)
+	for name, group := range groups {
+		obj, ok, err := d.buildListedObject(ctx, dirPath, name, group)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result = append(result, obj)
+		}
+	}
+	return result, nil
+}
+
+// listDirGroups lists the dirs of dirPath on all targets, and its files
+// grouped by their logical name with the chunks of each transaction.
+func (d *Chunker) listDirGroups(ctx context.Context, dirPath string, refresh bool) (map[string]model.Obj, map[string]*groupInfo, error) {
 	groups := map[string]*groupInfo{}
 	dirMap := map[string]model.Obj{}
 	found := false
@@ -346,7 +373,7 @@ func (d *Chunker) listDirObjects(ctx context.Context, dirPath string, refresh bo
 			if errs.IsObjectNotFound(err) {
 				continue
 			}
-			return nil, err
+			return nil, nil, err
 		}
 		found = true
 
@@ -401,31 +428,19 @@ func (d *Chunker) listDirObjects(ctx context.Context, dirPath string, refresh bo
 			existing, ok := g.partsByXact[xactID][chunkNo]
 			if !ok || part.RemoteIndex < existing.RemoteIndex {
 				g.partsByXact[xactID][chunkNo] = part
+				if ok {
+					g.duplicates = append(g.duplicates, existing)
+				}
+			} else {
+				g.duplicates = append(g.duplicates, part)
 			}
 		}
 	}
 
 	if !found && !utils.PathEqual(dirPath, "/") {
-		return nil, errs.ObjectNotFound
+		return nil, nil, errs.ObjectNotFound
 	}
-
-	dirs := make([]model.Obj, 0, len(dirMap))
-	for _, obj := range dirMap {
-		dirs = append(dirs, obj)
-	}
-
-	result := make([]model.Obj, 0, len(dirs)+len(groups))
-	result = append(result, dirs...)
-	for name, group := range groups {
-		obj, ok, err := d.buildListedObject(ctx, dirPath, name, group)
-		if err != nil {
-			return nil, err
-		}
-		if ok {
-			result = append(result, obj)
-		}
-	}
-	return result, nil
+	return dirMap, groups, nil
 }
 
 func (d *Chunker) targetPathExists(ctx context.Context, remoteIndex int, logicalPath string) (bool, error) {
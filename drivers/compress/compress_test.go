@@ -0,0 +1,76 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestName(t *testing.T) {
+	remote := encodeName("a.b.txt", 123456789, algZstd)
+	name, size, alg, ok := decodeName(remote)
+	if !ok || name != "a.b.txt" || size != 123456789 || alg != algZstd {
+		t.Errorf("decodeName(%s) = %s %d %s %v", remote, name, size, alg, ok)
+	}
+	for _, n := range []string{"a.txt", "a.mp4", "a.z-z.gz.alz", "a.1.bz.alz", ".1.gz.alz"} {
+		if _, _, _, ok := decodeName(n); ok {
+			t.Errorf("%s should not be a compressed file", n)
+		}
+	}
+}
+
+func TestBlockReader(t *testing.T) {
+	enc, _ := zstd.NewWriter(nil)
+	dec, _ := zstd.NewReader(nil)
+	defer dec.Close()
+	codecs := map[string]codec{
+		algZstd: &zstdCodec{enc: enc, dec: dec},
+		algGzip: &gzipCodec{level: gzip.DefaultCompression},
+	}
+	data := bytes.Repeat([]byte("some text that compresses well "), 1000)
+	rand.New(rand.NewSource(1)).Read(data[5000:6000])
+	const blockSize = 4096
+	for alg, c := range codecs {
+		var buf bytes.Buffer
+		n, err := compressStream(&buf, bytes.NewReader(data), c, alg, blockSize)
+		if err != nil || n != int64(len(data)) {
+			t.Fatalf("%s: compressed %d bytes: %v", alg, n, err)
+		}
+		file := buf.Bytes()
+		if len(file) >= len(data) {
+			t.Errorf("%s: %d bytes compressed to %d", alg, len(data), len(file))
+		}
+		blockSize, blocks, err := parseFooter(file[len(file)-footerSize:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		indexStart := int64(len(file)) - int64(footerSize) - indexSize(blocks)
+		idx, err := parseIndex(blockSize, file[indexStart:int64(len(file))-int64(footerSize)], indexStart)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range [][2]int64{{0, int64(len(data))}, {0, 1}, {4095, 2}, {5000, 10000}, {int64(len(data)) - 7, 7}} {
+			first, last := r[0]/blockSize, (r[0]+r[1]-1)/blockSize
+			br := &blockReader{
+				r:      io.NopCloser(bytes.NewReader(file[idx.offsets[first]:idx.offsets[last+1]])),
+				c:      c,
+				idx:    idx,
+				cur:    first,
+				last:   last,
+				skip:   r[0] - first*blockSize,
+				remain: r[1],
+			}
+			got, err := io.ReadAll(br)
+			if err != nil {
+				t.Fatalf("%s: read %v: %v", alg, r, err)
+			}
+			if !bytes.Equal(got, data[r[0]:r[0]+r[1]]) {
+				t.Errorf("%s: wrong data of range %v", alg, r)
+			}
+		}
+	}
+}
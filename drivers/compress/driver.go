@@ -0,0 +1,351 @@
+package compress
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	stdpath "path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Xhofe/go-cache"
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/stream"
+	"github.com/alist-org/alist/v3/pkg/http_range"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compress stores the files of another mount path compressed block by
+// block, so that ranges can still be read without the whole file.
+type Compress struct {
+	model.Storage
+	Addition
+	remoteStorage driver.Driver
+	skipExts      []string
+	codecs        map[string]codec
+	indexes       cache.ICache[*blockIndex]
+}
+
+func (d *Compress) Config() driver.Config {
+	return config
+}
+
+func (d *Compress) GetAddition() driver.Additional {
+	return &d.Addition
+}
+
+func (d *Compress) Init(ctx context.Context) error {
+	if d.BlockSize <= 0 {
+		d.BlockSize = defaultBlockSize
+	}
+	d.Algorithm = utils.GetNoneEmpty(d.Algorithm, algZstd)
+	if _, ok := algIDs[d.Algorithm]; !ok {
+		return fmt.Errorf("unknown algorithm: %s", d.Algorithm)
+	}
+	d.RemotePath = utils.FixAndCleanPath(d.RemotePath)
+	op.MustSaveDriverStorage(d)
+
+	storage, err := fs.GetStorage(d.RemotePath, &fs.GetStoragesArgs{})
+	if err != nil {
+		return fmt.Errorf("can't find remote storage: %w", err)
+	}
+	d.remoteStorage = storage
+
+	d.skipExts = nil
+	for _, ext := range strings.Split(d.SkipExtensions, ",") {
+		ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+		if ext != "" {
+			d.skipExts = append(d.skipExts, ext)
+		}
+	}
+
+	zstdLevel, gzipLevel := zstd.SpeedDefault, gzip.DefaultCompression
+	switch d.Level {
+	case "fastest":
+		zstdLevel, gzipLevel = zstd.SpeedFastest, gzip.BestSpeed
+	case "better":
+		zstdLevel, gzipLevel = zstd.SpeedBetterCompression, 7
+	case "best":
+		zstdLevel, gzipLevel = zstd.SpeedBestCompression, gzip.BestCompression
+	}
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstdLevel))
+	if err != nil {
+		return fmt.Errorf("failed to create zstd encoder: %w", err)
+	}
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		return fmt.Errorf("failed to create zstd decoder: %w", err)
+	}
+	d.codecs = map[string]codec{
+		algZstd: &zstdCodec{enc: enc, dec: dec},
+		algGzip: &gzipCodec{level: gzipLevel},
+	}
+	d.indexes = cache.NewMemCache(cache.WithShards[*blockIndex](16))
+	return nil
+}
+
+func (d *Compress) Drop(ctx context.Context) error {
+	if c, ok := d.codecs[algZstd].(*zstdCodec); ok {
+		_ = c.enc.Close()
+		c.dec.Close()
+	}
+	if d.indexes != nil {
+		d.indexes.Clear()
+	}
+	return nil
+}
+
+func (d *Compress) List(ctx context.Context, dir model.Obj, args model.ListArgs) ([]model.Obj, error) {
+	objs, err := fs.List(ctx, d.remotePath(dir.GetPath()), &fs.ListArgs{NoLog: true})
+	if err != nil {
+		return nil, err
+	}
+	return utils.SliceConvert(objs, func(obj model.Obj) (model.Obj, error) {
+		return wrapObj(dir.GetPath(), obj), nil
+	})
+}
+
+func (d *Compress) Get(ctx context.Context, path string) (model.Obj, error) {
+	if utils.PathEqual(path, "/") {
+		return &model.Object{
+			Name:     "Root",
+			IsFolder: true,
+			Path:     "/",
+		}, nil
+	}
+	obj, err := fs.Get(ctx, d.remotePath(path), &fs.GetArgs{NoLog: true})
+	if errs.IsObjectNotFound(err) {
+		// compressed files are stored under another name
+		obj, err = d.find(ctx, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return wrapObj(stdpath.Dir(path), obj), nil
+}
+
+func (d *Compress) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	remote, err := d.find(ctx, file.GetPath())
+	if err != nil {
+		return nil, err
+	}
+	actualPath, err := d.getActualPathForRemote(stdpath.Join(stdpath.Dir(file.GetPath()), remote.GetName()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert path to remote path: %w", err)
+	}
+	remoteLink, remoteFile, err := op.Link(ctx, d.remoteStorage, actualPath, args)
+	if err != nil {
+		return nil, err
+	}
+	_, size, alg, ok := decodeName(remote.GetName())
+	if !ok {
+		return remoteLink, nil
+	}
+	rrc, err := remoteRangeReader(remoteLink, remoteFile)
+	if err != nil {
+		return nil, err
+	}
+	key := actualPath + "|" + strconv.FormatInt(remoteFile.GetSize(), 10) + "|" + strconv.FormatInt(remoteFile.ModTime().Unix(), 10)
+	c := d.codecs[alg]
+	rangeReader := func(ctx context.Context, httpRange http_range.Range) (io.ReadCloser, error) {
+		if httpRange.Length < 0 || httpRange.Start+httpRange.Length > size {
+			httpRange.Length = size - httpRange.Start
+		}
+		if httpRange.Start < 0 || httpRange.Length < 0 {
+			return nil, fmt.Errorf("range %d-%d out of %d", httpRange.Start, httpRange.Length, size)
+		}
+		if httpRange.Length == 0 {
+			return io.NopCloser(strings.NewReader("")), nil
+		}
+		idx, err := d.index(ctx, key, rrc, remoteFile.GetSize())
+		if err != nil {
+			return nil, err
+		}
+		first := httpRange.Start / idx.blockSize
+		last := (httpRange.Start + httpRange.Length - 1) / idx.blockSize
+		if last >= idx.blocks() {
+			return nil, fmt.Errorf("index of %s doesn't cover %d bytes", file.GetName(), size)
+		}
+		start := idx.offsets[first]
+		rc, err := rrc.RangeRead(ctx, http_range.Range{Start: start, Length: idx.offsets[last+1] - start})
+		if err != nil {
+			return nil, err
+		}
+		return &blockReader{
+			r:      rc,
+			c:      c,
+			idx:    idx,
+			cur:    first,
+			last:   last,
+			skip:   httpRange.Start - first*idx.blockSize,
+			remain: httpRange.Length,
+		}, nil
+	}
+	return &model.Link{
+		RangeReadCloser: &model.RangeReadCloser{RangeReader: rangeReader, Closers: utils.NewClosers(rrc)},
+		Expiration:      remoteLink.Expiration,
+	}, nil
+}
+
+// index reads the block index from the end of a compressed file, it is
+// cached since every range needs it.
+func (d *Compress) index(ctx context.Context, key string, rrc model.RangeReadCloserIF, compressedSize int64) (*blockIndex, error) {
+	if idx, ok := d.indexes.Get(key); ok {
+		return idx, nil
+	}
+	readAt := func(start, length int64) ([]byte, error) {
+		rc, err := rrc.RangeRead(ctx, http_range.Range{Start: start, Length: length})
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		buf := make([]byte, length)
+		_, err = io.ReadFull(rc, buf)
+		return buf, err
+	}
+	if compressedSize < int64(headerSize+footerSize) {
+		return nil, fmt.Errorf("not a compressed file")
+	}
+	footer, err := readAt(compressedSize-int64(footerSize), int64(footerSize))
+	if err != nil {
+		return nil, err
+	}
+	blockSize, blocks, err := parseFooter(footer)
+	if err != nil {
+		return nil, err
+	}
+	indexStart := compressedSize - int64(footerSize) - indexSize(blocks)
+	if blockSize <= 0 || indexStart < int64(headerSize) {
+		return nil, fmt.Errorf("broken footer of compressed file")
+	}
+	data, err := readAt(indexStart, indexSize(blocks))
+	if err != nil {
+		return nil, err
+	}
+	idx, err := parseIndex(blockSize, data, indexStart)
+	if err != nil {
+		return nil, err
+	}
+	d.indexes.Set(key, idx, cache.WithEx[*blockIndex](30*time.Minute))
+	return idx, nil
+}
+
+func (d *Compress) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	actualPath, err := d.getActualPathForRemote(parentDir.GetPath())
+	if err != nil {
+		return fmt.Errorf("failed to convert path to remote path: %w", err)
+	}
+	return op.MakeDir(ctx, d.remoteStorage, stdpath.Join(actualPath, dirName))
+}
+
+func (d *Compress) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	srcActualPath, err := d.remoteActualPath(ctx, srcObj)
+	if err != nil {
+		return err
+	}
+	dstActualPath, err := d.getActualPathForRemote(dstDir.GetPath())
+	if err != nil {
+		return fmt.Errorf("failed to convert path to remote path: %w", err)
+	}
+	return op.Move(ctx, d.remoteStorage, srcActualPath, dstActualPath)
+}
+
+func (d *Compress) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	actualPath, err := d.remoteActualPath(ctx, srcObj)
+	if err != nil {
+		return err
+	}
+	if _, size, alg, ok := decodeName(stdpath.Base(actualPath)); ok && !srcObj.IsDir() {
+		newName = encodeName(newName, size, alg)
+	}
+	return op.Rename(ctx, d.remoteStorage, actualPath, newName)
+}
+
+func (d *Compress) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	srcActualPath, err := d.remoteActualPath(ctx, srcObj)
+	if err != nil {
+		return err
+	}
+	dstActualPath, err := d.getActualPathForRemote(dstDir.GetPath())
+	if err != nil {
+		return fmt.Errorf("failed to convert path to remote path: %w", err)
+	}
+	return op.Copy(ctx, d.remoteStorage, srcActualPath, dstActualPath)
+}
+
+func (d *Compress) Remove(ctx context.Context, obj model.Obj) error {
+	actualPath, err := d.remoteActualPath(ctx, obj)
+	if err != nil {
+		return err
+	}
+	return op.Remove(ctx, d.remoteStorage, actualPath)
+}
+
+func (d *Compress) Put(ctx context.Context, dstDir model.Obj, streamer model.FileStreamer, up driver.UpdateProgress) error {
+	dstActualPath, err := d.getActualPathForRemote(dstDir.GetPath())
+	if err != nil {
+		return fmt.Errorf("failed to convert path to remote path: %w", err)
+	}
+	name := streamer.GetName()
+	// an existing file may be stored under another name, as its size is in it
+	old, err := d.find(ctx, stdpath.Join(dstDir.GetPath(), name))
+	if err != nil && !errs.IsObjectNotFound(err) {
+		return err
+	}
+	remoteName := name
+	if d.skip(name) {
+		err = op.Put(ctx, d.remoteStorage, dstActualPath, streamer, up, false)
+	} else {
+		remoteName, err = d.putCompressed(ctx, dstActualPath, streamer, up)
+	}
+	if err != nil {
+		return err
+	}
+	if old != nil && old.GetName() != remoteName {
+		return op.Remove(ctx, d.remoteStorage, stdpath.Join(dstActualPath, old.GetName()))
+	}
+	return nil
+}
+
+// putCompressed compresses the stream into a temp file first, since the
+// remote needs the size of the upload.
+func (d *Compress) putCompressed(ctx context.Context, dstActualPath string, streamer model.FileStreamer, up driver.UpdateProgress) (string, error) {
+	tmp, err := os.CreateTemp(conf.Conf.TempDir, "file-*")
+	if err != nil {
+		return "", err
+	}
+	out := &stream.FileStream{Ctx: ctx, Mimetype: "application/octet-stream", WebPutAsTask: streamer.NeedStore()}
+	out.SetTmpFile(tmp)
+	in := &stream.ReaderUpdatingProgress{Reader: streamer, UpdateProgress: model.UpdateProgressWithRange(up, 0, 50)}
+	size, err := compressStream(tmp, in, d.codecs[d.Algorithm], d.Algorithm, d.BlockSize*1024*1024)
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		_ = out.Close()
+		return "", fmt.Errorf("failed to compress: %w", err)
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		_ = out.Close()
+		return "", err
+	}
+	remoteName := encodeName(streamer.GetName(), size, d.Algorithm)
+	out.Obj = &model.Object{
+		Name:     remoteName,
+		Size:     info.Size(),
+		Modified: streamer.ModTime(),
+	}
+	return remoteName, op.Put(ctx, d.remoteStorage, dstActualPath, out, model.UpdateProgressWithRange(up, 50, 100), false)
+}
+
+var _ driver.Driver = (*Compress)(nil)
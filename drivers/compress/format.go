@@ -0,0 +1,262 @@
+package compress
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// A compressed file is stored as
+//
+//	header | block 0 | block 1 | ... | index | footer
+//
+// every block holds blockSize bytes of the original data (the last one may
+// be shorter) compressed on its own, so a range only needs the blocks it
+// covers. The index lists the offsets of the blocks in the file plus the
+// end of the last one, the footer gives the block size and count.
+const (
+	headerMagic = "ALZ\x01"
+	footerMagic = "ALZI"
+	headerSize  = len(headerMagic) + 1
+	footerSize  = 4 + 4 + len(footerMagic)
+	// compressedSuffix ends the remote name of compressed files, which is
+	// <name>.<original size in base 36>.<algorithm extension>.alz
+	compressedSuffix = ".alz"
+)
+
+const (
+	algZstd = "zstd"
+	algGzip = "gzip"
+)
+
+var algIDs = map[string]byte{algZstd: 1, algGzip: 2}
+
+var algExts = map[string]string{algZstd: "zst", algGzip: "gz"}
+
+func encodeName(name string, size int64, alg string) string {
+	return name + "." + strconv.FormatInt(size, 36) + "." + algExts[alg] + compressedSuffix
+}
+
+// decodeName returns the original name and size of a compressed file, ok is
+// false if the file is stored as is.
+func decodeName(remoteName string) (name string, size int64, alg string, ok bool) {
+	rest, found := strings.CutSuffix(remoteName, compressedSuffix)
+	if !found {
+		return
+	}
+	i := strings.LastIndex(rest, ".")
+	if i < 0 {
+		return
+	}
+	for a, ext := range algExts {
+		if rest[i+1:] == ext {
+			alg = a
+		}
+	}
+	if alg == "" {
+		return
+	}
+	rest = rest[:i]
+	i = strings.LastIndex(rest, ".")
+	if i <= 0 {
+		return
+	}
+	size, err := strconv.ParseInt(rest[i+1:], 36, 64)
+	if err != nil || size < 0 {
+		return
+	}
+	return rest[:i], size, alg, true
+}
+
+type codec interface {
+	compress(dst, src []byte) ([]byte, error)
+	decompress(dst, src []byte) ([]byte, error)
+}
+
+type zstdCodec struct {
+	enc *zstd.Encoder
+	dec *zstd.Decoder
+}
+
+func (c *zstdCodec) compress(dst, src []byte) ([]byte, error) {
+	return c.enc.EncodeAll(src, dst[:0]), nil
+}
+
+func (c *zstdCodec) decompress(dst, src []byte) ([]byte, error) {
+	return c.dec.DecodeAll(src, dst[:0])
+}
+
+type gzipCodec struct {
+	level int
+}
+
+func (c *gzipCodec) compress(dst, src []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst[:0])
+	w, err := gzip.NewWriterLevel(buf, c.level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(src); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *gzipCodec) decompress(dst, src []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer(dst[:0])
+	if _, err = buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), r.Close()
+}
+
+// compressStream writes src to dst in the block format and returns the
+// number of bytes read from src.
+func compressStream(dst io.Writer, src io.Reader, c codec, alg string, blockSize int) (int64, error) {
+	if _, err := dst.Write(append([]byte(headerMagic), algIDs[alg])); err != nil {
+		return 0, err
+	}
+	var (
+		read    int64
+		off     = int64(headerSize)
+		offsets []int64
+		buf     = make([]byte, blockSize)
+		out     []byte
+	)
+	for {
+		n, err := io.ReadFull(src, buf)
+		if n > 0 {
+			out, err = c.compress(out, buf[:n])
+			if err != nil {
+				return read, err
+			}
+			if _, err = dst.Write(out); err != nil {
+				return read, err
+			}
+			offsets = append(offsets, off)
+			off += int64(len(out))
+			read += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return read, err
+		}
+	}
+	offsets = append(offsets, off)
+	trailer := make([]byte, 0, len(offsets)*8+footerSize)
+	for _, o := range offsets {
+		trailer = binary.LittleEndian.AppendUint64(trailer, uint64(o))
+	}
+	trailer = binary.LittleEndian.AppendUint32(trailer, uint32(blockSize))
+	trailer = binary.LittleEndian.AppendUint32(trailer, uint32(len(offsets)-1))
+	trailer = append(trailer, footerMagic...)
+	_, err := dst.Write(trailer)
+	return read, err
+}
+
+// blockIndex locates the blocks of a compressed file.
+type blockIndex struct {
+	blockSize int64
+	// offsets has one more entry than blocks, the end of the last block
+	offsets []int64
+}
+
+func (idx *blockIndex) blocks() int64 {
+	return int64(len(idx.offsets) - 1)
+}
+
+// parseFooter returns the block size and count from the last footerSize bytes.
+func parseFooter(footer []byte) (blockSize, blocks int64, err error) {
+	if len(footer) != footerSize || string(footer[8:]) != footerMagic {
+		return 0, 0, fmt.Errorf("not a compressed file")
+	}
+	return int64(binary.LittleEndian.Uint32(footer)), int64(binary.LittleEndian.Uint32(footer[4:])), nil
+}
+
+func indexSize(blocks int64) int64 {
+	return (blocks + 1) * 8
+}
+
+func parseIndex(blockSize int64, data []byte, compressedSize int64) (*blockIndex, error) {
+	idx := &blockIndex{blockSize: blockSize, offsets: make([]int64, len(data)/8)}
+	prev := int64(headerSize)
+	for i := range idx.offsets {
+		o := int64(binary.LittleEndian.Uint64(data[i*8:]))
+		if o < prev || o > compressedSize {
+			return nil, fmt.Errorf("broken index of compressed file")
+		}
+		idx.offsets[i], prev = o, o
+	}
+	return idx, nil
+}
+
+// blockReader decompresses the blocks first..last read from r, skipping
+// the first skip bytes and stopping after remain bytes.
+type blockReader struct {
+	r      io.ReadCloser
+	c      codec
+	idx    *blockIndex
+	cur    int64
+	last   int64
+	skip   int64
+	remain int64
+	cBuf   []byte
+	buf    []byte
+	data   []byte
+}
+
+func (r *blockReader) Read(p []byte) (int, error) {
+	for len(r.data) == 0 {
+		if r.remain <= 0 || r.cur > r.last {
+			return 0, io.EOF
+		}
+		n := r.idx.offsets[r.cur+1] - r.idx.offsets[r.cur]
+		if int64(cap(r.cBuf)) < n {
+			r.cBuf = make([]byte, n)
+		}
+		r.cBuf = r.cBuf[:n]
+		if _, err := io.ReadFull(r.r, r.cBuf); err != nil {
+			return 0, err
+		}
+		var err error
+		r.buf, err = r.c.decompress(r.buf, r.cBuf)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decompress block %d: %w", r.cur, err)
+		}
+		r.data = r.buf
+		if r.skip > 0 {
+			if r.skip > int64(len(r.data)) {
+				return 0, io.ErrUnexpectedEOF
+			}
+			r.data = r.data[r.skip:]
+			r.skip = 0
+		}
+		if int64(len(r.data)) > r.remain {
+			r.data = r.data[:r.remain]
+		}
+		r.cur++
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	r.remain -= int64(n)
+	return n, nil
+}
+
+func (r *blockReader) Close() error {
+	return r.r.Close()
+}
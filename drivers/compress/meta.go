@@ -0,0 +1,36 @@
+package compress
+
+import (
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/op"
+)
+
+const defaultBlockSize = 1 // MB
+
+type Addition struct {
+	RemotePath     string `json:"remote_path" required:"true" help:"AList mounted folder path used to store compressed data, e.g. /my-storage/backup"`
+	Algorithm      string `json:"algorithm" type:"select" required:"true" options:"zstd,gzip" default:"zstd" help:"algorithm of new uploads, files of both algorithms can be read"`
+	Level          string `json:"level" type:"select" required:"true" options:"fastest,default,better,best" default:"default"`
+	BlockSize      int    `json:"block_size" type:"number" default:"1" help:"Size in MB of the blocks compressed apart, smaller blocks make seeking cheaper but compress worse"`
+	SkipExtensions string `json:"skip_extensions" type:"text" default:"7z,zip,rar,gz,tgz,bz2,xz,zst,lz4,br,cab,jar,apk,ipa,dmg,iso,deb,rpm,jpg,jpeg,png,gif,webp,heic,heif,avif,jxl,mp3,aac,m4a,flac,ogg,opus,wma,mp4,mkv,avi,mov,webm,flv,wmv,m4v,ts,rmvb,pdf,epub,docx,xlsx,pptx,odt,ods,odp" help:"extensions of files that are already compressed, they are stored as is"`
+}
+
+var config = driver.Config{
+	Name:              "Compress",
+	LocalSort:         true,
+	OnlyLocal:         false,
+	OnlyProxy:         true,
+	NoCache:           true,
+	NoUpload:          false,
+	NeedMs:            false,
+	DefaultRoot:       "/",
+	CheckStatus:       false,
+	Alert:             "",
+	NoOverwriteUpload: false,
+}
+
+func init() {
+	op.RegisterDriver(func() driver.Driver {
+		return &Compress{}
+	})
+}
@@ -0,0 +1,120 @@
+package compress
+
+import (
+	"context"
+	"fmt"
+	"io"
+	stdpath "path"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/stream"
+	"github.com/alist-org/alist/v3/pkg/http_range"
+	"github.com/alist-org/alist/v3/pkg/utils"
+)
+
+func (d *Compress) remotePath(path string) string {
+	return stdpath.Join(d.RemotePath, path)
+}
+
+// actual path is used for internal only. any link for user should come from remotePath
+func (d *Compress) getActualPathForRemote(path string) (string, error) {
+	_, remoteActualPath, err := op.GetStorageAndActualPath(d.remotePath(path))
+	return remoteActualPath, err
+}
+
+func (d *Compress) skip(name string) bool {
+	ext := strings.ToLower(strings.TrimPrefix(stdpath.Ext(name), "."))
+	return ext != "" && utils.SliceContains(d.skipExts, ext)
+}
+
+// find returns the remote object of a file and its remote name, the size of
+// compressed files is only known from the name, so the parent is listed.
+func (d *Compress) find(ctx context.Context, path string) (model.Obj, error) {
+	dir, name := stdpath.Split(path)
+	objs, err := fs.List(ctx, d.remotePath(dir), &fs.ListArgs{NoLog: true})
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range objs {
+		if obj.IsDir() {
+			continue
+		}
+		remoteName := obj.GetName()
+		if n, _, _, ok := decodeName(remoteName); ok {
+			remoteName = n
+		}
+		if remoteName == name {
+			return obj, nil
+		}
+	}
+	return nil, errs.ObjectNotFound
+}
+
+// remoteActualPath returns the actual path of obj in the remote storage.
+func (d *Compress) remoteActualPath(ctx context.Context, obj model.Obj) (string, error) {
+	path := obj.GetPath()
+	if !obj.IsDir() {
+		remote, err := d.find(ctx, path)
+		if err != nil {
+			return "", err
+		}
+		path = stdpath.Join(stdpath.Dir(path), remote.GetName())
+	}
+	actualPath, err := d.getActualPathForRemote(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert path to remote path: %w", err)
+	}
+	return actualPath, nil
+}
+
+// wrapObj converts a remote object of the parent dir, compressed files get
+// their original name and size back.
+func wrapObj(parent string, obj model.Obj) model.Obj {
+	o := model.Object{
+		Name:     obj.GetName(),
+		Size:     obj.GetSize(),
+		Modified: obj.ModTime(),
+		Ctime:    obj.CreateTime(),
+		IsFolder: obj.IsDir(),
+		HashInfo: obj.GetHash(),
+	}
+	if !obj.IsDir() {
+		if name, size, _, ok := decodeName(obj.GetName()); ok {
+			// the hash and thumbnail of the remote are of the compressed data
+			o.Name, o.Size, o.HashInfo = name, size, utils.HashInfo{}
+			o.Path = stdpath.Join(parent, name)
+			return &o
+		}
+	}
+	o.Path = stdpath.Join(parent, o.Name)
+	if thumb, ok := model.GetThumb(obj); ok {
+		return &model.ObjThumb{
+			Object:    o,
+			Thumbnail: model.Thumbnail{Thumbnail: thumb},
+		}
+	}
+	return &o
+}
+
+func remoteRangeReader(remoteLink *model.Link, remoteFile model.Obj) (model.RangeReadCloserIF, error) {
+	if remoteLink.RangeReadCloser != nil {
+		return remoteLink.RangeReadCloser, nil
+	}
+	if remoteLink.MFile != nil {
+		mFile := remoteLink.MFile
+		return &model.RangeReadCloser{
+			RangeReader: func(ctx context.Context, httpRange http_range.Range) (io.ReadCloser, error) {
+				return io.NopCloser(io.NewSectionReader(mFile, httpRange.Start, httpRange.Length)), nil
+			},
+			Closers: utils.NewClosers(mFile),
+		}, nil
+	}
+	if len(remoteLink.URL) > 0 {
+		return stream.GetRangeReadCloserFromLink(remoteFile.GetSize(), remoteLink)
+	}
+	return nil, errs.NotSupport
+}
@@ -68,20 +68,9 @@ func (d *Crypt) Init(ctx context.Context) error {
 	}
 	d.remoteStorage = storage
 
-	p, _ := strings.CutPrefix(d.Password, obfuscatedPrefix)
-	p2, _ := strings.CutPrefix(d.Salt, obfuscatedPrefix)
-	config := configmap.Simple{
-		"password":                  p,
-		"password2":                 p2,
-		"filename_encryption":       d.FileNameEnc,
-		"directory_name_encryption": d.DirNameEnc,
-		"filename_encoding":         d.FileNameEncoding,
-		"suffix":                    d.EncryptedSuffix,
-		"pass_bad_blocks":           "",
-	}
-	c, err := rcCrypt.NewCipher(config)
+	c, err := newCipher(d.Addition)
 	if err != nil {
-		return fmt.Errorf("failed to create Cipher: %w", err)
+		return err
 	}
 	d.cipher = c
 
@@ -89,18 +78,46 @@ func (d *Crypt) Init(ctx context.Context) error {
 }
 
 func (d *Crypt) updateObfusParm(str *string) error {
-	temp := *str
-	if !strings.HasPrefix(temp, obfuscatedPrefix) {
-		temp, err := obscure.Obscure(temp)
-		if err != nil {
-			return err
-		}
-		temp = obfuscatedPrefix + temp
-		*str = temp
+	temp, err := ObfuscateKey(*str)
+	if err != nil {
+		return err
 	}
+	*str = temp
 	return nil
 }
 
+// ObfuscateKey obfuscates a password or salt the way it is saved in the
+// addition, keys that are already obfuscated are returned as is.
+func ObfuscateKey(key string) (string, error) {
+	if strings.HasPrefix(key, obfuscatedPrefix) {
+		return key, nil
+	}
+	temp, err := obscure.Obscure(key)
+	if err != nil {
+		return "", err
+	}
+	return obfuscatedPrefix + temp, nil
+}
+
+func newCipher(a Addition) (*rcCrypt.Cipher, error) {
+	p, _ := strings.CutPrefix(a.Password, obfuscatedPrefix)
+	p2, _ := strings.CutPrefix(a.Salt, obfuscatedPrefix)
+	config := configmap.Simple{
+		"password":                  p,
+		"password2":                 p2,
+		"filename_encryption":       a.FileNameEnc,
+		"directory_name_encryption": a.DirNameEnc,
+		"filename_encoding":         a.FileNameEncoding,
+		"suffix":                    a.EncryptedSuffix,
+		"pass_bad_blocks":           "",
+	}
+	c, err := rcCrypt.NewCipher(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Cipher: %w", err)
+	}
+	return c, nil
+}
+
 func (d *Crypt) Drop(ctx context.Context) error {
 	return nil
 }
@@ -0,0 +1,408 @@
+package crypt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	stdpath "path"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/stream"
+	"github.com/alist-org/alist/v3/pkg/http_range"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	rcCrypt "github.com/rclone/rclone/backend/crypt"
+	"github.com/rclone/rclone/fs/config/obscure"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// sizes of the rclone crypt format, the first block is read to tell the
+	// key of a file since every block is authenticated
+	fileHeaderSize = 32
+	blockDataSize  = 64 * 1024
+	blockSize      = 16 + blockDataSize
+	// rotatingSuffix marks a rotated file uploaded next to the old one when
+	// both have the same name, it is hidden since it can't be decrypted
+	rotatingSuffix = ".rotating"
+)
+
+// RotateProgress is reported after every file of a rotation.
+type RotateProgress struct {
+	DoneFiles  int
+	TotalFiles int
+	DoneSize   int64
+	TotalSize  int64
+	Skipped    int
+}
+
+// Rotator re-encrypts the files of a crypt storage from its keys to new ones
+// in place. Files that are already encrypted with the new keys are skipped,
+// so an interrupted rotation is resumed by running it again. The storage
+// should be disabled meanwhile, it can't read the rotated files until its
+// keys are switched by SetKeys.
+type Rotator struct {
+	remotePath    string
+	remoteStorage driver.Driver
+	old, new      *rcCrypt.Cipher
+	dirNameEnc    bool
+	progress      RotateProgress
+}
+
+// NewRotator creates a rotator of a crypt storage to the given password and
+// salt, which may be obfuscated already.
+func NewRotator(storage model.Storage, password, salt string) (*Rotator, error) {
+	if storage.Driver != config.Name {
+		return nil, fmt.Errorf("storage %s is not a crypt storage", storage.MountPath)
+	}
+	var a Addition
+	if err := utils.Json.UnmarshalFromString(storage.Addition, &a); err != nil {
+		return nil, fmt.Errorf("failed to parse addition: %w", err)
+	}
+	a.FileNameEncoding = utils.GetNoneEmpty(a.FileNameEncoding, "base64")
+	a.EncryptedSuffix = utils.GetNoneEmpty(a.EncryptedSuffix, ".bin")
+	var err error
+	if a.Password, err = ObfuscateKey(a.Password); err != nil {
+		return nil, err
+	}
+	if a.Salt, err = ObfuscateKey(a.Salt); err != nil {
+		return nil, err
+	}
+	newA := a
+	if newA.Password, err = ObfuscateKey(password); err != nil {
+		return nil, err
+	}
+	if newA.Salt, err = ObfuscateKey(salt); err != nil {
+		return nil, err
+	}
+	if revealKey(a.Password) == revealKey(newA.Password) && revealKey(a.Salt) == revealKey(newA.Salt) {
+		return nil, fmt.Errorf("the new password and salt are the same as the current ones")
+	}
+	r := &Rotator{remotePath: a.RemotePath, dirNameEnc: a.DirNameEnc == "true"}
+	if r.old, err = newCipher(a); err != nil {
+		return nil, err
+	}
+	if r.new, err = newCipher(newA); err != nil {
+		return nil, err
+	}
+	if r.remoteStorage, err = fs.GetStorage(a.RemotePath, &fs.GetStoragesArgs{}); err != nil {
+		return nil, fmt.Errorf("can't find remote storage: %w", err)
+	}
+	return r, nil
+}
+
+// SetKeys saves the new password and salt in the addition of the storage.
+func SetKeys(storage *model.Storage, password, salt string) error {
+	var addition map[string]interface{}
+	if err := utils.Json.UnmarshalFromString(storage.Addition, &addition); err != nil {
+		return fmt.Errorf("failed to parse addition: %w", err)
+	}
+	var err error
+	if addition["password"], err = ObfuscateKey(password); err != nil {
+		return err
+	}
+	if addition["salt"], err = ObfuscateKey(salt); err != nil {
+		return err
+	}
+	storage.Addition, err = utils.Json.MarshalToString(addition)
+	return err
+}
+
+func revealKey(key string) string {
+	key, _ = strings.CutPrefix(key, obfuscatedPrefix)
+	revealed, err := obscure.Reveal(key)
+	if err != nil {
+		return key
+	}
+	return revealed
+}
+
+// Rotate re-encrypts the files under path of the storage.
+func (r *Rotator) Rotate(ctx context.Context, path string, report func(RotateProgress)) error {
+	path = utils.FixAndCleanPath(path)
+	_, dir, err := op.GetStorageAndActualPath(r.remotePath)
+	if err != nil {
+		return err
+	}
+	var parent string
+	var dirObj model.Obj
+	for _, name := range strings.Split(strings.Trim(path, "/"), "/") {
+		if name == "" {
+			continue
+		}
+		parent = dir
+		if dirObj, err = r.findDir(ctx, dir, name); err != nil {
+			return err
+		}
+		dir = stdpath.Join(dir, dirObj.GetName())
+	}
+	r.progress = RotateProgress{}
+	if err = r.count(ctx, dir); err != nil {
+		return err
+	}
+	report(r.progress)
+	if err = r.rotateDir(ctx, dir, report); err != nil {
+		return err
+	}
+	if dirObj != nil {
+		// the subtree itself is rotated too
+		return r.renameDir(ctx, parent, dirObj)
+	}
+	return nil
+}
+
+// findDir finds the remote dir of name under dir, with either key.
+func (r *Rotator) findDir(ctx context.Context, dir, name string) (model.Obj, error) {
+	objs, err := op.List(ctx, r.remoteStorage, dir, model.ListArgs{})
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range []*rcCrypt.Cipher{r.new, r.old} {
+		for _, obj := range objs {
+			if !obj.IsDir() {
+				continue
+			}
+			if n, err := c.DecryptDirName(obj.GetName()); err == nil && n == name {
+				return obj, nil
+			}
+		}
+	}
+	return nil, errs.ObjectNotFound
+}
+
+func (r *Rotator) count(ctx context.Context, dir string) error {
+	objs, err := op.List(ctx, r.remoteStorage, dir, model.ListArgs{})
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		if obj.IsDir() {
+			if err = r.count(ctx, stdpath.Join(dir, obj.GetName())); err != nil {
+				return err
+			}
+			continue
+		}
+		r.progress.TotalFiles++
+		r.progress.TotalSize += obj.GetSize()
+	}
+	return nil
+}
+
+func (r *Rotator) rotateDir(ctx context.Context, dir string, report func(RotateProgress)) error {
+	objs, err := op.List(ctx, r.remoteStorage, dir, model.ListArgs{Refresh: true})
+	if err != nil {
+		return err
+	}
+	names := make(map[string]bool, len(objs))
+	for _, obj := range objs {
+		names[obj.GetName()] = true
+	}
+	// files left by an interrupted rotation go first, they are verified
+	// before the old file is removed, so they are only kept if that is gone
+	for _, obj := range objs {
+		name, ok := strings.CutSuffix(obj.GetName(), rotatingSuffix)
+		if !ok || obj.IsDir() {
+			continue
+		}
+		if names[name] {
+			err = op.Remove(ctx, r.remoteStorage, stdpath.Join(dir, obj.GetName()))
+		} else {
+			err = op.Rename(ctx, r.remoteStorage, stdpath.Join(dir, obj.GetName()), name)
+		}
+		if err != nil {
+			return err
+		}
+		r.progress.DoneFiles++
+		r.progress.DoneSize += obj.GetSize()
+	}
+	for _, obj := range objs {
+		if utils.IsCanceled(ctx) {
+			return ctx.Err()
+		}
+		if obj.IsDir() {
+			if err = r.rotateDir(ctx, stdpath.Join(dir, obj.GetName()), report); err != nil {
+				return err
+			}
+			if err = r.renameDir(ctx, dir, obj); err != nil {
+				return err
+			}
+			continue
+		}
+		if strings.HasSuffix(obj.GetName(), rotatingSuffix) {
+			continue
+		}
+		if err = r.rotateFile(ctx, dir, obj); err != nil {
+			return fmt.Errorf("failed to rotate %s: %w", stdpath.Join(dir, obj.GetName()), err)
+		}
+		r.progress.DoneFiles++
+		r.progress.DoneSize += obj.GetSize()
+		report(r.progress)
+	}
+	return nil
+}
+
+func (r *Rotator) renameDir(ctx context.Context, parent string, obj model.Obj) error {
+	if !r.dirNameEnc {
+		return nil
+	}
+	name := obj.GetName()
+	if _, err := r.new.DecryptDirName(name); err == nil {
+		// a name that decrypts with both keys is taken as rotated, the
+		// names are padded so it is rare
+		return nil
+	}
+	plain, err := r.old.DecryptDirName(name)
+	if err != nil {
+		log.Warnf("skip rotating dir %s: can't be decrypted", stdpath.Join(parent, name))
+		return nil
+	}
+	return op.Rename(ctx, r.remoteStorage, stdpath.Join(parent, name), r.new.EncryptDirName(plain))
+}
+
+func (r *Rotator) rotateFile(ctx context.Context, dir string, obj model.Obj) error {
+	name := obj.GetName()
+	path := stdpath.Join(dir, name)
+	if obj.GetSize() <= fileHeaderSize {
+		// an empty file has no block to tell its key, only its name changes
+		if _, err := r.new.DecryptFileName(name); err == nil {
+			return nil
+		}
+		plain, err := r.old.DecryptFileName(name)
+		if err != nil {
+			r.skip(path)
+			return nil
+		}
+		if newName := r.new.EncryptFileName(plain); newName != name {
+			return op.Rename(ctx, r.remoteStorage, path, newName)
+		}
+		return nil
+	}
+	head, err := r.readHead(ctx, path)
+	if err != nil {
+		return err
+	}
+	if _, err = decryptHead(r.new, head); err == nil {
+		return nil
+	}
+	if _, err = decryptHead(r.old, head); err != nil {
+		r.skip(path)
+		return nil
+	}
+	plain, err := r.old.DecryptFileName(name)
+	if err != nil {
+		r.skip(path)
+		return nil
+	}
+	size, err := r.old.DecryptedSize(obj.GetSize())
+	if err != nil {
+		return err
+	}
+	newName := r.new.EncryptFileName(plain)
+	upName := newName
+	if newName == name {
+		upName = name + rotatingSuffix
+	}
+
+	link, _, err := op.Link(ctx, r.remoteStorage, path, model.LinkArgs{})
+	if err != nil {
+		return err
+	}
+	ss, err := stream.NewSeekableStream(stream.FileStream{Obj: obj, Ctx: ctx}, link)
+	if err != nil {
+		return err
+	}
+	defer ss.Close()
+	decrypted, err := r.old.DecryptData(ss)
+	if err != nil {
+		return err
+	}
+	sample := &headBuffer{max: blockDataSize}
+	encrypted, err := r.new.EncryptData(io.TeeReader(decrypted, sample))
+	if err != nil {
+		return err
+	}
+	up := &stream.FileStream{
+		Ctx: ctx,
+		Obj: &model.Object{
+			Name:     upName,
+			Size:     r.new.EncryptedSize(size),
+			Modified: obj.ModTime(),
+		},
+		Reader:            encrypted,
+		Mimetype:          "application/octet-stream",
+		ForceStreamUpload: true,
+	}
+	if err = op.Put(ctx, r.remoteStorage, dir, up, func(float64) {}, false); err != nil {
+		return err
+	}
+	// verify by decrypting the first block of the upload
+	head, err = r.readHead(ctx, stdpath.Join(dir, upName))
+	if err != nil {
+		return err
+	}
+	data, err := decryptHead(r.new, head)
+	if err != nil || !bytes.Equal(data, sample.buf) {
+		return fmt.Errorf("verification of the re-encrypted file failed: %v", err)
+	}
+	if err = op.Remove(ctx, r.remoteStorage, path); err != nil {
+		return err
+	}
+	if upName != newName {
+		return op.Rename(ctx, r.remoteStorage, stdpath.Join(dir, upName), newName)
+	}
+	return nil
+}
+
+func (r *Rotator) skip(path string) {
+	log.Warnf("skip rotating file %s: it can't be decrypted with the current keys", path)
+	r.progress.Skipped++
+}
+
+// readHead reads the header and the first block of a remote file.
+func (r *Rotator) readHead(ctx context.Context, path string) ([]byte, error) {
+	link, obj, err := op.Link(ctx, r.remoteStorage, path, model.LinkArgs{})
+	if err != nil {
+		return nil, err
+	}
+	ss, err := stream.NewSeekableStream(stream.FileStream{Obj: obj, Ctx: ctx}, link)
+	if err != nil {
+		return nil, err
+	}
+	defer ss.Close()
+	length := min(obj.GetSize(), int64(fileHeaderSize+blockSize))
+	reader, err := ss.RangeRead(http_range.Range{Start: 0, Length: length})
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, length)
+	_, err = io.ReadFull(reader, buf)
+	return buf, err
+}
+
+// decryptHead decrypts the head of a file, which fails unless c has its key.
+func decryptHead(c *rcCrypt.Cipher, head []byte) ([]byte, error) {
+	rc, err := c.DecryptData(io.NopCloser(bytes.NewReader(head)))
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// headBuffer keeps the first max bytes written to it.
+type headBuffer struct {
+	buf []byte
+	max int
+}
+
+func (b *headBuffer) Write(p []byte) (int, error) {
+	if n := b.max - len(b.buf); n > 0 {
+		b.buf = append(b.buf, p[:min(n, len(p))]...)
+	}
+	return len(p), nil
+}
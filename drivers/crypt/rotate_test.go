@@ -0,0 +1,114 @@
+package crypt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	stdpath "path"
+	"testing"
+	"time"
+
+	_ "github.com/alist-org/alist/v3/drivers/local"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/stream"
+	"github.com/alist-org/alist/v3/internal/testutil"
+	"github.com/alist-org/alist/v3/pkg/http_range"
+)
+
+func init() {
+	testutil.InitDB()
+}
+
+func TestRotate(t *testing.T) {
+	for _, nameEnc := range []string{"standard", "off"} {
+		t.Run(nameEnc, func(t *testing.T) {
+			testRotate(t, nameEnc)
+		})
+	}
+}
+
+func testRotate(t *testing.T, nameEnc string) {
+	ctx := context.Background()
+	local := "/rotate_" + nameEnc
+	testutil.CreateLocal(t, local)
+	addition, _ := json.Marshal(Addition{
+		FileNameEnc:      nameEnc,
+		DirNameEnc:       "true",
+		RemotePath:       local,
+		Password:         "old",
+		Salt:             "old salt",
+		EncryptedSuffix:  ".bin",
+		FileNameEncoding: "base64",
+	})
+	mount := "/crypt_" + nameEnc
+	id, err := op.CreateStorage(ctx, model.Storage{Driver: "Crypt", MountPath: mount, Addition: string(addition)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"/a.txt":       bytes.Repeat([]byte("a"), 3*blockDataSize+5),
+		"/empty.txt":   {},
+		"/sub/b.txt":   []byte("b"),
+		"/sub/x/c.txt": bytes.Repeat([]byte("c"), 100),
+	}
+	for path, data := range files {
+		dir, name := stdpath.Split(path)
+		if err = fs.MakeDir(ctx, mount+dir); err != nil {
+			t.Fatal(err)
+		}
+		s := &stream.FileStream{
+			Obj:    &model.Object{Name: name, Size: int64(len(data)), Modified: time.Now()},
+			Reader: bytes.NewReader(data),
+		}
+		if err = fs.PutDirectly(ctx, mount+dir, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	storage, err := db.GetStorageById(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// rotate a subtree first, the root skips it then
+	for _, path := range []string{"/sub", "/"} {
+		r, err := NewRotator(*storage, "new", "new salt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var progress RotateProgress
+		if err = r.Rotate(ctx, path, func(p RotateProgress) { progress = p }); err != nil {
+			t.Fatal(err)
+		}
+		if progress.DoneFiles != progress.TotalFiles || progress.Skipped != 0 {
+			t.Errorf("rotate %s: %+v", path, progress)
+		}
+	}
+	if err = SetKeys(storage, "new", "new salt"); err != nil {
+		t.Fatal(err)
+	}
+	if err = op.UpdateStorage(ctx, *storage); err != nil {
+		t.Fatal(err)
+	}
+	for path, data := range files {
+		link, _, err := fs.Link(ctx, mount+path, model.LinkArgs{})
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		rc, err := link.RangeReadCloser.RangeRead(ctx, http_range.Range{Length: -1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("%s: wrong data after rotation", path)
+		}
+		_ = link.RangeReadCloser.Close()
+	}
+}
@@ -446,7 +446,7 @@ func (d *Doubao) uploadNode(uploadConfig *UploadConfig, dir model.Obj, file mode
 }
 
 // Upload 普通上传实现
-func (d *Doubao) Upload(config *UploadConfig, dstDir model.Obj, file model.FileStreamer, up driver.UpdateProgress, dataType string) (model.Obj, error) {
+func (d *Doubao) Upload(ctx context.Context, config *UploadConfig, dstDir model.Obj, file model.FileStreamer, up driver.UpdateProgress, dataType string) (model.Obj, error) {
 	data, err := io.ReadAll(file)
 	if err != nil {
 		return nil, err
@@ -464,7 +464,7 @@ func (d *Doubao) Upload(config *UploadConfig, dstDir model.Obj, file model.FileS
 
 	uploadResp := UploadResp{}
 
-	if _, err = d.uploadRequest(uploadUrl, http.MethodPost, storeInfo, func(req *resty.Request) {
+	if _, err = d.uploadRequest(ctx, uploadUrl, http.MethodPost, storeInfo, func(req *resty.Request) {
 		req.SetHeaders(map[string]string{
 			"Content-Type":        "application/octet-stream",
 			"Content-Crc32":       crc32Value,
@@ -504,7 +504,7 @@ func (d *Doubao) UploadByMultipart(ctx context.Context, config *UploadConfig, fi
 	var uploadID string
 	err := d._retryOperation("Initialize multipart upload", func() error {
 		var err error
-		uploadID, err = d.initMultipartUpload(config, uploadUrl, storeInfo)
+		uploadID, err = d.initMultipartUpload(ctx, config, uploadUrl, storeInfo)
 		return err
 	})
 	if err != nil {
@@ -560,7 +560,7 @@ func (d *Doubao) UploadByMultipart(ctx context.Context, config *UploadConfig, fi
 			var uploadPart UploadPart
 			if err = d._retryOperation(fmt.Sprintf("Upload part %d", partNumber), func() error {
 				var err error
-				uploadPart, err = d.uploadPart(config, uploadUrl, uploadID, partNumber, data, crc32Value)
+				uploadPart, err = d.uploadPart(ctx, config, uploadUrl, uploadID, partNumber, data, crc32Value)
 				return err
 			}); err != nil {
 				return fmt.Errorf("part %d upload failed: %w", partNumber, err)
@@ -586,7 +586,7 @@ func (d *Doubao) UploadByMultipart(ctx context.Context, config *UploadConfig, fi
 	}
 	// 完成上传-分片合并
 	if err = d._retryOperation("Complete multipart upload", func() error {
-		return d.completeMultipartUpload(config, uploadUrl, uploadID, parts)
+		return d.completeMultipartUpload(ctx, config, uploadUrl, uploadID, parts)
 	}); err != nil {
 		return nil, fmt.Errorf("failed to complete multipart upload: %w", err)
 	}
@@ -620,15 +620,17 @@ func (d *Doubao) UploadByMultipart(ctx context.Context, config *UploadConfig, fi
 }
 
 // 统一上传请求方法
-func (d *Doubao) uploadRequest(uploadUrl string, method string, storeInfo StoreInfo, callback base.ReqCallback, resp interface{}) ([]byte, error) {
+func (d *Doubao) uploadRequest(ctx context.Context, uploadUrl string, method string, storeInfo StoreInfo, callback base.ReqCallback, resp interface{}) ([]byte, error) {
 	client := resty.New()
 	client.SetTransport(&http.Transport{
 		DisableKeepAlives: true,  // 禁用连接复用
 		ForceAttemptHTTP2: false, // 强制使用HTTP/1.1
 	})
+	// the limiter of the storage has to see the 429 of the upload server too
+	base.WrapTransport(client)
 	client.SetTimeout(UploadTimeout)
 
-	req := client.R()
+	req := client.R().SetContext(ctx)
 	req.SetHeaders(map[string]string{
 		"Host":          strings.Split(uploadUrl, "/")[2],
 		"Referer":       BaseURL + "/",
@@ -659,10 +661,10 @@ func (d *Doubao) uploadRequest(uploadUrl string, method string, storeInfo StoreI
 }
 
 // 初始化分片上传
-func (d *Doubao) initMultipartUpload(config *UploadConfig, uploadUrl string, storeInfo StoreInfo) (uploadId string, err error) {
+func (d *Doubao) initMultipartUpload(ctx context.Context, config *UploadConfig, uploadUrl string, storeInfo StoreInfo) (uploadId string, err error) {
 	uploadResp := UploadResp{}
 
-	_, err = d.uploadRequest(uploadUrl, http.MethodPost, storeInfo, func(req *resty.Request) {
+	_, err = d.uploadRequest(ctx, uploadUrl, http.MethodPost, storeInfo, func(req *resty.Request) {
 		req.SetQueryParams(map[string]string{
 			"uploadmode": "part",
 			"phase":      "init",
@@ -681,11 +683,11 @@ func (d *Doubao) initMultipartUpload(config *UploadConfig, uploadUrl string, sto
 }
 
 // 分片上传实现
-func (d *Doubao) uploadPart(config *UploadConfig, uploadUrl, uploadID string, partNumber int64, data []byte, crc32Value string) (resp UploadPart, err error) {
+func (d *Doubao) uploadPart(ctx context.Context, config *UploadConfig, uploadUrl, uploadID string, partNumber int64, data []byte, crc32Value string) (resp UploadPart, err error) {
 	uploadResp := UploadResp{}
 	storeInfo := config.InnerUploadAddress.UploadNodes[0].StoreInfos[0]
 
-	_, err = d.uploadRequest(uploadUrl, http.MethodPost, storeInfo, func(req *resty.Request) {
+	_, err = d.uploadRequest(ctx, uploadUrl, http.MethodPost, storeInfo, func(req *resty.Request) {
 		req.SetHeaders(map[string]string{
 			"Content-Type":        "application/octet-stream",
 			"Content-Crc32":       crc32Value,
@@ -717,7 +719,7 @@ func (d *Doubao) uploadPart(config *UploadConfig, uploadUrl, uploadID string, pa
 }
 
 // 完成分片上传
-func (d *Doubao) completeMultipartUpload(config *UploadConfig, uploadUrl, uploadID string, parts []UploadPart) error {
+func (d *Doubao) completeMultipartUpload(ctx context.Context, config *UploadConfig, uploadUrl, uploadID string, parts []UploadPart) error {
 	uploadResp := UploadResp{}
 
 	storeInfo := config.InnerUploadAddress.UploadNodes[0].StoreInfos[0]
@@ -725,7 +727,7 @@ func (d *Doubao) completeMultipartUpload(config *UploadConfig, uploadUrl, upload
 	body := _convertUploadParts(parts)
 
 	err := utils.Retry(MaxRetryAttempts, time.Second, func() (err error) {
-		_, err = d.uploadRequest(uploadUrl, http.MethodPost, storeInfo, func(req *resty.Request) {
+		_, err = d.uploadRequest(ctx, uploadUrl, http.MethodPost, storeInfo, func(req *resty.Request) {
 			req.SetQueryParams(map[string]string{
 				"uploadid":   uploadID,
 				"phase":      "finish",
@@ -232,4 +232,19 @@ func (d *Dropbox) Put(ctx context.Context, dstDir model.Obj, stream model.FileSt
 	return err
 }
 
+func (d *Dropbox) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
+	var usage SpaceUsageResp
+	_, err := d.request("/2/users/get_space_usage", http.MethodPost, func(req *resty.Request) {
+		req.SetContext(ctx).SetResult(&usage)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &model.StorageDetails{
+		TotalSpace: usage.Allocation.Allocated,
+		FreeSpace:  usage.Allocation.Allocated - usage.Used,
+	}, nil
+}
+
 var _ driver.Driver = (*Dropbox)(nil)
+var _ driver.WithDetails = (*Dropbox)(nil)
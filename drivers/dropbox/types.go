@@ -84,3 +84,11 @@ func fileToObj(f File) *model.ObjThumb {
 		Thumbnail: model.Thumbnail{},
 	}
 }
+
+type SpaceUsageResp struct {
+	Used       int64 `json:"used"`
+	Allocation struct {
+		Tag       string `json:".tag"`
+		Allocated int64  `json:"allocated"`
+	} `json:"allocation"`
+}
@@ -3,6 +3,7 @@ package google_drive
 import (
 	"context"
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
 
@@ -167,4 +168,25 @@ func (d *GoogleDrive) Put(ctx context.Context, dstDir model.Obj, stream model.Fi
 	return err
 }
 
+func (d *GoogleDrive) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
+	var about AboutResp
+	_, err := d.request("https://www.googleapis.com/drive/v3/about", http.MethodGet, func(req *resty.Request) {
+		req.SetContext(ctx).SetQueryParam("fields", "storageQuota")
+	}, &about)
+	if err != nil {
+		return nil, err
+	}
+	usage, _ := strconv.ParseInt(about.StorageQuota.Usage, 10, 64)
+	limit, _ := strconv.ParseInt(about.StorageQuota.Limit, 10, 64)
+	if limit == 0 {
+		// no limit is returned for unlimited accounts
+		limit = math.MaxInt64
+	}
+	return &model.StorageDetails{
+		TotalSpace: limit,
+		FreeSpace:  limit - usage,
+	}, nil
+}
+
 var _ driver.Driver = (*GoogleDrive)(nil)
+var _ driver.WithDetails = (*GoogleDrive)(nil)
@@ -78,3 +78,10 @@ type Error struct {
 		Message string `json:"message"`
 	} `json:"error"`
 }
+
+type AboutResp struct {
+	StorageQuota struct {
+		Limit string `json:"limit"`
+		Usage string `json:"usage"`
+	} `json:"storageQuota"`
+}
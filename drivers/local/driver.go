@@ -24,6 +24,7 @@ import (
 	"github.com/alist-org/alist/v3/server/common"
 	"github.com/alist-org/times"
 	cp "github.com/otiai10/copy"
+	"github.com/shirou/gopsutil/v3/disk"
 	log "github.com/sirupsen/logrus"
 	_ "golang.org/x/image/webp"
 )
@@ -387,4 +388,15 @@ func (d *Local) Put(ctx context.Context, dstDir model.Obj, stream model.FileStre
 	return nil
 }
 
+func (d *Local) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
+	usage, err := disk.UsageWithContext(ctx, d.GetRootPath())
+	if err != nil {
+		return nil, err
+	}
+	return &model.StorageDetails{
+		TotalSpace: int64(usage.Total),
+		FreeSpace:  int64(usage.Free),
+	}, nil
+}
+
 var _ driver.Driver = (*Local)(nil)
@@ -0,0 +1,296 @@
+// Package backup exports and imports the persistent data of an instance
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"encoding/json"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// FormatVersion is increased when the layout of the archive changes
+const FormatVersion = 1
+
+const (
+	headerName = "header.json"
+	dataName   = "data.gob"
+)
+
+type Header struct {
+	Format    int       `json:"format"`
+	Version   string    `json:"version"` // alist version which created the backup
+	CreatedAt time.Time `json:"created_at"`
+	// Encrypted means the confidential values are encrypted with a passphrase,
+	// see transformSnapshot
+	Encrypted bool           `json:"encrypted"`
+	Salt      string         `json:"salt,omitempty"`
+	Check     string         `json:"check,omitempty"`
+	Counts    map[string]int `json:"counts"`
+}
+
+// Snapshot holds the session independent tables, it is encoded with gob
+// since many secret columns are hidden from json
+type Snapshot struct {
+	Storages          []model.Storage
+	Metas             []model.Meta
+	Users             []model.User
+	Roles             []model.Role
+	Settings          []model.SettingItem
+	Shares            []model.Share
+	Labels            []model.Label
+	LabelFileBindings []model.LabelFileBinding
+	ObjFiles          []model.ObjFile
+	SSHKeys           []model.SSHPublicKey
+	APITokens         []model.APIToken
+	S3Credentials     []model.S3Credential
+	S3ObjectMetas     []model.S3ObjectMeta
+	RSSSubscriptions  []model.RSSSubscription
+}
+
+func (s *Snapshot) counts() map[string]int {
+	return map[string]int{
+		"storages":            len(s.Storages),
+		"metas":               len(s.Metas),
+		"users":               len(s.Users),
+		"roles":               len(s.Roles),
+		"settings":            len(s.Settings),
+		"shares":              len(s.Shares),
+		"labels":              len(s.Labels),
+		"label_file_bindings": len(s.LabelFileBindings),
+		"obj_files":           len(s.ObjFiles),
+		"ssh_keys":            len(s.SSHKeys),
+		"api_tokens":          len(s.APITokens),
+		"s3_credentials":      len(s.S3Credentials),
+		"s3_object_metas":     len(s.S3ObjectMetas),
+		"rss_subscriptions":   len(s.RSSSubscriptions),
+	}
+}
+
+// tables lists the rows of every table in the order they are restored
+func (s *Snapshot) tables() []any {
+	return []any{
+		&s.Roles, &s.Users, &s.Storages, &s.Metas, &s.Settings, &s.Shares,
+		&s.Labels, &s.ObjFiles, &s.LabelFileBindings, &s.SSHKeys, &s.APITokens,
+		&s.S3Credentials, &s.S3ObjectMetas, &s.RSSSubscriptions,
+	}
+}
+
+func dump() (*Snapshot, error) {
+	s := &Snapshot{}
+	for _, rows := range s.tables() {
+		if err := db.GetDb().Find(rows).Error; err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return s, nil
+}
+
+// Create writes a backup of the database to w, confidential values are
+// encrypted if passphrase is not empty
+func Create(w io.Writer, passphrase string) (*Header, error) {
+	s, err := dump()
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to read database")
+	}
+	h := &Header{
+		Format:    FormatVersion,
+		Version:   conf.Version,
+		CreatedAt: time.Now(),
+		Counts:    s.counts(),
+	}
+	if passphrase != "" {
+		c, err := newCipher(passphrase, "")
+		if err != nil {
+			return nil, err
+		}
+		h.Encrypted = true
+		h.Salt = c.salt
+		if h.Check, err = c.encrypt(checkText); err != nil {
+			return nil, err
+		}
+		if err = c.encryptSnapshot(s); err != nil {
+			return nil, err
+		}
+	}
+	var data bytes.Buffer
+	if err = gob.NewEncoder(&data).Encode(s); err != nil {
+		return nil, errors.WithMessage(err, "failed to encode data")
+	}
+	header, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{{headerName, header}, {dataName, data.Bytes()}} {
+		err = tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0600, Size: int64(len(f.data)), ModTime: h.CreatedAt})
+		if err == nil {
+			_, err = tw.Write(f.data)
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	if err = tw.Close(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return h, errors.WithStack(gw.Close())
+}
+
+// Read parses a backup archive, the data is decrypted with passphrase if it's encrypted
+func Read(r io.Reader, passphrase string) (*Header, *Snapshot, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "not a backup archive")
+	}
+	defer gr.Close()
+	var h *Header
+	var s *Snapshot
+	tr := tar.NewReader(gr)
+	for {
+		f, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, errors.WithMessage(err, "not a backup archive")
+		}
+		switch f.Name {
+		case headerName:
+			h = &Header{}
+			if err = json.NewDecoder(tr).Decode(h); err != nil {
+				return nil, nil, errors.WithMessage(err, "invalid backup header")
+			}
+		case dataName:
+			s = &Snapshot{}
+			if err = gob.NewDecoder(tr).Decode(s); err != nil {
+				return nil, nil, errors.WithMessage(err, "invalid backup data")
+			}
+		}
+	}
+	if h == nil || s == nil {
+		return nil, nil, errors.New("incomplete backup archive")
+	}
+	if h.Format > FormatVersion {
+		return nil, nil, errors.Errorf("backup format %d is newer than supported %d", h.Format, FormatVersion)
+	}
+	if h.Encrypted {
+		if passphrase == "" {
+			return nil, nil, errors.New("backup is encrypted, passphrase is required")
+		}
+		c, err := newCipher(passphrase, h.Salt)
+		if err != nil {
+			return nil, nil, err
+		}
+		if text, err := c.decrypt(h.Check); err != nil || text != checkText {
+			return nil, nil, errors.New("wrong passphrase")
+		}
+		if err = c.decryptSnapshot(s); err != nil {
+			return nil, nil, err
+		}
+	}
+	return h, s, nil
+}
+
+// Restore replaces the tables of the database with the snapshot
+func Restore(s *Snapshot) error {
+	return db.GetDb().Transaction(func(tx *gorm.DB) error {
+		// delete in reverse order of the inserts
+		tables := s.tables()
+		for i := len(tables) - 1; i >= 0; i-- {
+			if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(newRow(tables[i])).Error; err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		for _, rows := range tables {
+			if isEmpty(rows) {
+				continue
+			}
+			if err := insert(tx, rows); err != nil {
+				return err
+			}
+		}
+		if conf.Conf.Database.Type == "postgres" {
+			return resetSequences(tx, tables)
+		}
+		return nil
+	})
+}
+
+// insert creates the rows keeping their zero values, gorm replaces
+// zero values of fields having a default value with the default
+func insert(tx *gorm.DB, rows any) error {
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(newRow(rows)); err != nil {
+		return errors.WithStack(err)
+	}
+	type zeroField struct {
+		row   reflect.Value
+		field *schema.Field
+	}
+	var zeros []zeroField
+	v := reflect.ValueOf(rows).Elem()
+	for i := 0; i < v.Len(); i++ {
+		for _, field := range stmt.Schema.Fields {
+			if field.DefaultValueInterface == nil || field.PrimaryKey {
+				continue
+			}
+			if _, isZero := field.ValueOf(tx.Statement.Context, v.Index(i)); isZero {
+				zeros = append(zeros, zeroField{row: v.Index(i), field: field})
+			}
+		}
+	}
+	if err := tx.CreateInBatches(rows, 100).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	for _, z := range zeros {
+		zero := reflect.Zero(z.field.FieldType).Interface()
+		if err := tx.Model(z.row.Addr().Interface()).UpdateColumn(z.field.DBName, zero).Error; err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// resetSequences moves the id sequences of postgres past the restored rows
+func resetSequences(tx *gorm.DB, tables []any) error {
+	for _, rows := range tables {
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(newRow(rows)); err != nil {
+			return errors.WithStack(err)
+		}
+		field := stmt.Schema.PrioritizedPrimaryField
+		if field == nil || !field.AutoIncrement {
+			continue
+		}
+		table, column := stmt.Schema.Table, field.DBName
+		err := tx.Exec("SELECT setval(pg_get_serial_sequence(?, ?), COALESCE(MAX("+column+"), 0) + 1, false) FROM "+table, table, column).Error
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// newRow returns a pointer to a zero value of the element type of rows, a pointer to a slice
+func newRow(rows any) any {
+	return reflect.New(reflect.TypeOf(rows).Elem().Elem()).Interface()
+}
+
+func isEmpty(rows any) bool {
+	return reflect.ValueOf(rows).Elem().Len() == 0
+}
@@ -0,0 +1,108 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/testutil"
+)
+
+func init() {
+	testutil.InitDB()
+}
+
+func TestBackupRestore(t *testing.T) {
+	storage := model.Storage{MountPath: "/s", Driver: "Local", Addition: `{"root_folder_path":"/x","token":"s3cr3t"}`}
+	if err := db.CreateStorage(&storage); err != nil {
+		t.Fatal(err)
+	}
+	// zero values must survive the restore instead of falling back to column defaults
+	storage.DownProxySign = false
+	if err := db.UpdateStorage(&storage); err != nil {
+		t.Fatal(err)
+	}
+	user := model.User{Username: "bob", Role: model.Roles{1}, OtpSecret: "otp-s3cr3t"}
+	user.SetPassword("pwd")
+	if err := db.CreateUser(&user); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveSettingItem(&model.SettingItem{Key: conf.Token, Value: "token-s3cr3t", Type: conf.TypeString, Group: model.SINGLE, Flag: model.PRIVATE}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateMeta(&model.Meta{Path: "/s", Password: "meta-s3cr3t"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateS3Credential(&model.S3Credential{UserID: user.ID, AccessKeyID: "AKBACKUP", SecretAccessKey: "key-s3cr3t"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	h, err := Create(&buf, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !h.Encrypted || h.Counts["storages"] != 1 || h.Counts["users"] != 1 {
+		t.Fatalf("unexpected header %+v", h)
+	}
+	gr, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := io.ReadAll(gr)
+	if bytes.Contains(raw, []byte("s3cr3t")) {
+		t.Fatal("confidential field is not encrypted")
+	}
+
+	if _, _, err = Read(bytes.NewReader(buf.Bytes()), "wrong"); err == nil {
+		t.Fatal("expected wrong passphrase to fail")
+	}
+	if err = db.DeleteStorageById(storage.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.CreateStorage(&model.Storage{MountPath: "/other", Driver: "Local"}); err != nil {
+		t.Fatal(err)
+	}
+	_, s, err := Read(bytes.NewReader(buf.Bytes()), "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = Restore(s); err != nil {
+		t.Fatal(err)
+	}
+
+	storages, _, err := db.GetStorages(1, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(storages) != 1 || storages[0].MountPath != "/s" || storages[0].DownProxySign {
+		t.Fatalf("unexpected storages %+v", storages)
+	}
+	if !strings.Contains(storages[0].Addition, `"s3cr3t"`) {
+		t.Errorf("confidential field not decrypted: %s", storages[0].Addition)
+	}
+	restored, err := db.GetUserByName("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.ValidateRawPassword("pwd") != nil {
+		t.Error("password hash not restored")
+	}
+	if restored.OtpSecret != "otp-s3cr3t" {
+		t.Errorf("otp secret not decrypted: %s", restored.OtpSecret)
+	}
+	if token, err := db.GetSettingItemByKey(conf.Token); err != nil || token.Value != "token-s3cr3t" {
+		t.Errorf("token setting not decrypted: %+v %v", token, err)
+	}
+	if meta, err := db.GetMetaByPath("/s"); err != nil || meta.Password != "meta-s3cr3t" {
+		t.Errorf("meta password not decrypted: %+v %v", meta, err)
+	}
+	if cred, err := db.GetS3CredentialByAccessKey("AKBACKUP"); err != nil || cred.SecretAccessKey != "key-s3cr3t" {
+		t.Errorf("S3 secret not decrypted: %+v %v", cred, err)
+	}
+}
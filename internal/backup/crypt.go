@@ -0,0 +1,203 @@
+package backup
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	checkText     = "alist-backup"
+	encryptPrefix = "enc:"
+)
+
+// secretNames are parts of addition field names treated as confidential
+// even if the driver doesn't tag them
+var secretNames = []string{"password", "passwd", "token", "secret", "cookie", "access_key", "private_key", "api_key"}
+
+type backupCipher struct {
+	aead cipher.AEAD
+	salt string
+}
+
+func newCipher(passphrase, salt string) (*backupCipher, error) {
+	var rawSalt []byte
+	if salt == "" {
+		rawSalt = make([]byte, 16)
+		if _, err := rand.Read(rawSalt); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	} else {
+		var err error
+		if rawSalt, err = base64.StdEncoding.DecodeString(salt); err != nil {
+			return nil, errors.WithMessage(err, "invalid salt")
+		}
+	}
+	key, err := scrypt.Key([]byte(passphrase), rawSalt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &backupCipher{aead: aead, salt: base64.StdEncoding.EncodeToString(rawSalt)}, nil
+}
+
+func (c *backupCipher) encrypt(text string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", errors.WithStack(err)
+	}
+	data := c.aead.Seal(nonce, nonce, []byte(text), nil)
+	return encryptPrefix + base64.StdEncoding.EncodeToString(data), nil
+}
+
+func (c *backupCipher) decrypt(text string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(text, encryptPrefix))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if len(data) < c.aead.NonceSize() {
+		return "", errors.New("encrypted value too short")
+	}
+	nonce, data := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, data, nil)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return string(plain), nil
+}
+
+func (c *backupCipher) encryptSnapshot(s *Snapshot) error {
+	return transformSnapshot(s, func(v string) (string, error) {
+		return c.encrypt(v)
+	})
+}
+
+func (c *backupCipher) decryptSnapshot(s *Snapshot) error {
+	return transformSnapshot(s, func(v string) (string, error) {
+		if !strings.HasPrefix(v, encryptPrefix) {
+			return v, nil
+		}
+		return c.decrypt(v)
+	})
+}
+
+// transformSnapshot applies f to the confidential values of the snapshot:
+// the storage additions, the settings named like secrets, the otp secrets of
+// the users, the meta passwords and the secret keys of the S3 credentials.
+// Password hashes and token hashes are left alone.
+func transformSnapshot(s *Snapshot, f func(string) (string, error)) error {
+	if err := transformAdditions(s.Storages, f); err != nil {
+		return err
+	}
+	for i := range s.Settings {
+		if err := transformField(&s.Settings[i].Value, isSecretName(s.Settings[i].Key), f); err != nil {
+			return errors.WithMessagef(err, "failed to process setting %s", s.Settings[i].Key)
+		}
+	}
+	for i := range s.Users {
+		if err := transformField(&s.Users[i].OtpSecret, true, f); err != nil {
+			return errors.WithMessagef(err, "failed to process otp secret of user %s", s.Users[i].Username)
+		}
+	}
+	for i := range s.Metas {
+		if err := transformField(&s.Metas[i].Password, true, f); err != nil {
+			return errors.WithMessagef(err, "failed to process password of meta %s", s.Metas[i].Path)
+		}
+	}
+	for i := range s.S3Credentials {
+		if err := transformField(&s.S3Credentials[i].SecretAccessKey, true, f); err != nil {
+			return errors.WithMessagef(err, "failed to process S3 credential %d", s.S3Credentials[i].ID)
+		}
+	}
+	return nil
+}
+
+func transformField(v *string, confidential bool, f func(string) (string, error)) error {
+	if !confidential || *v == "" {
+		return nil
+	}
+	res, err := f(*v)
+	if err != nil {
+		return err
+	}
+	*v = res
+	return nil
+}
+
+// transformAdditions applies f to the non-empty confidential string fields of the storages
+func transformAdditions(storages []model.Storage, f func(string) (string, error)) error {
+	infos := op.GetDriverInfoMap()
+	for i := range storages {
+		s := &storages[i]
+		var addition map[string]any
+		dec := json.NewDecoder(strings.NewReader(s.Addition))
+		dec.UseNumber()
+		if err := dec.Decode(&addition); err != nil {
+			// leave unparsable additions untouched
+			continue
+		}
+		fields := confidentialFields(infos[s.Driver].Additional)
+		changed := false
+		for name, v := range addition {
+			str, ok := v.(string)
+			if !ok || str == "" || !fields(name) {
+				continue
+			}
+			res, err := f(str)
+			if err != nil {
+				return errors.WithMessagef(err, "failed to process field %s of storage %s", name, s.MountPath)
+			}
+			addition[name] = res
+			changed = true
+		}
+		if changed {
+			data, err := json.Marshal(addition)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			s.Addition = string(data)
+		}
+	}
+	return nil
+}
+
+func confidentialFields(items []driver.Item) func(string) bool {
+	tagged := make(map[string]bool)
+	for _, item := range items {
+		if item.Confidential {
+			tagged[item.Name] = true
+		}
+	}
+	return func(name string) bool {
+		if tagged[name] {
+			return true
+		}
+		return isSecretName(name)
+	}
+}
+
+func isSecretName(name string) bool {
+	lower := strings.ToLower(name)
+	for _, s := range secretNames {
+		if strings.Contains(lower, s) {
+			return true
+		}
+	}
+	return false
+}
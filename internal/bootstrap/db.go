@@ -1,6 +1,7 @@
 package bootstrap
 
 import (
+	"errors"
 	"fmt"
 	stdlog "log"
 	"strings"
@@ -19,6 +20,21 @@ import (
 )
 
 func InitDB() {
+	var dB *gorm.DB
+	var err error
+	if flags.Dev {
+		dB, err = gorm.Open(sqlite.Open("file::memory:?cache=shared"), gormConfig(conf.Conf.Database.TablePrefix))
+		conf.Conf.Database.Type = "sqlite3"
+	} else {
+		dB, err = OpenDB(conf.Conf.Database)
+	}
+	if err != nil {
+		log.Fatalf("failed to connect database:%s", err.Error())
+	}
+	db.Init(dB)
+}
+
+func gormConfig(tablePrefix string) *gorm.Config {
 	logLevel := logger.Silent
 	if flags.Debug || flags.Dev {
 		logLevel = logger.Info
@@ -32,58 +48,45 @@ func InitDB() {
 			Colorful:                  true,
 		},
 	)
-	gormConfig := &gorm.Config{
+	return &gorm.Config{
 		NamingStrategy: schema.NamingStrategy{
-			TablePrefix: conf.Conf.Database.TablePrefix,
+			TablePrefix: tablePrefix,
 		},
 		Logger: newLogger,
 	}
-	var dB *gorm.DB
-	var err error
-	if flags.Dev {
-		dB, err = gorm.Open(sqlite.Open("file::memory:?cache=shared"), gormConfig)
-		conf.Conf.Database.Type = "sqlite3"
-	} else {
-		database := conf.Conf.Database
-		switch database.Type {
-		case "sqlite3":
-			{
-				if !(strings.HasSuffix(database.DBFile, ".db") && len(database.DBFile) > 3) {
-					log.Fatalf("db name error.")
-				}
-				dB, err = gorm.Open(sqlite.Open(fmt.Sprintf("%s?_journal=WAL&_vacuum=incremental",
-					database.DBFile)), gormConfig)
-			}
-		case "mysql":
-			{
-				dsn := database.DSN
-				if dsn == "" {
-					//[username[:password]@][protocol[(address)]]/dbname[?param1=value1&...&paramN=valueN]
-					dsn = fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local&tls=%s",
-						database.User, database.Password, database.Host, database.Port, database.Name, database.SSLMode)
-				}
-				dB, err = gorm.Open(mysql.Open(dsn), gormConfig)
-			}
-		case "postgres":
-			{
-				dsn := database.DSN
-				if dsn == "" {
-					if database.Password != "" {
-						dsn = fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s TimeZone=Asia/Shanghai",
-							database.Host, database.User, database.Password, database.Name, database.Port, database.SSLMode)
-					} else {
-						dsn = fmt.Sprintf("host=%s user=%s dbname=%s port=%d sslmode=%s TimeZone=Asia/Shanghai",
-							database.Host, database.User, database.Name, database.Port, database.SSLMode)
-					}
-				}
-				dB, err = gorm.Open(postgres.Open(dsn), gormConfig)
+}
+
+// OpenDB connects to the database, it's also used to open the target of a migration
+func OpenDB(database conf.Database) (*gorm.DB, error) {
+	gormConfig := gormConfig(database.TablePrefix)
+	switch database.Type {
+	case "sqlite3":
+		if !(strings.HasSuffix(database.DBFile, ".db") && len(database.DBFile) > 3) {
+			return nil, errors.New("db name error")
+		}
+		return gorm.Open(sqlite.Open(fmt.Sprintf("%s?_journal=WAL&_vacuum=incremental",
+			database.DBFile)), gormConfig)
+	case "mysql":
+		dsn := database.DSN
+		if dsn == "" {
+			//[username[:password]@][protocol[(address)]]/dbname[?param1=value1&...&paramN=valueN]
+			dsn = fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local&tls=%s",
+				database.User, database.Password, database.Host, database.Port, database.Name, database.SSLMode)
+		}
+		return gorm.Open(mysql.Open(dsn), gormConfig)
+	case "postgres":
+		dsn := database.DSN
+		if dsn == "" {
+			if database.Password != "" {
+				dsn = fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s TimeZone=Asia/Shanghai",
+					database.Host, database.User, database.Password, database.Name, database.Port, database.SSLMode)
+			} else {
+				dsn = fmt.Sprintf("host=%s user=%s dbname=%s port=%d sslmode=%s TimeZone=Asia/Shanghai",
+					database.Host, database.User, database.Name, database.Port, database.SSLMode)
 			}
-		default:
-			log.Fatalf("not supported database type: %s", database.Type)
 		}
+		return gorm.Open(postgres.Open(dsn), gormConfig)
+	default:
+		return nil, fmt.Errorf("not supported database type: %s", database.Type)
 	}
-	if err != nil {
-		log.Fatalf("failed to connect database:%s", err.Error())
-	}
-	db.Init(dB)
 }
@@ -0,0 +1,35 @@
+package bootstrap
+
+import (
+	"path/filepath"
+
+	"github.com/alist-org/alist/v3/cmd/flags"
+	"github.com/alist-org/alist/v3/internal/manifest"
+	"github.com/alist-org/alist/v3/pkg/utils"
+)
+
+// ApplyManifest applies manifest.yaml (or .yml, .json) in the data dir if it exists,
+// it runs before the storages are loaded so that they start with the applied config
+func ApplyManifest() {
+	for _, name := range []string{"manifest.yaml", "manifest.yml", "manifest.json"} {
+		path := filepath.Join(flags.DataDir, name)
+		if !utils.Exists(path) {
+			continue
+		}
+		m, err := manifest.Load(path)
+		if err != nil {
+			utils.Log.Fatalf("failed load manifest [%s]: %+v", path, err)
+		}
+		plan, err := manifest.Diff(m)
+		if err != nil {
+			utils.Log.Fatalf("failed diff manifest [%s]: %+v", path, err)
+		}
+		for _, c := range plan {
+			utils.Log.Infof("manifest: %s", c)
+		}
+		if err = plan.Apply(); err != nil {
+			utils.Log.Fatalf("failed apply manifest [%s]: %+v", path, err)
+		}
+		return
+	}
+}
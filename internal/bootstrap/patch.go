@@ -72,3 +72,26 @@ func InitUpgradePatch() {
 		}
 	}
 }
+
+// IsNewerVersion reports whether data written by version v may use a schema
+// unknown to the running build, development builds accept any version
+func IsNewerVersion(v string) bool {
+	if !strings.HasPrefix(conf.Version, "v") {
+		return false
+	}
+	major, minor, patchNum, err := getVersion(v)
+	if err != nil {
+		return false
+	}
+	curMajor, curMinor, curPatchNum, err := getVersion(conf.Version)
+	if err != nil {
+		return false
+	}
+	return !compareVersion(curMajor, curMinor, curPatchNum, major, minor, patchNum)
+}
+
+// UpgradeFrom runs the patches needed by data written by version v
+func UpgradeFrom(v string) {
+	LastLaunchedVersion = v
+	InitUpgradePatch()
+}
@@ -11,20 +11,31 @@ import (
 )
 
 func LoadStorages() {
+	go loadStorages(getEnabledStorages())
+}
+
+// LoadStoragesSync returns once all enabled storages are loaded
+func LoadStoragesSync() {
+	loadStorages(getEnabledStorages())
+}
+
+func getEnabledStorages() []model.Storage {
 	storages, err := db.GetEnabledStorages()
 	if err != nil {
 		utils.Log.Fatalf("failed get enabled storages: %+v", err)
 	}
-	go func(storages []model.Storage) {
-		for i := range storages {
-			err := op.LoadStorage(context.Background(), storages[i])
-			if err != nil {
-				utils.Log.Errorf("failed get enabled storages: %+v", err)
-			} else {
-				utils.Log.Infof("success load storage: [%s], driver: [%s], order: [%d]",
-					storages[i].MountPath, storages[i].Driver, storages[i].Order)
-			}
+	return storages
+}
+
+func loadStorages(storages []model.Storage) {
+	for i := range storages {
+		err := op.LoadStorage(context.Background(), storages[i])
+		if err != nil {
+			utils.Log.Errorf("failed get enabled storages: %+v", err)
+		} else {
+			utils.Log.Infof("success load storage: [%s], driver: [%s], order: [%d]",
+				storages[i].MountPath, storages[i].Driver, storages[i].Order)
 		}
-		conf.StoragesLoaded = true
-	}(storages)
+	}
+	conf.StoragesLoaded = true
 }
@@ -2,7 +2,10 @@ package bootstrap
 
 import (
 	"context"
+	"time"
+
 	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/metrics"
 	"github.com/alist-org/alist/v3/internal/op"
 	"github.com/alist-org/alist/v3/internal/setting"
 	"github.com/alist-org/alist/v3/internal/stream"
@@ -11,9 +14,16 @@ import (
 
 type blockBurstLimiter struct {
 	*rate.Limiter
+	name string
 }
 
 func (l blockBurstLimiter) WaitN(ctx context.Context, total int) error {
+	if l.Limiter.Limit() != rate.Inf {
+		start := time.Now()
+		defer func() {
+			metrics.LimiterWait(l.name, time.Since(start))
+		}()
+	}
 	for total > 0 {
 		n := l.Burst()
 		if l.Limiter.Limit() == rate.Inf || n > total {
@@ -35,9 +45,9 @@ func streamFilterNegative(limit int) (rate.Limit, int) {
 	return rate.Limit(limit) * 1024.0, limit * 1024
 }
 
-func initLimiter(limiter *stream.Limiter, s string) {
+func initLimiter(limiter *stream.Limiter, name, s string) {
 	clientDownLimit, burst := streamFilterNegative(setting.GetInt(s, -1))
-	*limiter = blockBurstLimiter{Limiter: rate.NewLimiter(clientDownLimit, burst)}
+	*limiter = blockBurstLimiter{Limiter: rate.NewLimiter(clientDownLimit, burst), name: name}
 	op.RegisterSettingChangingCallback(func() {
 		newLimit, newBurst := streamFilterNegative(setting.GetInt(s, -1))
 		(*limiter).SetLimit(newLimit)
@@ -46,8 +56,8 @@ func initLimiter(limiter *stream.Limiter, s string) {
 }
 
 func InitStreamLimit() {
-	initLimiter(&stream.ClientDownloadLimit, conf.StreamMaxClientDownloadSpeed)
-	initLimiter(&stream.ClientUploadLimit, conf.StreamMaxClientUploadSpeed)
-	initLimiter(&stream.ServerDownloadLimit, conf.StreamMaxServerDownloadSpeed)
-	initLimiter(&stream.ServerUploadLimit, conf.StreamMaxServerUploadSpeed)
+	initLimiter(&stream.ClientDownloadLimit, "client_download", conf.StreamMaxClientDownloadSpeed)
+	initLimiter(&stream.ClientUploadLimit, "client_upload", conf.StreamMaxClientUploadSpeed)
+	initLimiter(&stream.ServerDownloadLimit, "server_download", conf.StreamMaxServerDownloadSpeed)
+	initLimiter(&stream.ServerUploadLimit, "server_upload", conf.StreamMaxServerUploadSpeed)
 }
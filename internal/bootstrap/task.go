@@ -1,9 +1,12 @@
 package bootstrap
 
 import (
+	"github.com/alist-org/alist/v3/internal/chunkcheck"
 	"github.com/alist-org/alist/v3/internal/conf"
 	"github.com/alist-org/alist/v3/internal/db"
 	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/keyrotate"
+	"github.com/alist-org/alist/v3/internal/metrics"
 	"github.com/alist-org/alist/v3/internal/offline_download/tool"
 	"github.com/alist-org/alist/v3/internal/op"
 	"github.com/alist-org/alist/v3/internal/setting"
@@ -49,6 +52,8 @@ func InitTaskManager() {
 		),
 		tache.WithMaxRetry(conf.Conf.Tasks.S3Transition.MaxRetry),
 	)
+	keyrotate.TaskManager = tache.NewManager[*keyrotate.RotateTask](tache.WithWorks(int(taskFilterNegative(conf.Conf.Tasks.CryptRotate.Workers))), tache.WithPersistFunction(db.GetTaskDataFunc("crypt_rotate", conf.Conf.Tasks.CryptRotate.TaskPersistant), db.UpdateTaskDataFunc("crypt_rotate", conf.Conf.Tasks.CryptRotate.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.CryptRotate.MaxRetry))
+	chunkcheck.TaskManager = tache.NewManager[*chunkcheck.CheckTask](tache.WithWorks(int(taskFilterNegative(conf.Conf.Tasks.ChunkerCheck.Workers))), tache.WithPersistFunction(db.GetTaskDataFunc("chunker_check", conf.Conf.Tasks.ChunkerCheck.TaskPersistant), db.UpdateTaskDataFunc("chunker_check", conf.Conf.Tasks.ChunkerCheck.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.ChunkerCheck.MaxRetry))
 	fs.ArchiveDownloadTaskManager = tache.NewManager[*fs.ArchiveDownloadTask](tache.WithWorks(setting.GetInt(conf.TaskDecompressDownloadThreadsNum, conf.Conf.Tasks.Decompress.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("decompress", conf.Conf.Tasks.Decompress.TaskPersistant), db.UpdateTaskDataFunc("decompress", conf.Conf.Tasks.Decompress.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Decompress.MaxRetry))
 	op.RegisterSettingChangingCallback(func() {
 		fs.ArchiveDownloadTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(conf.TaskDecompressDownloadThreadsNum, conf.Conf.Tasks.Decompress.Workers)))
@@ -57,4 +62,13 @@ func InitTaskManager() {
 	op.RegisterSettingChangingCallback(func() {
 		fs.ArchiveContentUploadTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(conf.TaskDecompressUploadThreadsNum, conf.Conf.Tasks.DecompressUpload.Workers)))
 	})
+	metrics.RegisterTaskManager("upload", fs.UploadTaskManager)
+	metrics.RegisterTaskManager("copy", fs.CopyTaskManager)
+	metrics.RegisterTaskManager("offline_download", tool.DownloadTaskManager)
+	metrics.RegisterTaskManager("offline_download_transfer", tool.TransferTaskManager)
+	metrics.RegisterTaskManager("s3_transition", fs.S3TransitionTaskManager)
+	metrics.RegisterTaskManager("decompress", fs.ArchiveDownloadTaskManager)
+	metrics.RegisterTaskManager("decompress_upload", fs.ArchiveContentUploadTaskManager.Manager)
+	metrics.RegisterTaskManager("crypt_rotate", keyrotate.TaskManager)
+	metrics.RegisterTaskManager("chunker_check", chunkcheck.TaskManager)
 }
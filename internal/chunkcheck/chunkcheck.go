@@ -0,0 +1,129 @@
+package chunkcheck
+
+import (
+	"fmt"
+	stdpath "path"
+	"sync"
+	"time"
+
+	"github.com/alist-org/alist/v3/drivers/chunker"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/task"
+	"github.com/pkg/errors"
+	"github.com/xhofe/tache"
+)
+
+// maxIssues caps the issues kept in a task, the rest are only counted
+const maxIssues = 1000
+
+// CheckTask verifies the chunked files of a chunker storage, and optionally
+// removes orphaned chunks and rebalances the chunks over its remote paths.
+type CheckTask struct {
+	task.TaskExtension
+	status string
+
+	StorageID uint   `json:"storage_id"`
+	MountPath string `json:"mount_path"`
+	Path      string `json:"path"`
+	chunker.CheckOptions
+
+	mu       sync.Mutex
+	Issues   []chunker.CheckIssue  `json:"issues"`
+	Progress chunker.CheckProgress `json:"check_progress"`
+}
+
+type Details struct {
+	Issues   []chunker.CheckIssue  `json:"issues"`
+	Progress chunker.CheckProgress `json:"progress"`
+}
+
+var TaskManager *tache.Manager[*CheckTask]
+
+var _ task.TaskExtensionInfo = (*CheckTask)(nil)
+var _ task.TaskWithDetails = (*CheckTask)(nil)
+
+func (t *CheckTask) GetName() string {
+	return fmt.Sprintf("check chunks of [%s](%s)", t.MountPath, t.Path)
+}
+
+func (t *CheckTask) GetStatus() string {
+	return t.status
+}
+
+func (t *CheckTask) GetDetails() any {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return Details{
+		Issues:   append([]chunker.CheckIssue(nil), t.Issues...),
+		Progress: t.Progress,
+	}
+}
+
+func (t *CheckTask) Run() error {
+	t.ReinitCtx()
+	t.ClearEndTime()
+	t.SetStartTime(time.Now())
+	defer func() { t.SetEndTime(time.Now()) }()
+
+	storage, err := db.GetStorageById(t.StorageID)
+	if err != nil {
+		return errors.WithMessage(err, "failed get storage")
+	}
+	storageDriver, err := op.GetStorageByMountPath(storage.MountPath)
+	if err != nil {
+		return errors.WithMessage(err, "failed get storage driver")
+	}
+	d, ok := storageDriver.(*chunker.Chunker)
+	if !ok {
+		return errors.Errorf("storage %s is not a chunker storage", storage.MountPath)
+	}
+	t.mu.Lock()
+	t.Issues, t.Progress = nil, chunker.CheckProgress{}
+	t.mu.Unlock()
+	t.status = "listing files"
+	err = d.Check(t.Ctx(), t.Path, t.CheckOptions, func(issue chunker.CheckIssue) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if len(t.Issues) < maxIssues {
+			t.Issues = append(t.Issues, issue)
+		}
+	}, func(p chunker.CheckProgress) {
+		t.mu.Lock()
+		t.Progress = p
+		t.mu.Unlock()
+		if p.TotalFiles > 0 {
+			t.SetProgress(float64(p.Files) / float64(p.TotalFiles) * 100)
+		}
+		t.status = fmt.Sprintf("checked %d of %d files, %d issues", p.Files, p.TotalFiles, p.Issues)
+		if p.Moved > 0 {
+			t.status += fmt.Sprintf(", moved %d chunks", p.Moved)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	t.SetProgress(100)
+	return nil
+}
+
+// AddTask queues the check of path of a chunker storage.
+func AddTask(creator *model.User, storage *model.Storage, path string, opts chunker.CheckOptions) (*CheckTask, error) {
+	if storage.Driver != "Chunker" {
+		return nil, errors.Errorf("storage %s is not a chunker storage", storage.MountPath)
+	}
+	if storage.Disabled {
+		return nil, errors.Errorf("storage %s is disabled", storage.MountPath)
+	}
+	t := &CheckTask{
+		TaskExtension: task.TaskExtension{Creator: creator},
+		status:        "queued",
+		StorageID:     storage.ID,
+		MountPath:     storage.MountPath,
+		Path:          stdpath.Join("/", path),
+		CheckOptions:  opts,
+	}
+	TaskManager.Add(t)
+	return t, nil
+}
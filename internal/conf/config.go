@@ -61,6 +61,8 @@ type TasksConfig struct {
 	Decompress         TaskConfig `json:"decompress" envPrefix:"DECOMPRESS_"`
 	DecompressUpload   TaskConfig `json:"decompress_upload" envPrefix:"DECOMPRESS_UPLOAD_"`
 	S3Transition       TaskConfig `json:"s3_transition" envPrefix:"S3_TRANSITION_"`
+	CryptRotate        TaskConfig `json:"crypt_rotate" envPrefix:"CRYPT_ROTATE_"`
+	ChunkerCheck       TaskConfig `json:"chunker_check" envPrefix:"CHUNKER_CHECK_"`
 	AllowRetryCanceled bool       `json:"allow_retry_canceled" env:"ALLOW_RETRY_CANCELED"`
 }
 
@@ -94,6 +96,61 @@ type SFTP struct {
 	Listen string `json:"listen" env:"LISTEN"`
 }
 
+// NFS serves the files over NFSv3, the MOUNT and NFS programs share the
+// listen port and no portmapper is registered, so clients have to mount
+// with e.g. -o vers=3,tcp,port=2049,mountport=2049,nolock
+type NFS struct {
+	Enable bool   `json:"enable" env:"ENABLE"`
+	Listen string `json:"listen" env:"LISTEN"`
+	// User is the alist user all clients act as, guest if empty
+	User     string `json:"user" env:"USER"`
+	ReadOnly bool   `json:"read_only" env:"READ_ONLY"`
+	// AllowedCIDRs limits the clients, all clients are allowed if empty
+	AllowedCIDRs    []string `json:"allowed_cidrs" env:"ALLOWED_CIDRS"`
+	HandleCacheSize int      `json:"handle_cache_size" env:"HANDLE_CACHE_SIZE"`
+}
+
+// DLNA announces a UPnP MediaServer on the local network, the media are
+// streamed through the /p proxy of the http server
+type DLNA struct {
+	Enable       bool   `json:"enable" env:"ENABLE"`
+	Listen       string `json:"listen" env:"LISTEN"`
+	FriendlyName string `json:"friendly_name" env:"FRIENDLY_NAME"`
+	// User is the alist user whose permissions apply, guest if empty
+	User string `json:"user" env:"USER"`
+	// Paths are the folders shown to the renderers, the root of the user if empty
+	Paths []string `json:"paths" env:"PATHS"`
+}
+
+// Subsonic serves the Subsonic API at /rest for music clients
+type Subsonic struct {
+	Enable bool `json:"enable" env:"ENABLE"`
+	// Paths are the music folders, relative to the root of each user,
+	// the root of the user if empty
+	Paths []string `json:"paths" env:"PATHS"`
+}
+
+// Metrics exposes the Prometheus metrics at /metrics, on the main http
+// server when Port is -1, which requires a Token
+type Metrics struct {
+	Enable bool `json:"enable" env:"ENABLE"`
+	Port   int  `json:"port" env:"PORT"`
+	// Token is required as a bearer token by the scrapers if not empty
+	Token string `json:"token" env:"TOKEN"`
+}
+
+// Tracing exports the OpenTelemetry spans of the requests, the storage
+// operations and the outbound calls of the drivers over OTLP/HTTP
+type Tracing struct {
+	Enable bool `json:"enable" env:"ENABLE"`
+	// Endpoint is the base url of the collector, the spans are posted to
+	// Endpoint/v1/traces
+	Endpoint    string            `json:"endpoint" env:"ENDPOINT"`
+	Headers     map[string]string `json:"headers" env:"HEADERS"`
+	ServiceName string            `json:"service_name" env:"SERVICE_NAME"`
+	SampleRatio float64           `json:"sample_ratio" env:"SAMPLE_RATIO"`
+}
+
 type MCP struct {
 	Enable bool `json:"enable" env:"ENABLE"`
 	Port   int  `json:"port" env:"PORT"`
@@ -121,6 +178,11 @@ type Config struct {
 	S3                    S3          `json:"s3" envPrefix:"S3_"`
 	FTP                   FTP         `json:"ftp" envPrefix:"FTP_"`
 	SFTP                  SFTP        `json:"sftp" envPrefix:"SFTP_"`
+	NFS                   NFS         `json:"nfs" envPrefix:"NFS_"`
+	DLNA                  DLNA        `json:"dlna" envPrefix:"DLNA_"`
+	Subsonic              Subsonic    `json:"subsonic" envPrefix:"SUBSONIC_"`
+	Metrics               Metrics     `json:"metrics" envPrefix:"METRICS_"`
+	Tracing               Tracing     `json:"tracing" envPrefix:"TRACING_"`
 	MCP                   MCP         `json:"mcp" envPrefix:"MCP_"`
 	LastLaunchedVersion   string      `json:"last_launched_version"`
 }
@@ -196,6 +258,16 @@ func DefaultConfig() *Config {
 				MaxRetry: 2,
 				// TaskPersistant: true,
 			},
+			CryptRotate: TaskConfig{
+				Workers:  1,
+				MaxRetry: 2,
+				// the storage stays disabled until its rotation is done
+				TaskPersistant: true,
+			},
+			ChunkerCheck: TaskConfig{
+				Workers: 1,
+				// TaskPersistant: true,
+			},
 			AllowRetryCanceled: false,
 		},
 		Cors: Cors{
@@ -224,6 +296,34 @@ func DefaultConfig() *Config {
 			Enable: false,
 			Listen: ":5222",
 		},
+		NFS: NFS{
+			Enable:          false,
+			Listen:          ":2049",
+			ReadOnly:        false,
+			AllowedCIDRs:    []string{},
+			HandleCacheSize: 65536,
+		},
+		DLNA: DLNA{
+			Enable:       false,
+			Listen:       ":8200",
+			FriendlyName: "AList",
+			Paths:        []string{},
+		},
+		Subsonic: Subsonic{
+			Enable: false,
+			Paths:  []string{},
+		},
+		Metrics: Metrics{
+			Enable: false,
+			Port:   -1,
+		},
+		Tracing: Tracing{
+			Enable:      false,
+			Endpoint:    "http://localhost:4318",
+			Headers:     map[string]string{},
+			ServiceName: "alist",
+			SampleRatio: 1,
+		},
 		MCP: MCP{
 			Enable: false,
 			Port:   5248,
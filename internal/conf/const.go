@@ -69,6 +69,11 @@ const (
 	TransmissionUri      = "transmission_uri"
 	TransmissionSeedtime = "transmission_seedtime"
 
+	// bittorrent
+	BitTorrentListenPort = "bittorrent_listen_port"
+	BitTorrentSeedRatio  = "bittorrent_seed_ratio"
+	BitTorrentSeedtime   = "bittorrent_seedtime"
+
 	// 115
 	Pan115TempDir = "115_temp_dir"
 
@@ -12,12 +12,17 @@ var db *gorm.DB
 
 func Init(d *gorm.DB) {
 	db = d
-	err := AutoMigrate(new(model.Storage), new(model.User), new(model.Meta), new(model.SettingItem), new(model.SearchNode), new(model.TaskItem), new(model.SSHPublicKey), new(model.Role), new(model.Label), new(model.LabelFileBinding), new(model.ObjFile), new(model.Session), new(model.Share))
+	err := AutoMigrate(Models()...)
 	if err != nil {
 		log.Fatalf("failed migrate database: %s", err.Error())
 	}
 }
 
+// Models are all the tables stored in the database
+func Models() []interface{} {
+	return []interface{}{new(model.Storage), new(model.User), new(model.Meta), new(model.SettingItem), new(model.SearchNode), new(model.TaskItem), new(model.SSHPublicKey), new(model.Role), new(model.Label), new(model.LabelFileBinding), new(model.ObjFile), new(model.Session), new(model.Share), new(model.APIToken), new(model.S3Credential), new(model.S3ObjectMeta), new(model.RSSSubscription), new(model.RSSItem), new(model.MusicTag)}
+}
+
 func AutoMigrate(dst ...interface{}) error {
 	var err error
 	if conf.Conf.Database.Type == "mysql" {
@@ -0,0 +1,170 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// TableCount is the number of rows of a table in the source and the target
+// of a migration
+type TableCount struct {
+	Table  string `json:"table"`
+	Source int64  `json:"source"`
+	Target int64  `json:"target"`
+}
+
+type MigrateArgs struct {
+	BatchSize int
+	// Force clears the tables of the target instead of failing if not empty
+	Force bool
+	// Progress is called after each batch with the rows copied of the table
+	Progress func(table string, copied, total int64)
+}
+
+// MigrateTo copies every table to dst, which has its own table prefix. The
+// row counts of both sides are compared once a table is copied.
+func MigrateTo(dst *gorm.DB, args MigrateArgs) ([]TableCount, error) {
+	if args.BatchSize <= 0 {
+		args.BatchSize = 500
+	}
+	migrator := dst
+	if dst.Dialector.Name() == "mysql" {
+		migrator = dst.Set("gorm:table_options", "ENGINE=InnoDB CHARSET=utf8mb4")
+	}
+	if err := migrator.AutoMigrate(Models()...); err != nil {
+		return nil, errors.WithMessage(err, "failed migrate target database")
+	}
+	if !args.Force {
+		for _, m := range Models() {
+			var count int64
+			if err := dst.Model(m).Count(&count).Error; err != nil {
+				return nil, err
+			}
+			if count > 0 {
+				return nil, errors.Errorf("table [%s] of the target database is not empty", tableName(dst, m))
+			}
+		}
+	}
+	var counts []TableCount
+	for _, m := range Models() {
+		count, err := migrateTable(dst, m, args)
+		if err != nil {
+			return counts, errors.WithMessagef(err, "failed migrate table [%s]", count.Table)
+		}
+		counts = append(counts, count)
+	}
+	return counts, nil
+}
+
+func tableName(d *gorm.DB, m interface{}) string {
+	stmt := &gorm.Statement{DB: d}
+	if err := stmt.Parse(m); err != nil {
+		return fmt.Sprintf("%T", m)
+	}
+	return stmt.Schema.Table
+}
+
+func migrateTable(dst *gorm.DB, m interface{}, args MigrateArgs) (TableCount, error) {
+	count := TableCount{Table: tableName(dst, m)}
+	if err := db.Model(m).Count(&count.Source).Error; err != nil {
+		return count, err
+	}
+	stmt := &gorm.Statement{DB: dst}
+	if err := stmt.Parse(m); err != nil {
+		return count, err
+	}
+	var fields []*schema.Field
+	for _, f := range stmt.Schema.Fields {
+		if f.DBName != "" && f.Creatable {
+			fields = append(fields, f)
+		}
+	}
+	// the rows are inserted as maps since gorm replaces a zero value by the
+	// default of the column, hooks are skipped to copy them as stored
+	tx := dst.Session(&gorm.Session{SkipHooks: true, AllowGlobalUpdate: true})
+	err := tx.Transaction(func(tx *gorm.DB) error {
+		if args.Force {
+			if err := tx.Delete(m).Error; err != nil {
+				return err
+			}
+		}
+		rows, err := db.Model(m).Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		typ := reflect.TypeOf(m).Elem()
+		var batch []map[string]interface{}
+		var copied int64
+		flush := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			if err := tx.Model(m).Create(&batch).Error; err != nil {
+				return err
+			}
+			copied += int64(len(batch))
+			batch = batch[:0]
+			if args.Progress != nil {
+				args.Progress(count.Table, copied, count.Source)
+			}
+			return nil
+		}
+		for rows.Next() {
+			row := reflect.New(typ)
+			if err := db.ScanRows(rows, row.Interface()); err != nil {
+				return err
+			}
+			values := make(map[string]interface{}, len(fields))
+			for _, f := range fields {
+				values[f.DBName], _ = f.ValueOf(context.Background(), row.Elem())
+			}
+			batch = append(batch, values)
+			if len(batch) >= args.BatchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return flush()
+	})
+	if err != nil {
+		return count, err
+	}
+	if err = resetSequence(dst, m); err != nil {
+		return count, errors.WithMessage(err, "failed reset sequence")
+	}
+	if err = dst.Model(m).Count(&count.Target).Error; err != nil {
+		return count, err
+	}
+	if count.Source != count.Target {
+		return count, errors.Errorf("%d rows in the source but %d in the target", count.Source, count.Target)
+	}
+	return count, nil
+}
+
+// resetSequence moves the sequence of the auto increment key after the
+// copied ids, only postgres doesn't do it on its own
+func resetSequence(dst *gorm.DB, m interface{}) error {
+	if dst.Dialector.Name() != "postgres" {
+		return nil
+	}
+	stmt := &gorm.Statement{DB: dst}
+	if err := stmt.Parse(m); err != nil {
+		return err
+	}
+	pk := stmt.Schema.PrioritizedPrimaryField
+	if pk == nil || !pk.AutoIncrement || (pk.DataType != schema.Int && pk.DataType != schema.Uint) {
+		return nil
+	}
+	return dst.Exec("SELECT setval(pg_get_serial_sequence(?, ?), COALESCE((SELECT MAX(?) FROM ?), 0) + 1, false)",
+		stmt.Schema.Table, pk.DBName, gorm.Expr(dst.Statement.Quote(pk.DBName)), gorm.Expr(dst.Statement.Quote(stmt.Schema.Table))).Error
+}
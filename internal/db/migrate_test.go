@@ -0,0 +1,89 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+func TestMigrateTo(t *testing.T) {
+	src, err := gorm.Open(sqlite.Open("file:migrate_src?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.Conf = conf.DefaultConfig()
+	Init(src)
+	old := db
+	t.Cleanup(func() {
+		db = old
+		closeDB(t, src)
+	})
+	storage := &model.Storage{MountPath: "/local", Driver: "Local"}
+	if err = CreateStorage(storage); err != nil {
+		t.Fatal(err)
+	}
+	// false is not the default of the column
+	if err = src.Model(storage).Update("down_proxy_sign", false).Error; err != nil {
+		t.Fatal(err)
+	}
+	role := &model.Role{Name: "editor", PermissionScopes: []model.PermissionEntry{{Path: "/docs", Permission: 8}}}
+	if err = CreateRole(role); err != nil {
+		t.Fatal(err)
+	}
+	nodes := []model.SearchNode{{Parent: "/", Name: "a"}, {Parent: "/", Name: "b"}, {Parent: "/a", Name: "c", IsDir: true}}
+	if err = BatchCreateSearchNodes(&nodes); err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err := gorm.Open(sqlite.Open("file:migrate_dst?mode=memory&cache=shared"), &gorm.Config{
+		NamingStrategy: schema.NamingStrategy{TablePrefix: "x_"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, dst)
+	var batches int
+	counts, err := MigrateTo(dst, MigrateArgs{BatchSize: 2, Progress: func(table string, copied, total int64) {
+		batches++
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != len(Models()) || batches != 4 {
+		t.Fatalf("unexpected counts %+v after %d batches", counts, batches)
+	}
+	for _, c := range counts {
+		if c.Table == "x_search_nodes" && c.Target != 3 {
+			t.Fatalf("expected 3 search nodes, got %+v", c)
+		}
+	}
+	var s model.Storage
+	if err = dst.First(&s, storage.ID).Error; err != nil || s.DownProxySign || s.MountPath != "/local" {
+		t.Fatalf("storage not copied as is: %+v %v", s, err)
+	}
+	var r model.Role
+	if err = dst.First(&r, role.ID).Error; err != nil || len(r.PermissionScopes) != 1 || r.PermissionScopes[0].Path != "/docs" {
+		t.Fatalf("role not copied as is: %+v %v", r, err)
+	}
+
+	if _, err = MigrateTo(dst, MigrateArgs{}); err == nil {
+		t.Fatal("expected a non empty target to be refused")
+	}
+	if counts, err = MigrateTo(dst, MigrateArgs{Force: true}); err != nil || counts[0].Target != 1 {
+		t.Fatalf("failed to migrate again with force: %+v %v", counts, err)
+	}
+}
+
+func closeDB(t *testing.T, d *gorm.DB) {
+	sqlDB, err := d.DB()
+	if err == nil {
+		err = sqlDB.Close()
+	}
+	if err != nil {
+		t.Error(err)
+	}
+}
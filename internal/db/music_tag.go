@@ -0,0 +1,100 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func GetMusicTagsByDir(dir string) ([]model.MusicTag, error) {
+	var tags []model.MusicTag
+	if err := db.Where("dir = ?", dir).Find(&tags).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get music tags")
+	}
+	return tags, nil
+}
+
+func GetMusicTag(dir, name string) (*model.MusicTag, error) {
+	var t model.MusicTag
+	if err := db.Where("dir = ? AND name = ?", dir, name).First(&t).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get music tag")
+	}
+	return &t, nil
+}
+
+func SaveMusicTag(t *model.MusicTag) error {
+	return errors.WithStack(db.Save(t).Error)
+}
+
+// DeleteMusicTagsExcept deletes the tags of the files in dir not in names
+func DeleteMusicTagsExcept(dir string, names []string) error {
+	tx := db.Where("dir = ?", dir)
+	if len(names) > 0 {
+		tx = tx.Where("name NOT IN ?", names)
+	}
+	return errors.WithStack(tx.Delete(&model.MusicTag{}).Error)
+}
+
+// whereMusicIn limits the tags to the files under one of roots
+func whereMusicIn(roots []string) *gorm.DB {
+	tx := db.Where("1 = 0")
+	for _, root := range roots {
+		if root == "/" {
+			return db.Where("1 = 1")
+		}
+		tx = tx.Or("dir = ?", root).Or("dir LIKE ?", root+"/%")
+	}
+	return tx
+}
+
+func whereMusicLike(query string, columns ...string) *gorm.DB {
+	if query == "" {
+		return db.Where("1 = 1")
+	}
+	tx := db.Where("1 = 0")
+	for _, column := range columns {
+		tx = tx.Or(fmt.Sprintf("%s LIKE ?", column), "%"+query+"%")
+	}
+	return tx
+}
+
+// SearchMusicTags returns the songs under roots whose title, artist or album contain query
+func SearchMusicTags(roots []string, query string, offset, limit int) ([]model.MusicTag, error) {
+	var tags []model.MusicTag
+	err := db.Where(whereMusicIn(roots)).Where(whereMusicLike(query, "title", "artist", "album")).
+		Order("dir, disc, track, name").Offset(offset).Limit(limit).Find(&tags).Error
+	return tags, errors.WithStack(err)
+}
+
+// MusicAlbum is a folder of songs sharing an album tag
+type MusicAlbum struct {
+	Dir       string
+	Album     string
+	Artist    string
+	SongCount int
+}
+
+func SearchMusicAlbums(roots []string, query string, offset, limit int) ([]MusicAlbum, error) {
+	var albums []MusicAlbum
+	err := db.Model(&model.MusicTag{}).Select("dir, album, MAX(artist) AS artist, COUNT(*) AS song_count").
+		Where(whereMusicIn(roots)).Where("album <> ''").Where(whereMusicLike(query, "album")).
+		Group("dir, album").Order("album, dir").Offset(offset).Limit(limit).Scan(&albums).Error
+	return albums, errors.WithStack(err)
+}
+
+// MusicArtist is an artist tag, Dir is the folder of one of the songs
+type MusicArtist struct {
+	Artist     string
+	Dir        string
+	AlbumCount int
+}
+
+func SearchMusicArtists(roots []string, query string, offset, limit int) ([]MusicArtist, error) {
+	var artists []MusicArtist
+	err := db.Model(&model.MusicTag{}).Select("artist, MIN(dir) AS dir, COUNT(DISTINCT album) AS album_count").
+		Where(whereMusicIn(roots)).Where("artist <> ''").Where(whereMusicLike(query, "artist")).
+		Group("artist").Order("artist").Offset(offset).Limit(limit).Scan(&artists).Error
+	return artists, errors.WithStack(err)
+}
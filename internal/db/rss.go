@@ -0,0 +1,94 @@
+package db
+
+import (
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+)
+
+func GetRSSSubscriptions(userId uint, pageIndex, pageSize int) (subs []model.RSSSubscription, count int64, err error) {
+	subDB := db.Model(&model.RSSSubscription{})
+	if userId != 0 {
+		subDB = subDB.Where("user_id = ?", userId)
+	}
+	if err := subDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get rss subscriptions count")
+	}
+	if err := subDB.Order(columnName("id")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&subs).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find rss subscriptions")
+	}
+	return subs, count, nil
+}
+
+func GetEnabledRSSSubscriptions() ([]model.RSSSubscription, error) {
+	var subs []model.RSSSubscription
+	if err := db.Where("disabled = ?", false).Find(&subs).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find enabled rss subscriptions")
+	}
+	return subs, nil
+}
+
+func GetRSSSubscriptionById(id uint) (*model.RSSSubscription, error) {
+	var s model.RSSSubscription
+	if err := db.First(&s, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get rss subscription")
+	}
+	return &s, nil
+}
+
+func CreateRSSSubscription(s *model.RSSSubscription) error {
+	return errors.WithStack(db.Create(s).Error)
+}
+
+func UpdateRSSSubscription(s *model.RSSSubscription) error {
+	return errors.WithStack(db.Save(s).Error)
+}
+
+func UpdateRSSSubscriptionCheck(id uint, ts time.Time, lastErr string) error {
+	return errors.WithStack(db.Model(&model.RSSSubscription{}).Where("id = ?", id).
+		Updates(map[string]any{"last_check": ts, "last_error": lastErr}).Error)
+}
+
+func DeleteRSSSubscriptionById(id uint) error {
+	if err := db.Where("subscription_id = ?", id).Delete(&model.RSSItem{}).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(db.Delete(&model.RSSSubscription{}, id).Error)
+}
+
+func DeleteRSSSubscriptionsByUserId(userId uint) error {
+	var ids []uint
+	if err := db.Model(&model.RSSSubscription{}).Where("user_id = ?", userId).Pluck("id", &ids).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	for _, id := range ids {
+		if err := DeleteRSSSubscriptionById(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func GetRSSItems(subscriptionId uint, pageIndex, pageSize int) (items []model.RSSItem, count int64, err error) {
+	itemDB := db.Model(&model.RSSItem{}).Where("subscription_id = ?", subscriptionId)
+	if err := itemDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get rss items count")
+	}
+	if err := itemDB.Order(columnName("id") + " desc").Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&items).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find rss items")
+	}
+	return items, count, nil
+}
+
+func HasRSSItem(subscriptionId uint, guid string) (bool, error) {
+	var count int64
+	if err := db.Model(&model.RSSItem{}).Where("subscription_id = ? AND guid = ?", subscriptionId, guid).Count(&count).Error; err != nil {
+		return false, errors.Wrapf(err, "failed check rss item")
+	}
+	return count > 0, nil
+}
+
+func CreateRSSItem(item *model.RSSItem) error {
+	return errors.WithStack(db.Create(item).Error)
+}
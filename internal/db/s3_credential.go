@@ -0,0 +1,55 @@
+package db
+
+import (
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+)
+
+func GetS3CredentialsByUserId(userId uint, pageIndex, pageSize int) (creds []model.S3Credential, count int64, err error) {
+	credDB := db.Model(&model.S3Credential{}).Where("user_id = ?", userId)
+	if err := credDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get user's s3 credentials count")
+	}
+	if err := credDB.Order(columnName("id")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&creds).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find user's s3 credentials")
+	}
+	return creds, count, nil
+}
+
+func GetS3CredentialById(id uint) (*model.S3Credential, error) {
+	var c model.S3Credential
+	if err := db.First(&c, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get s3 credential")
+	}
+	return &c, nil
+}
+
+func GetS3CredentialByAccessKey(accessKey string) (*model.S3Credential, error) {
+	var c model.S3Credential
+	if err := db.Where("access_key_id = ?", accessKey).First(&c).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find s3 credential")
+	}
+	return &c, nil
+}
+
+func CreateS3Credential(c *model.S3Credential) error {
+	return errors.WithStack(db.Create(c).Error)
+}
+
+func UpdateS3Credential(c *model.S3Credential) error {
+	return errors.WithStack(db.Save(c).Error)
+}
+
+func UpdateS3CredentialLastUsed(id uint, ts time.Time) error {
+	return errors.WithStack(db.Model(&model.S3Credential{}).Where("id = ?", id).Update("last_used_time", ts).Error)
+}
+
+func DeleteS3CredentialById(id uint) error {
+	return errors.WithStack(db.Delete(&model.S3Credential{}, id).Error)
+}
+
+func DeleteS3CredentialsByUserId(userId uint) error {
+	return errors.WithStack(db.Where("user_id = ?", userId).Delete(&model.S3Credential{}).Error)
+}
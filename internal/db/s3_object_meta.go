@@ -0,0 +1,26 @@
+package db
+
+import (
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+)
+
+func GetS3ObjectMeta(storageId uint, path string) (*model.S3ObjectMeta, error) {
+	var m model.S3ObjectMeta
+	if err := db.Where("storage_id = ? AND path = ?", storageId, path).First(&m).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get s3 object meta")
+	}
+	return &m, nil
+}
+
+func SaveS3ObjectMeta(m *model.S3ObjectMeta) error {
+	return errors.WithStack(db.Save(m).Error)
+}
+
+func DeleteS3ObjectMeta(storageId uint, path string) error {
+	return errors.WithStack(db.Where("storage_id = ? AND path = ?", storageId, path).Delete(&model.S3ObjectMeta{}).Error)
+}
+
+func DeleteS3ObjectMetasByStorageId(storageId uint) error {
+	return errors.WithStack(db.Where("storage_id = ?", storageId).Delete(&model.S3ObjectMeta{}).Error)
+}
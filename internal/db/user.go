@@ -3,6 +3,7 @@ package db
 import (
 	"encoding/base64"
 	"fmt"
+	"github.com/alist-org/alist/v3/internal/conf"
 	"github.com/alist-org/alist/v3/internal/model"
 	"github.com/alist-org/alist/v3/pkg/utils"
 	"github.com/go-webauthn/webauthn/webauthn"
@@ -172,9 +173,17 @@ func UpdateUserBasePathPrefix(oldPath, newPath string, usersOpt ...[]model.User)
 func CountUsersByRoleAndEnabledExclude(roleID uint, excludeUserID uint) (int64, error) {
 	var count int64
 	jsonValue := fmt.Sprintf("[%d]", roleID)
-	err := db.Model(&model.User{}).
-		Where("disabled = ? AND id != ?", false, excludeUserID).
-		Where("JSON_CONTAINS(role, ?)", jsonValue).
-		Count(&count).Error
+	query := db.Model(&model.User{}).
+		Where("disabled = ? AND id != ?", false, excludeUserID)
+	// role is a json array stored as text, JSON_CONTAINS only exists in mysql
+	switch conf.Conf.Database.Type {
+	case "mysql":
+		query = query.Where("JSON_CONTAINS(role, ?)", jsonValue)
+	case "postgres":
+		query = query.Where("role::jsonb @> ?::jsonb", jsonValue)
+	default:
+		query = query.Where("EXISTS (SELECT 1 FROM json_each(role) WHERE json_each.value = ?)", roleID)
+	}
+	err := query.Count(&count).Error
 	return count, err
 }
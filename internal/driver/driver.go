@@ -208,3 +208,9 @@ type ArchiveDecompressResult interface {
 type Reference interface {
 	InitReference(storage Driver) error
 }
+
+type WithDetails interface {
+	// GetDetails get the space of the storage, used by drivers that spread
+	// files over other storages
+	GetDetails(ctx context.Context) (*model.StorageDetails, error)
+}
@@ -5,12 +5,13 @@ type Additional interface{}
 type Select string
 
 type Item struct {
-	Name     string `json:"name"`
-	Type     string `json:"type"`
-	Default  string `json:"default"`
-	Options  string `json:"options"`
-	Required bool   `json:"required"`
-	Help     string `json:"help"`
+	Name         string `json:"name"`
+	Type         string `json:"type"`
+	Default      string `json:"default"`
+	Options      string `json:"options"`
+	Required     bool   `json:"required"`
+	Help         string `json:"help"`
+	Confidential bool   `json:"confidential,omitempty"` // holds a password or key
 }
 
 type Info struct {
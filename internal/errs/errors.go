@@ -14,6 +14,7 @@ var (
 
 	MoveBetweenTwoStorages = errors.New("can't move files between two storages, try to copy")
 	UploadNotSupported     = errors.New("upload not supported")
+	UploadNotAllowed       = errors.New("upload not allowed by path policy")
 
 	MetaNotFound     = errors.New("meta not found")
 	StorageNotFound  = errors.New("storage not found")
@@ -3,8 +3,11 @@ package errs
 import "errors"
 
 var (
-	EmptyUsername      = errors.New("username is empty")
-	EmptyPassword      = errors.New("password is empty")
-	WrongPassword      = errors.New("password is incorrect")
-	DeleteAdminOrGuest = errors.New("cannot delete admin or guest")
+	EmptyUsername       = errors.New("username is empty")
+	EmptyPassword       = errors.New("password is empty")
+	WrongPassword       = errors.New("password is incorrect")
+	DeleteAdminOrGuest  = errors.New("cannot delete admin or guest")
+	InvalidAPIToken     = errors.New("api token is invalid")
+	APITokenExpired     = errors.New("api token is expired")
+	InvalidS3Credential = errors.New("s3 credential is invalid")
 )
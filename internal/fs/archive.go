@@ -232,6 +232,10 @@ func (t *ArchiveContentUploadTask) RunWithNextTaskCallback(f func(nextTsk *Archi
 			Reader:       file,
 		}
 		fs.Closers.Add(file)
+		if err = op.CheckUploadPolicy(t.Ctx(), t.dstStorage, t.DstDirPath, fs); err != nil {
+			_ = fs.Close()
+			return err
+		}
 		t.status = "uploading"
 		err = op.Put(t.Ctx(), t.dstStorage, t.DstDirPath, fs, t.SetProgress, true)
 		if err != nil {
@@ -6,6 +6,7 @@ import (
 	"github.com/alist-org/alist/v3/internal/errs"
 	"net/http"
 	stdpath "path"
+	"strings"
 	"time"
 
 	"github.com/alist-org/alist/v3/internal/conf"
@@ -28,6 +29,32 @@ type CopyTask struct {
 	dstStorage   driver.Driver `json:"-"`
 	SrcStorageMp string        `json:"src_storage_mp"`
 	DstStorageMp string        `json:"dst_storage_mp"`
+	Verify       bool          `json:"verify,omitempty"`
+	Conflict     string        `json:"conflict,omitempty"`
+}
+
+const (
+	// ConflictOverwrite always replaces an existing destination file
+	ConflictOverwrite = ""
+	// ConflictSkipSame skips files whose destination has the same size and hash
+	ConflictSkipSame = "skip_same"
+	// ConflictNewer only replaces the destination if the source is newer
+	ConflictNewer = "newer"
+)
+
+type CopyArgs struct {
+	// Verify compares the hash of source and destination after copying,
+	// a mismatch fails the task so that it's retried
+	Verify   bool
+	Conflict string
+}
+
+func (a CopyArgs) Validate() error {
+	switch a.Conflict {
+	case ConflictOverwrite, ConflictSkipSame, ConflictNewer:
+		return nil
+	}
+	return errors.Errorf("unknown conflict mode: %s", a.Conflict)
 }
 
 func (t *CopyTask) GetName() string {
@@ -60,7 +87,7 @@ var CopyTaskManager *tache.Manager[*CopyTask]
 
 // Copy if in the same storage, call move method
 // if not, add copy task
-func _copy(ctx context.Context, srcObjPath, dstDirPath string, lazyCache ...bool) (task.TaskExtensionInfo, error) {
+func _copy(ctx context.Context, srcObjPath, dstDirPath string, args CopyArgs, lazyCache ...bool) (task.TaskExtensionInfo, error) {
 	srcStorage, srcObjActualPath, err := op.GetStorageAndActualPath(srcObjPath)
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed get src storage")
@@ -69,36 +96,24 @@ func _copy(ctx context.Context, srcObjPath, dstDirPath string, lazyCache ...bool
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed get dst storage")
 	}
+	sameStorage := srcStorage.GetStorage() == dstStorage.GetStorage()
+	// a native copy of a dir can't skip, verify or police single files,
+	// they are left to the copy tasks then
+	plain := args.Conflict == ConflictOverwrite && !args.Verify && !op.HasUploadPolicy(dstStorage, dstDirActualPath)
 	// copy if in the same storage, just call driver.Copy
-	if srcStorage.GetStorage() == dstStorage.GetStorage() {
+	if sameStorage && plain {
 		err = op.Copy(ctx, srcStorage, srcObjActualPath, dstDirActualPath, lazyCache...)
 		if !errors.Is(err, errs.NotImplement) && !errors.Is(err, errs.NotSupport) {
 			return nil, err
 		}
 	}
-	if ctx.Value(conf.NoTaskKey) != nil {
+	if (sameStorage && !plain) || ctx.Value(conf.NoTaskKey) != nil {
 		srcObj, err := op.Get(ctx, srcStorage, srcObjActualPath)
 		if err != nil {
 			return nil, errors.WithMessagef(err, "failed get src [%s] file", srcObjPath)
 		}
 		if !srcObj.IsDir() {
-			// copy file directly
-			link, _, err := op.Link(ctx, srcStorage, srcObjActualPath, model.LinkArgs{
-				Header: http.Header{},
-			})
-			if err != nil {
-				return nil, errors.WithMessagef(err, "failed get [%s] link", srcObjPath)
-			}
-			fs := stream.FileStream{
-				Obj: srcObj,
-				Ctx: ctx,
-			}
-			// any link provided is seekable
-			ss, err := stream.NewSeekableStream(fs, link)
-			if err != nil {
-				return nil, errors.WithMessagef(err, "failed get [%s] stream", srcObjPath)
-			}
-			return nil, op.Put(ctx, dstStorage, dstDirActualPath, ss, nil, false)
+			return nil, copyFileDirectly(ctx, srcStorage, dstStorage, srcObjActualPath, dstDirActualPath, srcObj, args, lazyCache...)
 		}
 	}
 	// not in the same storage
@@ -113,6 +128,8 @@ func _copy(ctx context.Context, srcObjPath, dstDirPath string, lazyCache ...bool
 		DstDirPath:   dstDirActualPath,
 		SrcStorageMp: srcStorage.GetStorage().MountPath,
 		DstStorageMp: dstStorage.GetStorage().MountPath,
+		Verify:       args.Verify,
+		Conflict:     args.Conflict,
 	}
 	CopyTaskManager.Add(t)
 	return t, nil
@@ -146,6 +163,8 @@ func copyBetween2Storages(t *CopyTask, srcStorage, dstStorage driver.Driver, src
 				DstDirPath:   dstObjPath,
 				SrcStorageMp: srcStorage.GetStorage().MountPath,
 				DstStorageMp: dstStorage.GetStorage().MountPath,
+				Verify:       t.Verify,
+				Conflict:     t.Conflict,
 			})
 		}
 		t.Status = "src object is dir, added all copy tasks of objs"
@@ -160,7 +179,57 @@ func copyFileBetween2Storages(tsk *CopyTask, srcStorage, dstStorage driver.Drive
 		return errors.WithMessagef(err, "failed get src [%s] file", srcFilePath)
 	}
 	tsk.SetTotalBytes(srcFile.GetSize())
-	link, _, err := op.Link(tsk.Ctx(), srcStorage, srcFilePath, model.LinkArgs{
+	dstFilePath := stdpath.Join(dstDirPath, srcFile.GetName())
+	if tsk.Conflict != ConflictOverwrite {
+		tsk.Status = "checking dst file"
+		skip, reason, err := shouldSkipCopy(tsk.Ctx(), tsk.Conflict, srcStorage, dstStorage, srcFilePath, dstFilePath, srcFile)
+		if err != nil {
+			return err
+		}
+		if skip {
+			tsk.Status = "skipped, " + reason
+			tsk.SetProgress(100)
+			return nil
+		}
+		tsk.Status = "copying"
+	}
+	err = putFile(tsk.Ctx(), srcStorage, dstStorage, srcFilePath, dstDirPath, srcFile, tsk.SetProgress, true)
+	if err != nil || !tsk.Verify {
+		return err
+	}
+	tsk.Status = "verifying"
+	if err = verifyCopy(tsk.Ctx(), srcStorage, dstStorage, srcFilePath, dstDirPath, srcFile); err != nil {
+		return err
+	}
+	tsk.Status = "verified"
+	return nil
+}
+
+// copyFileDirectly copies a file without a task, natively if both paths are
+// in the same storage
+func copyFileDirectly(ctx context.Context, srcStorage, dstStorage driver.Driver, srcFilePath, dstDirPath string, srcFile model.Obj, args CopyArgs, lazyCache ...bool) error {
+	if args.Conflict != ConflictOverwrite {
+		dstFilePath := stdpath.Join(dstDirPath, srcFile.GetName())
+		skip, _, err := shouldSkipCopy(ctx, args.Conflict, srcStorage, dstStorage, srcFilePath, dstFilePath, srcFile)
+		if err != nil || skip {
+			return err
+		}
+	}
+	err := errs.NotImplement
+	if srcStorage.GetStorage() == dstStorage.GetStorage() && !op.HasUploadPolicy(dstStorage, dstDirPath) {
+		err = op.Copy(ctx, srcStorage, srcFilePath, dstDirPath, lazyCache...)
+	}
+	if errors.Is(err, errs.NotImplement) || errors.Is(err, errs.NotSupport) {
+		err = putFile(ctx, srcStorage, dstStorage, srcFilePath, dstDirPath, srcFile, nil, false)
+	}
+	if err != nil || !args.Verify {
+		return err
+	}
+	return verifyCopy(ctx, srcStorage, dstStorage, srcFilePath, dstDirPath, srcFile)
+}
+
+func putFile(ctx context.Context, srcStorage, dstStorage driver.Driver, srcFilePath, dstDirPath string, srcFile model.Obj, up driver.UpdateProgress, lazyCache bool) error {
+	link, _, err := op.Link(ctx, srcStorage, srcFilePath, model.LinkArgs{
 		Header: http.Header{},
 	})
 	if err != nil {
@@ -168,12 +237,128 @@ func copyFileBetween2Storages(tsk *CopyTask, srcStorage, dstStorage driver.Drive
 	}
 	fs := stream.FileStream{
 		Obj: srcFile,
-		Ctx: tsk.Ctx(),
+		Ctx: ctx,
 	}
 	// any link provided is seekable
 	ss, err := stream.NewSeekableStream(fs, link)
 	if err != nil {
 		return errors.WithMessagef(err, "failed get [%s] stream", srcFilePath)
 	}
-	return op.Put(tsk.Ctx(), dstStorage, dstDirPath, ss, tsk.SetProgress, true)
+	if err := op.CheckUploadPolicy(ctx, dstStorage, dstDirPath, ss); err != nil {
+		_ = ss.Close()
+		return err
+	}
+	return op.Put(ctx, dstStorage, dstDirPath, ss, up, lazyCache)
+}
+
+// verifyCopy compares a copied file with its source
+func verifyCopy(ctx context.Context, srcStorage, dstStorage driver.Driver, srcFilePath, dstDirPath string, srcFile model.Obj) error {
+	op.ClearCache(dstStorage, dstDirPath)
+	dstFilePath := stdpath.Join(dstDirPath, srcFile.GetName())
+	dstFile, err := op.Get(ctx, dstStorage, dstFilePath)
+	if err != nil {
+		return errors.WithMessagef(err, "failed get dst [%s] file", dstFilePath)
+	}
+	same, err := sameContent(ctx, srcStorage, dstStorage, srcFilePath, dstFilePath, srcFile, dstFile)
+	if err != nil {
+		return errors.WithMessage(err, "failed verify copied file")
+	}
+	if !same {
+		return errors.Errorf("checksum mismatch between [%s](%s) and [%s](%s)",
+			srcStorage.GetStorage().MountPath, srcFilePath, dstStorage.GetStorage().MountPath, dstFilePath)
+	}
+	return nil
+}
+
+// shouldSkipCopy checks the existing destination file against the conflict
+// mode, the reason is set if the copy is skipped
+func shouldSkipCopy(ctx context.Context, conflict string, srcStorage, dstStorage driver.Driver, srcFilePath, dstFilePath string, srcFile model.Obj) (bool, string, error) {
+	dstFile, err := op.Get(ctx, dstStorage, dstFilePath)
+	if err != nil {
+		if errs.IsObjectNotFound(err) {
+			return false, "", nil
+		}
+		return false, "", errors.WithMessagef(err, "failed get dst [%s] file", dstFilePath)
+	}
+	if dstFile.IsDir() {
+		return false, "", nil
+	}
+	switch conflict {
+	case ConflictNewer:
+		if !srcFile.ModTime().After(dstFile.ModTime()) {
+			return true, "dst file is not older", nil
+		}
+	case ConflictSkipSame:
+		same, err := sameContent(ctx, srcStorage, dstStorage, srcFilePath, dstFilePath, srcFile, dstFile)
+		if err != nil {
+			return false, "", err
+		}
+		if same {
+			return true, "dst file has the same size and hash", nil
+		}
+	}
+	return false, "", nil
+}
+
+// sameContent compares size and hash of two files. Hashes reported by the
+// drivers are used if both sides share a hash type, otherwise the missing
+// side is hashed by reading the file.
+func sameContent(ctx context.Context, srcStorage, dstStorage driver.Driver, srcFilePath, dstFilePath string, srcFile, dstFile model.Obj) (bool, error) {
+	if srcFile.GetSize() != dstFile.GetSize() {
+		return false, nil
+	}
+	srcHash, dstHash := srcFile.GetHash(), dstFile.GetHash()
+	var ht *utils.HashType
+	for t, h := range srcHash.All() {
+		if h == "" {
+			continue
+		}
+		if d := dstHash.GetHash(t); d != "" {
+			return strings.EqualFold(h, d), nil
+		}
+		if ht == nil {
+			ht = t
+		}
+	}
+	if ht == nil {
+		for t, h := range dstHash.All() {
+			if h != "" {
+				ht = t
+				break
+			}
+		}
+	}
+	if ht == nil {
+		ht = utils.MD5
+	}
+	s, err := fileHash(ctx, srcStorage, srcFilePath, srcFile, ht)
+	if err != nil {
+		return false, errors.WithMessagef(err, "failed hash src [%s] file", srcFilePath)
+	}
+	d, err := fileHash(ctx, dstStorage, dstFilePath, dstFile, ht)
+	if err != nil {
+		return false, errors.WithMessagef(err, "failed hash dst [%s] file", dstFilePath)
+	}
+	return strings.EqualFold(s, d), nil
+}
+
+func fileHash(ctx context.Context, storage driver.Driver, path string, obj model.Obj, ht *utils.HashType) (string, error) {
+	if h := obj.GetHash().GetHash(ht); h != "" {
+		return h, nil
+	}
+	link, _, err := op.Link(ctx, storage, path, model.LinkArgs{
+		Header: http.Header{},
+	})
+	if err != nil {
+		return "", err
+	}
+	ss, err := stream.NewSeekableStream(stream.FileStream{
+		Obj: obj,
+		Ctx: ctx,
+	}, link)
+	if err != nil {
+		return "", err
+	}
+	defer ss.Close()
+	return utils.HashReader(ht, ss)
 }
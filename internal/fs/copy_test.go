@@ -0,0 +1,161 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/alist-org/alist/v3/drivers/local"
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/testutil"
+	"github.com/alist-org/alist/v3/pkg/utils"
+)
+
+func init() {
+	testutil.InitDB()
+}
+
+func writeFile(t *testing.T, name, content string, modified time.Time) {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(name, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSameContent(t *testing.T) {
+	ctx := context.Background()
+	storage, dir := testutil.CreateLocal(t, "/same_content")
+	now := time.Now()
+	writeFile(t, filepath.Join(dir, "a.txt"), "hello", now)
+	writeFile(t, filepath.Join(dir, "b.txt"), "hello", now)
+	writeFile(t, filepath.Join(dir, "c.txt"), "world", now)
+	writeFile(t, filepath.Join(dir, "d.txt"), "hello!", now)
+	get := func(name string) model.Obj {
+		obj, err := op.Get(ctx, storage, "/"+name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return obj
+	}
+	for _, c := range []struct {
+		name string
+		same bool
+	}{{"b.txt", true}, {"c.txt", false}, {"d.txt", false}} {
+		same, err := sameContent(ctx, storage, storage, "/a.txt", "/"+c.name, get("a.txt"), get(c.name))
+		if err != nil {
+			t.Fatalf("failed compare with %s: %+v", c.name, err)
+		}
+		if same != c.same {
+			t.Errorf("a.txt and %s: expected same %t", c.name, c.same)
+		}
+	}
+	// hashes reported on both sides are trusted without reading the files
+	src := &model.Object{Name: "a.txt", Size: 5, HashInfo: utils.NewHashInfo(utils.MD5, "0123")}
+	dst := &model.Object{Name: "c.txt", Size: 5, HashInfo: utils.NewHashInfo(utils.MD5, "0123")}
+	if same, err := sameContent(ctx, storage, storage, "/a.txt", "/c.txt", src, dst); err != nil || !same {
+		t.Errorf("expected the same md5 to match: %v", err)
+	}
+}
+
+func TestShouldSkipCopy(t *testing.T) {
+	ctx := context.Background()
+	src, srcDir := testutil.CreateLocal(t, "/skip_src")
+	dst, dstDir := testutil.CreateLocal(t, "/skip_dst")
+	old, now := time.Now().Add(-time.Hour), time.Now()
+	writeFile(t, filepath.Join(srcDir, "old.txt"), "old", old)
+	writeFile(t, filepath.Join(dstDir, "old.txt"), "new", now)
+	writeFile(t, filepath.Join(srcDir, "new.txt"), "new", now)
+	writeFile(t, filepath.Join(dstDir, "new.txt"), "old", old)
+	writeFile(t, filepath.Join(srcDir, "same.txt"), "same", now)
+	writeFile(t, filepath.Join(dstDir, "same.txt"), "same", old)
+	writeFile(t, filepath.Join(srcDir, "missing.txt"), "missing", now)
+	for _, c := range []struct {
+		conflict string
+		name     string
+		skip     bool
+	}{
+		{ConflictNewer, "old.txt", true},
+		{ConflictNewer, "new.txt", false},
+		{ConflictNewer, "missing.txt", false},
+		{ConflictSkipSame, "same.txt", true},
+		{ConflictSkipSame, "new.txt", false},
+		{ConflictSkipSame, "missing.txt", false},
+	} {
+		srcFile, err := op.Get(ctx, src, "/"+c.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		skip, _, err := shouldSkipCopy(ctx, c.conflict, src, dst, "/"+c.name, "/"+c.name, srcFile)
+		if err != nil {
+			t.Fatalf("%s %s: %+v", c.conflict, c.name, err)
+		}
+		if skip != c.skip {
+			t.Errorf("%s %s: expected skip %t", c.conflict, c.name, c.skip)
+		}
+	}
+}
+
+func TestCopyConflictSameStorage(t *testing.T) {
+	_, dir := testutil.CreateLocal(t, "/conflict")
+	old, now := time.Now().Add(-time.Hour), time.Now()
+	writeFile(t, filepath.Join(dir, "src", "a.txt"), "old", old)
+	writeFile(t, filepath.Join(dir, "dst", "a.txt"), "new", now)
+	ctx := context.WithValue(context.Background(), conf.NoTaskKey, struct{}{})
+	// the native copy of the driver must not replace a newer file
+	if _, err := CopyWithArgs(ctx, "/conflict/src/a.txt", "/conflict/dst", CopyArgs{Conflict: ConflictNewer}); err != nil {
+		t.Fatalf("failed copy: %+v", err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "dst", "a.txt")); string(b) != "new" {
+		t.Errorf("newer dst file was replaced: %s", b)
+	}
+	writeFile(t, filepath.Join(dir, "src", "a.txt"), "newest", now.Add(time.Hour))
+	if _, err := CopyWithArgs(ctx, "/conflict/src/a.txt", "/conflict/dst", CopyArgs{Conflict: ConflictNewer, Verify: true}); err != nil {
+		t.Fatalf("failed copy: %+v", err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "dst", "a.txt")); string(b) != "newest" {
+		t.Errorf("older dst file was kept: %s", b)
+	}
+}
+
+func TestCopyUploadPolicy(t *testing.T) {
+	_, dir := testutil.CreateLocal(t, "/policed")
+	now := time.Now()
+	writeFile(t, filepath.Join(dir, "src", "tool.exe"), "binary", now)
+	writeFile(t, filepath.Join(dir, "src", "notes.txt"), "text", now)
+	if err := os.MkdirAll(filepath.Join(dir, "drop"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := op.CreateMeta(&model.Meta{Path: "/policed/drop", UploadDeny: "exe", USub: true}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if meta, err := op.GetMetaByPath("/policed/drop"); err == nil {
+			_ = op.DeleteMetaById(meta.ID)
+		}
+	}()
+	ctx := context.WithValue(context.Background(), conf.NoTaskKey, struct{}{})
+	// the native copy of the driver must not bypass the policy
+	if _, err := CopyWithArgs(ctx, "/policed/src/tool.exe", "/policed/drop", CopyArgs{}); !errors.Is(err, errs.UploadNotAllowed) {
+		t.Fatalf("expected the copy to be refused, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "drop", "tool.exe")); !os.IsNotExist(err) {
+		t.Fatalf("refused file was copied: %v", err)
+	}
+	if _, err := CopyWithArgs(ctx, "/policed/src/notes.txt", "/policed/drop", CopyArgs{}); err != nil {
+		t.Fatalf("failed copy: %+v", err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "drop", "notes.txt")); string(b) != "text" {
+		t.Errorf("allowed file was not copied: %s", b)
+	}
+}
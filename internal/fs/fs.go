@@ -74,7 +74,11 @@ func Move(ctx context.Context, srcPath, dstDirPath string, lazyCache ...bool) er
 }
 
 func Copy(ctx context.Context, srcObjPath, dstDirPath string, lazyCache ...bool) (task.TaskExtensionInfo, error) {
-	res, err := _copy(ctx, srcObjPath, dstDirPath, lazyCache...)
+	return CopyWithArgs(ctx, srcObjPath, dstDirPath, CopyArgs{}, lazyCache...)
+}
+
+func CopyWithArgs(ctx context.Context, srcObjPath, dstDirPath string, args CopyArgs, lazyCache ...bool) (task.TaskExtensionInfo, error) {
+	res, err := _copy(ctx, srcObjPath, dstDirPath, args, lazyCache...)
 	if err != nil {
 		log.Errorf("failed copy %s to %s: %+v", srcObjPath, dstDirPath, err)
 	}
@@ -98,7 +102,12 @@ func Remove(ctx context.Context, path string) error {
 }
 
 func PutDirectly(ctx context.Context, dstDirPath string, file model.FileStreamer, lazyCache ...bool) error {
-	err := putDirectly(ctx, dstDirPath, file, lazyCache...)
+	return PutDirectlyWithProgress(ctx, dstDirPath, file, nil, lazyCache...)
+}
+
+// PutDirectlyWithProgress is PutDirectly reporting the progress of the upload to up
+func PutDirectlyWithProgress(ctx context.Context, dstDirPath string, file model.FileStreamer, up driver.UpdateProgress, lazyCache ...bool) error {
+	err := putDirectly(ctx, dstDirPath, file, up, lazyCache...)
 	if err != nil {
 		log.Errorf("failed put %s: %+v", dstDirPath, err)
 	}
@@ -42,6 +42,12 @@ func list(ctx context.Context, path string, args *ListArgs) ([]model.Obj, error)
 		om.InitHideReg(meta.Hide)
 	}
 	objs := om.Merge(_objs, virtualFiles...)
+	if user != nil {
+		// versions are reached through the versions api
+		objs = utils.SliceFilter(objs, func(obj model.Obj) bool {
+			return obj.GetName() != op.VersionsDirName
+		})
+	}
 	return objs, nil
 }
 
@@ -46,6 +46,9 @@ func putAsTask(ctx context.Context, dstDirPath string, file model.FileStreamer)
 	if storage.Config().NoUpload {
 		return nil, errors.WithStack(errs.UploadNotSupported)
 	}
+	if err := op.CheckUploadPolicy(ctx, storage, dstDirActualPath, file); err != nil {
+		return nil, err
+	}
 	if file.NeedStore() {
 		_, err := file.CacheFullInTempFile()
 		if err != nil {
@@ -69,7 +72,7 @@ func putAsTask(ctx context.Context, dstDirPath string, file model.FileStreamer)
 }
 
 // putDirect put the file and return after finish
-func putDirectly(ctx context.Context, dstDirPath string, file model.FileStreamer, lazyCache ...bool) error {
+func putDirectly(ctx context.Context, dstDirPath string, file model.FileStreamer, up driver.UpdateProgress, lazyCache ...bool) error {
 	storage, dstDirActualPath, err := op.GetStorageAndActualPath(dstDirPath)
 	if err != nil {
 		return errors.WithMessage(err, "failed get storage")
@@ -77,5 +80,8 @@ func putDirectly(ctx context.Context, dstDirPath string, file model.FileStreamer
 	if storage.Config().NoUpload {
 		return errors.WithStack(errs.UploadNotSupported)
 	}
-	return op.Put(ctx, storage, dstDirActualPath, file, nil, lazyCache...)
+	if err := op.CheckUploadPolicy(ctx, storage, dstDirActualPath, file); err != nil {
+		return err
+	}
+	return op.Put(ctx, storage, dstDirActualPath, file, up, lazyCache...)
 }
@@ -0,0 +1,31 @@
+package fs
+
+import (
+	"context"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// ListVersions returns the kept versions of a file, newest first
+func ListVersions(ctx context.Context, path string) ([]model.Obj, error) {
+	storage, actualPath, err := op.GetStorageAndActualPath(path)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed get storage")
+	}
+	return op.ListVersions(ctx, storage, actualPath)
+}
+
+func RestoreVersion(ctx context.Context, path, version string) error {
+	storage, actualPath, err := op.GetStorageAndActualPath(path)
+	if err != nil {
+		return errors.WithMessage(err, "failed get storage")
+	}
+	err = op.RestoreVersion(ctx, storage, actualPath, version)
+	if err != nil {
+		log.Errorf("failed restore %s to version %s: %+v", path, version, err)
+	}
+	return err
+}
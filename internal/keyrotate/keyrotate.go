@@ -0,0 +1,139 @@
+package keyrotate
+
+import (
+	"fmt"
+	stdpath "path"
+	"time"
+
+	"github.com/alist-org/alist/v3/drivers/crypt"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/task"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+	"github.com/xhofe/tache"
+)
+
+// RotateTask re-encrypts a crypt storage, or a subtree of it, with a new
+// password and salt. The storage is disabled while it runs, since it can't
+// read the files that are rotated already, and the keys of the storage are
+// switched once its root is rotated. A subtree is rotated to split the work
+// of a big storage, the rotation of the root skips the files done by it.
+type RotateTask struct {
+	task.TaskExtension
+	status string
+
+	StorageID uint   `json:"storage_id"`
+	MountPath string `json:"mount_path"`
+	Path      string `json:"path"`
+	// Password and Salt are obfuscated like the addition of the storage
+	Password string `json:"password"`
+	Salt     string `json:"salt"`
+}
+
+var TaskManager *tache.Manager[*RotateTask]
+
+var _ task.TaskExtensionInfo = (*RotateTask)(nil)
+
+func (t *RotateTask) GetName() string {
+	return fmt.Sprintf("rotate keys of [%s](%s)", t.MountPath, t.Path)
+}
+
+func (t *RotateTask) GetStatus() string {
+	return t.status
+}
+
+func (t *RotateTask) Run() error {
+	t.ReinitCtx()
+	t.ClearEndTime()
+	t.SetStartTime(time.Now())
+	defer func() { t.SetEndTime(time.Now()) }()
+
+	ctx := t.Ctx()
+	storage, err := db.GetStorageById(t.StorageID)
+	if err != nil {
+		return errors.WithMessage(err, "failed get storage")
+	}
+	if !storage.Disabled {
+		t.status = "disabling storage"
+		if err = op.DisableStorage(ctx, storage.ID); err != nil {
+			return err
+		}
+		storage.Disabled = true
+	}
+	rotator, err := crypt.NewRotator(*storage, t.Password, t.Salt)
+	if err != nil {
+		return err
+	}
+	t.status = "counting files"
+	err = rotator.Rotate(ctx, t.Path, func(p crypt.RotateProgress) {
+		t.SetTotalBytes(p.TotalSize)
+		if p.TotalSize > 0 {
+			t.SetProgress(float64(p.DoneSize) / float64(p.TotalSize) * 99)
+		}
+		t.status = fmt.Sprintf("rotated %d of %d files", p.DoneFiles, p.TotalFiles)
+		if p.Skipped > 0 {
+			t.status += fmt.Sprintf(", skipped %d files that can't be decrypted", p.Skipped)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if !utils.PathEqual(t.Path, "/") {
+		t.SetProgress(100)
+		t.status += ", rotate the root to switch the keys of the storage"
+		return nil
+	}
+	t.status = "switching keys"
+	if err = crypt.SetKeys(storage, t.Password, t.Salt); err != nil {
+		return err
+	}
+	if err = op.UpdateStorage(ctx, *storage); err != nil {
+		return errors.WithMessage(err, "failed update storage")
+	}
+	if err = op.EnableStorage(ctx, storage.ID); err != nil {
+		return err
+	}
+	t.SetProgress(100)
+	t.status = "keys switched"
+	return nil
+}
+
+// AddTask queues the rotation of path of a crypt storage.
+func AddTask(creator *model.User, storage *model.Storage, path, password, salt string) (*RotateTask, error) {
+	if storage.Driver != "Crypt" {
+		return nil, errors.Errorf("storage %s is not a crypt storage", storage.MountPath)
+	}
+	if password == "" {
+		return nil, errors.New("password is required")
+	}
+	for _, t := range TaskManager.GetAll() {
+		if t.StorageID != storage.ID {
+			continue
+		}
+		switch t.GetState() {
+		case tache.StateSucceeded, tache.StateCanceled, tache.StateFailed:
+		default:
+			return nil, errors.Errorf("keys of %s are being rotated", storage.MountPath)
+		}
+	}
+	var err error
+	if password, err = crypt.ObfuscateKey(password); err != nil {
+		return nil, err
+	}
+	if salt, err = crypt.ObfuscateKey(salt); err != nil {
+		return nil, err
+	}
+	t := &RotateTask{
+		TaskExtension: task.TaskExtension{Creator: creator},
+		status:        "queued",
+		StorageID:     storage.ID,
+		MountPath:     storage.MountPath,
+		Path:          stdpath.Join("/", path),
+		Password:      password,
+		Salt:          salt,
+	}
+	TaskManager.Add(t)
+	return t, nil
+}
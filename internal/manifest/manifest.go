@@ -0,0 +1,104 @@
+// Package manifest applies a declarative description of storages, metas,
+// roles, users and settings to the database.
+package manifest
+
+import (
+	"os"
+	"regexp"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// Manifest describes the desired state of a site. Entries of each section are
+// matched with existing records by their key (mount_path, path, name and
+// username) and only the fields present in an entry are changed.
+type Manifest struct {
+	// Prune deletes records missing from a section, sections
+	// absent from the manifest are left untouched
+	Prune    bool              `json:"prune"`
+	Roles    []Entry           `json:"roles"`
+	Users    []Entry           `json:"users"`
+	Storages []Entry           `json:"storages"`
+	Metas    []Entry           `json:"metas"`
+	Settings map[string]string `json:"settings"`
+}
+
+type Entry map[string]any
+
+var envReg = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces ${NAME} in string values with the value of the
+// environment variable NAME, so that secrets don't have to be written into
+// the manifest. It runs after parsing, a value can't change the structure.
+func expandEnv(s string) (string, error) {
+	var err error
+	s = envReg.ReplaceAllStringFunc(s, func(m string) string {
+		name := envReg.FindStringSubmatch(m)[1]
+		value, ok := os.LookupEnv(name)
+		if !ok && err == nil {
+			err = errors.Errorf("environment variable %s is not set", name)
+		}
+		return value
+	})
+	return s, err
+}
+
+func expandEnvValue(v any) (any, error) {
+	var err error
+	switch v := v.(type) {
+	case string:
+		return expandEnv(v)
+	case map[string]any:
+		for k, item := range v {
+			if v[k], err = expandEnvValue(item); err != nil {
+				return nil, err
+			}
+		}
+	case []any:
+		for i, item := range v {
+			if v[i], err = expandEnvValue(item); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v, nil
+}
+
+func (m *Manifest) expandEnv() error {
+	for _, section := range [][]Entry{m.Roles, m.Users, m.Storages, m.Metas} {
+		for _, e := range section {
+			if _, err := expandEnvValue(map[string]any(e)); err != nil {
+				return err
+			}
+		}
+	}
+	for k, v := range m.Settings {
+		value, err := expandEnv(v)
+		if err != nil {
+			return err
+		}
+		m.Settings[k] = value
+	}
+	return nil
+}
+
+// Parse reads a manifest written in YAML or JSON
+func Parse(data []byte) (*Manifest, error) {
+	var m Manifest
+	if err := yaml.UnmarshalStrict(data, &m); err != nil {
+		return nil, errors.WithMessage(err, "failed to parse manifest")
+	}
+	if err := m.expandEnv(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return Parse(data)
+}
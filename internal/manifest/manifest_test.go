@@ -0,0 +1,138 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+
+	_ "github.com/alist-org/alist/v3/drivers/local"
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/testutil"
+)
+
+func init() {
+	testutil.InitDB()
+}
+
+func apply(t *testing.T, text string) Plan {
+	m, err := Parse([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := Diff(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = plan.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	return plan
+}
+
+func TestApply(t *testing.T) {
+	t.Setenv("LOCAL_ROOT", t.TempDir())
+	if err := op.SaveSettingItem(&model.SettingItem{Key: conf.SiteTitle, Value: "AList"}); err != nil {
+		t.Fatal(err)
+	}
+	text := `
+storages:
+  - mount_path: /data/
+    driver: Local
+    addition:
+      root_folder_path: ${LOCAL_ROOT}
+metas:
+  - path: /data
+    write: true
+settings:
+  site_title: My Site
+`
+	plan := apply(t, text)
+	if got := plan.String(); got != "+ storage /data\n+ meta /data\n~ setting site_title" {
+		t.Fatalf("unexpected plan:\n%s", got)
+	}
+	storage, err := db.GetStorageByMountPath("/data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(storage.Addition, "root_folder_path") {
+		t.Errorf("unexpected addition %s", storage.Addition)
+	}
+
+	// applying the same manifest again changes nothing
+	if plan = apply(t, text); len(plan) != 0 {
+		t.Fatalf("expected empty plan, got:\n%s", plan)
+	}
+
+	plan = apply(t, `
+prune: true
+storages:
+  - mount_path: /data
+    remark: shared
+metas: []
+`)
+	if got := plan.String(); got != "~ storage /data\n- meta /data" {
+		t.Fatalf("unexpected plan:\n%s", got)
+	}
+	storage, err = db.GetStorageByMountPath("/data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if storage.Remark != "shared" || storage.Driver != "Local" {
+		t.Errorf("unexpected storage %+v", storage)
+	}
+
+	t.Setenv("BOB_PASSWORD", "secret")
+	text = `
+roles:
+  - name: editors
+    permission_scopes:
+      - path: /data
+        permission: 8
+users:
+  - username: bob
+    password: ${BOB_PASSWORD}
+    roles: [editors]
+`
+	if plan = apply(t, text); plan.String() != "+ role editors\n+ user bob" {
+		t.Fatalf("unexpected plan:\n%s", plan)
+	}
+	if plan = apply(t, text); len(plan) != 0 {
+		t.Fatalf("expected empty plan, got:\n%s", plan)
+	}
+	user, err := op.GetUserByName("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	role, _ := op.GetRoleByName("editors")
+	if user.ValidateRawPassword("secret") != nil || !user.Role.Contains(int(role.ID)) {
+		t.Errorf("unexpected user %+v", user)
+	}
+
+	// pruned storages are deleted by op, after the users are updated
+	apply(t, "storages:\n  - mount_path: /old\n    driver: Local\n    addition:\n      root_folder_path: ${LOCAL_ROOT}\n")
+	if plan = apply(t, "prune: true\nstorages:\n  - mount_path: /data\n"); plan.String() != "- storage /old" {
+		t.Fatalf("unexpected plan:\n%s", plan)
+	}
+	if _, err := db.GetStorageByMountPath("/old"); err == nil {
+		t.Error("expected /old to be deleted")
+	}
+
+	// values are substituted after parsing, they can't change the manifest
+	secret := "p#ss: \"x\"\nprune: true"
+	t.Setenv("MANIFEST_SECRET", secret)
+	m, err := Parse([]byte("users:\n  - username: a\n    password: ${MANIFEST_SECRET}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Prune || m.Users[0]["password"] != secret {
+		t.Errorf("unexpected manifest %+v", m)
+	}
+	if _, err := Parse([]byte("users:\n  - username: a\n    password: ${MANIFEST_UNSET}\n")); err == nil {
+		t.Error("expected unset environment variable to fail")
+	}
+	if _, err := Parse([]byte("storage: []\n")); err == nil {
+		t.Error("expected unknown section to fail")
+	}
+}
@@ -0,0 +1,482 @@
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+type Action string
+
+const (
+	Create Action = "create"
+	Update Action = "update"
+	Delete Action = "delete"
+)
+
+type Change struct {
+	Kind   string
+	Action Action
+	Key    string
+	apply  func() error
+}
+
+func (c Change) String() string {
+	sign := map[Action]string{Create: "+", Update: "~", Delete: "-"}[c.Action]
+	return fmt.Sprintf("%s %s %s", sign, c.Kind, c.Key)
+}
+
+// Plan is the list of changes needed to bring the database to the manifest,
+// in the order they are applied
+type Plan []Change
+
+func (p Plan) Apply() error {
+	for _, c := range p {
+		if err := c.apply(); err != nil {
+			return errors.WithMessagef(err, "failed to %s %s %s", c.Action, c.Kind, c.Key)
+		}
+	}
+	return nil
+}
+
+// Diff compares the manifest with the database and returns the plan to apply it
+func Diff(m *Manifest) (Plan, error) {
+	var plan Plan
+	steps := []func(*Manifest) (Plan, error){diffRoles, diffStorages, diffMetas, diffSettings, diffUsers, pruneStorages, pruneRoles}
+	for _, step := range steps {
+		p, err := step(m)
+		if err != nil {
+			return nil, err
+		}
+		plan = append(plan, p...)
+	}
+	return plan, nil
+}
+
+// entryKey returns the normalized key field of an entry and removes the
+// fields that are managed by alist itself
+func entryKey(kind, field string, e Entry, isPath bool) (string, error) {
+	key, _ := e[field].(string)
+	if key == "" {
+		return "", errors.Errorf("%s without %s", kind, field)
+	}
+	delete(e, "id")
+	if isPath {
+		key = utils.FixAndCleanPath(key)
+		e[field] = key
+	}
+	return key, nil
+}
+
+// overlay decodes the entry into a copy of base, fields missing in the entry keep the value of base
+func overlay[T any](base T, e Entry) (T, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return base, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(&base); err != nil {
+		return base, err
+	}
+	return base, nil
+}
+
+func changed(a, b any) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return !bytes.Equal(x, y)
+}
+
+func checkDuplicate(seen map[string]struct{}, kind, key string) error {
+	if _, ok := seen[key]; ok {
+		return errors.Errorf("duplicate %s %s", kind, key)
+	}
+	seen[key] = struct{}{}
+	return nil
+}
+
+func diffRoles(m *Manifest) (Plan, error) {
+	var plan Plan
+	seen := map[string]struct{}{}
+	for _, e := range m.Roles {
+		name, err := entryKey("role", "name", e, false)
+		if err != nil {
+			return nil, err
+		}
+		if err = checkDuplicate(seen, "role", name); err != nil {
+			return nil, err
+		}
+		old, err := op.GetRoleByName(name)
+		if err != nil {
+			role, err := overlay(model.Role{}, e)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "invalid role %s", name)
+			}
+			plan = append(plan, Change{Kind: "role", Action: Create, Key: name, apply: func() error {
+				return op.CreateRole(&role)
+			}})
+			continue
+		}
+		base := *old
+		base.PermissionScopes = slices.Clone(old.PermissionScopes)
+		role, err := overlay(base, e)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid role %s", name)
+		}
+		if changed(*old, role) {
+			plan = append(plan, Change{Kind: "role", Action: Update, Key: name, apply: func() error {
+				return op.UpdateRole(&role)
+			}})
+		}
+	}
+	return plan, nil
+}
+
+func pruneRoles(m *Manifest) (Plan, error) {
+	if !m.Prune || m.Roles == nil {
+		return nil, nil
+	}
+	roles, _, err := op.GetRoles(1, -1)
+	if err != nil {
+		return nil, err
+	}
+	var plan Plan
+	for _, role := range roles {
+		if role.Name == "admin" || role.Name == "guest" || hasEntry(m.Roles, "name", role.Name) {
+			continue
+		}
+		id := role.ID
+		plan = append(plan, Change{Kind: "role", Action: Delete, Key: role.Name, apply: func() error {
+			return op.DeleteRole(id)
+		}})
+	}
+	return plan, nil
+}
+
+func hasEntry(entries []Entry, field, key string) bool {
+	for _, e := range entries {
+		if e[field] == key {
+			return true
+		}
+	}
+	return false
+}
+
+func diffStorages(m *Manifest) (Plan, error) {
+	var plan Plan
+	seen := map[string]struct{}{}
+	for _, e := range m.Storages {
+		mountPath, err := entryKey("storage", "mount_path", e, true)
+		if err != nil {
+			return nil, err
+		}
+		if err = checkDuplicate(seen, "storage", mountPath); err != nil {
+			return nil, err
+		}
+		delete(e, "status")
+		delete(e, "modified")
+		old, err := db.GetStorageByMountPath(mountPath)
+		if err != nil {
+			old = nil
+		}
+		base := model.Storage{}
+		if old != nil {
+			base = *old
+		}
+		if err = mergeAddition(e, base.Addition); err != nil {
+			return nil, errors.WithMessagef(err, "invalid addition of storage %s", mountPath)
+		}
+		storage, err := overlay(base, e)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid storage %s", mountPath)
+		}
+		if _, err = op.GetDriver(storage.Driver); err != nil {
+			return nil, errors.WithMessagef(err, "invalid storage %s", mountPath)
+		}
+		if old == nil {
+			plan = append(plan, Change{Kind: "storage", Action: Create, Key: mountPath, apply: func() error {
+				storage.Modified = time.Now()
+				return db.CreateStorage(&storage)
+			}})
+		} else if changed(*old, storage) {
+			plan = append(plan, Change{Kind: "storage", Action: Update, Key: mountPath, apply: func() error {
+				storage.Modified = time.Now()
+				return db.UpdateStorage(&storage)
+			}})
+		}
+	}
+	return plan, nil
+}
+
+// pruneStorages runs after the users are updated, a storage still used by a
+// base path can't be deleted
+func pruneStorages(m *Manifest) (Plan, error) {
+	if !m.Prune || m.Storages == nil {
+		return nil, nil
+	}
+	seen := map[string]struct{}{}
+	for _, e := range m.Storages {
+		mountPath, _ := e["mount_path"].(string)
+		seen[utils.FixAndCleanPath(mountPath)] = struct{}{}
+	}
+	storages, _, err := db.GetStorages(1, -1)
+	if err != nil {
+		return nil, err
+	}
+	var plan Plan
+	for _, storage := range storages {
+		if _, ok := seen[storage.MountPath]; ok {
+			continue
+		}
+		id := storage.ID
+		plan = append(plan, Change{Kind: "storage", Action: Delete, Key: storage.MountPath, apply: func() error {
+			return op.DeleteStorageById(context.Background(), id)
+		}})
+	}
+	return plan, nil
+}
+
+// mergeAddition lets the addition of a storage be written as an object,
+// whose fields are merged into the existing addition
+func mergeAddition(e Entry, old string) error {
+	patch, ok := e["addition"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	addition := map[string]any{}
+	if old != "" {
+		if err := json.Unmarshal([]byte(old), &addition); err != nil {
+			return err
+		}
+	}
+	before, _ := json.Marshal(addition)
+	for k, v := range patch {
+		addition[k] = v
+	}
+	data, err := json.Marshal(addition)
+	if err != nil {
+		return err
+	}
+	// keep the stored text if nothing changed, its field order may differ
+	if bytes.Equal(before, data) && old != "" {
+		e["addition"] = old
+	} else {
+		e["addition"] = string(data)
+	}
+	return nil
+}
+
+func diffMetas(m *Manifest) (Plan, error) {
+	var plan Plan
+	seen := map[string]struct{}{}
+	for _, e := range m.Metas {
+		path, err := entryKey("meta", "path", e, true)
+		if err != nil {
+			return nil, err
+		}
+		if err = checkDuplicate(seen, "meta", path); err != nil {
+			return nil, err
+		}
+		old, err := op.GetMetaByPath(path)
+		if err != nil {
+			meta, err := overlay(model.Meta{}, e)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "invalid meta %s", path)
+			}
+			plan = append(plan, Change{Kind: "meta", Action: Create, Key: path, apply: func() error {
+				return op.CreateMeta(&meta)
+			}})
+			continue
+		}
+		meta, err := overlay(*old, e)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid meta %s", path)
+		}
+		if changed(*old, meta) {
+			plan = append(plan, Change{Kind: "meta", Action: Update, Key: path, apply: func() error {
+				return op.UpdateMeta(&meta)
+			}})
+		}
+	}
+	if !m.Prune || m.Metas == nil {
+		return plan, nil
+	}
+	metas, _, err := op.GetMetas(1, -1)
+	if err != nil {
+		return nil, err
+	}
+	for _, meta := range metas {
+		if _, ok := seen[meta.Path]; ok {
+			continue
+		}
+		id := meta.ID
+		plan = append(plan, Change{Kind: "meta", Action: Delete, Key: meta.Path, apply: func() error {
+			return op.DeleteMetaById(id)
+		}})
+	}
+	return plan, nil
+}
+
+func diffSettings(m *Manifest) (Plan, error) {
+	keys := make([]string, 0, len(m.Settings))
+	for key := range m.Settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var plan Plan
+	for _, key := range keys {
+		item, err := op.GetSettingItemByKey(key)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "unknown setting %s", key)
+		}
+		if item.Value == m.Settings[key] {
+			continue
+		}
+		item.Value = m.Settings[key]
+		plan = append(plan, Change{Kind: "setting", Action: Update, Key: key, apply: func() error {
+			return op.SaveSettingItem(item)
+		}})
+	}
+	return plan, nil
+}
+
+func diffUsers(m *Manifest) (Plan, error) {
+	var plan Plan
+	seen := map[string]struct{}{}
+	for _, e := range m.Users {
+		username, err := entryKey("user", "username", e, false)
+		if err != nil {
+			return nil, err
+		}
+		if err = checkDuplicate(seen, "user", username); err != nil {
+			return nil, err
+		}
+		// roles may be given by name, they are resolved when applied
+		// since they might be created by the same plan
+		roleNames, err := popStrings(e, "roles")
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid roles of user %s", username)
+		}
+		password, _ := e["password"].(string)
+		delete(e, "password")
+		old, err := op.GetUserByName(username)
+		if err != nil {
+			old = nil
+		}
+		base := model.User{}
+		if old != nil {
+			base = *old
+			base.Role = slices.Clone(old.Role)
+		}
+		user, err := overlay(base, e)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid user %s", username)
+		}
+		user.Password = ""
+		apply := func(save func(*model.User) error) func() error {
+			return func() error {
+				if roleNames != nil {
+					ids, err := resolveRoles(roleNames)
+					if err != nil {
+						return err
+					}
+					user.Role = ids
+				}
+				if password != "" {
+					user.SetPassword(password)
+				}
+				return save(&user)
+			}
+		}
+		if old == nil {
+			if len(user.Role) == 0 && roleNames == nil {
+				user.Role = model.Roles{op.GetDefaultRoleID()}
+			}
+			if user.IsAdmin() || user.IsGuest() {
+				return nil, errors.Errorf("admin or guest user %s can not be created", username)
+			}
+			user.Authn = "[]"
+			plan = append(plan, Change{Kind: "user", Action: Create, Key: username, apply: apply(op.CreateUser)})
+			continue
+		}
+		modified := changed(*old, user) || (password != "" && old.ValidateRawPassword(password) != nil)
+		if roleNames != nil {
+			ids, err := resolveRoles(roleNames)
+			modified = modified || err != nil || changed(old.Role, ids)
+		}
+		if modified {
+			plan = append(plan, Change{Kind: "user", Action: Update, Key: username, apply: apply(op.UpdateUser)})
+		}
+	}
+	if !m.Prune || m.Users == nil {
+		return plan, nil
+	}
+	users, _, err := op.GetUsers(1, -1)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		if _, ok := seen[user.Username]; ok || user.IsAdmin() || user.IsGuest() {
+			continue
+		}
+		id := user.ID
+		plan = append(plan, Change{Kind: "user", Action: Delete, Key: user.Username, apply: func() error {
+			return op.DeleteUserById(id)
+		}})
+	}
+	return plan, nil
+}
+
+func popStrings(e Entry, field string) ([]string, error) {
+	v, ok := e[field]
+	if !ok {
+		return nil, nil
+	}
+	delete(e, field)
+	items, ok := v.([]any)
+	if !ok {
+		return nil, errors.Errorf("%s should be a list", field)
+	}
+	res := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, errors.Errorf("%s should be a list of strings", field)
+		}
+		res = append(res, s)
+	}
+	return res, nil
+}
+
+func resolveRoles(names []string) (model.Roles, error) {
+	ids := make(model.Roles, 0, len(names))
+	for _, name := range names {
+		role, err := op.GetRoleByName(name)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "unknown role %s", name)
+		}
+		ids = append(ids, int(role.ID))
+	}
+	return ids, nil
+}
+
+// String renders the plan one change per line
+func (p Plan) String() string {
+	lines := make([]string, len(p))
+	for i, c := range p {
+		lines[i] = c.String()
+	}
+	return strings.Join(lines, "\n")
+}
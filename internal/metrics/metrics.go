@@ -0,0 +1,168 @@
+// Package metrics collects the Prometheus metrics of the drivers, the
+// task managers and the protocol servers, see Handler for the exposition.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "alist"
+
+// Protocols served to the clients
+const (
+	HTTP   = "http"
+	WebDAV = "webdav"
+	S3     = "s3"
+	FTP    = "ftp"
+	SFTP   = "sftp"
+	NFS    = "nfs"
+)
+
+var registry = prometheus.NewRegistry()
+
+var (
+	driverCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "driver_calls_total",
+		Help:      "Number of driver calls by storage, method and result.",
+	}, []string{"storage", "driver", "method", "result"})
+	driverDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "driver_call_duration_seconds",
+		Help:      "Latency of the driver calls by storage and method.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"storage", "driver", "method"})
+	driverErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "driver_errors_total",
+		Help:      "Number of failed driver calls by driver, method and error type.",
+	}, []string{"driver", "method", "type"})
+	listCache = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "list_cache_requests_total",
+		Help:      "Number of directory listings answered from the cache or not.",
+	}, []string{"result"})
+	servedBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "served_bytes_total",
+		Help:      "Number of bytes sent to the clients by protocol.",
+	}, []string{"protocol"})
+	sessions = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_sessions",
+		Help:      "Number of active client sessions by protocol.",
+	}, []string{"protocol"})
+	limiterWait = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limiter_wait_seconds_total",
+		Help:      "Time spent waiting on the stream rate limiters.",
+	}, []string{"limiter"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		driverCalls, driverDuration, driverErrors, listCache,
+		servedBytes, sessions, limiterWait, tasks,
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// errTypes are the errs reported by type, any other error is "other"
+var errTypes = []struct {
+	err  error
+	name string
+}{
+	{errs.ObjectNotFound, "object_not_found"},
+	{errs.NotFolder, "not_folder"},
+	{errs.NotFile, "not_file"},
+	{errs.NotImplement, "not_implement"},
+	{errs.NotSupport, "not_support"},
+	{errs.PermissionDenied, "permission_denied"},
+	{errs.EmptyToken, "empty_token"},
+	{errs.StreamIncomplete, "stream_incomplete"},
+	{errs.StorageNotFound, "storage_not_found"},
+	{context.Canceled, "canceled"},
+	{context.DeadlineExceeded, "deadline_exceeded"},
+}
+
+func errType(err error) string {
+	for _, t := range errTypes {
+		if errors.Is(err, t.err) {
+			return t.name
+		}
+	}
+	return "other"
+}
+
+// DriverCall starts timing a call of method on the storage mounted at
+// mountPath, the returned func records its result
+func DriverCall(mountPath, driver, method string) func(err error) {
+	start := time.Now()
+	return func(err error) {
+		driverDuration.WithLabelValues(mountPath, driver, method).Observe(time.Since(start).Seconds())
+		result := "success"
+		if err != nil {
+			result = "error"
+			driverErrors.WithLabelValues(driver, method, errType(err)).Inc()
+		}
+		driverCalls.WithLabelValues(mountPath, driver, method, result).Inc()
+	}
+}
+
+// ListCache records whether a listing was found in the cache
+func ListCache(hit bool) {
+	if hit {
+		listCache.WithLabelValues("hit").Inc()
+	} else {
+		listCache.WithLabelValues("miss").Inc()
+	}
+}
+
+// ServedBytes records n bytes sent to a client over protocol
+func ServedBytes(protocol string, n int) {
+	if n > 0 {
+		servedBytes.WithLabelValues(protocol).Add(float64(n))
+	}
+}
+
+// SessionStarted records a new session of protocol
+func SessionStarted(protocol string) {
+	sessions.WithLabelValues(protocol).Inc()
+}
+
+// SessionEnded records the end of a session of protocol
+func SessionEnded(protocol string) {
+	sessions.WithLabelValues(protocol).Dec()
+}
+
+// LimiterWait records the time a transfer waited on the named limiter
+func LimiterWait(limiter string, d time.Duration) {
+	limiterWait.WithLabelValues(limiter).Add(d.Seconds())
+}
+
+type protocolKey struct{}
+
+// WithProtocol marks ctx as serving the clients of protocol
+func WithProtocol(ctx context.Context, protocol string) context.Context {
+	return context.WithValue(ctx, protocolKey{}, protocol)
+}
+
+// Protocol returns the protocol set by WithProtocol, empty if none
+func Protocol(ctx context.Context) string {
+	p, _ := ctx.Value(protocolKey{}).(string)
+	return p
+}
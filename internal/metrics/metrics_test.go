@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/errs"
+	pkgerrors "github.com/pkg/errors"
+	"github.com/xhofe/tache"
+)
+
+type testTask struct {
+	tache.Base
+	err error
+}
+
+func (t *testTask) Run() error {
+	return t.err
+}
+
+// value returns the value of the metric name with labels, 0 if not found
+func value(t *testing.T, name string, labels map[string]string) float64 {
+	t.Helper()
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, m := range f.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if v, ok := labels[l.GetName()]; ok && v != l.GetValue() {
+					continue metrics
+				}
+			}
+			switch {
+			case m.Counter != nil:
+				return m.GetCounter().GetValue()
+			case m.Gauge != nil:
+				return m.GetGauge().GetValue()
+			case m.Histogram != nil:
+				return float64(m.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+	return 0
+}
+
+func TestDriverCall(t *testing.T) {
+	calls := map[string]string{"storage": "/local", "method": "Link", "result": "error"}
+	observations := map[string]string{"storage": "/local", "method": "List"}
+	notFound := map[string]string{"method": "Link", "type": "object_not_found"}
+	other := map[string]string{"method": "Link", "type": "other"}
+	before := []float64{value(t, "alist_driver_calls_total", calls), value(t, "alist_driver_call_duration_seconds", observations),
+		value(t, "alist_driver_errors_total", notFound), value(t, "alist_driver_errors_total", other)}
+	DriverCall("/local", "Local", "List")(nil)
+	DriverCall("/local", "Local", "Link")(pkgerrors.Wrap(errs.ObjectNotFound, "failed get link"))
+	DriverCall("/local", "Local", "Link")(errors.New("boom"))
+	if v := value(t, "alist_driver_calls_total", calls) - before[0]; v != 2 {
+		t.Fatalf("expected 2 failed Link calls, got %v", v)
+	}
+	if v := value(t, "alist_driver_call_duration_seconds", observations) - before[1]; v != 1 {
+		t.Fatalf("expected 1 List observation, got %v", v)
+	}
+	if v := value(t, "alist_driver_errors_total", notFound) - before[2]; v != 1 {
+		t.Fatalf("expected 1 object_not_found error, got %v", v)
+	}
+	if v := value(t, "alist_driver_errors_total", other) - before[3]; v != 1 {
+		t.Fatalf("expected 1 other error, got %v", v)
+	}
+}
+
+func TestTaskManager(t *testing.T) {
+	labels := func(state string) map[string]string {
+		return map[string]string{"manager": "test", "state": state}
+	}
+	succeeded := value(t, "alist_tasks_finished_total", labels("succeeded"))
+	failed := value(t, "alist_tasks_finished_total", labels("failed"))
+	m := tache.NewManager[*testTask](tache.WithWorks(2))
+	RegisterTaskManager("test", m)
+	m.Add(&testTask{})
+	m.Add(&testTask{})
+	m.Add(&testTask{err: errors.New("boom")})
+	m.Wait()
+	if v := value(t, "alist_tasks", labels("succeeded")); v != 2 {
+		t.Fatalf("expected 2 succeeded tasks, got %v", v)
+	}
+	if v := value(t, "alist_tasks_finished_total", labels("failed")) - failed; v != 1 {
+		t.Fatalf("expected 1 failed task, got %v", v)
+	}
+	// finished tasks are counted once, even when removed afterwards
+	m.RemoveByState(tache.StateSucceeded)
+	m.Add(&testTask{})
+	m.Wait()
+	if v := value(t, "alist_tasks_finished_total", labels("succeeded")) - succeeded; v != 3 {
+		t.Fatalf("expected 3 succeeded tasks in total, got %v", v)
+	}
+	if v := value(t, "alist_tasks", labels("succeeded")); v != 1 {
+		t.Fatalf("expected 1 succeeded task left, got %v", v)
+	}
+}
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/xhofe/tache"
+)
+
+var stateNames = map[tache.State]string{
+	tache.StatePending:      "pending",
+	tache.StateRunning:      "running",
+	tache.StateSucceeded:    "succeeded",
+	tache.StateCanceling:    "canceling",
+	tache.StateCanceled:     "canceled",
+	tache.StateErrored:      "errored",
+	tache.StateFailing:      "failing",
+	tache.StateFailed:       "failed",
+	tache.StateWaitingRetry: "waiting_retry",
+	tache.StateBeforeRetry:  "before_retry",
+}
+
+func finished(state tache.State) bool {
+	return state == tache.StateSucceeded || state == tache.StateCanceled || state == tache.StateFailed
+}
+
+type taskManager struct {
+	name   string
+	states func() map[string]tache.State
+	// seen are the finished tasks already counted
+	seen  map[string]bool
+	total map[tache.State]float64
+}
+
+// taskCollector reads the task managers at scrape time, the tasks that
+// finish and are removed between two scrapes are not counted
+type taskCollector struct {
+	mu       sync.Mutex
+	managers []*taskManager
+	count    *prometheus.Desc
+	finished *prometheus.Desc
+}
+
+var tasks = &taskCollector{
+	count: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "tasks"),
+		"Number of tasks by manager and state.", []string{"manager", "state"}, nil),
+	finished: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "tasks_finished_total"),
+		"Number of finished tasks by manager and final state.", []string{"manager", "state"}, nil),
+}
+
+// RegisterTaskManager exposes the tasks of m under name, registering a
+// name again replaces the previous manager
+func RegisterTaskManager[T tache.Task](name string, m *tache.Manager[T]) {
+	states := func() map[string]tache.State {
+		all := m.GetAll()
+		res := make(map[string]tache.State, len(all))
+		for _, t := range all {
+			res[t.GetID()] = t.GetState()
+		}
+		return res
+	}
+	tasks.mu.Lock()
+	defer tasks.mu.Unlock()
+	for _, tm := range tasks.managers {
+		if tm.name == name {
+			tm.states = states
+			return
+		}
+	}
+	tasks.managers = append(tasks.managers, &taskManager{
+		name:   name,
+		states: states,
+		seen:   map[string]bool{},
+		total:  map[tache.State]float64{},
+	})
+}
+
+func (c *taskCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.count
+	ch <- c.finished
+}
+
+func (c *taskCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, tm := range c.managers {
+		counts := make(map[tache.State]int, len(stateNames))
+		seen := make(map[string]bool)
+		for id, state := range tm.states() {
+			counts[state]++
+			if finished(state) {
+				seen[id] = true
+				if !tm.seen[id] {
+					tm.total[state]++
+				}
+			}
+		}
+		// forget the removed and retried tasks
+		tm.seen = seen
+		for state, name := range stateNames {
+			ch <- prometheus.MustNewConstMetric(c.count, prometheus.GaugeValue, float64(counts[state]), tm.name, name)
+			if finished(state) {
+				ch <- prometheus.MustNewConstMetric(c.finished, prometheus.CounterValue, tm.total[state], tm.name, name)
+			}
+		}
+	}
+}
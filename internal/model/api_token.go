@@ -0,0 +1,111 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/alist-org/alist/v3/pkg/utils"
+)
+
+// APITokenPrefix marks a personal access token so that it can be told apart
+// from JWTs and the admin token without a database lookup.
+const APITokenPrefix = "alist_pat_"
+
+// apiTokenWritePermissions are the permission bits dropped from read-only tokens.
+const apiTokenWritePermissions int32 = 1<<2 | 1<<3 | 1<<4 | 1<<5 | 1<<6 | 1<<7 | 1<<9 | 1<<11 | 1<<13 | 1<<16
+
+// apiTokenPathLimitPermission is always inherited from the user, a token can
+// not lift the path limit of its owner.
+const apiTokenPathLimitPermission int32 = 1 << 14
+
+// APIToken is a named personal access token of a user. Only the hash of the
+// token is stored, the plain token is shown once on creation.
+type APIToken struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	UserID       uint       `json:"user_id" gorm:"index;not null"`
+	Name         string     `json:"name" gorm:"size:255;not null"`
+	TokenHash    string     `json:"-" gorm:"uniqueIndex;size:64;not null"`
+	Hint         string     `json:"hint" gorm:"size:32"`                   // leading characters of the token, used to recognize it
+	AccessKey    string     `json:"access_key" gorm:"uniqueIndex;size:32"` // S3 access key id bound to the token
+	Permission   int32      `json:"permission"`                            // subset of the user's permission bits
+	Paths        PathList   `json:"paths" gorm:"type:text"`                // allowed path prefixes, empty means unrestricted
+	ReadOnly     bool       `json:"read_only"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	LastUsedTime *time.Time `json:"last_used_time"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func (t *APIToken) IsExpired(now time.Time) bool {
+	return t.ExpiresAt != nil && !t.ExpiresAt.After(now)
+}
+
+// AllowPath reports whether reqPath lies under one of the token's path prefixes.
+func (t *APIToken) AllowPath(reqPath string) bool {
+	if len(t.Paths) == 0 {
+		return true
+	}
+	for _, p := range t.Paths {
+		if utils.IsSubPath(p, reqPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanReach reports whether reqPath is allowed or is a parent directory of an
+// allowed path, so that clients are able to navigate to it.
+func (t *APIToken) CanReach(reqPath string) bool {
+	if t.AllowPath(reqPath) {
+		return true
+	}
+	for _, p := range t.Paths {
+		if utils.IsSubPath(reqPath, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Mask narrows the permission bits of the owner down to what the token grants.
+func (t *APIToken) Mask(perm int32) int32 {
+	res := perm&t.Permission | perm&apiTokenPathLimitPermission
+	if t.ReadOnly {
+		res &^= apiTokenWritePermissions
+	}
+	return res
+}
+
+// Restrict is like Mask but also drops every permission outside the token's paths.
+func (t *APIToken) Restrict(perm int32, reqPath string) int32 {
+	if !t.AllowPath(reqPath) {
+		return 0
+	}
+	return t.Mask(perm)
+}
+
+type PathList []string
+
+func (p PathList) Value() (driver.Value, error) {
+	bs, err := json.Marshal([]string(p))
+	return string(bs), err
+}
+
+func (p *PathList) Scan(value interface{}) error {
+	var bs []byte
+	switch v := value.(type) {
+	case []byte:
+		bs = v
+	case string:
+		bs = []byte(v)
+	case nil:
+	default:
+		return fmt.Errorf("cannot scan %T", value)
+	}
+	if len(bs) == 0 {
+		*p = nil
+		return nil
+	}
+	return json.Unmarshal(bs, (*[]string)(p))
+}
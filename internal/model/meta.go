@@ -13,4 +13,16 @@ type Meta struct {
 	RSub      bool   `json:"r_sub"`
 	Header    string `json:"header"`
 	HeaderSub bool   `json:"header_sub"`
+	// upload policy, Allow and Deny are lists of extensions or mime types
+	UploadMaxSize   int64  `json:"upload_max_size"`
+	UploadAllow     string `json:"upload_allow"`
+	UploadDeny      string `json:"upload_deny"`
+	UploadNameRegex string `json:"upload_name_regex"`
+	UploadRename    bool   `json:"upload_rename"`
+	USub            bool   `json:"u_sub"`
+	// Versioning keeps previous contents of overwritten files
+	Versioning   bool `json:"versioning"`
+	VSub         bool `json:"v_sub"`
+	VersionsKeep int  `json:"versions_keep"`
+	VersionsDays int  `json:"versions_days"`
 }
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// MusicTag caches the tags read from an audio file, keyed by its path.
+// The tags are read again when the size or the modified time change.
+type MusicTag struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Dir         string    `json:"dir" gorm:"uniqueIndex:idx_music_tag_path;size:512;not null"`
+	Name        string    `json:"name" gorm:"uniqueIndex:idx_music_tag_path;size:255;not null"`
+	Size        int64     `json:"size"`
+	Modified    time.Time `json:"modified"`
+	Title       string    `json:"title"`
+	Artist      string    `json:"artist"`
+	Album       string    `json:"album"`
+	AlbumArtist string    `json:"album_artist"`
+	Genre       string    `json:"genre"`
+	Year        int       `json:"year"`
+	Track       int       `json:"track"`
+	Disc        int       `json:"disc"`
+	HasPicture  bool      `json:"has_picture"`
+}
+
+// Valid reports whether the tags still describe a file of size modified at modified.
+func (t *MusicTag) Valid(size int64, modified time.Time) bool {
+	return t.Size == size && t.Modified.Unix() == modified.Unix()
+}
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// RSSSubscription polls a RSS or Atom feed and adds the links of new items
+// matching its rules as offline downloads of the user.
+type RSSSubscription struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	UserID       uint       `json:"user_id" gorm:"index;not null"`
+	Name         string     `json:"name" gorm:"size:255"`
+	URL          string     `json:"url" gorm:"not null"`
+	Interval     int        `json:"interval"`             // minutes between two polls
+	Include      string     `json:"include"`              // regexp on the item title, empty matches all
+	Exclude      string     `json:"exclude"`              // regexp on the item title, empty excludes none
+	Path         string     `json:"path" gorm:"not null"` // relative to the user's base path
+	Tool         string     `json:"tool" gorm:"size:64"`  // offline download tool
+	DeletePolicy string     `json:"delete_policy" gorm:"size:64"`
+	Disabled     bool       `json:"disabled"`
+	LastCheck    *time.Time `json:"last_check"`
+	LastError    string     `json:"last_error"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// RSSItem is a feed item which was added as offline download, it keeps the
+// item from being added again.
+type RSSItem struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	SubscriptionID uint      `json:"subscription_id" gorm:"uniqueIndex:idx_rss_item_guid;not null"`
+	GUID           string    `json:"guid" gorm:"uniqueIndex:idx_rss_item_guid;size:512;not null"`
+	Title          string    `json:"title"`
+	URL            string    `json:"url"`
+	Published      time.Time `json:"published"`
+	CreatedAt      time.Time `json:"created_at"`
+}
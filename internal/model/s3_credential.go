@@ -0,0 +1,52 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// S3Credential is an access key pair of the S3 server issued to a user.
+// The secret has to be kept in plain text since SigV4 verification needs it.
+type S3Credential struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	UserID          uint       `json:"user_id" gorm:"index;not null"`
+	Name            string     `json:"name" gorm:"size:255"`
+	AccessKeyID     string     `json:"access_key_id" gorm:"uniqueIndex;size:32;not null"`
+	SecretAccessKey string     `json:"-" gorm:"size:64;not null"`
+	Buckets         S3Buckets  `json:"buckets" gorm:"type:text"` // paths are relative to the user's base path
+	Disabled        bool       `json:"disabled"`
+	LastUsedTime    *time.Time `json:"last_used_time"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+type S3Bucket struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+type S3Buckets []S3Bucket
+
+func (b S3Buckets) Value() (driver.Value, error) {
+	bs, err := json.Marshal([]S3Bucket(b))
+	return string(bs), err
+}
+
+func (b *S3Buckets) Scan(value interface{}) error {
+	var bs []byte
+	switch v := value.(type) {
+	case []byte:
+		bs = v
+	case string:
+		bs = []byte(v)
+	case nil:
+	default:
+		return fmt.Errorf("cannot scan %T", value)
+	}
+	if len(bs) == 0 {
+		*b = nil
+		return nil
+	}
+	return json.Unmarshal(bs, (*[]S3Bucket)(b))
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// S3ObjectMeta keeps the S3 attributes of an object that the storage itself
+// can not hold, keyed by the storage and the actual path of the object.
+type S3ObjectMeta struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	StorageID   uint      `json:"storage_id" gorm:"uniqueIndex:idx_s3_object_meta_path;not null"`
+	Path        string    `json:"path" gorm:"uniqueIndex:idx_s3_object_meta_path;size:700;not null"`
+	ContentType string    `json:"content_type" gorm:"size:255"`
+	Headers     StringMap `json:"headers" gorm:"type:text"` // x-amz-meta-* and stored Content-* headers
+	Tags        StringMap `json:"tags" gorm:"type:text"`
+	MD5         string    `json:"md5" gorm:"size:32"`
+	Size        int64     `json:"size"` // size of the content MD5 was computed on
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ContentMD5 returns the recorded md5 if it still describes an object of the
+// given size.
+func (m *S3ObjectMeta) ContentMD5(size int64) string {
+	if m == nil || m.Size != size {
+		return ""
+	}
+	return m.MD5
+}
+
+type StringMap map[string]string
+
+func (m StringMap) Value() (driver.Value, error) {
+	bs, err := json.Marshal(map[string]string(m))
+	return string(bs), err
+}
+
+func (m *StringMap) Scan(value interface{}) error {
+	var bs []byte
+	switch v := value.(type) {
+	case []byte:
+		bs = v
+	case string:
+		bs = []byte(v)
+	case nil:
+	default:
+		return fmt.Errorf("cannot scan %T", value)
+	}
+	if len(bs) == 0 {
+		*m = nil
+		return nil
+	}
+	return json.Unmarshal(bs, (*map[string]string)(m))
+}
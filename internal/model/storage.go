@@ -10,6 +10,8 @@ type Storage struct {
 	Order           int       `json:"order"`                                       // use to sort
 	Driver          string    `json:"driver"`                                      // driver used
 	CacheExpiration int       `json:"cache_expiration"`                            // cache expire time
+	RateLimit       float64   `json:"rate_limit"`                                  // max upstream calls per second, 0 for no limit
+	MaxConcurrency  int       `json:"max_concurrency"`                             // max upstream calls in flight, 0 for no limit
 	Status          string    `json:"status"`
 	Addition        string    `json:"addition" gorm:"type:text"` // Additional information, defined in the corresponding driver
 	Remark          string    `json:"remark"`
@@ -58,3 +60,13 @@ func (p Proxy) WebdavProxy() bool {
 func (p Proxy) WebdavNative() bool {
 	return !p.Webdav302() && !p.WebdavProxy()
 }
+
+// StorageDetails is the space of a storage in bytes.
+type StorageDetails struct {
+	TotalSpace int64 `json:"total_space"`
+	FreeSpace  int64 `json:"free_space"`
+}
+
+func (d StorageDetails) UsedSpace() int64 {
+	return d.TotalSpace - d.FreeSpace
+}
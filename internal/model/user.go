@@ -55,6 +55,8 @@ type User struct {
 	OtpSecret  string `json:"-"`
 	SsoID      string `json:"sso_id"` // unique by sso platform
 	Authn      string `gorm:"type:text" json:"-"`
+	// Scope is set when the request is authenticated by a personal access token
+	Scope *APIToken `json:"-" gorm:"-"`
 }
 
 func (u *User) IsGuest() bool {
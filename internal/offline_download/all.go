@@ -3,6 +3,7 @@ package offline_download
 import (
 	_ "github.com/alist-org/alist/v3/internal/offline_download/115"
 	_ "github.com/alist-org/alist/v3/internal/offline_download/aria2"
+	_ "github.com/alist-org/alist/v3/internal/offline_download/bittorrent"
 	_ "github.com/alist-org/alist/v3/internal/offline_download/guangyapan"
 	_ "github.com/alist-org/alist/v3/internal/offline_download/http"
 	_ "github.com/alist-org/alist/v3/internal/offline_download/pikpak"
@@ -0,0 +1,285 @@
+package bittorrent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/offline_download/tool"
+	"github.com/alist-org/alist/v3/internal/setting"
+	alog "github.com/anacrolix/log"
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/storage"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultListenPort = 6881
+
+// BitTorrent downloads torrents and magnet links with a built-in client, so
+// no external daemon is needed.
+type BitTorrent struct {
+	mu        sync.Mutex
+	ready     bool
+	port      int
+	client    *torrent.Client
+	downloads map[string]*download
+	// configure adjusts the client config, used by tests to stay offline
+	configure func(cfg *torrent.ClientConfig)
+}
+
+type download struct {
+	t        *torrent.Torrent
+	storage  storage.ClientImplCloser
+	tempDir  string
+	selected map[int]bool
+	// err is set when the selection does not match the files of the torrent
+	err      error
+	complete bool
+}
+
+func (b *BitTorrent) Run(task *tool.DownloadTask) error {
+	return errs.NotSupport
+}
+
+func (b *BitTorrent) Name() string {
+	return "BitTorrent"
+}
+
+func (b *BitTorrent) Items() []model.SettingItem {
+	return []model.SettingItem{
+		{Key: conf.BitTorrentListenPort, Value: strconv.Itoa(defaultListenPort), Type: conf.TypeNumber, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE, Help: "TCP and UDP port the built-in torrent client listens on for peers."},
+		{Key: conf.BitTorrentSeedRatio, Value: "0", Type: conf.TypeNumber, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE, Help: "Stop seeding once uploaded/downloaded reaches this ratio. Set 0 for no ratio limit."},
+		{Key: conf.BitTorrentSeedtime, Value: "0", Type: conf.TypeNumber, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE, Help: "Stop seeding after this many minutes. Set -1 for no time limit."},
+	}
+}
+
+// Init only validates the settings, the client is started by the first
+// download. A running client is restarted with a new port once it is idle.
+func (b *BitTorrent) Init() (string, error) {
+	port := setting.GetInt(conf.BitTorrentListenPort, defaultListenPort)
+	if port < 0 || port > 65535 {
+		return "", errors.Errorf("invalid listen port %d", port)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.client != nil && b.port != port && len(b.downloads) == 0 {
+		b.client.Close()
+		b.client = nil
+	}
+	b.port = port
+	b.ready = true
+	return fmt.Sprintf("listen port: %d", port), nil
+}
+
+func (b *BitTorrent) IsReady() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.ready
+}
+
+func (b *BitTorrent) getClient() (*torrent.Client, error) {
+	if b.client != nil {
+		return b.client, nil
+	}
+	cfg := torrent.NewDefaultClientConfig()
+	cfg.ListenPort = b.port
+	cfg.Seed = true
+	// every torrent has its own storage, see newStorage
+	cfg.DefaultStorage = storage.NewFileOpts(storage.NewFileClientOpts{
+		ClientBaseDir:   filepath.Join(conf.Conf.TempDir, b.Name()),
+		PieceCompletion: storage.NewMapPieceCompletion(),
+	})
+	cfg.Logger = alog.Default.WithNames("bittorrent").FilterLevel(alog.Warning)
+	if b.configure != nil {
+		b.configure(cfg)
+	}
+	client, err := torrent.NewClient(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start torrent client")
+	}
+	b.client = client
+	b.downloads = make(map[string]*download)
+	log.Infof("[bittorrent] client listening on port %d", client.LocalPort())
+	return client, nil
+}
+
+func (b *BitTorrent) AddURL(args *tool.AddUrlArgs) (string, error) {
+	spec, selected, err := torrentSpec(args.Url)
+	if err != nil {
+		return "", err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	client, err := b.getClient()
+	if err != nil {
+		return "", err
+	}
+	gid := spec.InfoHash.HexString()
+	if d, ok := b.downloads[gid]; ok {
+		if !d.complete {
+			return "", errors.Errorf("torrent %s is already being downloaded", gid)
+		}
+		// stop seeding the previous download, it has its own temp dir
+		b.drop(gid)
+	}
+	d := &download{
+		storage:  newStorage(args.TempDir, selected),
+		tempDir:  args.TempDir,
+		selected: selected,
+	}
+	spec.Storage = d.storage
+	t, _, err := client.AddTorrentSpec(spec)
+	if err != nil {
+		_ = d.storage.Close()
+		return "", errors.Wrap(err, "failed to add torrent")
+	}
+	d.t = t
+	b.downloads[gid] = d
+	go b.start(d)
+	return gid, nil
+}
+
+// start requests the selected files once the metainfo is known.
+func (b *BitTorrent) start(d *download) {
+	select {
+	case <-d.t.GotInfo():
+	case <-d.t.Closed():
+		return
+	}
+	if d.selected == nil {
+		d.t.DownloadAll()
+		return
+	}
+	files := d.t.Files()
+	for i := range d.selected {
+		if i >= len(files) {
+			b.mu.Lock()
+			d.err = errors.Errorf("file index %d out of range, the torrent has %d files", i, len(files))
+			b.mu.Unlock()
+			return
+		}
+	}
+	for i, f := range files {
+		if d.selected[i] {
+			f.Download()
+		}
+	}
+}
+
+func (b *BitTorrent) Remove(task *tool.DownloadTask) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.drop(task.GID)
+	return nil
+}
+
+// drop stops a torrent and removes its skipped parts, the caller must hold
+// b.mu. The downloaded files are left to the transfer task.
+func (b *BitTorrent) drop(gid string) {
+	d, ok := b.downloads[gid]
+	if !ok {
+		return
+	}
+	delete(b.downloads, gid)
+	d.t.Drop()
+	if err := d.storage.Close(); err != nil {
+		log.Warnf("[bittorrent] failed to close storage of %s: %+v", gid, err)
+	}
+	if err := os.RemoveAll(skippedDir(d.tempDir)); err != nil {
+		log.Warnf("[bittorrent] failed to remove skipped files of %s: %+v", gid, err)
+	}
+}
+
+func (b *BitTorrent) Status(task *tool.DownloadTask) (*tool.Status, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	d, ok := b.downloads[task.GID]
+	if !ok {
+		return nil, errors.Errorf("torrent %s not found", task.GID)
+	}
+	s := &tool.Status{}
+	if d.err != nil {
+		s.Err = d.err
+		return s, nil
+	}
+	if d.t.Info() == nil {
+		s.Status = "fetching metadata"
+		return s, nil
+	}
+	var completed int64
+	for i, f := range d.t.Files() {
+		if d.selected == nil || d.selected[i] {
+			s.TotalBytes += f.Length()
+			completed += f.BytesCompleted()
+		}
+	}
+	if s.TotalBytes > 0 {
+		s.Progress = float64(completed) / float64(s.TotalBytes) * 100
+	}
+	if completed < s.TotalBytes {
+		stats := d.t.Stats()
+		s.Status = fmt.Sprintf("downloading, %d/%d peers", stats.ActivePeers, stats.TotalPeers)
+		return s, nil
+	}
+	s.Progress = 100
+	s.Completed = true
+	if !d.complete {
+		d.complete = true
+		// the files may be removed by the transfer task while seeding,
+		// never fetch them again
+		d.t.DisallowDataDownload()
+		go b.seed(task.GID, d, s.TotalBytes)
+	}
+	return s, nil
+}
+
+// seed keeps a completed torrent until the seed time or ratio is reached,
+// whichever comes first.
+func (b *BitTorrent) seed(gid string, d *download, size int64) {
+	seedTime := setting.GetInt(conf.BitTorrentSeedtime, 0)
+	ratio, _ := strconv.ParseFloat(setting.GetStr(conf.BitTorrentSeedRatio), 64)
+	var deadline <-chan time.Time
+	if seedTime >= 0 {
+		timer := time.NewTimer(time.Duration(seedTime) * time.Minute)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	if deadline == nil && ratio <= 0 {
+		// seed until restart, like the external clients do
+		return
+	}
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.t.Closed():
+			return
+		case <-deadline:
+		case <-ticker.C:
+			if ratio <= 0 || size <= 0 {
+				continue
+			}
+			stats := d.t.Stats()
+			if float64(stats.BytesWrittenData.Int64())/float64(size) < ratio {
+				continue
+			}
+		}
+		b.mu.Lock()
+		b.drop(gid)
+		b.mu.Unlock()
+		return
+	}
+}
+
+var _ tool.Tool = (*BitTorrent)(nil)
+
+func init() {
+	tool.Tools.Add(&BitTorrent{})
+}
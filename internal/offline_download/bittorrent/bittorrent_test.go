@@ -0,0 +1,182 @@
+package bittorrent
+
+import (
+	"bytes"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/offline_download/tool"
+	"github.com/alist-org/alist/v3/internal/testutil"
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/anacrolix/torrent/storage"
+)
+
+func init() {
+	testutil.InitDB()
+}
+
+func offline(cfg *torrent.ClientConfig) {
+	cfg.NoDHT = true
+	cfg.DisableTrackers = true
+	cfg.DisableIPv6 = true
+	cfg.ListenHost = func(string) string { return "127.0.0.1" }
+	cfg.ListenPort = 0
+}
+
+func TestParseSelectOnly(t *testing.T) {
+	selected, err := parseSelectOnly("0,2,4-6")
+	if err != nil {
+		t.Fatalf("failed to parse: %+v", err)
+	}
+	for _, i := range []int{0, 2, 4, 5, 6} {
+		if !selected[i] {
+			t.Errorf("file %d should be selected", i)
+		}
+	}
+	if len(selected) != 5 {
+		t.Errorf("unexpected selection: %v", selected)
+	}
+	for _, so := range []string{"a", "3-1", "-1", "1,"} {
+		if _, err := parseSelectOnly(so); err == nil {
+			t.Errorf("%q should be rejected", so)
+		}
+	}
+}
+
+func TestDownloadSelectedFile(t *testing.T) {
+	conf.Conf.TempDir = t.TempDir()
+
+	// seed two files from a local client
+	seedDir := t.TempDir()
+	content := map[string][]byte{"a.bin": make([]byte, 100_000), "b.bin": make([]byte, 50_000)}
+	if err := os.Mkdir(filepath.Join(seedDir, "pack"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range content {
+		rand.Read(data)
+		if err := os.WriteFile(filepath.Join(seedDir, "pack", name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info := metainfo.Info{PieceLength: 32 * 1024}
+	if err := info.BuildFromFilePath(filepath.Join(seedDir, "pack")); err != nil {
+		t.Fatal(err)
+	}
+	infoBytes, err := bencode.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mi := &metainfo.MetaInfo{InfoBytes: infoBytes}
+	seederCfg := torrent.NewDefaultClientConfig()
+	offline(seederCfg)
+	seederCfg.DataDir = seedDir
+	seederCfg.Seed = true
+	seeder, err := torrent.NewClient(seederCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer seeder.Close()
+	seedTorrent, err := seeder.AddTorrent(mi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seedTorrent.VerifyData()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = mi.Write(w)
+	}))
+	defer srv.Close()
+
+	bt := &BitTorrent{configure: offline}
+	if _, err := bt.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if bt.client != nil {
+			bt.client.Close()
+		}
+	}()
+	tempDir := filepath.Join(conf.Conf.TempDir, bt.Name(), "task")
+	// files are ordered by path, so 1 is b.bin
+	gid, err := bt.AddURL(&tool.AddUrlArgs{Url: srv.URL + "/pack.torrent#so=1", TempDir: tempDir})
+	if err != nil {
+		t.Fatalf("failed to add url: %+v", err)
+	}
+	bt.mu.Lock()
+	bt.downloads[gid].t.AddClientPeer(seeder)
+	bt.mu.Unlock()
+
+	task := &tool.DownloadTask{GID: gid}
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		s, err := bt.Status(task)
+		if err != nil {
+			t.Fatalf("failed to get status: %+v", err)
+		}
+		if s.Err != nil {
+			t.Fatalf("download failed: %+v", s.Err)
+		}
+		if s.Completed {
+			if s.TotalBytes != int64(len(content["b.bin"])) {
+				t.Errorf("unexpected total bytes %d", s.TotalBytes)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("download timed out, status: %+v", s)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	got, err := os.ReadFile(filepath.Join(tempDir, "pack", "b.bin"))
+	if err != nil {
+		t.Fatalf("selected file not downloaded: %+v", err)
+	}
+	if !bytes.Equal(got, content["b.bin"]) {
+		t.Errorf("content of b.bin mismatch")
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "pack", "a.bin")); !os.IsNotExist(err) {
+		t.Errorf("unselected file should not be in temp dir: %v", err)
+	}
+	// without seed time the torrent is dropped right after completion
+	for time.Now().Before(deadline) {
+		bt.mu.Lock()
+		_, ok := bt.downloads[gid]
+		bt.mu.Unlock()
+		if !ok {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if _, err := os.Stat(skippedDir(tempDir)); !os.IsNotExist(err) {
+		t.Errorf("skipped files should be removed: %v", err)
+	}
+}
+
+// the sqlite piece completion of the torrent library runs on the sqlite of
+// the database driver, see pkg/sqlite_adapter
+func TestSqlitePieceCompletion(t *testing.T) {
+	pc, err := storage.NewSqlitePieceCompletion(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to open piece completion: %+v", err)
+	}
+	defer pc.Close()
+	pk := metainfo.PieceKey{InfoHash: metainfo.NewHashFromHex("0123456789abcdef0123456789abcdef01234567"), Index: 3}
+	if c, err := pc.Get(pk); err != nil || c.Ok {
+		t.Fatalf("expected an unknown piece, got %+v %v", c, err)
+	}
+	if err = pc.Set(pk, true); err != nil {
+		t.Fatalf("failed to set piece: %+v", err)
+	}
+	if c, err := pc.Get(pk); err != nil || !c.Ok || !c.Complete {
+		t.Fatalf("expected a complete piece, got %+v %v", c, err)
+	}
+}
@@ -0,0 +1,140 @@
+package bittorrent
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/anacrolix/torrent/storage"
+	"github.com/pkg/errors"
+)
+
+// maxTorrentFileSize limits the size of .torrent files fetched over http
+const maxTorrentFileSize = 16 << 20
+
+// parseSelectOnly parses the BEP 53 select-only list, like "0,2,4-6", into
+// a set of file indices. A nil set selects every file.
+func parseSelectOnly(so string) (map[int]bool, error) {
+	if so == "" {
+		return nil, nil
+	}
+	selected := make(map[int]bool)
+	for _, part := range strings.Split(so, ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(first)
+		if err != nil || start < 0 {
+			return nil, errors.Errorf("invalid file index %q", part)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(last)
+			if err != nil || end < start {
+				return nil, errors.Errorf("invalid file range %q", part)
+			}
+		}
+		for i := start; i <= end; i++ {
+			selected[i] = true
+		}
+	}
+	return selected, nil
+}
+
+// torrentSpec builds the spec of a magnet uri or of a .torrent file url and
+// returns the files selected with the "so" parameter. As .torrent files have
+// no parameters of their own, their selection is read from the url fragment,
+// e.g. https://example.com/a.torrent#so=0,2
+func torrentSpec(rawURL string) (*torrent.TorrentSpec, map[int]bool, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to parse url")
+	}
+	switch u.Scheme {
+	case "magnet":
+		selected, err := parseSelectOnly(u.Query().Get("so"))
+		if err != nil {
+			return nil, nil, err
+		}
+		spec, err := torrent.TorrentSpecFromMagnetUri(rawURL)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to parse magnet uri")
+		}
+		return spec, selected, nil
+	case "http", "https":
+		fragment, _ := url.ParseQuery(u.Fragment)
+		selected, err := parseSelectOnly(fragment.Get("so"))
+		if err != nil {
+			return nil, nil, err
+		}
+		u.Fragment = ""
+		mi, err := fetchMetaInfo(u.String())
+		if err != nil {
+			return nil, nil, err
+		}
+		spec, err := torrent.TorrentSpecFromMetaInfoErr(mi)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "invalid .torrent file")
+		}
+		return spec, selected, nil
+	default:
+		return nil, nil, errors.Errorf("unsupported url scheme %q", u.Scheme)
+	}
+}
+
+func fetchMetaInfo(torrentURL string) (*metainfo.MetaInfo, error) {
+	resp, err := http.Get(torrentURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get .torrent file")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to get .torrent file: %s", resp.Status)
+	}
+	mi, err := metainfo.Load(io.LimitReader(resp.Body, maxTorrentFileSize))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load .torrent file")
+	}
+	return mi, nil
+}
+
+// skippedDir is where the parts of files which were not selected end up,
+// pieces spanning a file boundary make them partially written. It sits next
+// to tempDir so that those parts are not transferred.
+func skippedDir(tempDir string) string {
+	return filepath.Join(filepath.Dir(tempDir), "."+filepath.Base(tempDir)+".skipped")
+}
+
+// newStorage stores the selected files of a torrent in tempDir and the others
+// in skippedDir(tempDir).
+func newStorage(tempDir string, selected map[int]bool) storage.ClientImplCloser {
+	baseDir := filepath.Dir(tempDir)
+	name := filepath.Base(tempDir)
+	skipped := filepath.Base(skippedDir(tempDir))
+	// the maker is given a copy of each file info, so files are matched by path
+	var index map[string]int
+	return storage.NewFileOpts(storage.NewFileClientOpts{
+		ClientBaseDir:   baseDir,
+		PieceCompletion: storage.NewMapPieceCompletion(),
+		FilePathMaker: func(opts storage.FilePathMakerOpts) string {
+			if index == nil {
+				index = make(map[string]int)
+				for i, f := range opts.Info.UpvertedFiles() {
+					index[f.DisplayPath(opts.Info)] = i
+				}
+			}
+			dir := name
+			if selected != nil && !selected[index[opts.File.DisplayPath(opts.Info)]] {
+				dir = skipped
+			}
+			parts := []string{dir}
+			if opts.Info.BestName() != metainfo.NoName {
+				parts = append(parts, opts.Info.BestName())
+			}
+			return filepath.Join(append(parts, opts.File.BestPath()...)...)
+		},
+	})
+}
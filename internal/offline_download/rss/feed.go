@@ -0,0 +1,135 @@
+package rss
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html/charset"
+)
+
+// Item is a feed item reduced to what is needed to download it.
+type Item struct {
+	GUID      string
+	Title     string
+	URL       string
+	Published time.Time
+}
+
+type rssLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Text string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL string `xml:"url,attr"`
+}
+
+// rssItem covers the items of RSS 0.9x, 1.0 and 2.0 as well as the entries
+// of Atom, which differ mostly in how links are written.
+type rssItem struct {
+	Title     string        `xml:"title"`
+	GUID      string        `xml:"guid"`
+	ID        string        `xml:"id"`
+	Links     []rssLink     `xml:"link"`
+	Enclosure *rssEnclosure `xml:"enclosure"`
+	PubDate   string        `xml:"pubDate"`
+	Date      string        `xml:"date"`
+	Published string        `xml:"published"`
+	Updated   string        `xml:"updated"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name
+	Items   []rssItem `xml:"channel>item"`
+	RDF     []rssItem `xml:"item"`
+	Entries []rssItem `xml:"entry"`
+}
+
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC3339,
+}
+
+func parseDate(values ...string) time.Time {
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// url prefers the enclosure, which is what torrent feeds and podcasts put
+// their files in, over the link to the item's page.
+func (i *rssItem) url() string {
+	if i.Enclosure != nil && i.Enclosure.URL != "" {
+		return strings.TrimSpace(i.Enclosure.URL)
+	}
+	var link string
+	for _, l := range i.Links {
+		href := strings.TrimSpace(l.Href)
+		if href == "" {
+			href = strings.TrimSpace(l.Text)
+		}
+		switch l.Rel {
+		case "enclosure":
+			return href
+		case "", "alternate":
+			if link == "" {
+				link = href
+			}
+		}
+	}
+	return link
+}
+
+// ParseFeed reads the items of a RSS or Atom feed. Items are identified by
+// their guid or id, falling back to their url.
+func ParseFeed(r io.Reader) ([]Item, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charset.NewReaderLabel
+	decoder.Strict = false
+	var feed rssFeed
+	if err := decoder.Decode(&feed); err != nil {
+		return nil, errors.Wrap(err, "failed to parse feed")
+	}
+	switch feed.XMLName.Local {
+	case "rss", "RDF", "feed":
+	default:
+		return nil, errors.Errorf("unknown feed format <%s>", feed.XMLName.Local)
+	}
+	var items []Item
+	for _, raw := range append(append(feed.Items, feed.RDF...), feed.Entries...) {
+		item := Item{
+			Title:     strings.TrimSpace(raw.Title),
+			URL:       raw.url(),
+			Published: parseDate(raw.PubDate, raw.Published, raw.Updated, raw.Date),
+		}
+		if item.URL == "" {
+			continue
+		}
+		item.GUID = strings.TrimSpace(raw.GUID)
+		if item.GUID == "" {
+			item.GUID = strings.TrimSpace(raw.ID)
+		}
+		if item.GUID == "" {
+			item.GUID = item.URL
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
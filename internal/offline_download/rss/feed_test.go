@@ -0,0 +1,50 @@
+package rss
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRSS(t *testing.T) {
+	items, err := ParseFeed(strings.NewReader(`<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"><channel><title>releases</title>
+<item><title>Show S01E02 1080p</title><link>https://example.com/2</link>
+<enclosure url="https://example.com/2.torrent" type="application/x-bittorrent" length="1"/>
+<guid>ep-2</guid><pubDate>Tue, 06 Feb 2024 10:00:00 +0000</pubDate></item>
+<item><title>Show S01E01 1080p</title><link>https://example.com/1</link></item>
+</channel></rss>`))
+	if err != nil {
+		t.Fatalf("failed to parse: %+v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(items))
+	}
+	if items[0].URL != "https://example.com/2.torrent" || items[0].GUID != "ep-2" || items[0].Published.Year() != 2024 {
+		t.Errorf("unexpected item: %+v", items[0])
+	}
+	// without guid the url identifies the item
+	if items[1].URL != "https://example.com/1" || items[1].GUID != "https://example.com/1" {
+		t.Errorf("unexpected item: %+v", items[1])
+	}
+}
+
+func TestParseAtom(t *testing.T) {
+	items, err := ParseFeed(strings.NewReader(`<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom"><title>podcast</title>
+<entry><title>Episode 1</title><id>urn:uuid:1</id><updated>2024-02-06T10:00:00Z</updated>
+<link rel="alternate" href="https://example.com/ep1"/>
+<link rel="enclosure" type="audio/mpeg" href="https://example.com/ep1.mp3"/></entry>
+</feed>`))
+	if err != nil {
+		t.Fatalf("failed to parse: %+v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(items))
+	}
+	if items[0].URL != "https://example.com/ep1.mp3" || items[0].GUID != "urn:uuid:1" || items[0].Published.IsZero() {
+		t.Errorf("unexpected item: %+v", items[0])
+	}
+	if _, err := ParseFeed(strings.NewReader(`<html></html>`)); err == nil {
+		t.Errorf("html should be rejected")
+	}
+}
@@ -0,0 +1,195 @@
+package rss
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/net"
+	"github.com/alist-org/alist/v3/internal/offline_download/tool"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/pkg/cron"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	fetchTimeout = 30 * time.Second
+	// maxGUIDLength is the size of the guid column, longer ones are hashed
+	maxGUIDLength = 512
+)
+
+var (
+	pollCron *cron.Cron
+	// checking holds the ids of the subscriptions being checked
+	checking sync.Map
+)
+
+// Init starts polling the enabled subscriptions, each one is checked once
+// its interval has passed since the last check.
+func Init() {
+	if pollCron != nil {
+		pollCron.Stop()
+	}
+	pollCron = cron.NewCron(time.Minute)
+	pollCron.Do(checkDue)
+}
+
+func checkDue() {
+	subs, err := op.GetEnabledRSSSubscriptions()
+	if err != nil {
+		log.Errorf("[rss] failed get subscriptions: %+v", err)
+		return
+	}
+	now := time.Now()
+	for i := range subs {
+		sub := &subs[i]
+		if sub.LastCheck != nil && now.Sub(*sub.LastCheck) < time.Duration(sub.Interval)*time.Minute {
+			continue
+		}
+		go func() {
+			if _, err := Check(context.Background(), sub); err != nil {
+				log.Warnf("[rss] failed check subscription %d: %+v", sub.ID, err)
+			}
+		}()
+	}
+}
+
+// Check fetches the feed of sub and adds the new items matching its rules as
+// offline downloads of its owner. The added items are returned.
+func Check(ctx context.Context, sub *model.RSSSubscription) ([]model.RSSItem, error) {
+	if _, loaded := checking.LoadOrStore(sub.ID, struct{}{}); loaded {
+		return nil, errors.Errorf("subscription %d is being checked", sub.ID)
+	}
+	defer checking.Delete(sub.ID)
+	added, err := check(ctx, sub)
+	var lastErr string
+	if err != nil {
+		lastErr = err.Error()
+	}
+	if err := op.UpdateRSSSubscriptionCheck(sub.ID, time.Now(), lastErr); err != nil {
+		log.Warnf("[rss] failed update check time of subscription %d: %+v", sub.ID, err)
+	}
+	return added, err
+}
+
+func check(ctx context.Context, sub *model.RSSSubscription) ([]model.RSSItem, error) {
+	user, dstDirPath, err := subscriptionUser(sub)
+	if err != nil {
+		return nil, err
+	}
+	include, err := regexp.Compile(sub.Include)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid include rule")
+	}
+	exclude, err := regexp.Compile(sub.Exclude)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid exclude rule")
+	}
+	items, err := fetchFeed(ctx, sub.URL)
+	if err != nil {
+		return nil, err
+	}
+	ctx = context.WithValue(ctx, "user", user)
+	var added []model.RSSItem
+	var failed int
+	// feeds list the newest items first, add them in publishing order
+	for i := len(items) - 1; i >= 0; i-- {
+		item := items[i]
+		if !include.MatchString(item.Title) || (sub.Exclude != "" && exclude.MatchString(item.Title)) {
+			continue
+		}
+		guid := item.GUID
+		if len(guid) > maxGUIDLength {
+			sum := sha1.Sum([]byte(guid))
+			guid = "sha1:" + hex.EncodeToString(sum[:])
+		}
+		exists, err := op.HasRSSItem(sub.ID, guid)
+		if err != nil {
+			return added, err
+		}
+		if exists {
+			continue
+		}
+		if _, err := tool.AddURL(ctx, &tool.AddURLArgs{
+			URL:          item.URL,
+			DstDirPath:   dstDirPath,
+			Tool:         sub.Tool,
+			DeletePolicy: tool.DeletePolicy(sub.DeletePolicy),
+		}); err != nil {
+			// not recorded, so it is tried again by the next check
+			log.Warnf("[rss] failed add %s of subscription %d: %+v", item.URL, sub.ID, err)
+			failed++
+			continue
+		}
+		rssItem := model.RSSItem{
+			SubscriptionID: sub.ID,
+			GUID:           guid,
+			Title:          item.Title,
+			URL:            item.URL,
+			Published:      item.Published,
+		}
+		if err := op.CreateRSSItem(&rssItem); err != nil {
+			return added, err
+		}
+		added = append(added, rssItem)
+	}
+	if failed > 0 {
+		return added, errors.Errorf("failed to add %d items", failed)
+	}
+	return added, nil
+}
+
+// subscriptionUser loads the owner of sub with its roles and checks it may
+// still add offline downloads to the destination of sub.
+func subscriptionUser(sub *model.RSSSubscription) (*model.User, string, error) {
+	user, err := op.GetUserById(sub.UserID)
+	if err != nil {
+		return nil, "", err
+	}
+	if user.Disabled {
+		return nil, "", errors.New("user is disabled")
+	}
+	if len(user.Role) > 0 {
+		roles, err := op.GetRolesByUserID(user.ID)
+		if err != nil {
+			return nil, "", err
+		}
+		user.RolesDetail = roles
+	}
+	dstDirPath, err := user.JoinPath(sub.Path)
+	if err != nil {
+		return nil, "", err
+	}
+	if !common.CheckPathLimitWithRoles(user, dstDirPath) ||
+		!common.HasPermission(common.MergeRolePermissions(user, dstDirPath), common.PermAddOfflineDownload) {
+		return nil, "", errors.WithStack(errs.PermissionDenied)
+	}
+	return user, dstDirPath, nil
+}
+
+func fetchFeed(ctx context.Context, feedURL string) ([]Item, error) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	resp, err := net.HttpClient().Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch feed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch feed: %s", resp.Status)
+	}
+	return ParseFeed(resp.Body)
+}
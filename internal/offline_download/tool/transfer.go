@@ -157,6 +157,10 @@ func transferStdFile(t *TransferTask) error {
 		Mimetype: mimetype,
 		Closers:  utils.NewClosers(rc),
 	}
+	if err := op.CheckUploadPolicy(t.Ctx(), t.DstStorage, t.DstDirPath, s); err != nil {
+		_ = s.Close()
+		return err
+	}
 	t.SetTotalBytes(info.Size())
 	return op.Put(t.Ctx(), t.DstStorage, t.DstDirPath, s, t.SetProgress)
 }
@@ -260,6 +264,10 @@ func transferObjFile(t *TransferTask) error {
 	if err != nil {
 		return errors.WithMessagef(err, "failed get [%s] stream", t.SrcObjPath)
 	}
+	if err := op.CheckUploadPolicy(t.Ctx(), t.DstStorage, t.DstDirPath, ss); err != nil {
+		_ = ss.Close()
+		return err
+	}
 	t.SetTotalBytes(srcFile.GetSize())
 	return op.Put(t.Ctx(), t.DstStorage, t.DstDirPath, ss, t.SetProgress)
 }
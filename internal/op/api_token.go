@@ -0,0 +1,169 @@
+package op
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Xhofe/go-cache"
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/alist-org/alist/v3/pkg/utils/random"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var apiTokenCache = cache.NewMemCache(cache.WithShards[*model.APIToken](2))
+
+// apiTokenTouchInterval limits how often the last used time is written back
+const apiTokenTouchInterval = time.Minute
+
+// lastTouched keeps when the last used time of a token or credential was
+// written back, the cached objects are shared by requests so they are read only
+var (
+	lastTouchedMu sync.Mutex
+	lastTouched   = map[string]time.Time{}
+)
+
+// touchDue reports whether the last used time of key is due to be written
+// back, stored is the time loaded from the database.
+func touchDue(key string, stored *time.Time, now time.Time) bool {
+	lastTouchedMu.Lock()
+	defer lastTouchedMu.Unlock()
+	last, ok := lastTouched[key]
+	if !ok && stored != nil {
+		last = *stored
+	}
+	if !last.IsZero() && now.Sub(last) <= apiTokenTouchInterval {
+		return false
+	}
+	lastTouched[key] = now
+	return true
+}
+
+func forgetTouched(key string) {
+	lastTouchedMu.Lock()
+	delete(lastTouched, key)
+	lastTouchedMu.Unlock()
+}
+
+func HashAPIToken(token string) string {
+	return utils.HashData(utils.SHA256, []byte(token))
+}
+
+// APITokenS3Secret derives the S3 secret access key of a token. The secret is
+// not stored, it is computed from the token hash and the jwt secret.
+func APITokenS3Secret(t *model.APIToken) string {
+	mac := hmac.New(sha256.New, []byte(conf.Conf.JwtSecret))
+	mac.Write([]byte(t.TokenHash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CreateAPIToken generates a new token for t.UserID, stores its hash and
+// returns the plain token which can not be recovered afterwards.
+func CreateAPIToken(t *model.APIToken) (string, error) {
+	token := model.APITokenPrefix + random.String(48)
+	t.TokenHash = HashAPIToken(token)
+	t.Hint = token[:len(model.APITokenPrefix)+6]
+	t.AccessKey = "AP" + strings.ToUpper(random.String(18))
+	t.CreatedAt = time.Now()
+	t.LastUsedTime = nil
+	for i, p := range t.Paths {
+		t.Paths[i] = utils.FixAndCleanPath(p)
+	}
+	if err := db.CreateAPIToken(t); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func GetAPITokensByUserId(userId uint, pageIndex, pageSize int) ([]model.APIToken, int64, error) {
+	return db.GetAPITokensByUserId(userId, pageIndex, pageSize)
+}
+
+func GetAPITokenByIdAndUserId(id uint, userId uint) (*model.APIToken, error) {
+	t, err := db.GetAPITokenById(id)
+	if err != nil {
+		return nil, err
+	}
+	if t.UserID != userId {
+		return nil, errors.WithStack(errs.InvalidAPIToken)
+	}
+	return t, nil
+}
+
+func DeleteAPITokenById(id uint) error {
+	t, err := db.GetAPITokenById(id)
+	if err != nil {
+		return err
+	}
+	apiTokenCache.Del(t.TokenHash)
+	apiTokenCache.Del("s3:" + t.AccessKey)
+	forgetTouched(t.TokenHash)
+	return db.DeleteAPITokenById(id)
+}
+
+func getAPIToken(key string, fetch func() (*model.APIToken, error)) (*model.APIToken, error) {
+	if t, ok := apiTokenCache.Get(key); ok {
+		return t, nil
+	}
+	t, err := fetch()
+	if err != nil {
+		return nil, errors.WithStack(errs.InvalidAPIToken)
+	}
+	apiTokenCache.Set(key, t, cache.WithEx[*model.APIToken](10*time.Minute))
+	return t, nil
+}
+
+// GetUserByAPIToken authenticates a plain personal access token and returns
+// a copy of its owner restricted to the token's scope.
+func GetUserByAPIToken(token string) (*model.User, error) {
+	hash := HashAPIToken(token)
+	t, err := getAPIToken(hash, func() (*model.APIToken, error) {
+		return db.GetAPITokenByHash(hash)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return scopedAPITokenUser(t)
+}
+
+// GetUserByAPITokenAccessKey is like GetUserByAPIToken but looks the token up
+// by its S3 access key id.
+func GetUserByAPITokenAccessKey(accessKey string) (*model.User, error) {
+	t, err := getAPIToken("s3:"+accessKey, func() (*model.APIToken, error) {
+		return db.GetAPITokenByAccessKey(accessKey)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return scopedAPITokenUser(t)
+}
+
+func scopedAPITokenUser(t *model.APIToken) (*model.User, error) {
+	now := time.Now()
+	if t.IsExpired(now) {
+		return nil, errors.WithStack(errs.APITokenExpired)
+	}
+	user, err := db.GetUserById(t.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := enforceAdminUserDefaults(user); err != nil {
+		return nil, err
+	}
+	if touchDue(t.TokenHash, t.LastUsedTime, now) {
+		if err := db.UpdateAPITokenLastUsed(t.ID, now); err != nil {
+			log.Warnf("failed update last used time of api token %d: %+v", t.ID, err)
+		}
+	}
+	user.Permission = t.Mask(user.Permission)
+	user.Scope = t
+	return user, nil
+}
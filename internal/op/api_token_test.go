@@ -0,0 +1,80 @@
+package op_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+)
+
+func TestAPIToken(t *testing.T) {
+	user := &model.User{Username: "pat_user", Permission: 0xFF}
+	if err := db.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	tk := &model.APIToken{
+		UserID:     user.ID,
+		Name:       "ci",
+		Permission: 1<<3 | 1<<7,
+		Paths:      model.PathList{"/data/"},
+		ReadOnly:   true,
+	}
+	token, err := op.CreateAPIToken(tk)
+	if err != nil {
+		t.Fatalf("failed to create token: %+v", err)
+	}
+	// the cached token is shared by concurrent requests
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := op.GetUserByAPIToken(token); err != nil {
+				t.Errorf("failed to authenticate token: %+v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if stored, err := db.GetAPITokenById(tk.ID); err != nil || stored.LastUsedTime == nil {
+		t.Errorf("last used time not stored: %v", err)
+	}
+	scoped, err := op.GetUserByAPIToken(token)
+	if err != nil {
+		t.Fatalf("failed to authenticate token: %+v", err)
+	}
+	if scoped.ID != user.ID || scoped.Scope == nil {
+		t.Fatalf("unexpected user: %+v", scoped)
+	}
+	if scoped.Permission != 0 {
+		t.Errorf("read-only token kept write permissions: %b", scoped.Permission)
+	}
+	if !scoped.Scope.AllowPath("/data/a") || scoped.Scope.AllowPath("/other") || !scoped.Scope.CanReach("/") {
+		t.Errorf("unexpected path scope: %v", scoped.Scope.Paths)
+	}
+	if _, err := op.GetUserByAPITokenAccessKey(tk.AccessKey); err != nil {
+		t.Errorf("failed to authenticate access key: %+v", err)
+	}
+	if _, err := op.GetUserByAPIToken(token + "x"); err == nil {
+		t.Errorf("unknown token accepted")
+	}
+
+	past := time.Now().Add(-time.Minute)
+	expired := &model.APIToken{UserID: user.ID, Name: "expired", ExpiresAt: &past}
+	expiredToken, err := op.CreateAPIToken(expired)
+	if err != nil {
+		t.Fatalf("failed to create token: %+v", err)
+	}
+	if _, err := op.GetUserByAPIToken(expiredToken); err == nil {
+		t.Errorf("expired token accepted")
+	}
+
+	if err := op.DeleteAPITokenById(tk.ID); err != nil {
+		t.Fatalf("failed to delete token: %+v", err)
+	}
+	if _, err := op.GetUserByAPIToken(token); err == nil {
+		t.Errorf("revoked token accepted")
+	}
+}
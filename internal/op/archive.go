@@ -137,7 +137,11 @@ func getArchiveMeta(ctx context.Context, storage driver.Driver, path string, arg
 		if obj.IsDir() {
 			return nil, nil, errors.WithStack(errs.NotFile)
 		}
-		meta, err := storageAr.GetArchiveMeta(ctx, obj, args.ArchiveArgs)
+		var meta model.ArchiveMeta
+		err = driverCall(ctx, storage, "GetArchiveMeta", func(ctx context.Context) (err error) {
+			meta, err = storageAr.GetArchiveMeta(ctx, obj, args.ArchiveArgs)
+			return
+		})
 		if !errors.Is(err, errs.NotImplement) {
 			archiveMetaProvider := &model.ArchiveMetaProvider{ArchiveMeta: meta, DriverProviding: true}
 			if meta != nil && meta.GetTree() != nil {
@@ -243,7 +247,11 @@ func _listArchive(ctx context.Context, storage driver.Driver, path string, args
 		if obj.IsDir() {
 			return nil, nil, errors.WithStack(errs.NotFile)
 		}
-		files, err := storageAr.ListArchive(ctx, obj, args.ArchiveInnerArgs)
+		var files []model.Obj
+		err = driverCall(ctx, storage, "ListArchive", func(ctx context.Context) (err error) {
+			files, err = storageAr.ListArchive(ctx, obj, args.ArchiveInnerArgs)
+			return
+		})
 		if !errors.Is(err, errs.NotImplement) {
 			return obj, files, err
 		}
@@ -343,7 +351,11 @@ func ArchiveGet(ctx context.Context, storage driver.Driver, path string, args mo
 		return nil, nil, errors.WithStack(errs.NotFile)
 	}
 	if g, ok := storage.(driver.ArchiveGetter); ok {
-		obj, err := g.ArchiveGet(ctx, af, args.ArchiveInnerArgs)
+		var obj model.Obj
+		err := driverCall(ctx, storage, "ArchiveGet", func(ctx context.Context) (err error) {
+			obj, err = g.ArchiveGet(ctx, af, args.ArchiveInnerArgs)
+			return
+		})
 		if err == nil {
 			return af, model.WrapObjName(obj), nil
 		}
@@ -437,7 +449,11 @@ func driverExtract(ctx context.Context, storage driver.Driver, path string, args
 	if extracted.IsDir() {
 		return nil, errors.WithStack(errs.NotFile)
 	}
-	link, err := storageAr.Extract(ctx, archiveFile, args)
+	var link *model.Link
+	err = driverCall(ctx, storage, "Extract", func(ctx context.Context) (err error) {
+		link, err = storageAr.Extract(ctx, archiveFile, args)
+		return
+	})
 	return &extractLink{Link: link, Obj: extracted}, err
 }
 
@@ -496,7 +512,10 @@ func ArchiveDecompress(ctx context.Context, storage driver.Driver, srcPath, dstD
 	switch s := storage.(type) {
 	case driver.ArchiveDecompressResult:
 		var newObjs []model.Obj
-		newObjs, err = s.ArchiveDecompress(ctx, srcObj, dstDir, args)
+		err = driverCall(ctx, storage, "ArchiveDecompress", func(ctx context.Context) (err error) {
+			newObjs, err = s.ArchiveDecompress(ctx, srcObj, dstDir, args)
+			return
+		})
 		if err == nil {
 			if newObjs != nil && len(newObjs) > 0 {
 				for _, newObj := range newObjs {
@@ -507,7 +526,9 @@ func ArchiveDecompress(ctx context.Context, storage driver.Driver, srcPath, dstD
 			}
 		}
 	case driver.ArchiveDecompress:
-		err = s.ArchiveDecompress(ctx, srcObj, dstDir, args)
+		err = driverCall(ctx, storage, "ArchiveDecompress", func(ctx context.Context) error {
+			return s.ArchiveDecompress(ctx, srcObj, dstDir, args)
+		})
 		if err == nil && !utils.IsBool(lazyCache...) {
 			ClearCache(storage, dstDirPath)
 		}
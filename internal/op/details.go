@@ -0,0 +1,45 @@
+package op
+
+import (
+	"context"
+	"time"
+
+	"github.com/Xhofe/go-cache"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/singleflight"
+	"github.com/pkg/errors"
+)
+
+var detailsCache = cache.NewMemCache(cache.WithShards[*model.StorageDetails](16))
+var detailsG singleflight.Group[*model.StorageDetails]
+
+// GetStorageDetails returns the space of a storage, it is cached for a
+// minute since most drivers have to ask their remote for it.
+func GetStorageDetails(ctx context.Context, storage driver.Driver) (*model.StorageDetails, error) {
+	wd, ok := storage.(driver.WithDetails)
+	if !ok {
+		return nil, errs.NotImplement
+	}
+	if storage.Config().CheckStatus && storage.GetStorage().Status != WORK {
+		return nil, errors.Errorf("storage not init: %s", storage.GetStorage().Status)
+	}
+	key := storage.GetStorage().MountPath
+	if details, ok := detailsCache.Get(key); ok {
+		return details, nil
+	}
+	details, err, _ := detailsG.Do(key, func() (*model.StorageDetails, error) {
+		var details *model.StorageDetails
+		err := driverCall(ctx, storage, "GetDetails", func(ctx context.Context) (err error) {
+			details, err = wd.GetDetails(ctx)
+			return
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed get details of %s", key)
+		}
+		detailsCache.Set(key, details, cache.WithEx[*model.StorageDetails](time.Minute))
+		return details, nil
+	})
+	return details, err
+}
@@ -167,12 +167,13 @@ func getAdditionalItems(t reflect.Type, defaultRoot string) []driver.Item {
 			continue
 		}
 		item := driver.Item{
-			Name:     name,
-			Type:     strings.ToLower(field.Type.Name()),
-			Default:  tag.Get("default"),
-			Options:  tag.Get("options"),
-			Required: tag.Get("required") == "true",
-			Help:     tag.Get("help"),
+			Name:         name,
+			Type:         strings.ToLower(field.Type.Name()),
+			Default:      tag.Get("default"),
+			Options:      tag.Get("options"),
+			Required:     tag.Get("required") == "true",
+			Help:         tag.Get("help"),
+			Confidential: tag.Get("confidential") == "true",
 		}
 		if tag.Get("type") != "" {
 			item.Type = tag.Get("type")
@@ -9,13 +9,16 @@ import (
 	"github.com/Xhofe/go-cache"
 	"github.com/alist-org/alist/v3/internal/driver"
 	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/metrics"
 	"github.com/alist-org/alist/v3/internal/model"
 	"github.com/alist-org/alist/v3/internal/stream"
+	"github.com/alist-org/alist/v3/internal/tracing"
 	"github.com/alist-org/alist/v3/pkg/generic_sync"
 	"github.com/alist-org/alist/v3/pkg/singleflight"
 	"github.com/alist-org/alist/v3/pkg/utils"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // In order to facilitate adding some other things before and after file op
@@ -107,8 +110,20 @@ func Key(storage driver.Driver, path string) string {
 	return stdpath.Join(storage.GetStorage().MountPath, utils.FixAndCleanPath(path))
 }
 
+// storageSpan starts the span of an operation on path of storage
+func storageSpan(ctx context.Context, storage driver.Driver, name, path string) (context.Context, trace.Span) {
+	return tracing.WithStorage(ctx, name, storage.GetStorage().MountPath, storage.Config().Name, path)
+}
+
 // List files in storage, not contains virtual file
 func List(ctx context.Context, storage driver.Driver, path string, args model.ListArgs) ([]model.Obj, error) {
+	ctx, span := storageSpan(ctx, storage, "op.List", path)
+	objs, err := list(ctx, storage, path, args)
+	tracing.End(span, err)
+	return objs, err
+}
+
+func list(ctx context.Context, storage driver.Driver, path string, args model.ListArgs) ([]model.Obj, error) {
 	if storage.Config().CheckStatus && storage.GetStorage().Status != WORK {
 		return nil, errors.Errorf("storage not init: %s", storage.GetStorage().Status)
 	}
@@ -118,8 +133,10 @@ func List(ctx context.Context, storage driver.Driver, path string, args model.Li
 	if !args.Refresh {
 		if files, ok := listCache.Get(key); ok {
 			log.Debugf("use cache when list %s", path)
+			metrics.ListCache(true)
 			return files, nil
 		}
+		metrics.ListCache(false)
 	}
 	dir, err := GetUnwrap(ctx, storage, path)
 	if err != nil {
@@ -130,7 +147,11 @@ func List(ctx context.Context, storage driver.Driver, path string, args model.Li
 		return nil, errors.WithStack(errs.NotFolder)
 	}
 	objs, err, _ := listG.Do(key, func() ([]model.Obj, error) {
-		files, err := storage.List(ctx, dir, args)
+		var files []model.Obj
+		err := driverCall(ctx, storage, "List", func(ctx context.Context) (err error) {
+			files, err = storage.List(ctx, dir, args)
+			return
+		})
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to list objs")
 		}
@@ -169,12 +190,23 @@ func List(ctx context.Context, storage driver.Driver, path string, args model.Li
 
 // Get object from list of files
 func Get(ctx context.Context, storage driver.Driver, path string) (model.Obj, error) {
+	ctx, span := storageSpan(ctx, storage, "op.Get", path)
+	obj, err := get(ctx, storage, path)
+	tracing.End(span, err)
+	return obj, err
+}
+
+func get(ctx context.Context, storage driver.Driver, path string) (model.Obj, error) {
 	path = utils.FixAndCleanPath(path)
 	log.Debugf("op.Get %s", path)
 
 	// get the obj directly without list so that we can reduce the io
 	if g, ok := storage.(driver.Getter); ok {
-		obj, err := g.Get(ctx, path)
+		var obj model.Obj
+		err := driverCall(ctx, storage, "Get", func(ctx context.Context) (err error) {
+			obj, err = g.Get(ctx, path)
+			return
+		})
 		if err == nil {
 			return model.WrapObjName(obj), nil
 		}
@@ -184,7 +216,11 @@ func Get(ctx context.Context, storage driver.Driver, path string) (model.Obj, er
 	if utils.PathEqual(path, "/") {
 		var rootObj model.Obj
 		if getRooter, ok := storage.(driver.GetRooter); ok {
-			obj, err := getRooter.GetRoot(ctx)
+			var obj model.Obj
+			err := driverCall(ctx, storage, "GetRoot", func(ctx context.Context) (err error) {
+				obj, err = getRooter.GetRoot(ctx)
+				return
+			})
 			if err != nil {
 				return nil, errors.WithMessage(err, "failed get root obj")
 			}
@@ -248,6 +284,13 @@ var linkG singleflight.Group[*model.Link]
 
 // Link get link, if is an url. should have an expiry time
 func Link(ctx context.Context, storage driver.Driver, path string, args model.LinkArgs) (*model.Link, model.Obj, error) {
+	ctx, span := storageSpan(ctx, storage, "op.Link", path)
+	l, file, err := link(ctx, storage, path, args)
+	tracing.End(span, err)
+	return l, file, err
+}
+
+func link(ctx context.Context, storage driver.Driver, path string, args model.LinkArgs) (*model.Link, model.Obj, error) {
 	if storage.Config().CheckStatus && storage.GetStorage().Status != WORK {
 		return nil, nil, errors.Errorf("storage not init: %s", storage.GetStorage().Status)
 	}
@@ -263,7 +306,11 @@ func Link(ctx context.Context, storage driver.Driver, path string, args model.Li
 		return link, file, nil
 	}
 	fn := func() (*model.Link, error) {
-		link, err := storage.Link(ctx, file, args)
+		var link *model.Link
+		err := driverCall(ctx, storage, "Link", func(ctx context.Context) (err error) {
+			link, err = storage.Link(ctx, file, args)
+			return
+		})
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed get link")
 		}
@@ -292,11 +339,16 @@ func Other(ctx context.Context, storage driver.Driver, args model.FsOtherArgs) (
 		return nil, errors.WithMessagef(err, "failed to get obj")
 	}
 	if o, ok := storage.(driver.Other); ok {
-		return o.Other(ctx, model.OtherArgs{
-			Obj:    obj,
-			Method: args.Method,
-			Data:   args.Data,
+		var res interface{}
+		err = driverCall(ctx, storage, "Other", func(ctx context.Context) (err error) {
+			res, err = o.Other(ctx, model.OtherArgs{
+				Obj:    obj,
+				Method: args.Method,
+				Data:   args.Data,
+			})
+			return
 		})
+		return res, err
 	} else {
 		return nil, errs.NotImplement
 	}
@@ -329,7 +381,10 @@ func MakeDir(ctx context.Context, storage driver.Driver, path string, lazyCache
 				switch s := storage.(type) {
 				case driver.MkdirResult:
 					var newObj model.Obj
-					newObj, err = s.MakeDir(ctx, parentDir, dirName)
+					err = driverCall(ctx, storage, "MakeDir", func(ctx context.Context) (err error) {
+						newObj, err = s.MakeDir(ctx, parentDir, dirName)
+						return
+					})
 					if err == nil {
 						if newObj != nil {
 							addCacheObj(storage, parentPath, model.WrapObjName(newObj))
@@ -338,7 +393,9 @@ func MakeDir(ctx context.Context, storage driver.Driver, path string, lazyCache
 						}
 					}
 				case driver.Mkdir:
-					err = s.MakeDir(ctx, parentDir, dirName)
+					err = driverCall(ctx, storage, "MakeDir", func(ctx context.Context) error {
+						return s.MakeDir(ctx, parentDir, dirName)
+					})
 					if err == nil && !utils.IsBool(lazyCache...) {
 						ClearCache(storage, parentPath)
 					}
@@ -379,7 +436,10 @@ func Move(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string
 	switch s := storage.(type) {
 	case driver.MoveResult:
 		var newObj model.Obj
-		newObj, err = s.Move(ctx, srcObj, dstDir)
+		err = driverCall(ctx, storage, "Move", func(ctx context.Context) (err error) {
+			newObj, err = s.Move(ctx, srcObj, dstDir)
+			return
+		})
 		if err == nil {
 			delCacheObj(storage, srcDirPath, srcRawObj)
 			if newObj != nil {
@@ -389,7 +449,9 @@ func Move(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string
 			}
 		}
 	case driver.Move:
-		err = s.Move(ctx, srcObj, dstDir)
+		err = driverCall(ctx, storage, "Move", func(ctx context.Context) error {
+			return s.Move(ctx, srcObj, dstDir)
+		})
 		if err == nil {
 			delCacheObj(storage, srcDirPath, srcRawObj)
 			if !utils.IsBool(lazyCache...) {
@@ -417,7 +479,10 @@ func Rename(ctx context.Context, storage driver.Driver, srcPath, dstName string,
 	switch s := storage.(type) {
 	case driver.RenameResult:
 		var newObj model.Obj
-		newObj, err = s.Rename(ctx, srcObj, dstName)
+		err = driverCall(ctx, storage, "Rename", func(ctx context.Context) (err error) {
+			newObj, err = s.Rename(ctx, srcObj, dstName)
+			return
+		})
 		if err == nil {
 			if newObj != nil {
 				updateCacheObj(storage, srcDirPath, srcRawObj, model.WrapObjName(newObj))
@@ -426,7 +491,9 @@ func Rename(ctx context.Context, storage driver.Driver, srcPath, dstName string,
 			}
 		}
 	case driver.Rename:
-		err = s.Rename(ctx, srcObj, dstName)
+		err = driverCall(ctx, storage, "Rename", func(ctx context.Context) error {
+			return s.Rename(ctx, srcObj, dstName)
+		})
 		if err == nil && !utils.IsBool(lazyCache...) {
 			ClearCache(storage, srcDirPath)
 		}
@@ -455,7 +522,10 @@ func Copy(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string
 	switch s := storage.(type) {
 	case driver.CopyResult:
 		var newObj model.Obj
-		newObj, err = s.Copy(ctx, srcObj, dstDir)
+		err = driverCall(ctx, storage, "Copy", func(ctx context.Context) (err error) {
+			newObj, err = s.Copy(ctx, srcObj, dstDir)
+			return
+		})
 		if err == nil {
 			if newObj != nil {
 				addCacheObj(storage, dstDirPath, model.WrapObjName(newObj))
@@ -464,7 +534,9 @@ func Copy(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string
 			}
 		}
 	case driver.Copy:
-		err = s.Copy(ctx, srcObj, dstDir)
+		err = driverCall(ctx, storage, "Copy", func(ctx context.Context) error {
+			return s.Copy(ctx, srcObj, dstDir)
+		})
 		if err == nil && !utils.IsBool(lazyCache...) {
 			ClearCache(storage, dstDirPath)
 		}
@@ -495,7 +567,9 @@ func Remove(ctx context.Context, storage driver.Driver, path string) error {
 
 	switch s := storage.(type) {
 	case driver.Remove:
-		err = s.Remove(ctx, model.UnwrapObj(rawObj))
+		err = driverCall(ctx, storage, "Remove", func(ctx context.Context) error {
+			return s.Remove(ctx, model.UnwrapObj(rawObj))
+		})
 		if err == nil {
 			delCacheObj(storage, dirPath, rawObj)
 			// clear folder cache recursively
@@ -510,6 +584,13 @@ func Remove(ctx context.Context, storage driver.Driver, path string) error {
 }
 
 func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file model.FileStreamer, up driver.UpdateProgress, lazyCache ...bool) error {
+	ctx, span := storageSpan(ctx, storage, "op.Put", stdpath.Join(dstDirPath, file.GetName()))
+	err := put(ctx, storage, dstDirPath, file, up, lazyCache...)
+	tracing.End(span, err)
+	return err
+}
+
+func put(ctx context.Context, storage driver.Driver, dstDirPath string, file model.FileStreamer, up driver.UpdateProgress, lazyCache ...bool) error {
 	if storage.Config().CheckStatus && storage.GetStorage().Status != WORK {
 		return errors.Errorf("storage not init: %s", storage.GetStorage().Status)
 	}
@@ -530,12 +611,21 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 	tempName := file.GetName() + ".alist_to_delete"
 	tempPath := stdpath.Join(dstDirPath, tempName)
 	fi, err := GetUnwrap(ctx, storage, dstPath)
+	var versionMeta *model.Meta
+	var versionPath string
 	if err == nil {
 		if fi.GetSize() == 0 {
 			err = Remove(ctx, storage, dstPath)
 			if err != nil {
 				return errors.WithMessagef(err, "while uploading, failed remove existing file which size = 0")
 			}
+		} else if versionMeta = getVersioningMeta(storage, dstPath); versionMeta != nil && !fi.IsDir() {
+			// keep the old obj as a version instead of overwriting it
+			versionPath, err = archiveVersion(ctx, storage, dstPath)
+			if err != nil {
+				return errors.WithMessage(err, "while uploading, failed keep previous version")
+			}
+			fi = nil
 		} else if storage.Config().NoOverwriteUpload {
 			// try to rename old obj
 			err = Rename(ctx, storage, dstPath, tempName)
@@ -563,7 +653,10 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 	switch s := storage.(type) {
 	case driver.PutResult:
 		var newObj model.Obj
-		newObj, err = s.Put(ctx, parentDir, file, up)
+		err = driverCall(ctx, storage, "Put", func(ctx context.Context) (err error) {
+			newObj, err = s.Put(ctx, parentDir, file, up)
+			return
+		})
 		if err == nil {
 			if newObj != nil {
 				addCacheObj(storage, dstDirPath, model.WrapObjName(newObj))
@@ -572,7 +665,9 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 			}
 		}
 	case driver.Put:
-		err = s.Put(ctx, parentDir, file, up)
+		err = driverCall(ctx, storage, "Put", func(ctx context.Context) error {
+			return s.Put(ctx, parentDir, file, up)
+		})
 		if err == nil && !utils.IsBool(lazyCache...) {
 			ClearCache(storage, dstDirPath)
 		}
@@ -580,6 +675,17 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 		return errs.NotImplement
 	}
 	log.Debugf("put file [%s] done", file.GetName())
+	if versionPath != "" {
+		if err != nil {
+			// upload failed, recover old obj
+			if err := unarchiveVersion(ctx, storage, versionPath, dstPath); err != nil {
+				log.Errorf("failed recover old obj from version: %+v", err)
+			}
+		} else {
+			linkCache.Del(Key(storage, dstPath))
+			pruneVersions(ctx, storage, dstPath, versionMeta)
+		}
+	}
 	if storage.Config().NoOverwriteUpload && fi != nil && fi.GetSize() > 0 {
 		if err != nil {
 			// upload failed, recover old obj
@@ -621,7 +727,10 @@ func PutURL(ctx context.Context, storage driver.Driver, dstDirPath, dstName, url
 	switch s := storage.(type) {
 	case driver.PutURLResult:
 		var newObj model.Obj
-		newObj, err = s.PutURL(ctx, dstDir, dstName, url)
+		err = driverCall(ctx, storage, "PutURL", func(ctx context.Context) (err error) {
+			newObj, err = s.PutURL(ctx, dstDir, dstName, url)
+			return
+		})
 		if err == nil {
 			if newObj != nil {
 				addCacheObj(storage, dstDirPath, model.WrapObjName(newObj))
@@ -630,7 +739,9 @@ func PutURL(ctx context.Context, storage driver.Driver, dstDirPath, dstName, url
 			}
 		}
 	case driver.PutURL:
-		err = s.PutURL(ctx, dstDir, dstName, url)
+		err = driverCall(ctx, storage, "PutURL", func(ctx context.Context) error {
+			return s.PutURL(ctx, dstDir, dstName, url)
+		})
 		if err == nil && !utils.IsBool(lazyCache...) {
 			ClearCache(storage, dstDirPath)
 		}
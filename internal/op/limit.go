@@ -0,0 +1,59 @@
+package op
+
+import (
+	"context"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/metrics"
+	"github.com/alist-org/alist/v3/internal/ratelimit"
+	"github.com/alist-org/alist/v3/pkg/generic_sync"
+)
+
+// storageLimiters are the limiters of the upstream calls by storage id
+var storageLimiters generic_sync.MapOf[uint, *ratelimit.Limiter]
+
+func storageLimiter(storage driver.Driver) *ratelimit.Limiter {
+	s := storage.GetStorage()
+	l, ok := storageLimiters.Load(s.ID)
+	if ok && l.Is(s.RateLimit, s.MaxConcurrency) {
+		return l
+	}
+	l = ratelimit.New(s.RateLimit, s.MaxConcurrency)
+	if !ok {
+		// the first calls may race, they have to share the limiter
+		actual, loaded := storageLimiters.LoadOrStore(s.ID, l)
+		if !loaded || actual.Is(s.RateLimit, s.MaxConcurrency) {
+			return actual
+		}
+	}
+	storageLimiters.Store(s.ID, l)
+	return l
+}
+
+// GetStorageQueue returns the number of upstream calls of the storage
+// waiting for the limiter and in flight
+func GetStorageQueue(id uint) (waiting, inFlight int64) {
+	if l, ok := storageLimiters.Load(id); ok {
+		return l.Waiting(), l.InFlight()
+	}
+	return 0, 0
+}
+
+// driverCall runs fn, a call of method on storage, within the limits of
+// the storage and records it in the metrics. The limiter is reentrant so
+// that a driver can call op on its own storage
+func driverCall(ctx context.Context, storage driver.Driver, method string, fn func(ctx context.Context) error) error {
+	l := storageLimiter(storage)
+	if ratelimit.FromContext(ctx) != l {
+		release, err := l.Acquire(ctx)
+		if err != nil {
+			return err
+		}
+		defer release()
+		ctx = ratelimit.WithLimiter(ctx, l)
+	}
+	done := metrics.DriverCall(storage.GetStorage().MountPath, storage.Config().Name, method)
+	err := fn(ctx)
+	done(err)
+	return err
+}
@@ -0,0 +1,89 @@
+package op
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+)
+
+// MinRSSInterval is the shortest polling interval of a subscription in minutes
+const MinRSSInterval = 5
+
+func validateRSSSubscription(s *model.RSSSubscription) error {
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid feed url %q", s.URL)
+	}
+	if s.Interval < MinRSSInterval {
+		return fmt.Errorf("interval must be at least %d minutes", MinRSSInterval)
+	}
+	if _, err := regexp.Compile(s.Include); err != nil {
+		return fmt.Errorf("invalid include rule: %w", err)
+	}
+	if _, err := regexp.Compile(s.Exclude); err != nil {
+		return fmt.Errorf("invalid exclude rule: %w", err)
+	}
+	if s.Tool == "" {
+		return fmt.Errorf("tool is required")
+	}
+	s.Path = utils.FixAndCleanPath(s.Path)
+	return nil
+}
+
+func CreateRSSSubscription(s *model.RSSSubscription) error {
+	if err := validateRSSSubscription(s); err != nil {
+		return err
+	}
+	s.CreatedAt = time.Now()
+	s.LastCheck = nil
+	s.LastError = ""
+	return db.CreateRSSSubscription(s)
+}
+
+func UpdateRSSSubscription(s *model.RSSSubscription) error {
+	if err := validateRSSSubscription(s); err != nil {
+		return err
+	}
+	return db.UpdateRSSSubscription(s)
+}
+
+// GetRSSSubscriptions lists the subscriptions of a user, or of every user
+// when userId is 0.
+func GetRSSSubscriptions(userId uint, pageIndex, pageSize int) ([]model.RSSSubscription, int64, error) {
+	return db.GetRSSSubscriptions(userId, pageIndex, pageSize)
+}
+
+func GetEnabledRSSSubscriptions() ([]model.RSSSubscription, error) {
+	return db.GetEnabledRSSSubscriptions()
+}
+
+func GetRSSSubscriptionById(id uint) (*model.RSSSubscription, error) {
+	return db.GetRSSSubscriptionById(id)
+}
+
+func UpdateRSSSubscriptionCheck(id uint, ts time.Time, lastErr string) error {
+	return db.UpdateRSSSubscriptionCheck(id, ts, lastErr)
+}
+
+// DeleteRSSSubscriptionById deletes a subscription along with its history.
+func DeleteRSSSubscriptionById(id uint) error {
+	return db.DeleteRSSSubscriptionById(id)
+}
+
+func GetRSSItems(subscriptionId uint, pageIndex, pageSize int) ([]model.RSSItem, int64, error) {
+	return db.GetRSSItems(subscriptionId, pageIndex, pageSize)
+}
+
+func HasRSSItem(subscriptionId uint, guid string) (bool, error) {
+	return db.HasRSSItem(subscriptionId, guid)
+}
+
+func CreateRSSItem(item *model.RSSItem) error {
+	item.CreatedAt = time.Now()
+	return db.CreateRSSItem(item)
+}
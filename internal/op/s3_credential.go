@@ -0,0 +1,111 @@
+package op
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Xhofe/go-cache"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/alist-org/alist/v3/pkg/utils/random"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var s3CredentialCache = cache.NewMemCache(cache.WithShards[*model.S3Credential](2))
+
+func validateS3Buckets(buckets model.S3Buckets) error {
+	seen := make(map[string]struct{}, len(buckets))
+	for i, b := range buckets {
+		name := strings.TrimSpace(b.Name)
+		if name == "" || strings.Contains(name, "/") {
+			return fmt.Errorf("invalid bucket name %q", b.Name)
+		}
+		if _, ok := seen[name]; ok {
+			return fmt.Errorf("duplicate bucket name %q", name)
+		}
+		seen[name] = struct{}{}
+		buckets[i].Name = name
+		buckets[i].Path = utils.FixAndCleanPath(b.Path)
+	}
+	return nil
+}
+
+// CreateS3Credential generates a new access key pair for c.UserID.
+func CreateS3Credential(c *model.S3Credential) error {
+	if err := validateS3Buckets(c.Buckets); err != nil {
+		return err
+	}
+	c.AccessKeyID = "AK" + strings.ToUpper(random.String(18))
+	c.SecretAccessKey = random.String(40)
+	c.CreatedAt = time.Now()
+	c.LastUsedTime = nil
+	return db.CreateS3Credential(c)
+}
+
+func UpdateS3Credential(c *model.S3Credential) error {
+	if err := validateS3Buckets(c.Buckets); err != nil {
+		return err
+	}
+	s3CredentialCache.Del(c.AccessKeyID)
+	return db.UpdateS3Credential(c)
+}
+
+func GetS3CredentialsByUserId(userId uint, pageIndex, pageSize int) ([]model.S3Credential, int64, error) {
+	return db.GetS3CredentialsByUserId(userId, pageIndex, pageSize)
+}
+
+func GetS3CredentialById(id uint) (*model.S3Credential, error) {
+	return db.GetS3CredentialById(id)
+}
+
+func DeleteS3CredentialById(id uint) error {
+	c, err := db.GetS3CredentialById(id)
+	if err != nil {
+		return err
+	}
+	s3CredentialCache.Del(c.AccessKeyID)
+	forgetTouched("s3:" + c.AccessKeyID)
+	return db.DeleteS3CredentialById(id)
+}
+
+func GetS3CredentialByAccessKey(accessKey string) (*model.S3Credential, error) {
+	if c, ok := s3CredentialCache.Get(accessKey); ok {
+		return c, nil
+	}
+	c, err := db.GetS3CredentialByAccessKey(accessKey)
+	if err != nil {
+		return nil, errors.WithStack(errs.InvalidS3Credential)
+	}
+	s3CredentialCache.Set(accessKey, c, cache.WithEx[*model.S3Credential](10*time.Minute))
+	return c, nil
+}
+
+// GetUserByS3AccessKey returns the enabled credential of accessKey along
+// with its owner.
+func GetUserByS3AccessKey(accessKey string) (*model.User, *model.S3Credential, error) {
+	c, err := GetS3CredentialByAccessKey(accessKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.Disabled {
+		return nil, nil, errors.WithStack(errs.InvalidS3Credential)
+	}
+	user, err := db.GetUserById(c.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := enforceAdminUserDefaults(user); err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	if touchDue("s3:"+c.AccessKeyID, c.LastUsedTime, now) {
+		if err := db.UpdateS3CredentialLastUsed(c.ID, now); err != nil {
+			log.Warnf("failed update last used time of s3 credential %d: %+v", c.ID, err)
+		}
+	}
+	return user, c, nil
+}
@@ -0,0 +1,47 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+)
+
+func TestS3Credential(t *testing.T) {
+	user := &model.User{Username: "s3_user", BasePath: "/team"}
+	if err := db.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	dup := &model.S3Credential{UserID: user.ID, Buckets: model.S3Buckets{{Name: "a", Path: "/"}, {Name: "a", Path: "/x"}}}
+	if err := op.CreateS3Credential(dup); err == nil {
+		t.Errorf("duplicate bucket names accepted")
+	}
+	cred := &model.S3Credential{UserID: user.ID, Buckets: model.S3Buckets{{Name: "docs", Path: "docs/"}}}
+	if err := op.CreateS3Credential(cred); err != nil {
+		t.Fatalf("failed to create credential: %+v", err)
+	}
+	if cred.AccessKeyID == "" || cred.SecretAccessKey == "" || cred.Buckets[0].Path != "/docs" {
+		t.Fatalf("unexpected credential: %+v", cred)
+	}
+	u, c, err := op.GetUserByS3AccessKey(cred.AccessKeyID)
+	if err != nil {
+		t.Fatalf("failed to resolve access key: %+v", err)
+	}
+	if u.ID != user.ID || c.SecretAccessKey != cred.SecretAccessKey {
+		t.Errorf("unexpected user or credential: %+v %+v", u, c)
+	}
+	cred.Disabled = true
+	if err := op.UpdateS3Credential(cred); err != nil {
+		t.Fatalf("failed to update credential: %+v", err)
+	}
+	if _, _, err := op.GetUserByS3AccessKey(cred.AccessKeyID); err == nil {
+		t.Errorf("disabled credential accepted")
+	}
+	if err := op.DeleteS3CredentialById(cred.ID); err != nil {
+		t.Fatalf("failed to delete credential: %+v", err)
+	}
+	if _, _, err := op.GetUserByS3AccessKey(cred.AccessKeyID); err == nil {
+		t.Errorf("deleted credential accepted")
+	}
+}
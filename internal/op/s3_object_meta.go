@@ -0,0 +1,47 @@
+package op
+
+import (
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// GetS3ObjectMeta returns the S3 attributes recorded for the object at
+// rawPath, nil if there are none.
+func GetS3ObjectMeta(rawPath string) (*model.S3ObjectMeta, error) {
+	storage, actualPath, err := GetStorageAndActualPath(rawPath)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed get storage")
+	}
+	m, err := db.GetS3ObjectMeta(storage.GetStorage().ID, actualPath)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return m, err
+}
+
+// UpdateS3ObjectMeta applies update to the S3 attributes of the object at
+// rawPath and saves them, creating the record if needed.
+func UpdateS3ObjectMeta(rawPath string, update func(m *model.S3ObjectMeta)) error {
+	storage, actualPath, err := GetStorageAndActualPath(rawPath)
+	if err != nil {
+		return errors.WithMessage(err, "failed get storage")
+	}
+	m, err := db.GetS3ObjectMeta(storage.GetStorage().ID, actualPath)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		m = &model.S3ObjectMeta{StorageID: storage.GetStorage().ID, Path: actualPath}
+	} else if err != nil {
+		return err
+	}
+	update(m)
+	return db.SaveS3ObjectMeta(m)
+}
+
+func DeleteS3ObjectMeta(rawPath string) error {
+	storage, actualPath, err := GetStorageAndActualPath(rawPath)
+	if err != nil {
+		return errors.WithMessage(err, "failed get storage")
+	}
+	return db.DeleteS3ObjectMeta(storage.GetStorage().ID, actualPath)
+}
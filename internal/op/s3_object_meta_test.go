@@ -0,0 +1,50 @@
+package op_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+)
+
+func TestS3ObjectMeta(t *testing.T) {
+	_, err := op.CreateStorage(context.Background(), model.Storage{Driver: "Local", MountPath: "/s3meta", Addition: `{"root_folder_path":"."}`})
+	if err != nil {
+		t.Fatalf("failed to create storage: %+v", err)
+	}
+	if m, err := op.GetS3ObjectMeta("/s3meta/a.txt"); err != nil || m != nil {
+		t.Fatalf("expected no meta, got %+v %+v", m, err)
+	}
+	err = op.UpdateS3ObjectMeta("/s3meta/a.txt", func(m *model.S3ObjectMeta) {
+		m.ContentType = "text/x-test"
+		m.Headers = model.StringMap{"X-Amz-Meta-Foo": "bar"}
+		m.MD5 = "5d41402abc4b2a76b9719d911017c592"
+		m.Size = 5
+	})
+	if err != nil {
+		t.Fatalf("failed to save meta: %+v", err)
+	}
+	err = op.UpdateS3ObjectMeta("/s3meta/a.txt", func(m *model.S3ObjectMeta) {
+		m.Tags = model.StringMap{"k": "v"}
+	})
+	if err != nil {
+		t.Fatalf("failed to update meta: %+v", err)
+	}
+	m, err := op.GetS3ObjectMeta("/s3meta/a.txt")
+	if err != nil || m == nil {
+		t.Fatalf("failed to get meta: %+v", err)
+	}
+	if m.ContentType != "text/x-test" || m.Headers["X-Amz-Meta-Foo"] != "bar" || m.Tags["k"] != "v" {
+		t.Errorf("unexpected meta: %+v", m)
+	}
+	if m.ContentMD5(5) == "" || m.ContentMD5(6) != "" {
+		t.Errorf("md5 should only match the recorded size")
+	}
+	if err := op.DeleteS3ObjectMeta("/s3meta/a.txt"); err != nil {
+		t.Fatalf("failed to delete meta: %+v", err)
+	}
+	if m, _ := op.GetS3ObjectMeta("/s3meta/a.txt"); m != nil {
+		t.Errorf("meta not deleted: %+v", m)
+	}
+}
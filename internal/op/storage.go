@@ -310,11 +310,8 @@ func DeleteStorageById(ctx context.Context, id uint) error {
 			return errors.Errorf("storage is used by %s, please cancel usage first", strings.Join(usedBy, ", "))
 		}
 	}
-	if !storage.Disabled {
-		storageDriver, err := GetStorageByMountPath(storage.MountPath)
-		if err != nil {
-			return errors.WithMessage(err, "failed get storage driver")
-		}
+	// the offline commands don't load the storages, there is nothing to drop then
+	if storageDriver, ok := storagesMap.Load(storage.MountPath); ok && !storage.Disabled {
 		// drop the storage in the driver
 		if err := storageDriver.Drop(ctx); err != nil {
 			return errors.Wrapf(err, "failed drop storage")
@@ -327,6 +324,10 @@ func DeleteStorageById(ctx context.Context, id uint) error {
 	if err := db.DeleteStorageById(id); err != nil {
 		return errors.WithMessage(err, "failed delete storage in database")
 	}
+	storageLimiters.Delete(id)
+	if err := db.DeleteS3ObjectMetasByStorageId(id); err != nil {
+		log.Warnf("failed delete s3 object meta of storage %d: %+v", id, err)
+	}
 	return nil
 }
 
@@ -0,0 +1,165 @@
+package op
+
+import (
+	"context"
+	"fmt"
+	"io"
+	stdpath "path"
+	"regexp"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/stream"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+const maxRenameAttempts = 1000
+
+// getUploadPolicyMeta returns the meta whose upload policy applies to files put into dirPath, or nil
+func getUploadPolicyMeta(storage driver.Driver, dirPath string) *model.Meta {
+	mountPath := utils.GetActualMountPath(storage.GetStorage().MountPath)
+	dir := stdpath.Join(mountPath, dirPath)
+	meta, err := GetNearestMeta(dir)
+	if err != nil {
+		return nil
+	}
+	if !meta.USub && !utils.PathEqual(meta.Path, dir) {
+		return nil
+	}
+	if meta.UploadMaxSize <= 0 && meta.UploadAllow == "" && meta.UploadDeny == "" &&
+		meta.UploadNameRegex == "" && !meta.UploadRename {
+		return nil
+	}
+	return meta
+}
+
+// HasUploadPolicy reports whether files put into dirPath of storage are
+// checked by an upload policy, the native copy of a driver would bypass it
+func HasUploadPolicy(storage driver.Driver, dirPath string) bool {
+	return getUploadPolicyMeta(storage, utils.FixAndCleanPath(dirPath)) != nil
+}
+
+// CheckUploadPolicy enforces the upload policy of the nearest meta on a file
+// about to be put into dstDirPath of storage. If the policy asks to rename
+// on conflict, the file is renamed to a free name.
+func CheckUploadPolicy(ctx context.Context, storage driver.Driver, dstDirPath string, file model.FileStreamer) error {
+	meta := getUploadPolicyMeta(storage, utils.FixAndCleanPath(dstDirPath))
+	if meta == nil {
+		return nil
+	}
+	name := file.GetName()
+	if meta.UploadMaxSize > 0 {
+		if err := limitUploadSize(file, meta.UploadMaxSize); err != nil {
+			return err
+		}
+	}
+	mimetype := file.GetMimetype()
+	if mimetype == "" {
+		mimetype = utils.GetMimeType(name)
+	}
+	if meta.UploadDeny != "" && matchTypes(meta.UploadDeny, name, mimetype) {
+		return errs.NewErr(errs.UploadNotAllowed, "type of [%s] is denied", name)
+	}
+	if meta.UploadAllow != "" && !matchTypes(meta.UploadAllow, name, mimetype) {
+		return errs.NewErr(errs.UploadNotAllowed, "type of [%s] is not allowed", name)
+	}
+	if meta.UploadNameRegex != "" {
+		reg, err := regexp.Compile(meta.UploadNameRegex)
+		if err != nil {
+			return errors.WithMessagef(err, "invalid upload name regex of meta [%s]", meta.Path)
+		}
+		if !reg.MatchString(name) {
+			return errs.NewErr(errs.UploadNotAllowed, "name [%s] doesn't match %s", name, meta.UploadNameRegex)
+		}
+	}
+	if meta.UploadRename {
+		return renameOnConflict(ctx, storage, dstDirPath, file)
+	}
+	return nil
+}
+
+// limitUploadSize checks the size of file, the declared size comes from the
+// client so the reader also fails once more than max bytes are read.
+func limitUploadSize(file model.FileStreamer, max int64) error {
+	size := file.GetSize()
+	if size < 0 {
+		return errs.NewErr(errs.UploadNotAllowed, "size of [%s] is unknown but limited to %d bytes", file.GetName(), max)
+	}
+	if size > max {
+		return errs.NewErr(errs.UploadNotAllowed, "size of [%s] exceeds %d bytes", file.GetName(), max)
+	}
+	fs, ok := file.(*stream.FileStream)
+	if !ok || fs.Reader == nil {
+		return nil
+	}
+	// a temp file is already written, its size is known
+	if _, ok := fs.Reader.(model.File); ok {
+		return nil
+	}
+	fs.Reader = &maxSizeReader{Reader: fs.Reader, name: file.GetName(), max: max}
+	return nil
+}
+
+type maxSizeReader struct {
+	io.Reader
+	name string
+	max  int64
+	read int64
+}
+
+func (r *maxSizeReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.read += int64(n)
+	if r.read > r.max {
+		return n, errs.NewErr(errs.UploadNotAllowed, "size of [%s] exceeds %d bytes", r.name, r.max)
+	}
+	return n, err
+}
+
+// matchTypes reports whether a file matches any of the extensions (".exe", "exe")
+// or mime types ("application/zip", "image/*") in list
+func matchTypes(list, name, mimetype string) bool {
+	ext := strings.ToLower(utils.Ext(name))
+	mimetype = strings.ToLower(mimetype)
+	for _, item := range strings.FieldsFunc(strings.ToLower(list), func(r rune) bool {
+		return r == ',' || r == '\n' || r == ' ' || r == '\r'
+	}) {
+		if strings.Contains(item, "/") {
+			if ok, _ := stdpath.Match(item, mimetype); ok {
+				return true
+			}
+			continue
+		}
+		if strings.TrimPrefix(item, ".") == ext {
+			return true
+		}
+	}
+	return false
+}
+
+func renameOnConflict(ctx context.Context, storage driver.Driver, dstDirPath string, file model.FileStreamer) error {
+	name := file.GetName()
+	if _, err := GetUnwrap(ctx, storage, stdpath.Join(dstDirPath, name)); err != nil {
+		return nil
+	}
+	s, ok := file.(interface{ SetName(string) })
+	if !ok {
+		return errs.NewErr(errs.UploadNotAllowed, "[%s] exists and can't be renamed", name)
+	}
+	ext := stdpath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; i <= maxRenameAttempts; i++ {
+		newName := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if _, err := GetUnwrap(ctx, storage, stdpath.Join(dstDirPath, newName)); err != nil {
+			if !errs.IsObjectNotFound(err) {
+				return err
+			}
+			s.SetName(newName)
+			return nil
+		}
+	}
+	return errs.NewErr(errs.UploadNotAllowed, "no free name for [%s]", name)
+}
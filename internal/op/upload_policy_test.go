@@ -0,0 +1,85 @@
+package op_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/stream"
+	"github.com/alist-org/alist/v3/internal/testutil"
+)
+
+func TestCheckUploadPolicy(t *testing.T) {
+	ctx := context.Background()
+	d, dir := testutil.CreateLocal(t, "/policy")
+	if err := op.CreateMeta(&model.Meta{
+		Path:            "/policy/drop",
+		UploadMaxSize:   100,
+		UploadAllow:     ".txt, .exe, image/*",
+		UploadDeny:      "exe",
+		UploadNameRegex: `^[a-z0-9 ()]+\.[a-z]+$`,
+		UploadRename:    true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "drop", "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "drop", "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	newFile := func(name string, size int64) *stream.FileStream {
+		return &stream.FileStream{Obj: &model.Object{Name: name, Size: size}}
+	}
+	tests := []struct {
+		dir     string
+		name    string
+		size    int64
+		allowed bool
+	}{
+		{"/drop", "b.txt", 10, true},
+		{"/drop", "b.png", 10, true},
+		{"/drop", "b.txt", 1000, false},
+		// chunked uploads don't declare their size
+		{"/drop", "b.txt", -1, false},
+		{"/drop", "setup.exe", 10, false},
+		{"/drop", "b.zip", 10, false},
+		{"/drop", "B.txt", 10, false},
+		// the policy doesn't apply to sub folders
+		{"/drop/sub", "setup.exe", 10, true},
+	}
+	for _, tt := range tests {
+		err := op.CheckUploadPolicy(ctx, d, tt.dir, newFile(tt.name, tt.size))
+		if tt.allowed && err != nil {
+			t.Errorf("expected %s/%s to be allowed, got %v", tt.dir, tt.name, err)
+		}
+		if !tt.allowed && !errors.Is(err, errs.UploadNotAllowed) {
+			t.Errorf("expected %s/%s to be rejected, got %v", tt.dir, tt.name, err)
+		}
+	}
+
+	// the declared size is not trusted
+	lying := newFile("c.txt", 10)
+	lying.Reader = strings.NewReader(strings.Repeat("c", 200))
+	if err := op.CheckUploadPolicy(ctx, d, "/drop", lying); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(lying); !errors.Is(err, errs.UploadNotAllowed) {
+		t.Errorf("expected reading past the limit to fail, got %v", err)
+	}
+
+	file := newFile("a.txt", 1)
+	if err := op.CheckUploadPolicy(ctx, d, "/drop", file); err != nil {
+		t.Fatal(err)
+	}
+	if file.GetName() != "a (1).txt" {
+		t.Errorf("expected conflicting file to be renamed to a (1).txt, got %s", file.GetName())
+	}
+}
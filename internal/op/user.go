@@ -142,6 +142,15 @@ func DeleteUserById(id uint) error {
 		return errs.DeleteAdminOrGuest
 	}
 	userCache.Del(old.Username)
+	if err := db.DeleteAPITokensByUserId(id); err != nil {
+		return err
+	}
+	if err := db.DeleteS3CredentialsByUserId(id); err != nil {
+		return err
+	}
+	if err := db.DeleteRSSSubscriptionsByUserId(id); err != nil {
+		return err
+	}
 	return db.DeleteUserById(id)
 }
 
@@ -0,0 +1,36 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+)
+
+func TestCountEnabledAdminsExcluding(t *testing.T) {
+	role, err := op.GetRoleByName("admin")
+	if err != nil {
+		role = &model.Role{Name: "admin"}
+		if err = op.CreateRole(role); err != nil {
+			t.Fatalf("failed to create role: %+v", err)
+		}
+	}
+	admin := &model.User{Username: "count_admin", Role: model.Roles{int(role.ID)}}
+	other := &model.User{Username: "count_other_admin", Role: model.Roles{int(role.ID)}, Disabled: true}
+	for _, u := range []*model.User{admin, other} {
+		if err := db.CreateUser(u); err != nil {
+			t.Fatalf("failed to create user: %+v", err)
+		}
+	}
+	if count, err := op.CountEnabledAdminsExcluding(admin.ID); err != nil || count != 0 {
+		t.Fatalf("expected no other enabled admin, got %d: %v", count, err)
+	}
+	other.Disabled = false
+	if err := db.UpdateUser(other); err != nil {
+		t.Fatalf("failed to update user: %+v", err)
+	}
+	if count, err := op.CountEnabledAdminsExcluding(admin.ID); err != nil || count != 1 {
+		t.Fatalf("expected one other enabled admin, got %d: %v", count, err)
+	}
+}
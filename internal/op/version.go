@@ -0,0 +1,223 @@
+package op
+
+import (
+	"context"
+	"net/http"
+	stdpath "path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/stream"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// VersionsDirName is the hidden folder next to a versioned file,
+// previous contents of <dir>/<name> are kept as <dir>/.versions/<name>/<timestamp>
+const VersionsDirName = ".versions"
+
+const versionTimeFormat = "20060102T150405.000000Z"
+
+func IsVersionPath(path string) bool {
+	for _, name := range strings.Split(path, "/") {
+		if name == VersionsDirName {
+			return true
+		}
+	}
+	return false
+}
+
+func versionsDir(path string) string {
+	return stdpath.Join(stdpath.Dir(path), VersionsDirName, stdpath.Base(path))
+}
+
+func parseVersion(name string) (time.Time, bool) {
+	t, err := time.Parse(versionTimeFormat, name)
+	return t, err == nil
+}
+
+// getVersioningMeta returns the meta enabling versioning for the file at path, or nil
+func getVersioningMeta(storage driver.Driver, path string) *model.Meta {
+	if IsVersionPath(path) {
+		return nil
+	}
+	mountPath := utils.GetActualMountPath(storage.GetStorage().MountPath)
+	dir := stdpath.Dir(stdpath.Join(mountPath, path))
+	meta, err := GetNearestMeta(dir)
+	if err != nil || !meta.Versioning {
+		return nil
+	}
+	if !meta.VSub && !utils.PathEqual(meta.Path, dir) {
+		return nil
+	}
+	return meta
+}
+
+// archiveVersion moves the file at path into its versions folder
+// and returns the path of the created version
+func archiveVersion(ctx context.Context, storage driver.Driver, path string) (string, error) {
+	dir := versionsDir(path)
+	if err := MakeDir(ctx, storage, dir); err != nil {
+		return "", errors.WithMessagef(err, "failed to make versions dir [%s]", dir)
+	}
+	versionPath := stdpath.Join(dir, time.Now().UTC().Format(versionTimeFormat))
+	if err := moveFile(ctx, storage, path, versionPath); err != nil {
+		return "", errors.WithMessage(err, "failed to move file to versions dir")
+	}
+	return versionPath, nil
+}
+
+// unarchiveVersion moves a version back to path
+func unarchiveVersion(ctx context.Context, storage driver.Driver, versionPath, path string) error {
+	if err := moveFile(ctx, storage, versionPath, path); err != nil {
+		return errors.WithMessage(err, "failed to move version back")
+	}
+	return nil
+}
+
+// moveFile moves the file at src to dst of the same storage. The file is
+// streamed to dst and removed from src if the storage can't move or rename.
+func moveFile(ctx context.Context, storage driver.Driver, src, dst string) error {
+	err := moveAndRename(ctx, storage, src, dst)
+	if !errors.Is(err, errs.NotImplement) && !errors.Is(err, errs.NotSupport) {
+		return err
+	}
+	return copyAndRemove(ctx, storage, src, dst)
+}
+
+func moveAndRename(ctx context.Context, storage driver.Driver, src, dst string) error {
+	dstDir := stdpath.Dir(dst)
+	if err := Move(ctx, storage, src, dstDir); err != nil {
+		return err
+	}
+	moved := stdpath.Join(dstDir, stdpath.Base(src))
+	if err := Rename(ctx, storage, moved, stdpath.Base(dst)); err != nil {
+		// the file would be left under a name nothing looks for
+		if err := Move(ctx, storage, moved, stdpath.Dir(src)); err != nil {
+			log.Errorf("failed to move [%s] back: %+v", moved, err)
+		}
+		return err
+	}
+	return nil
+}
+
+func copyAndRemove(ctx context.Context, storage driver.Driver, src, dst string) error {
+	obj, err := GetUnwrap(ctx, storage, src)
+	if err != nil {
+		return err
+	}
+	link, _, err := Link(ctx, storage, src, model.LinkArgs{Header: http.Header{}})
+	if err != nil {
+		return errors.WithMessagef(err, "failed get [%s] link", src)
+	}
+	ss, err := stream.NewSeekableStream(stream.FileStream{
+		Obj: &model.Object{
+			Name:     stdpath.Base(dst),
+			Size:     obj.GetSize(),
+			Modified: obj.ModTime(),
+			HashInfo: obj.GetHash(),
+		},
+		Ctx: ctx,
+	}, link)
+	if err != nil {
+		return errors.WithMessagef(err, "failed get [%s] stream", src)
+	}
+	if err = put(ctx, storage, stdpath.Dir(dst), ss, nil); err != nil {
+		return err
+	}
+	if err = Remove(ctx, storage, src); err != nil {
+		if err := Remove(ctx, storage, dst); err != nil {
+			log.Errorf("failed to remove the copy [%s]: %+v", dst, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// pruneVersions applies the retention rules of meta to the versions of the file at path
+func pruneVersions(ctx context.Context, storage driver.Driver, path string, meta *model.Meta) {
+	if meta.VersionsKeep <= 0 && meta.VersionsDays <= 0 {
+		return
+	}
+	versions, err := ListVersions(ctx, storage, path)
+	if err != nil {
+		log.Warnf("failed list versions of [%s]: %+v", path, err)
+		return
+	}
+	deadline := time.Now().AddDate(0, 0, -meta.VersionsDays)
+	dir := versionsDir(path)
+	for i, v := range versions {
+		t, _ := parseVersion(v.GetName())
+		if (meta.VersionsKeep > 0 && i >= meta.VersionsKeep) || (meta.VersionsDays > 0 && t.Before(deadline)) {
+			if err := Remove(ctx, storage, stdpath.Join(dir, v.GetName())); err != nil {
+				log.Warnf("failed remove version [%s] of [%s]: %+v", v.GetName(), path, err)
+			}
+		}
+	}
+}
+
+// ListVersions returns the kept versions of the file at path, newest first
+func ListVersions(ctx context.Context, storage driver.Driver, path string) ([]model.Obj, error) {
+	path = utils.FixAndCleanPath(path)
+	objs, err := List(ctx, storage, versionsDir(path), model.ListArgs{})
+	if err != nil {
+		if errs.IsObjectNotFound(err) {
+			return []model.Obj{}, nil
+		}
+		return nil, err
+	}
+	versions := make([]model.Obj, 0, len(objs))
+	for _, obj := range objs {
+		if _, ok := parseVersion(obj.GetName()); ok && !obj.IsDir() {
+			versions = append(versions, obj)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].GetName() > versions[j].GetName()
+	})
+	return versions, nil
+}
+
+// GetVersionPath returns the actual path of a version of the file at path
+func GetVersionPath(path, version string) (string, error) {
+	if _, ok := parseVersion(version); !ok {
+		return "", errors.Errorf("invalid version: %s", version)
+	}
+	return stdpath.Join(versionsDir(utils.FixAndCleanPath(path)), version), nil
+}
+
+// RestoreVersion replaces the file at path with one of its versions,
+// the current content is kept as a new version
+func RestoreVersion(ctx context.Context, storage driver.Driver, path, version string) error {
+	if storage.Config().CheckStatus && storage.GetStorage().Status != WORK {
+		return errors.Errorf("storage not init: %s", storage.GetStorage().Status)
+	}
+	path = utils.FixAndCleanPath(path)
+	versionPath, err := GetVersionPath(path, version)
+	if err != nil {
+		return err
+	}
+	if _, err = GetUnwrap(ctx, storage, versionPath); err != nil {
+		return errors.WithMessage(err, "failed to get version")
+	}
+	if _, err = GetUnwrap(ctx, storage, path); err == nil {
+		if _, err = archiveVersion(ctx, storage, path); err != nil {
+			return errors.WithMessage(err, "failed to keep current version")
+		}
+	} else if !errs.IsObjectNotFound(err) {
+		return err
+	}
+	if err = unarchiveVersion(ctx, storage, versionPath, path); err != nil {
+		return err
+	}
+	linkCache.Del(Key(storage, path))
+	if meta := getVersioningMeta(storage, path); meta != nil {
+		pruneVersions(ctx, storage, path, meta)
+	}
+	return nil
+}
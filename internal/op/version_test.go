@@ -0,0 +1,180 @@
+package op_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alist-org/alist/v3/drivers/local"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/stream"
+	"github.com/alist-org/alist/v3/internal/testutil"
+)
+
+func TestVersioning(t *testing.T) {
+	ctx := context.Background()
+	d, dir := testutil.CreateLocal(t, "/versioning")
+	if err := op.CreateMeta(&model.Meta{Path: "/versioning", Versioning: true, VSub: true, VersionsKeep: 2}); err != nil {
+		t.Fatal(err)
+	}
+	put := func(content string) {
+		file := &stream.FileStream{
+			Obj:    &model.Object{Name: "doc.txt", Size: int64(len(content))},
+			Reader: strings.NewReader(content),
+		}
+		if err := op.Put(ctx, d, "/sub", file, nil); err != nil {
+			t.Fatalf("failed to put: %+v", err)
+		}
+	}
+	read := func() string {
+		data, err := os.ReadFile(filepath.Join(dir, "sub", "doc.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	for _, content := range []string{"v1", "v2", "v3", "v4"} {
+		put(content)
+	}
+	if got := read(); got != "v4" {
+		t.Fatalf("expected v4, got %s", got)
+	}
+	versions, err := op.ListVersions(ctx, d, "/sub/doc.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("expected 2 versions kept, got %d", len(versions))
+	}
+	objs, err := op.List(ctx, d, "/sub", model.ListArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, obj := range objs {
+		if obj.GetName() != "doc.txt" && obj.GetName() != op.VersionsDirName {
+			t.Errorf("unexpected object %s", obj.GetName())
+		}
+	}
+
+	// the oldest kept version is v2
+	if err := op.RestoreVersion(ctx, d, "/sub/doc.txt", versions[1].GetName()); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(); got != "v2" {
+		t.Fatalf("expected v2 after restore, got %s", got)
+	}
+	versions, err = op.ListVersions(ctx, d, "/sub/doc.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("expected 2 versions kept after restore, got %d", len(versions))
+	}
+	link, err := os.Open(filepath.Join(dir, "sub", op.VersionsDirName, "doc.txt", versions[0].GetName()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer link.Close()
+	data, _ := io.ReadAll(link)
+	if string(data) != "v4" {
+		t.Fatalf("expected newest version v4, got %s", data)
+	}
+	if err := op.RestoreVersion(ctx, d, "/sub/doc.txt", "../../doc.txt"); err == nil {
+		t.Fatal("expected invalid version to fail")
+	}
+}
+
+// noMoveLocal is a local storage that can't move or rename files, the
+// methods of Local are hidden by ones the driver interfaces don't match
+type noMoveLocal struct{ local.Local }
+
+func (d *noMoveLocal) Config() driver.Config {
+	config := d.Local.Config()
+	config.Name = "NoMoveLocal"
+	return config
+}
+func (d *noMoveLocal) Move()   {}
+func (d *noMoveLocal) Rename() {}
+
+// failRenameLocal is a local storage whose renames fail
+type failRenameLocal struct{ local.Local }
+
+func (d *failRenameLocal) Config() driver.Config {
+	config := d.Local.Config()
+	config.Name = "FailRenameLocal"
+	return config
+}
+func (d *failRenameLocal) Rename(context.Context, model.Obj, string) error {
+	return errors.New("rename failed")
+}
+
+func init() {
+	op.RegisterDriver(func() driver.Driver { return &noMoveLocal{} })
+	op.RegisterDriver(func() driver.Driver { return &failRenameLocal{} })
+}
+
+func createVersioned(t *testing.T, driverName, mountPath string) (driver.Driver, string) {
+	d, dir := testutil.CreateStorage(t, driverName, mountPath)
+	if err := op.CreateMeta(&model.Meta{Path: mountPath, Versioning: true, VSub: true}); err != nil {
+		t.Fatal(err)
+	}
+	return d, dir
+}
+
+func putContent(ctx context.Context, d driver.Driver, dir, name, content string) error {
+	return op.Put(ctx, d, dir, &stream.FileStream{
+		Obj:    &model.Object{Name: name, Size: int64(len(content))},
+		Reader: strings.NewReader(content),
+	}, nil)
+}
+
+func TestVersioningWithoutMove(t *testing.T) {
+	ctx := context.Background()
+	d, dir := createVersioned(t, "NoMoveLocal", "/versioning_copy")
+	for _, content := range []string{"v1", "v2"} {
+		if err := putContent(ctx, d, "/", "doc.txt", content); err != nil {
+			t.Fatalf("failed to put: %+v", err)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "doc.txt")); string(data) != "v2" {
+		t.Fatalf("expected v2, got %s", data)
+	}
+	versions, err := op.ListVersions(ctx, d, "/doc.txt")
+	if err != nil || len(versions) != 1 {
+		t.Fatalf("expected 1 version, got %d %v", len(versions), err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, op.VersionsDirName, "doc.txt", versions[0].GetName()))
+	if string(data) != "v1" {
+		t.Fatalf("expected version v1, got %s", data)
+	}
+	if err = op.RestoreVersion(ctx, d, "/doc.txt", versions[0].GetName()); err != nil {
+		t.Fatalf("failed to restore: %+v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "doc.txt")); string(data) != "v1" {
+		t.Fatalf("expected v1 after restore, got %s", data)
+	}
+}
+
+func TestVersioningFailedArchive(t *testing.T) {
+	ctx := context.Background()
+	d, dir := createVersioned(t, "FailRenameLocal", "/versioning_undo")
+	if err := putContent(ctx, d, "/", "doc.txt", "v1"); err != nil {
+		t.Fatalf("failed to put: %+v", err)
+	}
+	if err := putContent(ctx, d, "/", "doc.txt", "v2"); err == nil {
+		t.Fatal("expected the put to fail without a version")
+	}
+	// the live file is moved back instead of being left in the versions dir
+	if data, _ := os.ReadFile(filepath.Join(dir, "doc.txt")); string(data) != "v1" {
+		t.Fatalf("expected v1 to be kept, got %s", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, op.VersionsDirName, "doc.txt", "doc.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected no file left in the versions dir, got %v", err)
+	}
+}
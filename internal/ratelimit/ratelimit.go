@@ -0,0 +1,127 @@
+// Package ratelimit limits the calls of a storage to its upstream API: a
+// QPS limit, a cap on the calls in flight and the pauses asked by the
+// upstream with 429 or Retry-After.
+package ratelimit
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// MaxPause caps the pause asked by an upstream
+const MaxPause = 10 * time.Minute
+
+type Limiter struct {
+	qps            float64
+	maxConcurrency int
+	rate           *rate.Limiter
+	slots          chan struct{}
+	waiting        atomic.Int64
+	inFlight       atomic.Int64
+	// pausedUntil is the unix nano time before which no call is made
+	pausedUntil atomic.Int64
+}
+
+// New limits the calls to qps per second and maxConcurrency in flight,
+// no limit applies if zero
+func New(qps float64, maxConcurrency int) *Limiter {
+	l := &Limiter{qps: qps, maxConcurrency: maxConcurrency}
+	if qps > 0 {
+		burst := int(qps)
+		if burst < 1 {
+			burst = 1
+		}
+		l.rate = rate.NewLimiter(rate.Limit(qps), burst)
+	}
+	if maxConcurrency > 0 {
+		l.slots = make(chan struct{}, maxConcurrency)
+	}
+	return l
+}
+
+// Is reports whether l applies the given limits
+func (l *Limiter) Is(qps float64, maxConcurrency int) bool {
+	return l.qps == qps && l.maxConcurrency == maxConcurrency
+}
+
+// Acquire waits for the pause, a free slot and a token, the returned func
+// frees the slot once the call is done
+func (l *Limiter) Acquire(ctx context.Context) (func(), error) {
+	l.waiting.Add(1)
+	defer l.waiting.Add(-1)
+	for {
+		d := time.Until(time.Unix(0, l.pausedUntil.Load()))
+		if d <= 0 {
+			break
+		}
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+	if l.slots != nil {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case l.slots <- struct{}{}:
+		}
+	}
+	if l.rate != nil {
+		if err := l.rate.Wait(ctx); err != nil {
+			if l.slots != nil {
+				<-l.slots
+			}
+			return nil, err
+		}
+	}
+	l.inFlight.Add(1)
+	return func() {
+		l.inFlight.Add(-1)
+		if l.slots != nil {
+			<-l.slots
+		}
+	}, nil
+}
+
+// Pause holds the calls not started yet for d
+func (l *Limiter) Pause(d time.Duration) {
+	if d > MaxPause {
+		d = MaxPause
+	}
+	until := time.Now().Add(d).UnixNano()
+	for {
+		cur := l.pausedUntil.Load()
+		if cur >= until || l.pausedUntil.CompareAndSwap(cur, until) {
+			return
+		}
+	}
+}
+
+// Waiting is the number of calls queued
+func (l *Limiter) Waiting() int64 {
+	return l.waiting.Load()
+}
+
+// InFlight is the number of calls running
+func (l *Limiter) InFlight() int64 {
+	return l.inFlight.Load()
+}
+
+type limiterKey struct{}
+
+// WithLimiter marks the calls made with ctx as holding a slot of l
+func WithLimiter(ctx context.Context, l *Limiter) context.Context {
+	return context.WithValue(ctx, limiterKey{}, l)
+}
+
+// FromContext returns the limiter set by WithLimiter, nil if none
+func FromContext(ctx context.Context) *Limiter {
+	l, _ := ctx.Value(limiterKey{}).(*Limiter)
+	return l
+}
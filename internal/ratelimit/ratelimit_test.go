@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMaxConcurrency(t *testing.T) {
+	l := New(0, 1)
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.InFlight() != 1 {
+		t.Fatalf("expected 1 call in flight, got %d", l.InFlight())
+	}
+	done := make(chan error)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_, err := l.Acquire(ctx)
+		done <- err
+	}()
+	for l.Waiting() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the queued call to be canceled, got %v", err)
+	}
+	release()
+	if _, err = l.Acquire(context.Background()); err != nil || l.Waiting() != 0 {
+		t.Fatalf("expected the slot to be free: %v", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer upstream.Close()
+	l := New(0, 0)
+	client := &http.Client{Transport: Transport(nil)}
+	req, _ := http.NewRequestWithContext(WithLimiter(context.Background(), l), http.MethodGet, upstream.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err = l.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the storage to be paused, got %v", err)
+	}
+
+	for v, want := range map[string]time.Duration{"120": 2 * time.Minute, "0": 0, "": -1, "soon": -1, "-1": -1} {
+		d, ok := RetryAfter(v)
+		if (want < 0 && ok) || (want >= 0 && (!ok || d != want)) {
+			t.Fatalf("RetryAfter(%q) = %v, %v", v, d, ok)
+		}
+	}
+	if d, ok := RetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)); !ok || d < 59*time.Minute {
+		t.Fatalf("unexpected delay for an HTTP date: %v %v", d, ok)
+	}
+}
@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultPause is the pause after a 429 without Retry-After
+const defaultPause = time.Second
+
+type transport struct {
+	base http.RoundTripper
+}
+
+// Transport wraps base so that a 429, or a 503 with Retry-After, pauses
+// the limiter of the request context
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return resp, nil
+	}
+	l := FromContext(req.Context())
+	if l == nil {
+		return resp, nil
+	}
+	if d, ok := RetryAfter(resp.Header.Get("Retry-After")); ok {
+		l.Pause(d)
+	} else if resp.StatusCode == http.StatusTooManyRequests {
+		l.Pause(defaultPause)
+	}
+	return resp, nil
+}
+
+// RetryAfter parses a Retry-After header, delay-seconds or an HTTP date
+func RetryAfter(v string) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
@@ -78,6 +78,11 @@ func (f *FileStream) SetExist(obj model.Obj) {
 	f.Exist = obj
 }
 
+// SetName changes the name the stream is uploaded as
+func (f *FileStream) SetName(name string) {
+	f.Obj = &model.ObjWrapName{Name: name, Obj: f.Obj}
+}
+
 // CacheFullInTempFile save all data into tmpFile. Not recommended since it wears disk,
 // and can't start upload until the file is written. It's not thread-safe!
 func (f *FileStream) CacheFullInTempFile() (model.File, error) {
@@ -88,3 +88,9 @@ type TaskExtensionInfo interface {
 	GetEndTime() *time.Time
 	GetTotalBytes() int64
 }
+
+// TaskWithDetails is implemented by tasks that have more to show than their
+// status, like the result of every file they handled
+type TaskWithDetails interface {
+	GetDetails() any
+}
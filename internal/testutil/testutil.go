@@ -0,0 +1,54 @@
+// Package testutil holds the fixtures shared by the tests of the other
+// packages, it must not be imported by non-test code
+package testutil
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	_ "github.com/alist-org/alist/v3/drivers/local"
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// InitDB resets the config to the defaults and opens the in-memory
+// database, which is shared by all the tests of a package
+func InitDB() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig()
+	db.Init(dB)
+}
+
+// CreateLocal mounts a Local storage at mountPath rooted at a temporary
+// directory until the end of the test and returns the storage and the
+// directory
+func CreateLocal(t testing.TB, mountPath string) (driver.Driver, string) {
+	return CreateStorage(t, "Local", mountPath)
+}
+
+// CreateStorage is like CreateLocal for the drivers taking the
+// root_folder_path of Local
+func CreateStorage(t testing.TB, driverName, mountPath string) (driver.Driver, string) {
+	t.Helper()
+	dir := t.TempDir()
+	addition, _ := json.Marshal(map[string]string{"root_folder_path": dir})
+	id, err := op.CreateStorage(context.Background(), model.Storage{Driver: driverName, MountPath: mountPath, Addition: string(addition)})
+	if err != nil {
+		t.Fatalf("failed to create storage: %+v", err)
+	}
+	t.Cleanup(func() { _ = op.DeleteStorageById(context.Background(), id) })
+	storage, err := op.GetStorageByMountPath(mountPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return storage, dir
+}
@@ -0,0 +1,113 @@
+// Package tracing creates the OpenTelemetry spans of the http requests,
+// the storage operations and the outbound calls of the drivers.
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const scope = "github.com/alist-org/alist/v3"
+
+// attributes of the storage spans
+var (
+	StorageMountPath = attribute.Key("alist.storage.mount_path")
+	StorageDriver    = attribute.Key("alist.storage.driver")
+	Path             = attribute.Key("alist.path")
+)
+
+var provider *sdktrace.TracerProvider
+
+// Init exports the spans to the configured OTLP collector
+func Init() {
+	if !conf.Conf.Tracing.Enable {
+		return
+	}
+	exporter, err := newExporter(conf.Conf.Tracing.Endpoint, conf.Conf.Tracing.Headers)
+	if err != nil {
+		log.Errorf("failed init tracing: %+v", err)
+		return
+	}
+	setProvider(sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(conf.Conf.Tracing.SampleRatio))))
+}
+
+// newExporter posts the spans to endpoint/v1/traces over OTLP/HTTP
+func newExporter(endpoint string, headers map[string]string) (sdktrace.SpanExporter, error) {
+	return otlptracehttp.New(context.Background(),
+		otlptracehttp.WithEndpointURL(strings.TrimSuffix(endpoint, "/")+"/v1/traces"),
+		otlptracehttp.WithHeaders(headers))
+}
+
+// InitWithExporter exports every span to exporter as soon as it ends, for
+// the tests with e.g. an in-memory exporter
+func InitWithExporter(exporter sdktrace.SpanExporter) {
+	setProvider(sdktrace.WithSyncer(exporter), sdktrace.WithSampler(sdktrace.AlwaysSample()))
+}
+
+func setProvider(opts ...sdktrace.TracerProviderOption) {
+	name := conf.Conf.Tracing.ServiceName
+	if name == "" {
+		name = "alist"
+	}
+	opts = append(opts, sdktrace.WithResource(resource.NewSchemaless(
+		attribute.String("service.name", name),
+		attribute.String("service.version", conf.Version),
+	)))
+	provider = sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// Shutdown flushes the spans not exported yet
+func Shutdown(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+	return provider.Shutdown(ctx)
+}
+
+// Start starts a span as a child of the span in ctx, a no-op span if
+// tracing is not enabled
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(scope).Start(ctx, name, opts...)
+}
+
+// End records err on span and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+type storageKey struct{}
+
+// WithStorage starts a span of an operation on the storage mounted at
+// mountPath, the outbound calls made with the returned ctx carry the
+// attributes of the storage
+func WithStorage(ctx context.Context, name, mountPath, driver, path string) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{StorageMountPath.String(mountPath), StorageDriver.String(driver)}
+	ctx, span := Start(ctx, name, trace.WithAttributes(append(attrs, Path.String(path))...))
+	if !span.IsRecording() {
+		return ctx, span
+	}
+	return context.WithValue(ctx, storageKey{}, attrs), span
+}
+
+func storageAttrs(ctx context.Context) []attribute.KeyValue {
+	attrs, _ := ctx.Value(storageKey{}).([]attribute.KeyValue)
+	return attrs
+}
@@ -0,0 +1,113 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func attr(s sdktrace.ReadOnlySpan, key string) string {
+	for _, kv := range s.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func TestTransport(t *testing.T) {
+	conf.Conf = conf.DefaultConfig()
+	exporter := tracetest.NewInMemoryExporter()
+	InitWithExporter(exporter)
+	defer Shutdown(context.Background())
+
+	var traceparent, baggage string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent, baggage = r.Header.Get("traceparent"), r.Header.Get("baggage")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer upstream.Close()
+	client := &http.Client{Transport: Transport(nil)}
+
+	// requests without a span are not traced
+	resp, err := client.Get(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if traceparent != "" || len(exporter.GetSpans()) != 0 {
+		t.Fatalf("untraced request got a span: %q", traceparent)
+	}
+
+	ctx, span := WithStorage(context.Background(), "op.List", "/cloud", "Cloud", "/cloud/dir")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL+"/api/list", nil)
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	End(span, errors.New("not found"))
+
+	spans := exporter.GetSpans().Snapshots()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	outbound, op := spans[0], spans[1]
+	if outbound.Parent().SpanID() != op.SpanContext().SpanID() || outbound.SpanKind() != trace.SpanKindClient {
+		t.Fatalf("outbound call is not a child of the op span")
+	}
+	if attr(outbound, string(StorageMountPath)) != "/cloud" || attr(outbound, string(StorageDriver)) != "Cloud" ||
+		attr(outbound, "http.response.status_code") != "404" || attr(outbound, "url.path") != "/api/list" {
+		t.Fatalf("unexpected attributes: %v", outbound.Attributes())
+	}
+	if traceparent != "" || baggage != "" {
+		t.Fatalf("the trace context leaked upstream: %q %q", traceparent, baggage)
+	}
+	if op.Status().Description != "not found" || attr(op, string(Path)) != "/cloud/dir" {
+		t.Fatalf("unexpected op span: %+v %v", op.Status(), op.Attributes())
+	}
+
+	// the spans are posted to the collector over OTLP/HTTP
+	var received coltracepb.ExportTraceServiceRequest
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := proto.Unmarshal(body, &received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer collector.Close()
+	otlp, err := newExporter(collector.URL+"/", map[string]string{"Authorization": "Bearer key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = otlp.ExportSpans(context.Background(), spans); err != nil {
+		t.Fatal(err)
+	}
+	if len(received.ResourceSpans) != 1 || len(received.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("unexpected request: %+v", &received)
+	}
+	got := received.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(got) != 2 || !bytes.Equal(got[0].ParentSpanId, got[1].SpanId) || got[0].Kind != tracepb.Span_SPAN_KIND_CLIENT ||
+		got[1].Status.GetCode() != tracepb.Status_STATUS_CODE_ERROR || hex.EncodeToString(got[0].TraceId) != op.SpanContext().TraceID().String() {
+		t.Fatalf("unexpected spans: %+v", got)
+	}
+}
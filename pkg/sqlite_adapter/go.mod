@@ -0,0 +1,5 @@
+module github.com/go-llsqlite/adapter
+
+go 1.18
+
+require github.com/mattn/go-sqlite3 v1.14.22
@@ -0,0 +1,123 @@
+// Package sqlite replaces github.com/go-llsqlite/adapter for the piece
+// completion of github.com/anacrolix/torrent/storage. The adapter links its
+// own copy of sqlite which clashes with the one of the database driver, this
+// one runs on top of github.com/mattn/go-sqlite3 instead.
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type OpenFlags int
+
+type Conn struct {
+	db *sql.DB
+}
+
+// OpenConn opens the database at path, flags are ignored.
+func OpenConn(path string, flags ...OpenFlags) (*Conn, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	// the connection is used like a single sqlite connection
+	db.SetMaxOpenConns(1)
+	if err = db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &Conn{db: db}, nil
+}
+
+func (c *Conn) Close() error {
+	return c.db.Close()
+}
+
+// Stmt is the current result row of a query.
+type Stmt struct {
+	values []any
+}
+
+func (s *Stmt) ColumnCount() int {
+	return len(s.values)
+}
+
+func (s *Stmt) ColumnInt64(col int) int64 {
+	switch v := s.values[col].(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	case []byte:
+		var i int64
+		_, _ = fmt.Sscan(string(v), &i)
+		return i
+	case string:
+		var i int64
+		_, _ = fmt.Sscan(v, &i)
+		return i
+	}
+	return 0
+}
+
+func (s *Stmt) ColumnInt(col int) int {
+	return int(s.ColumnInt64(col))
+}
+
+func (s *Stmt) ColumnText(col int) string {
+	switch v := s.values[col].(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Exec runs query with args and calls resultFn for each row of its result.
+func (c *Conn) Exec(query string, resultFn func(stmt *Stmt) error, args ...any) error {
+	if resultFn == nil {
+		_, err := c.db.Exec(query, args...)
+		return err
+	}
+	rows, err := c.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	stmt := &Stmt{values: make([]any, len(cols))}
+	dest := make([]any, len(cols))
+	for i := range dest {
+		dest[i] = &stmt.values[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			return err
+		}
+		if err = resultFn(stmt); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ExecScript runs the statements of queries.
+func (c *Conn) ExecScript(queries string) error {
+	_, err := c.db.Exec(queries)
+	return err
+}
@@ -0,0 +1,15 @@
+// Package sqlitex provides the helpers of github.com/go-llsqlite/adapter/sqlitex
+// used by github.com/anacrolix/torrent.
+package sqlitex
+
+import (
+	sqlite "github.com/go-llsqlite/adapter"
+)
+
+func Exec(conn *sqlite.Conn, query string, resultFn func(stmt *sqlite.Stmt) error, args ...any) error {
+	return conn.Exec(query, resultFn, args...)
+}
+
+func ExecScript(conn *sqlite.Conn, queries string) error {
+	return conn.ExecScript(queries)
+}
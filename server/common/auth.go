@@ -20,6 +20,32 @@ type UserClaims struct {
 
 var validTokenCache = cache.NewMemCache[bool]()
 
+// LoginCache counts the failed sign-ins of each ip. It's shared by every way
+// to sign in so that none of them can be used to guess passwords.
+var LoginCache = cache.NewMemCache[int]()
+
+var (
+	LoginLockDuration = time.Minute * 5
+	LoginMaxAttempts  = 5
+)
+
+// LoginLocked tells whether ip failed to sign in too many times, every
+// attempt meanwhile extends the lock
+func LoginLocked(ip string) bool {
+	count, ok := LoginCache.Get(ip)
+	if ok && count >= LoginMaxAttempts {
+		LoginCache.Expire(ip, LoginLockDuration)
+		return true
+	}
+	return false
+}
+
+// LoginFailed counts a failed sign-in of ip
+func LoginFailed(ip string) {
+	count, _ := LoginCache.Get(ip)
+	LoginCache.Set(ip, count+1)
+}
+
 func GenerateToken(user *model.User) (tokenString string, err error) {
 	claim := UserClaims{
 		Username: user.Username,
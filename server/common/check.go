@@ -53,3 +53,26 @@ func ShouldProxy(storage driver.Driver, filename string) bool {
 	}
 	return false
 }
+
+// CanProxy TODO need optimize
+// when can be proxy?
+// 1. text file
+// 2. config.MustProxy()
+// 3. storage.WebProxy
+// 4. proxy_types
+// solution: text_file + shouldProxy()
+func CanProxy(storage driver.Driver, filename string) bool {
+	if storage.Config().MustProxy() || storage.GetStorage().WebProxy || storage.GetStorage().WebdavProxy() {
+		return true
+	}
+	if storage.GetStorage().Driver == "Quark" && utils.GetFileType(filename) == conf.VIDEO {
+		return true
+	}
+	if utils.SliceContains(conf.SlicesMap[conf.ProxyTypes], utils.Ext(filename)) {
+		return true
+	}
+	if utils.SliceContains(conf.SlicesMap[conf.TextTypes], utils.Ext(filename)) {
+		return true
+	}
+	return false
+}
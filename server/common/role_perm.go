@@ -57,6 +57,9 @@ func MergeRolePermissions(u *model.User, reqPath string) int32 {
 			}
 		}
 	}
+	if u.Scope != nil {
+		perm = u.Scope.Restrict(perm, reqPath)
+	}
 	return perm
 }
 
@@ -90,6 +93,9 @@ func CanReadPathByRole(u *model.User, reqPath string) bool {
 	if u == nil {
 		return false
 	}
+	if u.Scope != nil && !u.Scope.CanReach(reqPath) {
+		return false
+	}
 	if reqPath == "/" || utils.PathEqual(reqPath, u.BasePath) {
 		return len(u.Role) > 0
 	}
@@ -113,12 +119,18 @@ func HasChildPermission(u *model.User, reqPath string, bit uint) bool {
 	if u == nil {
 		return false
 	}
+	if u.Scope != nil && !HasPermission(u.Scope.Mask(-1), bit) {
+		return false
+	}
 	for _, rid := range u.Role {
 		role, err := op.GetRole(uint(rid))
 		if err != nil {
 			continue
 		}
 		for _, entry := range role.PermissionScopes {
+			if u.Scope != nil && !u.Scope.CanReach(entry.Path) {
+				continue
+			}
 			if utils.IsSubPath(reqPath, entry.Path) && HasPermission(entry.Permission, bit) {
 				return true
 			}
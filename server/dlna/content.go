@@ -0,0 +1,367 @@
+package dlna
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/http"
+	stdpath "path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/sign"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/pkg/errors"
+)
+
+const rootID = "0"
+
+// mimeTypes complements utils.GetMimeType for media the renderers know
+var mimeTypes = map[string]string{
+	"mkv":  "video/x-matroska",
+	"flv":  "video/x-flv",
+	"ts":   "video/mp2t",
+	"m2ts": "video/mp2t",
+	"rmvb": "application/vnd.rn-realmedia-vbr",
+	"wmv":  "video/x-ms-wmv",
+	"avi":  "video/x-msvideo",
+	"mov":  "video/quicktime",
+	"flac": "audio/flac",
+	"ape":  "audio/x-ape",
+	"m4a":  "audio/mp4",
+	"wma":  "audio/x-ms-wma",
+}
+
+type didlRes struct {
+	ProtocolInfo string `xml:"protocolInfo,attr"`
+	Size         int64  `xml:"size,attr,omitempty"`
+	URL          string `xml:",chardata"`
+}
+
+type didlObject struct {
+	XMLName    xml.Name
+	ID         string   `xml:"id,attr"`
+	ParentID   string   `xml:"parentID,attr"`
+	Restricted int      `xml:"restricted,attr"`
+	Searchable *int     `xml:"searchable,attr"`
+	Title      string   `xml:"dc:title"`
+	Class      string   `xml:"upnp:class"`
+	Date       string   `xml:"dc:date,omitempty"`
+	AlbumArt   string   `xml:"upnp:albumArtURI,omitempty"`
+	Res        *didlRes `xml:"res,omitempty"`
+}
+
+func didl(objects []didlObject) (string, error) {
+	data, err := xml.Marshal(objects)
+	if err != nil {
+		return "", err
+	}
+	return `<DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" xmlns:dc="http://purl.org/dc/elements/1.1/" ` +
+		`xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/">` + string(data) + `</DIDL-Lite>`, nil
+}
+
+// browser answers the Browse actions of a request as user
+type browser struct {
+	s    *Server
+	ctx  context.Context
+	user *model.User
+	// base is the address of the http server streaming the media
+	base string
+}
+
+func (s *Server) newBrowser(r *http.Request) (*browser, error) {
+	user, err := s.getUser()
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.WithValue(r.Context(), "user", user)
+	return &browser{s: s, ctx: ctx, user: user, base: streamBase(r)}, nil
+}
+
+// streamBase returns the address of the http server, the renderers
+// reach it at the same host as the dlna server
+func streamBase(r *http.Request) string {
+	if strings.HasPrefix(conf.Conf.SiteURL, "http") {
+		return common.GetApiUrl(nil)
+	}
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+	scheme, port := "http", conf.Conf.Scheme.HttpPort
+	if port == -1 {
+		scheme, port = "https", conf.Conf.Scheme.HttpsPort
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(host, strconv.Itoa(port)), strings.TrimSuffix(conf.Conf.SiteURL, "/"))
+}
+
+// roots returns the exposed folders, nil means the root of the user
+func (s *Server) roots() []string {
+	var roots []string
+	for _, p := range conf.Conf.DLNA.Paths {
+		if p = utils.FixAndCleanPath(p); p == "/" {
+			return nil
+		}
+		roots = append(roots, p)
+	}
+	return roots
+}
+
+// exposed reports whether the object of path can be browsed
+func (s *Server) exposed(path string) bool {
+	roots := s.roots()
+	if roots == nil {
+		return true
+	}
+	for _, root := range roots {
+		if utils.IsSubPath(root, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) parentID(path string) string {
+	roots := s.roots()
+	if roots == nil {
+		if stdpath.Dir(path) == "/" {
+			return rootID
+		}
+		return stdpath.Dir(path)
+	}
+	if utils.SliceContains(roots, path) {
+		return rootID
+	}
+	return stdpath.Dir(path)
+}
+
+// access returns the actual path of path if the user can read it
+func (b *browser) access(path string) (string, error) {
+	reqPath, err := b.user.JoinPath(path)
+	if err != nil {
+		return "", err
+	}
+	meta, err := op.GetNearestMeta(reqPath)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		return "", err
+	}
+	if !common.CanAccessWithRoles(b.user, meta, reqPath, "") {
+		return "", errs.PermissionDenied
+	}
+	return reqPath, nil
+}
+
+func (b *browser) get(path string) (model.Obj, error) {
+	reqPath, err := b.access(path)
+	if err != nil {
+		return nil, err
+	}
+	return fs.Get(b.ctx, reqPath, &fs.GetArgs{})
+}
+
+// list returns the folders and the media in path, folders first
+func (b *browser) list(path string) ([]model.Obj, error) {
+	reqPath, err := b.access(path)
+	if err != nil {
+		return nil, err
+	}
+	objs, err := fs.List(b.ctx, reqPath, &fs.ListArgs{})
+	if err != nil {
+		return nil, err
+	}
+	meta, _ := op.GetNearestMeta(reqPath)
+	res := make([]model.Obj, 0, len(objs))
+	for _, obj := range objs {
+		if !obj.IsDir() && mediaClass(obj.GetName()) == "" {
+			continue
+		}
+		if !common.CanAccessWithRoles(b.user, meta, stdpath.Join(reqPath, obj.GetName()), "") {
+			continue
+		}
+		res = append(res, obj)
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].IsDir() != res[j].IsDir() {
+			return res[i].IsDir()
+		}
+		return strings.ToLower(res[i].GetName()) < strings.ToLower(res[j].GetName())
+	})
+	return res, nil
+}
+
+func mediaClass(name string) string {
+	switch utils.GetFileType(name) {
+	case conf.VIDEO:
+		return "object.item.videoItem"
+	case conf.AUDIO:
+		return "object.item.audioItem.musicTrack"
+	case conf.IMAGE:
+		return "object.item.imageItem.photo"
+	}
+	return ""
+}
+
+func mimeType(name string) string {
+	if m, ok := mimeTypes[strings.ToLower(utils.Ext(name))]; ok {
+		return m
+	}
+	return utils.GetMimeType(name)
+}
+
+func (b *browser) rootObject() didlObject {
+	searchable := 0
+	return didlObject{
+		XMLName:    xml.Name{Local: "container"},
+		ID:         rootID,
+		ParentID:   "-1",
+		Restricted: 1,
+		Searchable: &searchable,
+		Title:      conf.Conf.DLNA.FriendlyName,
+		Class:      "object.container.storageFolder",
+	}
+}
+
+// object converts obj at path to a DIDL object
+func (b *browser) object(path string, obj model.Obj) didlObject {
+	o := didlObject{
+		ID:         path,
+		ParentID:   b.s.parentID(path),
+		Restricted: 1,
+		Title:      obj.GetName(),
+	}
+	if !obj.ModTime().IsZero() {
+		o.Date = obj.ModTime().Format("2006-01-02T15:04:05")
+	}
+	if obj.IsDir() {
+		searchable := 0
+		o.XMLName.Local = "container"
+		o.Searchable = &searchable
+		o.Class = "object.container.storageFolder"
+		return o
+	}
+	o.XMLName.Local = "item"
+	o.Class = mediaClass(obj.GetName())
+	reqPath, _ := b.user.JoinPath(path)
+	// /p refuses files of storages that don't allow proxying, the renderers
+	// follow the redirect of /d to the link of the driver instead
+	route := "d"
+	if storage, _, err := op.GetStorageAndActualPath(reqPath); err == nil && common.CanProxy(storage, obj.GetName()) {
+		route = "p"
+	}
+	o.Res = &didlRes{
+		ProtocolInfo: fmt.Sprintf("http-get:*:%s:DLNA.ORG_OP=01;DLNA.ORG_CI=0", mimeType(obj.GetName())),
+		Size:         obj.GetSize(),
+		URL:          fmt.Sprintf("%s/%s%s?sign=%s", b.base, route, utils.EncodePath(reqPath, true), sign.Sign(reqPath)),
+	}
+	if thumb, ok := model.GetThumb(obj); ok {
+		o.AlbumArt = thumb
+	}
+	return o
+}
+
+// children returns the objects in the container id
+func (b *browser) children(id string) ([]didlObject, error) {
+	if id == rootID {
+		roots := b.s.roots()
+		if roots == nil {
+			return b.childrenOf("/")
+		}
+		var res []didlObject
+		for _, root := range roots {
+			obj, err := b.get(root)
+			if err != nil || !obj.IsDir() {
+				continue
+			}
+			res = append(res, b.object(root, obj))
+		}
+		return res, nil
+	}
+	return b.childrenOf(id)
+}
+
+func (b *browser) childrenOf(path string) ([]didlObject, error) {
+	objs, err := b.list(path)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]didlObject, len(objs))
+	for i, obj := range objs {
+		res[i] = b.object(stdpath.Join(path, obj.GetName()), obj)
+	}
+	return res, nil
+}
+
+func (b *browser) metadata(id string) (didlObject, error) {
+	if id == rootID {
+		return b.rootObject(), nil
+	}
+	obj, err := b.get(id)
+	if err != nil {
+		return didlObject{}, err
+	}
+	return b.object(id, obj), nil
+}
+
+// browse handles the Browse action
+func (b *browser) browse(args map[string]string) ([]result, error) {
+	id := args["ObjectID"]
+	if id != rootID {
+		id = utils.FixAndCleanPath(id)
+		if id == "/" || !b.s.exposed(id) {
+			return nil, &upnpError{Code: errNoSuchObject, Desc: "no such object"}
+		}
+	}
+	start, _ := strconv.Atoi(args["StartingIndex"])
+	count, _ := strconv.Atoi(args["RequestedCount"])
+	var objects []didlObject
+	total := 1
+	switch args["BrowseFlag"] {
+	case "BrowseMetadata":
+		object, err := b.metadata(id)
+		if err != nil {
+			return nil, browseError(err)
+		}
+		objects = []didlObject{object}
+	case "BrowseDirectChildren":
+		all, err := b.children(id)
+		if err != nil {
+			return nil, browseError(err)
+		}
+		total = len(all)
+		if start < 0 || start > total {
+			start = total
+		}
+		end := total
+		if count > 0 && start+count < total {
+			end = start + count
+		}
+		objects = all[start:end]
+	default:
+		return nil, &upnpError{Code: errInvalidArgs, Desc: "invalid BrowseFlag"}
+	}
+	data, err := didl(objects)
+	if err != nil {
+		return nil, err
+	}
+	return []result{
+		{"Result", data},
+		{"NumberReturned", strconv.Itoa(len(objects))},
+		{"TotalMatches", strconv.Itoa(total)},
+		{"UpdateID", "0"},
+	}, nil
+}
+
+func browseError(err error) error {
+	if errs.IsObjectNotFound(err) || errors.Is(err, errs.PermissionDenied) {
+		return &upnpError{Code: errNoSuchObject, Desc: err.Error()}
+	}
+	return err
+}
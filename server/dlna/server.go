@@ -0,0 +1,175 @@
+// Package dlna implements a DLNA media server, the ContentDirectory
+// lists the folders and media of a user and the media are streamed
+// through the /p proxy of the http server.
+package dlna
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/google/uuid"
+)
+
+type Server struct {
+	udn      string
+	listener net.Listener
+	http     *http.Server
+	ssdp     *ssdp
+}
+
+func NewServer() (*Server, error) {
+	hostname, _ := os.Hostname()
+	id := uuid.NewSHA1(uuid.NameSpaceOID, []byte("alist-dlna:"+hostname+":"+conf.Conf.DLNA.FriendlyName))
+	s := &Server{udn: "uuid:" + id.String()}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rootDesc.xml", s.handleDescription)
+	mux.HandleFunc("/scpd/", s.handleSCPD)
+	mux.HandleFunc("/ctl/", s.handleControl)
+	mux.HandleFunc("/evt/", s.handleEvent)
+	s.http = &http.Server{Handler: mux}
+	return s, nil
+}
+
+// Handler returns the http handler of the description and control requests
+func (s *Server) Handler() http.Handler {
+	return s.http.Handler
+}
+
+func (s *Server) ListenAndServe() error {
+	l, err := net.Listen("tcp4", conf.Conf.DLNA.Listen)
+	if err != nil {
+		return err
+	}
+	s.listener = l
+	s.ssdp, err = newSSDP(s.udn, l.Addr().(*net.TCPAddr).Port)
+	if err != nil {
+		_ = l.Close()
+		return err
+	}
+	go s.ssdp.serve()
+	err = s.http.Serve(l)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Close announces the server leaving and stops it
+func (s *Server) Close() error {
+	if s.ssdp != nil {
+		_ = s.ssdp.close()
+	}
+	return s.http.Close()
+}
+
+// getUser returns the user browsing the media, guest if not configured
+func (s *Server) getUser() (*model.User, error) {
+	var user *model.User
+	var err error
+	if conf.Conf.DLNA.User == "" {
+		user, err = op.GetGuest()
+	} else {
+		user, err = op.GetUserByName(conf.Conf.DLNA.User)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.Disabled {
+		return nil, errors.New("user is disabled")
+	}
+	return user, nil
+}
+
+func (s *Server) handleDescription(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	_, _ = w.Write([]byte(deviceDescription(conf.Conf.DLNA.FriendlyName, s.udn)))
+}
+
+func (s *Server) handleSCPD(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/scpd/"), ".xml")
+	desc, ok := scpds[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	_, _ = w.Write([]byte(desc))
+}
+
+// handleEvent accepts the subscriptions without sending events,
+// the content never announces changes
+func (s *Server) handleEvent(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "SUBSCRIBE":
+		w.Header().Set("SID", "uuid:"+uuid.NewString())
+		w.Header().Set("TIMEOUT", "Second-1800")
+	case "UNSUBSCRIBE":
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleControl(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/ctl/")
+	var serviceType string
+	for _, service := range services {
+		if service.Name == name {
+			serviceType = service.Type
+		}
+	}
+	if serviceType == "" {
+		http.NotFound(w, r)
+		return
+	}
+	action, args, err := readAction(r.Body)
+	if err != nil {
+		writeFault(w, &upnpError{Code: errInvalidAction, Desc: err.Error()})
+		return
+	}
+	results, err := s.call(r, serviceType, action, args)
+	if err != nil {
+		var upnpErr *upnpError
+		if !errors.As(err, &upnpErr) {
+			utils.Log.Errorf("[dlna] failed to handle %s: %+v", action, err)
+			upnpErr = &upnpError{Code: errActionFailed, Desc: err.Error()}
+		}
+		writeFault(w, upnpErr)
+		return
+	}
+	writeResponse(w, serviceType, action, results)
+}
+
+func (s *Server) call(r *http.Request, serviceType, action string, args map[string]string) ([]result, error) {
+	switch serviceType + "#" + action {
+	case contentDirectoryType + "#Browse":
+		b, err := s.newBrowser(r)
+		if err != nil {
+			return nil, err
+		}
+		return b.browse(args)
+	case contentDirectoryType + "#GetSearchCapabilities":
+		return []result{{"SearchCaps", ""}}, nil
+	case contentDirectoryType + "#GetSortCapabilities":
+		return []result{{"SortCaps", ""}}, nil
+	case contentDirectoryType + "#GetSystemUpdateID":
+		return []result{{"Id", "0"}}, nil
+	case connectionManagerType + "#GetProtocolInfo":
+		return []result{{"Source", "http-get:*:*:*"}, {"Sink", ""}}, nil
+	case connectionManagerType + "#GetCurrentConnectionIDs":
+		return []result{{"ConnectionIDs", "0"}}, nil
+	case registrarType + "#IsAuthorized", registrarType + "#IsValidated":
+		return []result{{"Result", "1"}}, nil
+	}
+	return nil, &upnpError{Code: errInvalidAction, Desc: "invalid action " + action}
+}
@@ -0,0 +1,99 @@
+package dlna
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/alist-org/alist/v3/drivers"
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/testutil"
+)
+
+func browseRequest(objectID, flag string) string {
+	return `<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>` +
+		`<u:Browse xmlns:u="` + contentDirectoryType + `"><ObjectID>` + objectID + `</ObjectID>` +
+		`<BrowseFlag>` + flag + `</BrowseFlag><Filter>*</Filter><StartingIndex>0</StartingIndex>` +
+		`<RequestedCount>0</RequestedCount><SortCriteria></SortCriteria></u:Browse></s:Body></s:Envelope>`
+}
+
+func TestBrowse(t *testing.T) {
+	testutil.InitDB()
+	conf.Conf.SiteURL = "http://alist.test"
+	conf.Conf.DLNA.User = "dlna"
+	conf.Conf.DLNA.Paths = []string{"/media", "/linked"}
+	conf.SlicesMap[conf.VideoTypes] = []string{"mp4"}
+	role := &model.Role{Name: "dlna", PermissionScopes: []model.PermissionEntry{{Path: "/", Permission: 0xFFFF}}}
+	err := op.CreateRole(role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = op.CreateUser(&model.User{Username: "dlna", BasePath: "/", Role: model.Roles{int(role.ID)}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, mount := range []string{"/media", "/other"} {
+		_, dir := testutil.CreateLocal(t, mount)
+		if err = os.WriteFile(filepath.Join(dir, "movie.mp4"), []byte("video"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("text"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the alias doesn't proxy the files of /other
+	addition, _ := json.Marshal(map[string]string{"paths": "/other"})
+	if _, err = op.CreateStorage(context.Background(), model.Storage{Driver: "Alias", MountPath: "/linked", Addition: string(addition)}); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+	browse := func(objectID, flag string) (int, string) {
+		resp, err := http.Post(ts.URL+"/ctl/ContentDirectory", `text/xml; charset="utf-8"`, strings.NewReader(browseRequest(objectID, flag)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(data)
+	}
+
+	code, body := browse(rootID, "BrowseDirectChildren")
+	if code != http.StatusOK || !strings.Contains(body, "&lt;dc:title&gt;media&lt;/dc:title&gt;") {
+		t.Fatalf("unexpected root children: %d %s", code, body)
+	}
+	if strings.Contains(body, "other") {
+		t.Fatalf("unexposed folder listed: %s", body)
+	}
+	code, body = browse("/media", "BrowseDirectChildren")
+	if code != http.StatusOK || !strings.Contains(body, "&lt;dc:title&gt;movie.mp4&lt;/dc:title&gt;") ||
+		!strings.Contains(body, "http://alist.test/p/media/movie.mp4?sign=") {
+		t.Fatalf("unexpected media children: %d %s", code, body)
+	}
+	if strings.Contains(body, "notes.txt") {
+		t.Fatalf("non media file listed: %s", body)
+	}
+	code, body = browse("/linked", "BrowseDirectChildren")
+	if code != http.StatusOK || !strings.Contains(body, "http://alist.test/d/linked/movie.mp4?sign=") {
+		t.Fatalf("expected a download link of a storage without proxy: %d %s", code, body)
+	}
+	if code, body = browse("/other", "BrowseDirectChildren"); code != http.StatusInternalServerError ||
+		!strings.Contains(body, "<errorCode>701</errorCode>") {
+		t.Fatalf("expected no such object, got %d %s", code, body)
+	}
+}
@@ -0,0 +1,84 @@
+package dlna
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// UPnP error codes of the control responses
+const (
+	errInvalidAction = 401
+	errInvalidArgs   = 402
+	errActionFailed  = 501
+	errNoSuchObject  = 701
+)
+
+type upnpError struct {
+	Code int
+	Desc string
+}
+
+func (e *upnpError) Error() string {
+	return fmt.Sprintf("upnp error %d: %s", e.Code, e.Desc)
+}
+
+type soapArg struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+type soapAction struct {
+	XMLName xml.Name
+	Args    []soapArg `xml:",any"`
+}
+
+type soapEnvelope struct {
+	Body struct {
+		Action soapAction `xml:",any"`
+	} `xml:"Body"`
+}
+
+// readAction returns the name and the arguments of a control request
+func readAction(r io.Reader) (string, map[string]string, error) {
+	var env soapEnvelope
+	if err := xml.NewDecoder(io.LimitReader(r, 1<<20)).Decode(&env); err != nil {
+		return "", nil, err
+	}
+	args := make(map[string]string, len(env.Body.Action.Args))
+	for _, arg := range env.Body.Action.Args {
+		args[arg.XMLName.Local] = arg.Value
+	}
+	return env.Body.Action.XMLName.Local, args, nil
+}
+
+// result is an output argument of an action, the order matters to some renderers
+type result struct {
+	name  string
+	value string
+}
+
+func writeResponse(w http.ResponseWriter, serviceType, action string, results []result) {
+	var b strings.Builder
+	for _, r := range results {
+		fmt.Fprintf(&b, "<%s>%s</%s>", r.name, escape(r.value), r.name)
+	}
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	w.Header().Set("Ext", "")
+	_, _ = fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?>`+
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">`+
+		`<s:Body><u:%sResponse xmlns:u="%s">%s</u:%sResponse></s:Body></s:Envelope>`,
+		action, serviceType, b.String(), action)
+}
+
+func writeFault(w http.ResponseWriter, err *upnpError) {
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	w.WriteHeader(http.StatusInternalServerError)
+	_, _ = fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?>`+
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">`+
+		`<s:Body><s:Fault><faultcode>s:Client</faultcode><faultstring>UPnPError</faultstring><detail>`+
+		`<UPnPError xmlns="urn:schemas-upnp-org:control-1-0"><errorCode>%d</errorCode><errorDescription>%s</errorDescription></UPnPError>`+
+		`</detail></s:Fault></s:Body></s:Envelope>`, err.Code, escape(err.Desc))
+}
@@ -0,0 +1,212 @@
+package dlna
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"golang.org/x/net/ipv4"
+)
+
+const (
+	ssdpPort   = 1900
+	ssdpMaxAge = 1800
+)
+
+var ssdpGroup = &net.UDPAddr{IP: net.IPv4(239, 255, 255, 250), Port: ssdpPort}
+
+// ssdp answers the searches of the renderers and announces the server
+// on the multicast interfaces (UPnP device architecture 1.0, section 1)
+type ssdp struct {
+	udn    string
+	port   int
+	conn   *ipv4.PacketConn
+	ifaces []net.Interface
+	done   chan struct{}
+}
+
+func newSSDP(udn string, port int) (*ssdp, error) {
+	c, err := net.ListenPacket("udp4", fmt.Sprintf(":%d", ssdpPort))
+	if err != nil {
+		return nil, err
+	}
+	s := &ssdp{udn: udn, port: port, conn: ipv4.NewPacketConn(c), done: make(chan struct{})}
+	ifaces, _ := net.Interfaces()
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagMulticast == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		if ifaceIPv4(&iface) == nil {
+			continue
+		}
+		if err := s.conn.JoinGroup(&iface, ssdpGroup); err != nil {
+			utils.Log.Warnf("[dlna] failed to join ssdp group on %s: %+v", iface.Name, err)
+			continue
+		}
+		s.ifaces = append(s.ifaces, iface)
+	}
+	if len(s.ifaces) == 0 {
+		_ = c.Close()
+		return nil, fmt.Errorf("no multicast interface available")
+	}
+	return s, nil
+}
+
+func ifaceIPv4(iface *net.Interface) net.IP {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+			return ipNet.IP.To4()
+		}
+	}
+	return nil
+}
+
+// localIP returns the address of the interface in the network of remote
+func (s *ssdp) localIP(remote net.IP) net.IP {
+	var first net.IP
+	for i := range s.ifaces {
+		addrs, err := s.ifaces[i].Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.To4() == nil {
+				continue
+			}
+			if ipNet.Contains(remote) {
+				return ipNet.IP.To4()
+			}
+			if first == nil {
+				first = ipNet.IP.To4()
+			}
+		}
+	}
+	return first
+}
+
+func (s *ssdp) location(ip net.IP) string {
+	return fmt.Sprintf("http://%s/rootDesc.xml", net.JoinHostPort(ip.String(), fmt.Sprint(s.port)))
+}
+
+// targets returns the notification types with their unique service names
+func (s *ssdp) targets() [][2]string {
+	res := [][2]string{
+		{"upnp:rootdevice", s.udn + "::upnp:rootdevice"},
+		{s.udn, s.udn},
+		{deviceType, s.udn + "::" + deviceType},
+	}
+	for _, service := range services {
+		res = append(res, [2]string{service.Type, s.udn + "::" + service.Type})
+	}
+	return res
+}
+
+func serverHeader() string {
+	return fmt.Sprintf("Linux/1.0 UPnP/1.0 AList/%s", conf.Version)
+}
+
+func (s *ssdp) serve() {
+	go s.announce()
+	buf := make([]byte, 2048)
+	for {
+		n, _, src, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			utils.Log.Warnf("[dlna] ssdp read error: %+v", err)
+			continue
+		}
+		req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(buf[:n])))
+		if err != nil || req.Method != "M-SEARCH" || req.Header.Get("Man") != `"ssdp:discover"` {
+			continue
+		}
+		if addr, ok := src.(*net.UDPAddr); ok {
+			s.reply(req.Header.Get("St"), addr)
+		}
+	}
+}
+
+func (s *ssdp) reply(st string, addr *net.UDPAddr) {
+	ip := s.localIP(addr.IP)
+	if ip == nil {
+		return
+	}
+	for _, target := range s.targets() {
+		if st != "ssdp:all" && st != target[0] {
+			continue
+		}
+		msg := "HTTP/1.1 200 OK\r\n" +
+			fmt.Sprintf("CACHE-CONTROL: max-age=%d\r\n", ssdpMaxAge) +
+			"DATE: " + time.Now().UTC().Format(http.TimeFormat) + "\r\n" +
+			"EXT:\r\n" +
+			"LOCATION: " + s.location(ip) + "\r\n" +
+			"SERVER: " + serverHeader() + "\r\n" +
+			"ST: " + target[0] + "\r\n" +
+			"USN: " + target[1] + "\r\n" +
+			"Content-Length: 0\r\n\r\n"
+		if _, err := s.conn.WriteTo([]byte(msg), nil, addr); err != nil {
+			utils.Log.Debugf("[dlna] failed to reply to %s: %+v", addr, err)
+		}
+	}
+}
+
+func (s *ssdp) notify(nts string) {
+	for i := range s.ifaces {
+		ip := ifaceIPv4(&s.ifaces[i])
+		if ip == nil || s.conn.SetMulticastInterface(&s.ifaces[i]) != nil {
+			continue
+		}
+		for _, target := range s.targets() {
+			lines := []string{
+				"NOTIFY * HTTP/1.1",
+				"HOST: " + ssdpGroup.String(),
+				"NT: " + target[0],
+				"NTS: " + nts,
+				"USN: " + target[1],
+			}
+			if nts == "ssdp:alive" {
+				lines = append(lines,
+					fmt.Sprintf("CACHE-CONTROL: max-age=%d", ssdpMaxAge),
+					"LOCATION: "+s.location(ip),
+					"SERVER: "+serverHeader())
+			}
+			msg := strings.Join(lines, "\r\n") + "\r\n\r\n"
+			_, _ = s.conn.WriteTo([]byte(msg), nil, ssdpGroup)
+		}
+	}
+}
+
+// announce sends ssdp:alive regularly before the announcement expires
+func (s *ssdp) announce() {
+	ticker := time.NewTicker(ssdpMaxAge / 2 * time.Second)
+	defer ticker.Stop()
+	s.notify("ssdp:alive")
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.notify("ssdp:alive")
+		}
+	}
+}
+
+func (s *ssdp) close() error {
+	close(s.done)
+	s.notify("ssdp:byebye")
+	return s.conn.Close()
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	ftpserver "github.com/KirCute/ftpserverlib-pasvportmap"
 	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/metrics"
 	"github.com/alist-org/alist/v3/internal/model"
 	"github.com/alist-org/alist/v3/internal/op"
 	"github.com/alist-org/alist/v3/internal/setting"
@@ -102,6 +103,7 @@ func (d *FtpMainDriver) ClientConnected(cc ftpserver.ClientContext) (string, err
 	}
 	defer d.shutdownLock.RUnlock()
 	d.clients[cc.ID()] = cc
+	metrics.SessionStarted(metrics.FTP)
 	return "AList FTP Endpoint", nil
 }
 
@@ -110,7 +112,10 @@ func (d *FtpMainDriver) ClientDisconnected(cc ftpserver.ClientContext) {
 	if err != nil {
 		utils.Log.Errorf("failed to close client: %v", err)
 	}
-	delete(d.clients, cc.ID())
+	if _, ok := d.clients[cc.ID()]; ok {
+		delete(d.clients, cc.ID())
+		metrics.SessionEnded(metrics.FTP)
+	}
 }
 
 func (d *FtpMainDriver) AuthUser(cc ftpserver.ClientContext, user, pass string) (ftpserver.ClientDriver, error) {
@@ -145,6 +150,7 @@ func (d *FtpMainDriver) AuthUser(cc ftpserver.ClientContext, user, pass string)
 	}
 	ctx = context.WithValue(ctx, "client_ip", cc.RemoteAddr().String())
 	ctx = context.WithValue(ctx, "proxy_header", d.proxyHeader)
+	ctx = metrics.WithProtocol(ctx, metrics.FTP)
 	return ftp.NewAferoAdapter(ctx), nil
 }
 
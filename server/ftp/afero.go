@@ -106,11 +106,10 @@ func (a *AferoAdapter) GetHandle(name string, flags int, offset int64) (ftpserve
 		if offset != 0 {
 			return nil, errs.NotSupport
 		}
-		trunc := (flags & os.O_TRUNC) != 0
 		if fileSize > 0 {
-			return OpenUploadWithLength(a.ctx, path, trunc, fileSize)
+			return OpenUploadWithLength(a.ctx, path, fileSize)
 		} else {
-			return OpenUpload(a.ctx, path, trunc)
+			return OpenUpload(a.ctx, path)
 		}
 	}
 	return OpenDownload(a.ctx, path, offset)
@@ -5,6 +5,7 @@ import (
 	ftpserver "github.com/KirCute/ftpserverlib-pasvportmap"
 	"github.com/alist-org/alist/v3/internal/errs"
 	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/metrics"
 	"github.com/alist-org/alist/v3/internal/model"
 	"github.com/alist-org/alist/v3/internal/op"
 	"github.com/alist-org/alist/v3/internal/stream"
@@ -19,6 +20,8 @@ import (
 type FileDownloadProxy struct {
 	ftpserver.FileTransfer
 	reader stream.SStreamReadAtSeeker
+	// protocol the bytes read are counted for, none if empty
+	protocol string
 }
 
 func OpenDownload(ctx context.Context, reqPath string, offset int64) (*FileDownloadProxy, error) {
@@ -56,11 +59,14 @@ func OpenDownload(ctx context.Context, reqPath string, offset int64) (*FileDownl
 		_ = ss.Close()
 		return nil, err
 	}
-	return &FileDownloadProxy{reader: reader}, nil
+	return &FileDownloadProxy{reader: reader, protocol: metrics.Protocol(ctx)}, nil
 }
 
 func (f *FileDownloadProxy) Read(p []byte) (n int, err error) {
 	n, err = f.reader.Read(p)
+	if f.protocol != "" {
+		metrics.ServedBytes(f.protocol, n)
+	}
 	if err != nil {
 		return
 	}
@@ -24,7 +24,6 @@ type FileUploadProxy struct {
 	buffer *os.File
 	path   string
 	ctx    context.Context
-	trunc  bool
 }
 
 func uploadAuth(ctx context.Context, path string) error {
@@ -44,7 +43,10 @@ func uploadAuth(ctx context.Context, path string) error {
 	return nil
 }
 
-func OpenUpload(ctx context.Context, path string, trunc bool) (*FileUploadProxy, error) {
+// OpenUpload buffers the upload of path in a temp file. An existing file is
+// not removed beforehand, the put replaces it once the upload policy allows
+// it and keeps it as a version if asked to.
+func OpenUpload(ctx context.Context, path string) (*FileUploadProxy, error) {
 	err := uploadAuth(ctx, path)
 	if err != nil {
 		return nil, err
@@ -53,7 +55,7 @@ func OpenUpload(ctx context.Context, path string, trunc bool) (*FileUploadProxy,
 	if err != nil {
 		return nil, err
 	}
-	return &FileUploadProxy{buffer: tmpFile, path: path, ctx: ctx, trunc: trunc}, nil
+	return &FileUploadProxy{buffer: tmpFile, path: path, ctx: ctx}, nil
 }
 
 func (f *FileUploadProxy) Read(p []byte) (n int, err error) {
@@ -73,7 +75,27 @@ func (f *FileUploadProxy) Seek(offset int64, whence int) (int64, error) {
 	return f.buffer.Seek(offset, whence)
 }
 
+// Truncate changes the size of the buffered content, the offset is not changed
+func (f *FileUploadProxy) Truncate(size int64) error {
+	return f.buffer.Truncate(size)
+}
+
 func (f *FileUploadProxy) Close() error {
+	return f.upload(true)
+}
+
+// Abort drops the buffered content without uploading it
+func (f *FileUploadProxy) Abort() error {
+	_ = f.buffer.Close()
+	return os.Remove(f.buffer.Name())
+}
+
+// CloseSync uploads the buffered content and waits until it's done
+func (f *FileUploadProxy) CloseSync() error {
+	return f.upload(false)
+}
+
+func (f *FileUploadProxy) upload(asTask bool) error {
 	dir, name := stdpath.Split(f.path)
 	size, err := f.buffer.Seek(0, io.SeekCurrent)
 	if err != nil {
@@ -83,16 +105,13 @@ func (f *FileUploadProxy) Close() error {
 		return err
 	}
 	arr := make([]byte, 512)
-	if _, err := f.buffer.Read(arr); err != nil {
+	if _, err := f.buffer.Read(arr); err != nil && err != io.EOF {
 		return err
 	}
 	contentType := http.DetectContentType(arr)
 	if _, err := f.buffer.Seek(0, io.SeekStart); err != nil {
 		return err
 	}
-	if f.trunc {
-		_ = fs.Remove(f.ctx, f.path)
-	}
 	s := &stream.FileStream{
 		Obj: &model.Object{
 			Name:     name,
@@ -103,6 +122,10 @@ func (f *FileUploadProxy) Close() error {
 		WebPutAsTask: true,
 	}
 	s.SetTmpFile(f.buffer)
+	if !asTask {
+		defer s.Close()
+		return fs.PutDirectly(f.ctx, dir, s)
+	}
 	_, err = fs.PutAsTask(f.ctx, dir, s)
 	return err
 }
@@ -118,14 +141,11 @@ type FileUploadWithLengthProxy struct {
 	errChan       chan error
 }
 
-func OpenUploadWithLength(ctx context.Context, path string, trunc bool, length int64) (*FileUploadWithLengthProxy, error) {
+func OpenUploadWithLength(ctx context.Context, path string, length int64) (*FileUploadWithLengthProxy, error) {
 	err := uploadAuth(ctx, path)
 	if err != nil {
 		return nil, err
 	}
-	if trunc {
-		_ = fs.Remove(ctx, path)
-	}
 	return &FileUploadWithLengthProxy{ctx: ctx, path: path, length: length}, nil
 }
 
@@ -0,0 +1,82 @@
+package ftp
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/alist-org/alist/v3/drivers"
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/stream"
+	"github.com/alist-org/alist/v3/internal/testutil"
+	"golang.org/x/time/rate"
+)
+
+func TestUploadReplace(t *testing.T) {
+	testutil.InitDB()
+	conf.Conf.TempDir = t.TempDir()
+	stream.ClientUploadLimit = rate.NewLimiter(rate.Inf, 0)
+	role := &model.Role{Name: "ftp", PermissionScopes: []model.PermissionEntry{{Path: "/", Permission: 0xFFFF}}}
+	err := op.CreateRole(role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &model.User{Username: "ftp", BasePath: "/", Role: model.Roles{int(role.ID)}}
+	_, dir := testutil.CreateLocal(t, "/local")
+	ctx := context.WithValue(context.Background(), "user", user)
+	ctx = context.WithValue(ctx, "meta_pass", "")
+	ctx = context.WithValue(ctx, "client_ip", "127.0.0.1")
+	ctx = context.WithValue(ctx, "proxy_header", &http.Header{})
+	put := func(path, content string) error {
+		f, err := OpenUpload(ctx, path)
+		if err != nil {
+			return err
+		}
+		if _, err = f.Write([]byte(content)); err != nil {
+			return err
+		}
+		return f.CloseSync()
+	}
+
+	// a refused upload keeps the file it was meant to replace
+	if err = os.MkdirAll(filepath.Join(dir, "drop"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "drop", "tool.exe"), []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err = op.CreateMeta(&model.Meta{Path: "/local/drop", UploadDeny: "exe"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = put("/local/drop/tool.exe", "new"); err == nil {
+		t.Fatalf("expected the upload to be refused")
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "drop", "tool.exe")); string(data) != "old" {
+		t.Fatalf("expected the refused upload to keep the file, got %q", data)
+	}
+
+	// an overwrite keeps the previous content as a version
+	if err = op.CreateMeta(&model.Meta{Path: "/local/docs", Versioning: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, content := range []string{"v1", "v2"} {
+		if err = put("/local/docs/doc.txt", content); err != nil {
+			t.Fatalf("failed to upload: %+v", err)
+		}
+	}
+	storage, err := op.GetStorageByMountPath("/local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions, err := op.ListVersions(context.Background(), storage, "/docs/doc.txt")
+	if err != nil || len(versions) != 1 {
+		t.Fatalf("expected 1 version, got %d %v", len(versions), err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "docs", "doc.txt")); string(data) != "v2" {
+		t.Fatalf("expected v2, got %q", data)
+	}
+}
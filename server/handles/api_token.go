@@ -0,0 +1,156 @@
+package handles
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+type APITokenCreateReq struct {
+	Name        string   `json:"name" binding:"required"`
+	Permission  int32    `json:"permission"`
+	Paths       []string `json:"paths"`
+	ReadOnly    bool     `json:"read_only"`
+	ExpireAt    string   `json:"expire_at"`
+	ExpireHours int64    `json:"expire_hours"`
+}
+
+type APITokenCreateResp struct {
+	model.APIToken
+	Token             string `json:"token"`
+	S3SecretAccessKey string `json:"s3_secret_access_key"`
+}
+
+func CreateMyAPIToken(c *gin.Context) {
+	userObj, ok := c.Value("user").(*model.User)
+	if !ok || userObj.IsGuest() {
+		common.ErrorStrResp(c, "user invalid", 401)
+		return
+	}
+	var req APITokenCreateReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		common.ErrorStrResp(c, "name is required", 400)
+		return
+	}
+	expiresAt, err := resolveShareExpireAt(req.ExpireAt, req.ExpireHours)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	paths := make(model.PathList, 0, len(req.Paths))
+	for _, p := range req.Paths {
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+		reqPath, err := userObj.JoinPath(p)
+		if err != nil {
+			common.ErrorResp(c, err, 403)
+			return
+		}
+		paths = append(paths, reqPath)
+	}
+	t := &model.APIToken{
+		UserID:     userObj.ID,
+		Name:       req.Name,
+		Permission: req.Permission,
+		Paths:      paths,
+		ReadOnly:   req.ReadOnly,
+		ExpiresAt:  expiresAt,
+	}
+	token, err := op.CreateAPIToken(t)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, APITokenCreateResp{
+		APIToken:          *t,
+		Token:             token,
+		S3SecretAccessKey: op.APITokenS3Secret(t),
+	})
+}
+
+func ListMyAPITokens(c *gin.Context) {
+	userObj, ok := c.Value("user").(*model.User)
+	if !ok || userObj.IsGuest() {
+		common.ErrorStrResp(c, "user invalid", 401)
+		return
+	}
+	listAPITokens(c, userObj)
+}
+
+func DeleteMyAPIToken(c *gin.Context) {
+	userObj, ok := c.Value("user").(*model.User)
+	if !ok || userObj.IsGuest() {
+		common.ErrorStrResp(c, "user invalid", 401)
+		return
+	}
+	tokenId, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorStrResp(c, "id format invalid", 400)
+		return
+	}
+	t, err := op.GetAPITokenByIdAndUserId(uint(tokenId), userObj.ID)
+	if err != nil {
+		common.ErrorStrResp(c, "failed to get api token", 404)
+		return
+	}
+	if err := op.DeleteAPITokenById(t.ID); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+func ListAPITokens(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Query("uid"))
+	if err != nil {
+		common.ErrorStrResp(c, "user id format invalid", 400)
+		return
+	}
+	userObj, err := op.GetUserById(uint(userId))
+	if err != nil {
+		common.ErrorStrResp(c, "user invalid", 404)
+		return
+	}
+	listAPITokens(c, userObj)
+}
+
+func DeleteAPIToken(c *gin.Context) {
+	tokenId, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorStrResp(c, "id format invalid", 400)
+		return
+	}
+	if err := op.DeleteAPITokenById(uint(tokenId)); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+func listAPITokens(c *gin.Context, userObj *model.User) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	tokens, total, err := op.GetAPITokensByUserId(userObj.ID, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: tokens,
+		Total:   total,
+	})
+}
@@ -365,7 +365,7 @@ func ArchiveProxy(c *gin.Context) {
 		common.ErrorResp(c, err, 500)
 		return
 	}
-	if canProxy(storage, filename) {
+	if common.CanProxy(storage, filename) {
 		// TODO: Support external download proxy URL
 		link, file, err := fs.ArchiveDriverExtract(c, archiveRawPath, model.ArchiveInnerArgs{
 			ArchiveArgs: model.ArchiveArgs{
@@ -8,9 +8,7 @@ import (
 	"image/png"
 	"path"
 	"strings"
-	"time"
 
-	"github.com/Xhofe/go-cache"
 	"github.com/alist-org/alist/v3/internal/conf"
 	"github.com/alist-org/alist/v3/internal/device"
 	"github.com/alist-org/alist/v3/internal/errs"
@@ -24,10 +22,10 @@ import (
 	"github.com/pquerna/otp/totp"
 )
 
-var loginCache = cache.NewMemCache[int]()
+var loginCache = common.LoginCache
 var (
-	defaultDuration            = time.Minute * 5
-	defaultTimes               = 5
+	defaultDuration            = common.LoginLockDuration
+	defaultTimes               = common.LoginMaxAttempts
 	invalidLoginCredentialsMsg = "username or password is incorrect"
 )
 
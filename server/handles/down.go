@@ -8,7 +8,6 @@ import (
 	"strconv"
 
 	"github.com/alist-org/alist/v3/internal/conf"
-	"github.com/alist-org/alist/v3/internal/driver"
 	"github.com/alist-org/alist/v3/internal/fs"
 	"github.com/alist-org/alist/v3/internal/model"
 	"github.com/alist-org/alist/v3/internal/setting"
@@ -70,7 +69,7 @@ func Proxy(c *gin.Context) {
 		localProxy(c, link, file, storage.GetStorage().ProxyRange)
 		return
 	}
-	if canProxy(storage, filename) {
+	if common.CanProxy(storage, filename) {
 		downProxyUrl := storage.GetStorage().DownProxyUrl
 		if downProxyUrl != "" {
 			_, ok := c.GetQuery("d")
@@ -177,26 +176,3 @@ func localProxy(c *gin.Context, link *model.Link, file model.Obj, proxyRange boo
 		common.ErrorResp(c, err, 500, true)
 	}
 }
-
-// TODO need optimize
-// when can be proxy?
-// 1. text file
-// 2. config.MustProxy()
-// 3. storage.WebProxy
-// 4. proxy_types
-// solution: text_file + shouldProxy()
-func canProxy(storage driver.Driver, filename string) bool {
-	if storage.Config().MustProxy() || storage.GetStorage().WebProxy || storage.GetStorage().WebdavProxy() {
-		return true
-	}
-	if storage.GetStorage().Driver == "Quark" && utils.GetFileType(filename) == conf.VIDEO {
-		return true
-	}
-	if utils.SliceContains(conf.SlicesMap[conf.ProxyTypes], utils.Ext(filename)) {
-		return true
-	}
-	if utils.SliceContains(conf.SlicesMap[conf.TextTypes], utils.Ext(filename)) {
-		return true
-	}
-	return false
-}
@@ -66,6 +66,9 @@ type MoveCopyReq struct {
 	DstDir    string   `json:"dst_dir"`
 	Names     []string `json:"names"`
 	Overwrite bool     `json:"overwrite"`
+	// Verify and Conflict only apply to copy
+	Verify   bool   `json:"verify"`
+	Conflict string `json:"conflict"`
 }
 
 func FsMove(c *gin.Context) {
@@ -145,6 +148,11 @@ func FsCopy(c *gin.Context) {
 		common.ErrorStrResp(c, "Empty file names", 400)
 		return
 	}
+	args := fs.CopyArgs{Verify: req.Verify, Conflict: req.Conflict}
+	if err := args.Validate(); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
 	user := c.MustGet("user").(*model.User)
 	srcDir, err := user.JoinPath(req.SrcDir)
 	if err != nil {
@@ -169,7 +177,8 @@ func FsCopy(c *gin.Context) {
 		common.ErrorResp(c, errs.PermissionDenied, 403)
 		return
 	}
-	if !req.Overwrite {
+	// a conflict mode decides per file whether the destination is replaced
+	if !req.Overwrite && req.Conflict == fs.ConflictOverwrite {
 		for _, name := range req.Names {
 			dstPath, err := utils.JoinUnderBase(dstDir, name)
 			if err != nil {
@@ -194,7 +203,7 @@ func FsCopy(c *gin.Context) {
 			common.ErrorResp(c, err, 400)
 			return
 		}
-		t, err := fs.Copy(c, srcPath, dstDir, len(req.Names) > i+1)
+		t, err := fs.CopyWithArgs(c, srcPath, dstDir, args, len(req.Names) > i+1)
 		if t != nil {
 			addedTasks = append(addedTasks, t)
 		}
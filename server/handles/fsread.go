@@ -165,7 +165,7 @@ func FsDirs(c *gin.Context) {
 	user := c.MustGet("user").(*model.User)
 	reqPath := req.Path
 	if req.ForceRoot {
-		if !user.IsAdmin() {
+		if !user.IsAdmin() || user.Scope != nil {
 			common.ErrorStrResp(c, "Permission denied", 403)
 			return
 		}
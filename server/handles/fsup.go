@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/alist-org/alist/v3/internal/errs"
 	"github.com/alist-org/alist/v3/internal/fs"
 	"github.com/alist-org/alist/v3/internal/model"
 	"github.com/alist-org/alist/v3/internal/stream"
@@ -14,6 +15,7 @@ import (
 	"github.com/alist-org/alist/v3/pkg/utils"
 	"github.com/alist-org/alist/v3/server/common"
 	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
 )
 
 func getLastModified(c *gin.Context) time.Time {
@@ -88,6 +90,10 @@ func FsStream(c *gin.Context) {
 		err = fs.PutDirectly(c, dir, s, true)
 	}
 	defer c.Request.Body.Close()
+	if errors.Is(err, errs.UploadNotAllowed) {
+		common.ErrorResp(c, err, 403)
+		return
+	}
 	if err != nil {
 		common.ErrorResp(c, err, 500)
 		return
@@ -181,6 +187,10 @@ func FsForm(c *gin.Context) {
 	} else {
 		err = fs.PutDirectly(c, dir, &s, true)
 	}
+	if errors.Is(err, errs.UploadNotAllowed) {
+		common.ErrorResp(c, err, 403)
+		return
+	}
 	if err != nil {
 		common.ErrorResp(c, err, 500)
 		return
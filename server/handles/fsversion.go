@@ -0,0 +1,116 @@
+package handles
+
+import (
+	"fmt"
+	stdpath "path"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/sign"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+type FsVersionsReq struct {
+	Path     string `json:"path" form:"path"`
+	Password string `json:"password" form:"password"`
+}
+
+type VersionResp struct {
+	Version  string    `json:"version"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	RawURL   string    `json:"raw_url"`
+}
+
+func FsVersions(c *gin.Context) {
+	var req FsVersionsReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.MustGet("user").(*model.User)
+	reqPath, err := user.JoinPath(req.Path)
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	meta, err := op.GetNearestMeta(stdpath.Dir(reqPath))
+	if err != nil {
+		if !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+			common.ErrorResp(c, err, 500)
+			return
+		}
+	}
+	if !common.CanAccessWithRoles(user, meta, reqPath, req.Password) {
+		common.ErrorStrResp(c, "password is incorrect or you have no permission", 403)
+		return
+	}
+	versions, err := fs.ListVersions(c, reqPath)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	resp := make([]VersionResp, 0, len(versions))
+	for _, v := range versions {
+		versionPath, _ := op.GetVersionPath(reqPath, v.GetName())
+		resp = append(resp, VersionResp{
+			Version:  v.GetName(),
+			Size:     v.GetSize(),
+			Modified: v.ModTime(),
+			RawURL: fmt.Sprintf("%s/d%s?sign=%s",
+				common.GetApiUrl(c.Request),
+				utils.EncodePath(versionPath, true),
+				sign.Sign(versionPath)),
+		})
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: resp,
+		Total:   int64(len(resp)),
+	})
+}
+
+type FsRestoreVersionReq struct {
+	Path    string `json:"path" binding:"required"`
+	Version string `json:"version" binding:"required"`
+}
+
+func FsRestoreVersion(c *gin.Context) {
+	var req FsRestoreVersionReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.MustGet("user").(*model.User)
+	reqPath, err := user.JoinPath(req.Path)
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	if !common.CheckPathLimitWithRoles(user, reqPath) {
+		common.ErrorResp(c, errs.PermissionDenied, 403)
+		return
+	}
+	meta, err := op.GetNearestMeta(stdpath.Dir(reqPath))
+	if err != nil {
+		if !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+			common.ErrorResp(c, err, 500, true)
+			return
+		}
+	}
+	perm := common.MergeRolePermissions(user, reqPath)
+	if !common.HasPermission(perm, common.PermWrite) && !common.CanWrite(meta, stdpath.Dir(reqPath)) {
+		common.ErrorResp(c, errs.PermissionDenied, 403)
+		return
+	}
+	if err := fs.RestoreVersion(c, reqPath, req.Version); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c)
+}
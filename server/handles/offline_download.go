@@ -109,6 +109,39 @@ func SetTransmission(c *gin.Context) {
 	common.SuccessResp(c, "ok")
 }
 
+type SetBitTorrentReq struct {
+	ListenPort string `json:"listen_port" form:"listen_port"`
+	SeedRatio  string `json:"seed_ratio" form:"seed_ratio"`
+	Seedtime   string `json:"seedtime" form:"seedtime"`
+}
+
+func SetBitTorrent(c *gin.Context) {
+	var req SetBitTorrentReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	_tool, err := tool.Tools.Get("BitTorrent")
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	items := []model.SettingItem{
+		{Key: conf.BitTorrentListenPort, Value: req.ListenPort, Type: conf.TypeNumber, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE},
+		{Key: conf.BitTorrentSeedRatio, Value: req.SeedRatio, Type: conf.TypeNumber, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE},
+		{Key: conf.BitTorrentSeedtime, Value: req.Seedtime, Type: conf.TypeNumber, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE},
+	}
+	if err := op.SaveSettingItems(items); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	if _, err := _tool.Init(); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, "ok")
+}
+
 type Set115Req struct {
 	TempDir string `json:"temp_dir" form:"temp_dir"`
 }
@@ -0,0 +1,231 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/offline_download/rss"
+	"github.com/alist-org/alist/v3/internal/offline_download/tool"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+type RSSSubscriptionReq struct {
+	ID           uint   `json:"id"`
+	Name         string `json:"name"`
+	URL          string `json:"url" binding:"required"`
+	Interval     int    `json:"interval"`
+	Include      string `json:"include"`
+	Exclude      string `json:"exclude"`
+	Path         string `json:"path"`
+	Tool         string `json:"tool" binding:"required"`
+	DeletePolicy string `json:"delete_policy"`
+	Disabled     bool   `json:"disabled"`
+}
+
+func (req *RSSSubscriptionReq) apply(sub *model.RSSSubscription) {
+	sub.Name = req.Name
+	sub.URL = req.URL
+	sub.Interval = req.Interval
+	sub.Include = req.Include
+	sub.Exclude = req.Exclude
+	sub.Path = req.Path
+	sub.Tool = req.Tool
+	sub.DeletePolicy = req.DeletePolicy
+	sub.Disabled = req.Disabled
+}
+
+// checkRSSSubscription makes sure the tool exists and the owner may add
+// offline downloads to the destination of sub.
+func checkRSSSubscription(c *gin.Context, user *model.User, sub *model.RSSSubscription) bool {
+	if _, err := tool.Tools.Get(sub.Tool); err != nil {
+		common.ErrorResp(c, err, 400)
+		return false
+	}
+	reqPath, err := user.JoinPath(sub.Path)
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return false
+	}
+	if !common.CheckPathLimitWithRoles(user, reqPath) ||
+		!common.HasPermission(common.MergeRolePermissions(user, reqPath), common.PermAddOfflineDownload) {
+		common.ErrorResp(c, errs.PermissionDenied, 403)
+		return false
+	}
+	return true
+}
+
+// getRSSSubscription returns the subscription of the "id" query, it has to
+// belong to user unless user is nil.
+func getRSSSubscription(c *gin.Context, user *model.User) (*model.RSSSubscription, bool) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorStrResp(c, "id format invalid", 400)
+		return nil, false
+	}
+	sub, err := op.GetRSSSubscriptionById(uint(id))
+	if err != nil || (user != nil && sub.UserID != user.ID) {
+		common.ErrorStrResp(c, "failed to get rss subscription", 404)
+		return nil, false
+	}
+	return sub, true
+}
+
+func listRSSSubscriptions(c *gin.Context, userId uint) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	subs, total, err := op.GetRSSSubscriptions(userId, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: subs,
+		Total:   total,
+	})
+}
+
+func updateRSSSubscription(c *gin.Context, user *model.User) {
+	var req RSSSubscriptionReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	sub, err := op.GetRSSSubscriptionById(req.ID)
+	if err != nil || (user != nil && sub.UserID != user.ID) {
+		common.ErrorStrResp(c, "failed to get rss subscription", 404)
+		return
+	}
+	req.apply(sub)
+	if user != nil && !checkRSSSubscription(c, user, sub) {
+		return
+	}
+	if err := op.UpdateRSSSubscription(sub); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+func deleteRSSSubscription(c *gin.Context, user *model.User) {
+	sub, ok := getRSSSubscription(c, user)
+	if !ok {
+		return
+	}
+	if err := op.DeleteRSSSubscriptionById(sub.ID); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+func listRSSItems(c *gin.Context, user *model.User) {
+	sub, ok := getRSSSubscription(c, user)
+	if !ok {
+		return
+	}
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	items, total, err := op.GetRSSItems(sub.ID, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: items,
+		Total:   total,
+	})
+}
+
+func checkRSSSubscriptionNow(c *gin.Context, user *model.User) {
+	sub, ok := getRSSSubscription(c, user)
+	if !ok {
+		return
+	}
+	added, err := rss.Check(c, sub)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, added)
+}
+
+func CreateMyRSSSubscription(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+	var req RSSSubscriptionReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	sub := &model.RSSSubscription{UserID: user.ID}
+	req.apply(sub)
+	if !checkRSSSubscription(c, user, sub) {
+		return
+	}
+	if err := op.CreateRSSSubscription(sub); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, sub)
+}
+
+func ListMyRSSSubscriptions(c *gin.Context) {
+	listRSSSubscriptions(c, c.MustGet("user").(*model.User).ID)
+}
+
+func UpdateMyRSSSubscription(c *gin.Context) {
+	updateRSSSubscription(c, c.MustGet("user").(*model.User))
+}
+
+func DeleteMyRSSSubscription(c *gin.Context) {
+	deleteRSSSubscription(c, c.MustGet("user").(*model.User))
+}
+
+func ListMyRSSItems(c *gin.Context) {
+	listRSSItems(c, c.MustGet("user").(*model.User))
+}
+
+func CheckMyRSSSubscription(c *gin.Context) {
+	checkRSSSubscriptionNow(c, c.MustGet("user").(*model.User))
+}
+
+// ListRSSSubscriptions lists the subscriptions of the "uid" query, or of
+// every user without it.
+func ListRSSSubscriptions(c *gin.Context) {
+	var userId uint
+	if uid := c.Query("uid"); uid != "" {
+		id, err := strconv.Atoi(uid)
+		if err != nil {
+			common.ErrorStrResp(c, "user id format invalid", 400)
+			return
+		}
+		userId = uint(id)
+	}
+	listRSSSubscriptions(c, userId)
+}
+
+func UpdateRSSSubscription(c *gin.Context) {
+	updateRSSSubscription(c, nil)
+}
+
+func DeleteRSSSubscription(c *gin.Context) {
+	deleteRSSSubscription(c, nil)
+}
+
+func ListRSSItems(c *gin.Context) {
+	listRSSItems(c, nil)
+}
+
+func CheckRSSSubscription(c *gin.Context) {
+	checkRSSSubscriptionNow(c, nil)
+}
@@ -0,0 +1,175 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+type S3CredentialCreateReq struct {
+	UserID  uint            `json:"user_id"` // only used by admins
+	Name    string          `json:"name"`
+	Buckets model.S3Buckets `json:"buckets"`
+}
+
+type S3CredentialUpdateReq struct {
+	ID       uint            `json:"id" binding:"required"`
+	Name     string          `json:"name"`
+	Buckets  model.S3Buckets `json:"buckets"`
+	Disabled bool            `json:"disabled"`
+}
+
+type S3CredentialCreateResp struct {
+	model.S3Credential
+	SecretAccessKey string `json:"secret_access_key"`
+}
+
+func createS3Credential(c *gin.Context, userId uint, req *S3CredentialCreateReq) {
+	cred := &model.S3Credential{
+		UserID:  userId,
+		Name:    req.Name,
+		Buckets: req.Buckets,
+	}
+	if err := op.CreateS3Credential(cred); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, S3CredentialCreateResp{
+		S3Credential:    *cred,
+		SecretAccessKey: cred.SecretAccessKey,
+	})
+}
+
+func CreateMyS3Credential(c *gin.Context) {
+	userObj, ok := c.Value("user").(*model.User)
+	if !ok || userObj.IsGuest() {
+		common.ErrorStrResp(c, "user invalid", 401)
+		return
+	}
+	var req S3CredentialCreateReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	createS3Credential(c, userObj.ID, &req)
+}
+
+func UpdateMyS3Credential(c *gin.Context) {
+	userObj, ok := c.Value("user").(*model.User)
+	if !ok || userObj.IsGuest() {
+		common.ErrorStrResp(c, "user invalid", 401)
+		return
+	}
+	var req S3CredentialUpdateReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	cred, err := op.GetS3CredentialById(req.ID)
+	if err != nil || cred.UserID != userObj.ID {
+		common.ErrorStrResp(c, "failed to get s3 credential", 404)
+		return
+	}
+	cred.Name = req.Name
+	cred.Buckets = req.Buckets
+	cred.Disabled = req.Disabled
+	if err := op.UpdateS3Credential(cred); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+func ListMyS3Credentials(c *gin.Context) {
+	userObj, ok := c.Value("user").(*model.User)
+	if !ok || userObj.IsGuest() {
+		common.ErrorStrResp(c, "user invalid", 401)
+		return
+	}
+	listS3Credentials(c, userObj)
+}
+
+func DeleteMyS3Credential(c *gin.Context) {
+	userObj, ok := c.Value("user").(*model.User)
+	if !ok || userObj.IsGuest() {
+		common.ErrorStrResp(c, "user invalid", 401)
+		return
+	}
+	credId, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorStrResp(c, "id format invalid", 400)
+		return
+	}
+	cred, err := op.GetS3CredentialById(uint(credId))
+	if err != nil || cred.UserID != userObj.ID {
+		common.ErrorStrResp(c, "failed to get s3 credential", 404)
+		return
+	}
+	if err := op.DeleteS3CredentialById(cred.ID); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+func CreateS3Credential(c *gin.Context) {
+	var req S3CredentialCreateReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	userObj, err := op.GetUserById(req.UserID)
+	if err != nil || userObj.IsGuest() {
+		common.ErrorStrResp(c, "user invalid", 404)
+		return
+	}
+	createS3Credential(c, userObj.ID, &req)
+}
+
+func ListS3Credentials(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Query("uid"))
+	if err != nil {
+		common.ErrorStrResp(c, "user id format invalid", 400)
+		return
+	}
+	userObj, err := op.GetUserById(uint(userId))
+	if err != nil {
+		common.ErrorStrResp(c, "user invalid", 404)
+		return
+	}
+	listS3Credentials(c, userObj)
+}
+
+func DeleteS3Credential(c *gin.Context) {
+	credId, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorStrResp(c, "id format invalid", 400)
+		return
+	}
+	if err := op.DeleteS3CredentialById(uint(credId)); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+func listS3Credentials(c *gin.Context, userObj *model.User) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	creds, total, err := op.GetS3CredentialsByUserId(userObj.ID, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: creds,
+		Total:   total,
+	})
+}
@@ -402,7 +402,7 @@ func buildPublicShareAssetURL(c *gin.Context, prefix, shareID, relPath, token st
 func buildPublicSharePreviewURL(c *gin.Context, obj model.Obj, targetPath, shareID, relPath, token string) string {
 	prefix := "/sd/"
 	storage, err := fs.GetStorage(targetPath, &fs.GetStoragesArgs{})
-	if err == nil && canProxy(storage, obj.GetName()) {
+	if err == nil && common.CanProxy(storage, obj.GetName()) {
 		prefix = "/sp/"
 	}
 	return buildPublicShareAssetURL(c, prefix, shareID, relPath, token, true)
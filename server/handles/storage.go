@@ -4,8 +4,11 @@ import (
 	"context"
 	"strconv"
 
+	"github.com/alist-org/alist/v3/drivers/chunker"
+	"github.com/alist-org/alist/v3/internal/chunkcheck"
 	"github.com/alist-org/alist/v3/internal/conf"
 	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/keyrotate"
 	"github.com/alist-org/alist/v3/internal/model"
 	"github.com/alist-org/alist/v3/internal/op"
 	"github.com/alist-org/alist/v3/server/common"
@@ -13,6 +16,13 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// StorageResp is a storage with the upstream calls queued by its limiter
+type StorageResp struct {
+	model.Storage
+	Waiting  int64 `json:"waiting"`
+	InFlight int64 `json:"in_flight"`
+}
+
 func ListStorages(c *gin.Context) {
 	var req model.PageReq
 	if err := c.ShouldBind(&req); err != nil {
@@ -26,8 +36,13 @@ func ListStorages(c *gin.Context) {
 		common.ErrorResp(c, err, 500)
 		return
 	}
+	resp := make([]StorageResp, len(storages))
+	for i, storage := range storages {
+		resp[i].Storage = storage
+		resp[i].Waiting, resp[i].InFlight = op.GetStorageQueue(storage.ID)
+	}
 	common.SuccessResp(c, common.PageResp{
-		Content: storages,
+		Content: resp,
 		Total:   total,
 	})
 }
@@ -119,6 +134,66 @@ func GetStorage(c *gin.Context) {
 	common.SuccessResp(c, storage)
 }
 
+type CryptRotateReq struct {
+	ID       uint   `json:"id" binding:"required"`
+	Path     string `json:"path"`
+	Password string `json:"password" binding:"required"`
+	Salt     string `json:"salt"`
+}
+
+// CryptRotate re-encrypts a crypt storage with a new password and salt in
+// a background task
+func CryptRotate(c *gin.Context) {
+	var req CryptRotateReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	storage, err := db.GetStorageById(req.ID)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	user := c.MustGet("user").(*model.User)
+	t, err := keyrotate.AddTask(user, storage, req.Path, req.Password, req.Salt)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, gin.H{
+		"task": getTaskInfo(t),
+	})
+}
+
+type ChunkerCheckReq struct {
+	ID   uint   `json:"id" binding:"required"`
+	Path string `json:"path"`
+	chunker.CheckOptions
+}
+
+// ChunkerCheck verifies the chunks of a chunker storage in a background task
+func ChunkerCheck(c *gin.Context) {
+	var req ChunkerCheckReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	storage, err := db.GetStorageById(req.ID)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	user := c.MustGet("user").(*model.User)
+	t, err := chunkcheck.AddTask(user, storage, req.Path, req.CheckOptions)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, gin.H{
+		"task": getTaskInfo(t),
+	})
+}
+
 func LoadAllStorages(c *gin.Context) {
 	storages, err := db.GetEnabledStorages()
 	if err != nil {
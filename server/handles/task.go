@@ -6,7 +6,9 @@ import (
 	"math"
 	"time"
 
+	"github.com/alist-org/alist/v3/internal/chunkcheck"
 	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/keyrotate"
 	"github.com/alist-org/alist/v3/internal/offline_download/tool"
 	"github.com/alist-org/alist/v3/pkg/utils"
 	"github.com/alist-org/alist/v3/server/common"
@@ -26,6 +28,7 @@ type TaskInfo struct {
 	EndTime     *time.Time  `json:"end_time"`
 	TotalBytes  int64       `json:"total_bytes"`
 	Error       string      `json:"error"`
+	Details     any         `json:"details,omitempty"`
 }
 
 func getTaskInfo[T task.TaskExtensionInfo](task T) TaskInfo {
@@ -56,9 +59,17 @@ func getTaskInfo[T task.TaskExtensionInfo](task T) TaskInfo {
 		EndTime:     task.GetEndTime(),
 		TotalBytes:  task.GetTotalBytes(),
 		Error:       errMsg,
+		Details:     getTaskDetails(task),
 	}
 }
 
+func getTaskDetails(t any) any {
+	if d, ok := t.(task.TaskWithDetails); ok {
+		return d.GetDetails()
+	}
+	return nil
+}
+
 func getTaskInfos[T task.TaskExtensionInfo](tasks []T) []TaskInfo {
 	return utils.MustSliceConvert(tasks, getTaskInfo[T])
 }
@@ -221,6 +232,8 @@ func SetupTaskRoute(g *gin.RouterGroup) {
 	taskRoute(g.Group("/offline_download"), tool.DownloadTaskManager)
 	taskRoute(g.Group("/offline_download_transfer"), tool.TransferTaskManager)
 	taskRoute(g.Group("/s3_transition"), fs.S3TransitionTaskManager)
+	taskRoute(g.Group("/crypt_rotate"), keyrotate.TaskManager)
+	taskRoute(g.Group("/chunker_check"), chunkcheck.TaskManager)
 	taskRoute(g.Group("/decompress"), fs.ArchiveDownloadTaskManager)
 	taskRoute(g.Group("/decompress_upload"), fs.ArchiveContentUploadTaskManager)
 }
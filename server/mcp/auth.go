@@ -64,6 +64,21 @@ func authenticateToken(token string) (*model.User, error) {
 		return guest, nil
 	}
 
+	// Personal access token
+	if strings.HasPrefix(token, model.APITokenPrefix) {
+		user, err := op.GetUserByAPIToken(token)
+		if err != nil {
+			return nil, fmt.Errorf("invalid api token: %w", err)
+		}
+		if user.Disabled {
+			return nil, fmt.Errorf("user is disabled")
+		}
+		if err := loadRoles(user); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
 	// JWT token
 	claims, err := common.ParseToken(token)
 	if err != nil {
@@ -127,7 +142,7 @@ func checkAccess(user *model.User, reqPath string) error {
 		return fmt.Errorf("permission denied")
 	}
 	perm := common.MergeRolePermissions(user, reqPath)
-	if !user.IsAdmin() && !common.HasPermission(perm, common.PermMCPAccess) {
+	if !unrestrictedAdmin(user) && !common.HasPermission(perm, common.PermMCPAccess) {
 		return fmt.Errorf("MCP access not permitted")
 	}
 	return nil
@@ -139,15 +154,21 @@ func checkManage(user *model.User, reqPath string, permBit uint) error {
 		return err
 	}
 	perm := common.MergeRolePermissions(user, reqPath)
-	if !user.IsAdmin() && !common.HasPermission(perm, common.PermMCPManage) {
+	if !unrestrictedAdmin(user) && !common.HasPermission(perm, common.PermMCPManage) {
 		return fmt.Errorf("MCP manage not permitted")
 	}
-	if !user.IsAdmin() && !common.HasPermission(perm, permBit) {
+	if !unrestrictedAdmin(user) && !common.HasPermission(perm, permBit) {
 		return fmt.Errorf("permission denied for this operation")
 	}
 	return nil
 }
 
+// unrestrictedAdmin reports whether permission bits can be skipped for user,
+// admins authenticated by a scoped api token are checked like everyone else.
+func unrestrictedAdmin(user *model.User) bool {
+	return user.IsAdmin() && user.Scope == nil
+}
+
 // UserContextFunc returns an HTTPContextFunc that injects a specific user (for STDIO mode).
 func userContextMiddleware(user *model.User) func(ctx context.Context) context.Context {
 	return func(ctx context.Context) context.Context {
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/metrics"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics serves the Prometheus metrics, behind the bearer token if one
+// is configured
+func Metrics(c *gin.Context) {
+	if token := conf.Conf.Metrics.Token; token != "" {
+		bearer := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="metrics"`)
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+	}
+	metrics.Handler().ServeHTTP(c.Writer, c.Request)
+}
+
+// metricsOnMainListener reports whether the metrics are served by the main
+// http server. The main listener is public so a token is required there.
+func metricsOnMainListener() bool {
+	if !conf.Conf.Metrics.Enable || conf.Conf.Metrics.Port != -1 {
+		return false
+	}
+	if conf.Conf.Metrics.Token == "" {
+		utils.Log.Warnf("metrics are not served on the main listener without a token, set metrics.token or metrics.port")
+		return false
+	}
+	return true
+}
+
+// InitMetrics serves the metrics on their own port
+func InitMetrics(e *gin.Engine) {
+	e.GET("/metrics", Metrics)
+}
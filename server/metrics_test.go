@@ -0,0 +1,23 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+)
+
+func TestMetricsOnMainListener(t *testing.T) {
+	conf.Conf = conf.DefaultConfig()
+	conf.Conf.Metrics.Enable = true
+	if metricsOnMainListener() {
+		t.Fatal("metrics served on the main listener without a token")
+	}
+	conf.Conf.Metrics.Token = "scrape"
+	if !metricsOnMainListener() {
+		t.Fatal("expected the metrics on the main listener with a token")
+	}
+	conf.Conf.Metrics.Port = 9090
+	if metricsOnMainListener() {
+		t.Fatal("metrics served on the main listener with their own port")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/subtle"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/alist-org/alist/v3/internal/conf"
 	"github.com/alist-org/alist/v3/internal/device"
@@ -63,6 +64,34 @@ func Auth(c *gin.Context) {
 		c.Next()
 		return
 	}
+	if strings.HasPrefix(token, model.APITokenPrefix) {
+		user, err := op.GetUserByAPIToken(token)
+		if err != nil {
+			common.ErrorResp(c, err, 401)
+			c.Abort()
+			return
+		}
+		if user.Disabled {
+			common.ErrorStrResp(c, "Current user is disabled, replace please", 401)
+			c.Abort()
+			return
+		}
+		if len(user.Role) > 0 {
+			roles, err := op.GetRolesByUserID(user.ID)
+			if err != nil {
+				common.ErrorStrResp(c, fmt.Sprintf("Fail to load roles: %v", err), 500)
+				c.Abort()
+				return
+			}
+			user.RolesDetail = roles
+		}
+		if !HandleSession(c, user) {
+			return
+		}
+		log.Debugf("use api token %d: %+v", user.Scope.ID, user)
+		c.Next()
+		return
+	}
 	userClaims, err := common.ParseToken(token)
 	if err != nil {
 		common.ErrorResp(c, err, 401)
@@ -207,6 +236,21 @@ func AuthAdmin(c *gin.Context) {
 	if !user.IsAdmin() {
 		common.ErrorStrResp(c, "You are not an admin", 403)
 		c.Abort()
+	} else if user.Scope != nil {
+		common.ErrorStrResp(c, "API tokens can not access admin APIs", 403)
+		c.Abort()
+	} else {
+		c.Next()
+	}
+}
+
+// AuthNotAPIToken rejects requests authenticated by a personal access token,
+// e.g. a token must not be able to mint new tokens.
+func AuthNotAPIToken(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+	if user.Scope != nil {
+		common.ErrorStrResp(c, "Not allowed with an API token", 403)
+		c.Abort()
 	} else {
 		c.Next()
 	}
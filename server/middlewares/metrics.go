@@ -0,0 +1,14 @@
+package middlewares
+
+import (
+	"github.com/alist-org/alist/v3/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// ServedBytes counts the bytes of the responses sent over protocol
+func ServedBytes(protocol string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		metrics.ServedBytes(protocol, c.Writer.Size())
+	}
+}
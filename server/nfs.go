@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/metrics"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/setting"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/alist-org/alist/v3/server/ftp"
+	"github.com/alist-org/alist/v3/server/nfs"
+	"github.com/pkg/errors"
+)
+
+type NfsDriver struct {
+	proxyHeader *http.Header
+	allowed     []*net.IPNet
+}
+
+func NewNfsDriver() (*NfsDriver, error) {
+	header := &http.Header{}
+	header.Add("User-Agent", setting.GetStr(conf.FTPProxyUserAgent))
+	d := &NfsDriver{proxyHeader: header}
+	for _, cidr := range conf.Conf.NFS.AllowedCIDRs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid nfs allowed cidr %s", cidr)
+		}
+		d.allowed = append(d.allowed, ipNet)
+	}
+	return d, nil
+}
+
+func NewNfsServer(d *NfsDriver) (*nfs.Server, error) {
+	return nfs.NewServer(conf.Conf.NFS.Listen, d, conf.Conf.NFS.HandleCacheSize)
+}
+
+func (d *NfsDriver) Allowed(addr net.Addr) bool {
+	if len(d.allowed) == 0 {
+		return true
+	}
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, ipNet := range d.allowed {
+		if ipNet.Contains(tcpAddr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *NfsDriver) ReadOnly() bool {
+	return conf.Conf.NFS.ReadOnly
+}
+
+// GetFileSystem returns the file system of the configured user, NFS clients
+// are not authenticated so they all act as this user
+func (d *NfsDriver) GetFileSystem(addr net.Addr) (*ftp.AferoAdapter, error) {
+	var userObj *model.User
+	var err error
+	if conf.Conf.NFS.User == "" {
+		userObj, err = op.GetGuest()
+	} else {
+		userObj, err = op.GetUserByName(conf.Conf.NFS.User)
+	}
+	if err != nil {
+		return nil, err
+	}
+	perm := common.MergeRolePermissions(userObj, userObj.BasePath)
+	if userObj.Disabled || !common.HasPermission(perm, common.PermFTPAccess) {
+		return nil, errors.New("user is not allowed to access via NFS")
+	}
+	ctx := context.Background()
+	ctx = context.WithValue(ctx, "user", userObj)
+	ctx = context.WithValue(ctx, "meta_pass", "")
+	ctx = context.WithValue(ctx, "client_ip", addr.String())
+	ctx = context.WithValue(ctx, "proxy_header", d.proxyHeader)
+	ctx = metrics.WithProtocol(ctx, metrics.NFS)
+	return ftp.NewAferoAdapter(ctx), nil
+}
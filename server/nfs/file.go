@@ -0,0 +1,310 @@
+package nfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	stdpath "path"
+	"strings"
+	"sync"
+	"time"
+
+	ftpserver "github.com/KirCute/ftpserverlib-pasvportmap"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/alist-org/alist/v3/server/ftp"
+	"github.com/pkg/errors"
+)
+
+const (
+	// readerIdle is how long an unused download stays open for following reads
+	readerIdle = 30 * time.Second
+	// flushIdle is how long written data waits for a COMMIT before it's uploaded
+	flushIdle = time.Minute
+)
+
+// openReader is a download kept open between READ calls
+type openReader struct {
+	mu       sync.Mutex
+	file     ftpserver.FileTransfer
+	lastUsed time.Time
+}
+
+// pendingWrite buffers the WRITE calls of a file until it's committed,
+// alist can only upload whole files
+type pendingWrite struct {
+	mu   sync.Mutex
+	path string
+	file *ftp.FileUploadProxy
+	size int64
+	// minSize is the size of the file the write started on, a rewrite has to
+	// cover it since the upload replaces the whole file
+	minSize  int64
+	modified time.Time
+	timer    *time.Timer
+	err      error
+	done     bool
+}
+
+func (p *pendingWrite) writeAt(data []byte, offset int64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.done {
+		return p.err
+	}
+	if _, err := p.file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := p.file.Write(data); err != nil {
+		return err
+	}
+	if end := offset + int64(len(data)); end > p.size {
+		p.size = end
+	}
+	p.modified = time.Now()
+	p.timer.Reset(flushIdle)
+	return nil
+}
+
+func (p *pendingWrite) truncate(size int64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.done {
+		return p.err
+	}
+	if err := p.file.Truncate(size); err != nil {
+		return err
+	}
+	p.size = size
+	p.minSize = min(p.minSize, size)
+	p.modified = time.Now()
+	p.timer.Reset(flushIdle)
+	return nil
+}
+
+// flush uploads the buffered file, only the first call does the upload
+func (p *pendingWrite) flush() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.done {
+		return p.err
+	}
+	p.done = true
+	p.timer.Stop()
+	if p.size < p.minSize {
+		// uploading would cut the rest of the file off
+		_ = p.file.Abort()
+		p.err = errors.Errorf("only %d of %d bytes of %s are rewritten", p.size, p.minSize, p.path)
+	} else if _, p.err = p.file.Seek(p.size, io.SeekStart); p.err == nil {
+		// the size of the upload is the offset of the buffer
+		p.err = p.file.CloseSync()
+	}
+	if p.err != nil {
+		utils.Log.Errorf("[nfs] failed to upload %s: %+v", p.path, p.err)
+	}
+	return p.err
+}
+
+func (p *pendingWrite) info() os.FileInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return &pendingInfo{name: stdpath.Base(p.path), size: p.size, modified: p.modified}
+}
+
+// pendingInfo describes a file which is not uploaded yet
+type pendingInfo struct {
+	name     string
+	size     int64
+	modified time.Time
+}
+
+func (i *pendingInfo) Name() string       { return i.name }
+func (i *pendingInfo) Size() int64        { return i.size }
+func (i *pendingInfo) Mode() fs.FileMode  { return 0755 }
+func (i *pendingInfo) ModTime() time.Time { return i.modified }
+func (i *pendingInfo) IsDir() bool        { return false }
+func (i *pendingInfo) Sys() any           { return nil }
+
+// fileCache keeps the downloads and uploads in progress, both are keyed by path
+type fileCache struct {
+	mu      sync.Mutex
+	readers map[string]*openReader
+	writes  map[string]*pendingWrite
+	closed  chan struct{}
+}
+
+func newFileCache() *fileCache {
+	c := &fileCache{
+		readers: make(map[string]*openReader),
+		writes:  make(map[string]*pendingWrite),
+		closed:  make(chan struct{}),
+	}
+	go c.cleanReaders()
+	return c
+}
+
+func (c *fileCache) cleanReaders() {
+	ticker := time.NewTicker(readerIdle)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-ticker.C:
+		}
+		c.mu.Lock()
+		for path, r := range c.readers {
+			if r.mu.TryLock() {
+				if time.Since(r.lastUsed) > readerIdle {
+					delete(c.readers, path)
+					_ = r.file.Close()
+				}
+				r.mu.Unlock()
+			}
+		}
+		c.mu.Unlock()
+	}
+}
+
+// readAt reads from the download of path opened by open, the download is
+// reused by the following reads
+func (c *fileCache) readAt(path string, p []byte, offset int64, open func() (ftpserver.FileTransfer, error)) (int, error) {
+	c.mu.Lock()
+	r, ok := c.readers[path]
+	if !ok {
+		file, err := open()
+		if err != nil {
+			c.mu.Unlock()
+			return 0, err
+		}
+		r = &openReader{file: file}
+		c.readers[path] = r
+	}
+	r.mu.Lock()
+	c.mu.Unlock()
+	defer r.mu.Unlock()
+	r.lastUsed = time.Now()
+	if _, err := r.file.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.ReadFull(r.file, p)
+}
+
+// pending returns the write in progress of path, or nil
+func (c *fileCache) pending(path string) *pendingWrite {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.writes[path]
+}
+
+// pendingIn returns the writes in progress of the files in dir
+func (c *fileCache) pendingIn(dir string) []*pendingWrite {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var res []*pendingWrite
+	for path, p := range c.writes {
+		if stdpath.Dir(path) == dir {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// startWrite buffers the writes of path until they are committed, the file
+// is replaced when it's uploaded. minSize is the size of the existing content
+// the writes have to cover.
+func (c *fileCache) startWrite(path string, adapter *ftp.AferoAdapter, minSize int64) (*pendingWrite, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok := c.writes[path]; ok {
+		return p, nil
+	}
+	c.closeReader(path)
+	file, err := adapter.GetHandle(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0)
+	if err != nil {
+		return nil, err
+	}
+	upload, ok := file.(*ftp.FileUploadProxy)
+	if !ok {
+		// the size of the file is unknown, so the upload is always buffered
+		_ = file.Close()
+		return nil, os.ErrInvalid
+	}
+	p := &pendingWrite{path: path, file: upload, minSize: minSize, modified: time.Now()}
+	p.timer = time.AfterFunc(flushIdle, func() {
+		_ = c.flush(path)
+	})
+	c.writes[path] = p
+	return p, nil
+}
+
+// flush uploads the pending write of path if there is one
+func (c *fileCache) flush(path string) error {
+	c.mu.Lock()
+	p, ok := c.writes[path]
+	c.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	err := p.flush()
+	c.mu.Lock()
+	if c.writes[path] == p {
+		delete(c.writes, path)
+	}
+	c.mu.Unlock()
+	return err
+}
+
+// forget flushes the pending writes of path and its children and closes
+// their downloads, it's called before path is changed
+func (c *fileCache) forget(path string) error {
+	under := func(p string) bool {
+		return p == path || strings.HasPrefix(p, path+"/")
+	}
+	c.mu.Lock()
+	var writes []string
+	for p := range c.writes {
+		if under(p) {
+			writes = append(writes, p)
+		}
+	}
+	for p := range c.readers {
+		if under(p) {
+			c.closeReader(p)
+		}
+	}
+	c.mu.Unlock()
+	var err error
+	for _, p := range writes {
+		if e := c.flush(p); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (c *fileCache) closeReader(path string) {
+	if r, ok := c.readers[path]; ok {
+		delete(c.readers, path)
+		go func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			_ = r.file.Close()
+		}()
+	}
+}
+
+func (c *fileCache) close() {
+	close(c.closed)
+	c.mu.Lock()
+	paths := make([]string, 0, len(c.writes))
+	for path := range c.writes {
+		paths = append(paths, path)
+	}
+	for path := range c.readers {
+		c.closeReader(path)
+	}
+	c.mu.Unlock()
+	for _, path := range paths {
+		_ = c.flush(path)
+	}
+}
@@ -0,0 +1,126 @@
+package nfs
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"strings"
+	"sync"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+const handleSize = 16
+
+// handleCache maps opaque file handles to paths. A handle is the random
+// id of the server instance followed by a counter, so handles issued
+// before a restart or evicted from the cache are reported as stale.
+// The root handle is never evicted.
+type handleCache struct {
+	mu       sync.Mutex
+	instance [8]byte
+	next     uint64
+	handles  *lru.Cache[string, string] // handle -> path
+	paths    map[string]string          // path -> handle
+}
+
+func newHandleCache(size int) (*handleCache, error) {
+	c := &handleCache{paths: make(map[string]string)}
+	if _, err := rand.Read(c.instance[:]); err != nil {
+		return nil, err
+	}
+	var err error
+	c.handles, err = lru.NewWithEvict[string, string](size, func(handle string, path string) {
+		if c.paths[path] == handle {
+			delete(c.paths, path)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *handleCache) newHandle() string {
+	b := make([]byte, handleSize)
+	copy(b, c.instance[:])
+	binary.BigEndian.PutUint64(b[8:], c.next)
+	c.next++
+	return string(b)
+}
+
+func (c *handleCache) root() []byte {
+	b := make([]byte, handleSize)
+	copy(b, c.instance[:])
+	return b
+}
+
+// toHandle returns the handle of path, a new one is issued if it's not cached
+func (c *handleCache) toHandle(path string) []byte {
+	if path == "/" {
+		return c.root()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if handle, ok := c.paths[path]; ok {
+		c.handles.Get(handle)
+		return []byte(handle)
+	}
+	if c.next == 0 {
+		// 0 is the root
+		c.next = 1
+	}
+	handle := c.newHandle()
+	c.paths[path] = handle
+	c.handles.Add(handle, path)
+	return []byte(handle)
+}
+
+// toPath returns the path of handle, ok is false if the handle is stale
+func (c *handleCache) toPath(handle []byte) (string, bool) {
+	if len(handle) != handleSize || string(handle[:8]) != string(c.instance[:]) {
+		return "", false
+	}
+	if binary.BigEndian.Uint64(handle[8:]) == 0 {
+		return "/", true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.handles.Get(string(handle))
+}
+
+// rename keeps the handles of from and its children valid after it's moved to to
+func (c *handleCache) rename(from, to string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, handle := range c.handles.Keys() {
+		path, ok := c.handles.Peek(handle)
+		if !ok {
+			continue
+		}
+		var newPath string
+		if path == from {
+			newPath = to
+		} else if strings.HasPrefix(path, from+"/") {
+			newPath = to + strings.TrimPrefix(path, from)
+		} else {
+			continue
+		}
+		if c.paths[path] == handle {
+			delete(c.paths, path)
+		}
+		if old, ok := c.paths[newPath]; ok && old != handle {
+			c.handles.Remove(old)
+		}
+		c.paths[newPath] = handle
+		c.handles.Add(handle, newPath)
+	}
+}
+
+// remove forgets the handle of path
+func (c *handleCache) remove(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if handle, ok := c.paths[path]; ok {
+		c.handles.Remove(handle)
+	}
+}
@@ -0,0 +1,86 @@
+package nfs
+
+import (
+	"github.com/alist-org/alist/v3/pkg/utils"
+)
+
+// MOUNT v3 procedures (RFC 1813 appendix I)
+const (
+	mountProcNull    = 0
+	mountProcMnt     = 1
+	mountProcDump    = 2
+	mountProcUmnt    = 3
+	mountProcUmntAll = 4
+	mountProcExport  = 5
+
+	mnt3OK        = 0
+	mnt3ErrNoEnt  = 2
+	mnt3ErrAcces  = 13
+	mnt3ErrNotDir = 20
+
+	maxPathLen = 1024
+)
+
+func (s *Server) initMountProcs() {
+	s.mountProcs = map[uint32]procFunc{
+		mountProcNull:    s.null,
+		mountProcMnt:     s.mount,
+		mountProcDump:    s.mountDump,
+		mountProcUmnt:    s.null,
+		mountProcUmntAll: s.null,
+		mountProcExport:  s.mountExport,
+	}
+}
+
+func (s *Server) null(_ *request, _ *xdrWriter) error {
+	return nil
+}
+
+// mount returns the handle of a folder, the folders of the user are all exported
+func (s *Server) mount(req *request, w *xdrWriter) error {
+	dirPath := req.args.string(maxPathLen)
+	if req.args.err != nil {
+		return req.args.err
+	}
+	adapter, err := s.driver.GetFileSystem(req.addr)
+	if err != nil {
+		utils.Log.Warnf("[nfs] refuse to mount for %s: %+v", req.addr, err)
+		w.uint32(mnt3ErrAcces)
+		return nil
+	}
+	path := utils.FixAndCleanPath(dirPath)
+	info, err := adapter.Stat(path)
+	if err != nil {
+		switch status := statusOf(err); status {
+		case nfs3ErrNoEnt, nfs3ErrAcces, nfs3ErrNotSupp:
+			w.uint32(status)
+		default:
+			w.uint32(mnt3ErrNoEnt)
+		}
+		return nil
+	}
+	if !info.IsDir() {
+		w.uint32(mnt3ErrNotDir)
+		return nil
+	}
+	utils.Log.Infof("[nfs] %s mounted %s", req.addr, path)
+	w.uint32(mnt3OK)
+	w.opaque(s.handles.toHandle(path))
+	w.uint32(1) // auth flavors
+	w.uint32(authUnix)
+	return nil
+}
+
+// mountDump lists no mounts, clients are not tracked
+func (s *Server) mountDump(_ *request, w *xdrWriter) error {
+	w.bool(false)
+	return nil
+}
+
+func (s *Server) mountExport(_ *request, w *xdrWriter) error {
+	w.bool(true)
+	w.string("/")
+	w.bool(false) // no groups
+	w.bool(false)
+	return nil
+}
@@ -0,0 +1,992 @@
+package nfs
+
+import (
+	"errors"
+	"hash/fnv"
+	"io"
+	"os"
+	stdpath "path"
+	"strings"
+	"time"
+
+	ftpserver "github.com/KirCute/ftpserverlib-pasvportmap"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/alist-org/alist/v3/server/ftp"
+)
+
+// NFS v3 procedures (RFC 1813)
+const (
+	nfsProcNull        = 0
+	nfsProcGetAttr     = 1
+	nfsProcSetAttr     = 2
+	nfsProcLookup      = 3
+	nfsProcAccess      = 4
+	nfsProcReadLink    = 5
+	nfsProcRead        = 6
+	nfsProcWrite       = 7
+	nfsProcCreate      = 8
+	nfsProcMkdir       = 9
+	nfsProcSymlink     = 10
+	nfsProcMknod       = 11
+	nfsProcRemove      = 12
+	nfsProcRmdir       = 13
+	nfsProcRename      = 14
+	nfsProcLink        = 15
+	nfsProcReadDir     = 16
+	nfsProcReadDirPlus = 17
+	nfsProcFsStat      = 18
+	nfsProcFsInfo      = 19
+	nfsProcPathConf    = 20
+	nfsProcCommit      = 21
+)
+
+const (
+	nfs3OK             = 0
+	nfs3ErrNoEnt       = 2
+	nfs3ErrIO          = 5
+	nfs3ErrAcces       = 13
+	nfs3ErrExist       = 17
+	nfs3ErrNotDir      = 20
+	nfs3ErrIsDir       = 21
+	nfs3ErrInval       = 22
+	nfs3ErrROFS        = 30
+	nfs3ErrNameTooLong = 63
+	nfs3ErrNotEmpty    = 66
+	nfs3ErrStale       = 70
+	nfs3ErrBadHandle   = 10001
+	nfs3ErrBadCookie   = 10003
+	nfs3ErrNotSupp     = 10004
+	nfs3ErrTooSmall    = 10005
+)
+
+const (
+	typeReg = 1
+	typeDir = 2
+
+	accessRead   = 0x01
+	accessLookup = 0x02
+	accessModify = 0x04
+	accessExtend = 0x08
+	accessDelete = 0x10
+
+	stableUnstable = 0
+	stableFileSync = 2
+
+	createUnchecked = 0
+	createGuarded   = 1
+	createExclusive = 2
+
+	maxNameLen   = 255
+	maxHandleLen = 64
+	maxData      = 1 << 20
+	// attrSize is the encoded size of fattr3
+	attrSize = 84
+)
+
+// statusOf returns the status replied for an error of the file system
+func statusOf(err error) uint32 {
+	switch {
+	case err == nil:
+		return nfs3OK
+	case errs.IsObjectNotFound(err):
+		return nfs3ErrNoEnt
+	case errors.Is(err, errs.PermissionDenied), errors.Is(err, errs.UploadNotAllowed):
+		return nfs3ErrAcces
+	case errors.Is(err, errs.NotSupport), errors.Is(err, errs.NotImplement), errors.Is(err, errs.UploadNotSupported):
+		return nfs3ErrNotSupp
+	case errors.Is(err, errs.NotFolder):
+		return nfs3ErrNotDir
+	default:
+		return nfs3ErrIO
+	}
+}
+
+func (s *Server) initProcs() {
+	s.nfsProcs = map[uint32]procFunc{
+		nfsProcNull:        s.null,
+		nfsProcGetAttr:     s.getAttr,
+		nfsProcSetAttr:     s.setAttr,
+		nfsProcLookup:      s.lookup,
+		nfsProcAccess:      s.access,
+		nfsProcReadLink:    s.readLink,
+		nfsProcRead:        s.read,
+		nfsProcWrite:       s.write,
+		nfsProcCreate:      s.create,
+		nfsProcMkdir:       s.mkdir,
+		nfsProcSymlink:     s.notSupportedInDir,
+		nfsProcMknod:       s.notSupportedInDir,
+		nfsProcRemove:      s.remove,
+		nfsProcRmdir:       s.remove,
+		nfsProcRename:      s.rename,
+		nfsProcLink:        s.link,
+		nfsProcReadDir:     s.readDir,
+		nfsProcReadDirPlus: s.readDir,
+		nfsProcFsStat:      s.fsStat,
+		nfsProcFsInfo:      s.fsInfo,
+		nfsProcPathConf:    s.pathConf,
+		nfsProcCommit:      s.commit,
+	}
+	s.initMountProcs()
+}
+
+// call is a procedure call resolved to the file system of the client
+type call struct {
+	*request
+	fs *ftp.AferoAdapter
+}
+
+// begin checks the arguments and returns the file system of the client,
+// the status is not OK if the procedure can't go on
+func (s *Server) begin(req *request) (*call, uint32, error) {
+	if req.args.err != nil {
+		return nil, 0, req.args.err
+	}
+	adapter, err := s.driver.GetFileSystem(req.addr)
+	if err != nil {
+		utils.Log.Warnf("[nfs] refuse request of %s: %+v", req.addr, err)
+		return nil, nfs3ErrAcces, nil
+	}
+	return &call{request: req, fs: adapter}, nfs3OK, nil
+}
+
+func (s *Server) toPath(handle []byte) (string, uint32) {
+	if len(handle) != handleSize {
+		return "", nfs3ErrBadHandle
+	}
+	path, ok := s.handles.toPath(handle)
+	if !ok {
+		return "", nfs3ErrStale
+	}
+	return path, nfs3OK
+}
+
+// child joins a name to a folder, . and .. are not allowed
+func child(dir, name string) (string, uint32) {
+	if len(name) > maxNameLen {
+		return "", nfs3ErrNameTooLong
+	}
+	if name == "" || name == "." || name == ".." || strings.Contains(name, "/") {
+		return "", nfs3ErrInval
+	}
+	return stdpath.Join(dir, name), nfs3OK
+}
+
+// stat returns the info of path, files being written are included
+func (s *Server) stat(c *call, path string) (os.FileInfo, error) {
+	if p := s.files.pending(path); p != nil {
+		return p.info(), nil
+	}
+	return c.fs.Stat(path)
+}
+
+func (s *Server) writeAttr(w *xdrWriter, c *call, path string, info os.FileInfo) {
+	mode := uint32(info.Mode().Perm())
+	if s.driver.ReadOnly() {
+		mode &^= 0222
+	}
+	if info.IsDir() {
+		w.uint32(typeDir)
+		w.uint32(mode)
+		w.uint32(2)
+	} else {
+		w.uint32(typeReg)
+		w.uint32(mode)
+		w.uint32(1)
+	}
+	// the files belong to whoever asks, permissions are checked by alist
+	w.uint32(c.uid)
+	w.uint32(c.gid)
+	size := uint64(0)
+	if info.Size() > 0 {
+		size = uint64(info.Size())
+	}
+	w.uint64(size)
+	w.uint64(size)
+	w.uint32(0) // rdev
+	w.uint32(0)
+	w.uint64(1) // fsid
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(path))
+	w.uint64(h.Sum64())
+	modified := info.ModTime()
+	for i := 0; i < 3; i++ {
+		writeTime(w, modified)
+	}
+}
+
+func writeTime(w *xdrWriter, t time.Time) {
+	if t.Unix() <= 0 {
+		w.uint32(0)
+		w.uint32(0)
+		return
+	}
+	w.uint32(uint32(t.Unix()))
+	w.uint32(uint32(t.Nanosecond()))
+}
+
+// postOpAttr writes the attributes of path if they can be got
+func (s *Server) postOpAttr(w *xdrWriter, c *call, path string) {
+	if c == nil || path == "" {
+		w.bool(false)
+		return
+	}
+	info, err := s.stat(c, path)
+	if err != nil {
+		w.bool(false)
+		return
+	}
+	w.bool(true)
+	s.writeAttr(w, c, path, info)
+}
+
+// wccData writes the attributes of path after a change, the attributes
+// before the change are not kept
+func (s *Server) wccData(w *xdrWriter, c *call, path string) {
+	w.bool(false)
+	s.postOpAttr(w, c, path)
+}
+
+func (s *Server) getAttr(req *request, w *xdrWriter) error {
+	handle := req.args.opaque(maxHandleLen)
+	c, status, err := s.begin(req)
+	if err != nil {
+		return err
+	}
+	path := ""
+	if status == nfs3OK {
+		path, status = s.toPath(handle)
+	}
+	var info os.FileInfo
+	if status == nfs3OK {
+		info, err = s.stat(c, path)
+		status = statusOf(err)
+	}
+	w.uint32(status)
+	if status == nfs3OK {
+		s.writeAttr(w, c, path, info)
+	}
+	return nil
+}
+
+// sattr3 of SETATTR, CREATE and MKDIR, only the size can be changed
+type setAttrs struct {
+	setSize bool
+	size    uint64
+}
+
+func readSetAttrs(r *xdrReader) setAttrs {
+	var a setAttrs
+	for i := 0; i < 3; i++ {
+		// mode, uid and gid
+		if r.bool() {
+			r.uint32()
+		}
+	}
+	if a.setSize = r.bool(); a.setSize {
+		a.size = r.uint64()
+	}
+	for i := 0; i < 2; i++ {
+		// atime and mtime, 2 means the time of the client
+		if r.uint32() == 2 {
+			r.uint32()
+			r.uint32()
+		}
+	}
+	return a
+}
+
+// setAttr only supports changing the size of a file being written or
+// truncating a file to be rewritten, other attributes are ignored
+func (s *Server) setAttr(req *request, w *xdrWriter) error {
+	handle := req.args.opaque(maxHandleLen)
+	attrs := readSetAttrs(req.args)
+	if req.args.bool() {
+		// guard ctime
+		req.args.uint32()
+		req.args.uint32()
+	}
+	c, status, err := s.begin(req)
+	if err != nil {
+		return err
+	}
+	path := ""
+	if status == nfs3OK {
+		path, status = s.toPath(handle)
+	}
+	if status == nfs3OK && attrs.setSize {
+		status = s.truncate(c, path, int64(attrs.size))
+	}
+	w.uint32(status)
+	s.wccData(w, c, path)
+	return nil
+}
+
+func (s *Server) truncate(c *call, path string, size int64) uint32 {
+	if s.driver.ReadOnly() {
+		return nfs3ErrROFS
+	}
+	if p := s.files.pending(path); p != nil {
+		return statusOf(p.truncate(size))
+	}
+	info, err := c.fs.Stat(path)
+	if err != nil {
+		return statusOf(err)
+	}
+	if info.IsDir() {
+		return nfs3ErrIsDir
+	}
+	if info.Size() == size {
+		return nfs3OK
+	}
+	if size != 0 {
+		return nfs3ErrNotSupp
+	}
+	_, err = s.files.startWrite(path, c.fs, 0)
+	return statusOf(err)
+}
+
+func (s *Server) lookup(req *request, w *xdrWriter) error {
+	dirHandle := req.args.opaque(maxHandleLen)
+	name := req.args.string(maxPathLen)
+	c, status, err := s.begin(req)
+	if err != nil {
+		return err
+	}
+	dir, path := "", ""
+	if status == nfs3OK {
+		dir, status = s.toPath(dirHandle)
+	}
+	if status == nfs3OK {
+		switch name {
+		case ".":
+			path = dir
+		case "..":
+			path = stdpath.Dir(dir)
+		default:
+			path, status = child(dir, name)
+		}
+	}
+	var info os.FileInfo
+	if status == nfs3OK {
+		info, err = s.stat(c, path)
+		status = statusOf(err)
+	}
+	w.uint32(status)
+	if status == nfs3OK {
+		w.opaque(s.handles.toHandle(path))
+		w.bool(true)
+		s.writeAttr(w, c, path, info)
+	}
+	s.postOpAttr(w, c, dir)
+	return nil
+}
+
+// access grants everything alist may allow, the permissions
+// are checked when the files are actually accessed
+func (s *Server) access(req *request, w *xdrWriter) error {
+	handle := req.args.opaque(maxHandleLen)
+	requested := req.args.uint32()
+	c, status, err := s.begin(req)
+	if err != nil {
+		return err
+	}
+	path := ""
+	if status == nfs3OK {
+		path, status = s.toPath(handle)
+	}
+	var info os.FileInfo
+	if status == nfs3OK {
+		info, err = s.stat(c, path)
+		status = statusOf(err)
+	}
+	w.uint32(status)
+	if status != nfs3OK {
+		w.bool(false)
+		return nil
+	}
+	w.bool(true)
+	s.writeAttr(w, c, path, info)
+	granted := uint32(accessRead | accessModify | accessExtend | accessDelete)
+	if info.IsDir() {
+		granted |= accessLookup
+	}
+	if s.driver.ReadOnly() {
+		granted &^= accessModify | accessExtend | accessDelete
+	}
+	w.uint32(requested & granted)
+	return nil
+}
+
+func (s *Server) readLink(req *request, w *xdrWriter) error {
+	handle := req.args.opaque(maxHandleLen)
+	c, status, err := s.begin(req)
+	if err != nil {
+		return err
+	}
+	path := ""
+	if status == nfs3OK {
+		path, status = s.toPath(handle)
+	}
+	if status == nfs3OK {
+		// there are no symbolic links
+		status = nfs3ErrInval
+	}
+	w.uint32(status)
+	s.postOpAttr(w, c, path)
+	return nil
+}
+
+func (s *Server) read(req *request, w *xdrWriter) error {
+	handle := req.args.opaque(maxHandleLen)
+	offset := req.args.uint64()
+	count := req.args.uint32()
+	c, status, err := s.begin(req)
+	if err != nil {
+		return err
+	}
+	path := ""
+	if status == nfs3OK {
+		path, status = s.toPath(handle)
+	}
+	if status == nfs3OK {
+		// read what has been written
+		status = statusOf(s.files.flush(path))
+	}
+	var info os.FileInfo
+	if status == nfs3OK {
+		info, err = c.fs.Stat(path)
+		status = statusOf(err)
+	}
+	if status == nfs3OK && info.IsDir() {
+		status = nfs3ErrIsDir
+	}
+	var data []byte
+	eof := true
+	if status == nfs3OK && int64(offset) < info.Size() {
+		if count > maxData {
+			count = maxData
+		}
+		if remain := info.Size() - int64(offset); int64(count) > remain {
+			count = uint32(remain)
+		}
+		data = make([]byte, count)
+		var n int
+		n, err = s.files.readAt(path, data, int64(offset), func() (ftpserver.FileTransfer, error) {
+			return c.fs.GetHandle(path, os.O_RDONLY, 0)
+		})
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			utils.Log.Warnf("[nfs] failed to read %s: %+v", path, err)
+			status = statusOf(err)
+		}
+		data = data[:n]
+		eof = int64(offset)+int64(n) >= info.Size()
+	}
+	w.uint32(status)
+	s.postOpAttr(w, c, path)
+	if status == nfs3OK {
+		w.uint32(uint32(len(data)))
+		w.bool(eof)
+		w.opaque(data)
+	}
+	return nil
+}
+
+// write buffers the data until it's committed, the file is replaced
+// by the buffer so only new files and rewrites from the start are supported.
+// Stable writes are buffered as well, the reply tells the client to commit.
+func (s *Server) write(req *request, w *xdrWriter) error {
+	handle := req.args.opaque(maxHandleLen)
+	offset := req.args.uint64()
+	req.args.uint32() // count
+	req.args.uint32() // stable
+	data := req.args.opaque(maxData)
+	c, status, err := s.begin(req)
+	if err != nil {
+		return err
+	}
+	path := ""
+	if status == nfs3OK {
+		path, status = s.toPath(handle)
+	}
+	if status == nfs3OK && s.driver.ReadOnly() {
+		status = nfs3ErrROFS
+	}
+	var p *pendingWrite
+	if status == nfs3OK {
+		p = s.files.pending(path)
+	}
+	if status == nfs3OK && p == nil {
+		var info os.FileInfo
+		info, err = c.fs.Stat(path)
+		if err != nil {
+			status = statusOf(err)
+		} else if info.IsDir() {
+			status = nfs3ErrIsDir
+		} else if offset != 0 && info.Size() > 0 {
+			// alist can't change a part of a file
+			status = nfs3ErrNotSupp
+		} else {
+			p, err = s.files.startWrite(path, c.fs, info.Size())
+			status = statusOf(err)
+		}
+	}
+	if status == nfs3OK {
+		status = statusOf(p.writeAt(data, int64(offset)))
+	}
+	w.uint32(status)
+	s.wccData(w, c, path)
+	if status == nfs3OK {
+		w.uint32(uint32(len(data)))
+		w.uint32(stableUnstable)
+		w.fixed(s.verifier[:])
+	}
+	return nil
+}
+
+func (s *Server) commit(req *request, w *xdrWriter) error {
+	handle := req.args.opaque(maxHandleLen)
+	req.args.uint64() // offset
+	req.args.uint32() // count
+	c, status, err := s.begin(req)
+	if err != nil {
+		return err
+	}
+	path := ""
+	if status == nfs3OK {
+		path, status = s.toPath(handle)
+	}
+	if status == nfs3OK {
+		status = statusOf(s.files.flush(path))
+	}
+	w.uint32(status)
+	s.wccData(w, c, path)
+	if status == nfs3OK {
+		w.fixed(s.verifier[:])
+	}
+	return nil
+}
+
+// dirOp resolves the folder and the name of CREATE, MKDIR, REMOVE and RMDIR
+func (s *Server) dirOp(req *request, dirHandle []byte, name string) (c *call, dir, path string, status uint32, err error) {
+	c, status, err = s.begin(req)
+	if err != nil || status != nfs3OK {
+		return
+	}
+	if dir, status = s.toPath(dirHandle); status != nfs3OK {
+		return
+	}
+	if path, status = child(dir, name); status != nfs3OK {
+		return
+	}
+	if s.driver.ReadOnly() {
+		status = nfs3ErrROFS
+	}
+	return
+}
+
+// writeNewObj writes the result of CREATE and MKDIR
+func (s *Server) writeNewObj(w *xdrWriter, c *call, dir, path string, status uint32) {
+	w.uint32(status)
+	if status == nfs3OK {
+		w.bool(true)
+		w.opaque(s.handles.toHandle(path))
+		s.postOpAttr(w, c, path)
+	}
+	s.wccData(w, c, dir)
+}
+
+// create makes an empty file which is uploaded when it's committed,
+// or after a while without writes
+func (s *Server) create(req *request, w *xdrWriter) error {
+	dirHandle := req.args.opaque(maxHandleLen)
+	name := req.args.string(maxPathLen)
+	how := req.args.uint32()
+	var attrs setAttrs
+	switch how {
+	case createUnchecked, createGuarded:
+		attrs = readSetAttrs(req.args)
+	case createExclusive:
+		req.args.fixed(8)
+	}
+	c, dir, path, status, err := s.dirOp(req, dirHandle, name)
+	if err != nil {
+		return err
+	}
+	if status == nfs3OK {
+		info, err := s.stat(c, path)
+		switch {
+		case err == nil && how != createUnchecked:
+			status = nfs3ErrExist
+		case err == nil && info.IsDir():
+			status = nfs3ErrIsDir
+		case err == nil:
+			if attrs.setSize {
+				status = s.truncate(c, path, int64(attrs.size))
+			}
+		case errs.IsObjectNotFound(err):
+			_, err = s.files.startWrite(path, c.fs, 0)
+			status = statusOf(err)
+		default:
+			status = statusOf(err)
+		}
+	}
+	s.writeNewObj(w, c, dir, path, status)
+	return nil
+}
+
+func (s *Server) mkdir(req *request, w *xdrWriter) error {
+	dirHandle := req.args.opaque(maxHandleLen)
+	name := req.args.string(maxPathLen)
+	readSetAttrs(req.args)
+	c, dir, path, status, err := s.dirOp(req, dirHandle, name)
+	if err != nil {
+		return err
+	}
+	if status == nfs3OK {
+		if _, err = s.stat(c, path); err == nil {
+			status = nfs3ErrExist
+		} else {
+			status = statusOf(c.fs.Mkdir(path, 0755))
+		}
+	}
+	s.writeNewObj(w, c, dir, path, status)
+	return nil
+}
+
+// notSupportedInDir replies SYMLINK and MKNOD, the arguments start with the folder
+func (s *Server) notSupportedInDir(req *request, w *xdrWriter) error {
+	dirHandle := req.args.opaque(maxHandleLen)
+	c, status, err := s.begin(req)
+	if err != nil {
+		return err
+	}
+	dir := ""
+	if status == nfs3OK {
+		dir, status = s.toPath(dirHandle)
+	}
+	if status == nfs3OK {
+		status = nfs3ErrNotSupp
+	}
+	w.uint32(status)
+	s.wccData(w, c, dir)
+	return nil
+}
+
+func (s *Server) link(req *request, w *xdrWriter) error {
+	handle := req.args.opaque(maxHandleLen)
+	dirHandle := req.args.opaque(maxHandleLen)
+	req.args.string(maxPathLen)
+	c, status, err := s.begin(req)
+	if err != nil {
+		return err
+	}
+	path, dir := "", ""
+	if status == nfs3OK {
+		path, status = s.toPath(handle)
+	}
+	if status == nfs3OK {
+		dir, status = s.toPath(dirHandle)
+	}
+	if status == nfs3OK {
+		status = nfs3ErrNotSupp
+	}
+	w.uint32(status)
+	s.postOpAttr(w, c, path)
+	s.wccData(w, c, dir)
+	return nil
+}
+
+// remove handles REMOVE and RMDIR
+func (s *Server) remove(req *request, w *xdrWriter) error {
+	dirHandle := req.args.opaque(maxHandleLen)
+	name := req.args.string(maxPathLen)
+	c, dir, path, status, err := s.dirOp(req, dirHandle, name)
+	if err != nil {
+		return err
+	}
+	var info os.FileInfo
+	if status == nfs3OK {
+		info, err = s.stat(c, path)
+		status = statusOf(err)
+	}
+	if status == nfs3OK {
+		if req.proc == nfsProcRmdir {
+			status = s.checkRmdir(c, path, info)
+		} else if info.IsDir() {
+			status = nfs3ErrIsDir
+		}
+	}
+	if status == nfs3OK {
+		if err = s.files.forget(path); err == nil {
+			err = c.fs.Remove(path)
+		}
+		status = statusOf(err)
+	}
+	if status == nfs3OK {
+		s.handles.remove(path)
+	}
+	w.uint32(status)
+	s.wccData(w, c, dir)
+	return nil
+}
+
+func (s *Server) checkRmdir(c *call, path string, info os.FileInfo) uint32 {
+	if !info.IsDir() {
+		return nfs3ErrNotDir
+	}
+	if len(s.files.pendingIn(path)) > 0 {
+		return nfs3ErrNotEmpty
+	}
+	children, err := c.fs.ReadDir(path)
+	if err != nil {
+		return statusOf(err)
+	}
+	if len(children) > 0 {
+		return nfs3ErrNotEmpty
+	}
+	return nfs3OK
+}
+
+// rename replaces the target file like rename(2)
+func (s *Server) rename(req *request, w *xdrWriter) error {
+	fromHandle := req.args.opaque(maxHandleLen)
+	fromName := req.args.string(maxPathLen)
+	toHandle := req.args.opaque(maxHandleLen)
+	toName := req.args.string(maxPathLen)
+	c, fromDir, from, status, err := s.dirOp(req, fromHandle, fromName)
+	if err != nil {
+		return err
+	}
+	toDir, to := "", ""
+	if status == nfs3OK {
+		toDir, status = s.toPath(toHandle)
+	}
+	if status == nfs3OK {
+		to, status = child(toDir, toName)
+	}
+	var info os.FileInfo
+	if status == nfs3OK {
+		if err = s.files.forget(from); err == nil {
+			info, err = c.fs.Stat(from)
+		}
+		status = statusOf(err)
+	}
+	if status == nfs3OK && from != to {
+		status = s.replace(c, to, info)
+	}
+	if status == nfs3OK && from != to {
+		status = statusOf(c.fs.Rename(from, to))
+	}
+	if status == nfs3OK {
+		s.handles.rename(from, to)
+	}
+	w.uint32(status)
+	s.wccData(w, c, fromDir)
+	s.wccData(w, c, toDir)
+	return nil
+}
+
+// replace removes the target of a rename if it's allowed to be replaced
+func (s *Server) replace(c *call, to string, from os.FileInfo) uint32 {
+	if err := s.files.forget(to); err != nil {
+		return statusOf(err)
+	}
+	target, err := c.fs.Stat(to)
+	if errs.IsObjectNotFound(err) {
+		return nfs3OK
+	}
+	if err != nil {
+		return statusOf(err)
+	}
+	switch {
+	case from.IsDir() && !target.IsDir():
+		return nfs3ErrNotDir
+	case !from.IsDir() && target.IsDir():
+		return nfs3ErrIsDir
+	case target.IsDir():
+		if status := s.checkRmdir(c, to, target); status != nfs3OK {
+			return status
+		}
+	}
+	if err = c.fs.Remove(to); err != nil {
+		return statusOf(err)
+	}
+	s.handles.remove(to)
+	return nfs3OK
+}
+
+// readDir handles READDIR and READDIRPLUS, the cookie of an entry is its index plus one
+func (s *Server) readDir(req *request, w *xdrWriter) error {
+	plus := req.proc == nfsProcReadDirPlus
+	handle := req.args.opaque(maxHandleLen)
+	cookie := req.args.uint64()
+	req.args.fixed(8) // cookie verifier
+	dirCount := req.args.uint32()
+	maxCount := dirCount
+	if plus {
+		maxCount = req.args.uint32()
+	}
+	c, status, err := s.begin(req)
+	if err != nil {
+		return err
+	}
+	path := ""
+	if status == nfs3OK {
+		path, status = s.toPath(handle)
+	}
+	var entries []os.FileInfo
+	if status == nfs3OK {
+		entries, err = s.list(c, path)
+		status = statusOf(err)
+	}
+	if status == nfs3OK && cookie > uint64(len(entries)) {
+		status = nfs3ErrBadCookie
+	}
+	body := &xdrWriter{}
+	eof := true
+	if status == nfs3OK {
+		// status, attributes, verifier and the end of the list
+		size := 4 + 4 + attrSize + 8 + 8
+		names := 0
+		for i := int(cookie); i < len(entries); i++ {
+			info := entries[i]
+			childPath := stdpath.Join(path, info.Name())
+			entry := &xdrWriter{}
+			entry.bool(true)
+			h := fnv.New64a()
+			_, _ = h.Write([]byte(childPath))
+			entry.uint64(h.Sum64())
+			entry.string(info.Name())
+			entry.uint64(uint64(i + 1))
+			nameSize := entry.Len()
+			if plus {
+				entry.bool(true)
+				s.writeAttr(entry, c, childPath, info)
+				entry.bool(true)
+				entry.opaque(s.handles.toHandle(childPath))
+			}
+			if size+entry.Len() > int(maxCount) || (plus && names+nameSize > int(dirCount)) {
+				eof = false
+				break
+			}
+			size += entry.Len()
+			names += nameSize
+			body.Write(entry.Bytes())
+		}
+		if body.Len() == 0 && !eof {
+			status = nfs3ErrTooSmall
+		}
+	}
+	w.uint32(status)
+	s.postOpAttr(w, c, path)
+	if status == nfs3OK {
+		w.fixed(make([]byte, 8))
+		w.Write(body.Bytes())
+		w.bool(false)
+		w.bool(eof)
+	}
+	return nil
+}
+
+// list returns the children of path with the files being written
+func (s *Server) list(c *call, path string) ([]os.FileInfo, error) {
+	entries, err := c.fs.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	pending := s.files.pendingIn(path)
+	if len(pending) == 0 {
+		return entries, nil
+	}
+	res := make([]os.FileInfo, 0, len(entries)+len(pending))
+	names := make(map[string]bool, len(pending))
+	for _, p := range pending {
+		info := p.info()
+		names[info.Name()] = true
+		res = append(res, info)
+	}
+	for _, info := range entries {
+		if !names[info.Name()] {
+			res = append(res, info)
+		}
+	}
+	return res, nil
+}
+
+func (s *Server) fsStat(req *request, w *xdrWriter) error {
+	handle := req.args.opaque(maxHandleLen)
+	c, status, err := s.begin(req)
+	if err != nil {
+		return err
+	}
+	path := ""
+	if status == nfs3OK {
+		path, status = s.toPath(handle)
+	}
+	w.uint32(status)
+	s.postOpAttr(w, c, path)
+	if status == nfs3OK {
+		// the space of the storages is unknown
+		const space = 1 << 50
+		w.uint64(space) // total bytes
+		w.uint64(space) // free bytes
+		w.uint64(space) // available bytes
+		w.uint64(1 << 30)
+		w.uint64(1 << 30)
+		w.uint64(1 << 30)
+		w.uint32(0) // invarsec
+	}
+	return nil
+}
+
+func (s *Server) fsInfo(req *request, w *xdrWriter) error {
+	handle := req.args.opaque(maxHandleLen)
+	c, status, err := s.begin(req)
+	if err != nil {
+		return err
+	}
+	path := ""
+	if status == nfs3OK {
+		path, status = s.toPath(handle)
+	}
+	w.uint32(status)
+	s.postOpAttr(w, c, path)
+	if status == nfs3OK {
+		w.uint32(maxData) // rtmax
+		w.uint32(maxData) // rtpref
+		w.uint32(4096)    // rtmult
+		w.uint32(maxData) // wtmax
+		w.uint32(maxData) // wtpref
+		w.uint32(4096)    // wtmult
+		w.uint32(8192)    // dtpref
+		w.uint64(1<<63 - 1)
+		w.uint32(1) // time delta
+		w.uint32(0)
+		w.uint32(0x08) // FSF3_HOMOGENEOUS
+	}
+	return nil
+}
+
+func (s *Server) pathConf(req *request, w *xdrWriter) error {
+	handle := req.args.opaque(maxHandleLen)
+	c, status, err := s.begin(req)
+	if err != nil {
+		return err
+	}
+	path := ""
+	if status == nfs3OK {
+		path, status = s.toPath(handle)
+	}
+	w.uint32(status)
+	s.postOpAttr(w, c, path)
+	if status == nfs3OK {
+		w.uint32(1)          // link max
+		w.uint32(maxNameLen) // name max
+		w.bool(true)         // no trunc
+		w.bool(true)         // chown restricted
+		w.bool(false)        // case insensitive
+		w.bool(true)         // case preserving
+	}
+	return nil
+}
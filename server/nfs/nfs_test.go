@@ -0,0 +1,369 @@
+package nfs
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/alist-org/alist/v3/drivers"
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/stream"
+	"github.com/alist-org/alist/v3/internal/testutil"
+	"github.com/alist-org/alist/v3/server/ftp"
+	"golang.org/x/time/rate"
+)
+
+type testDriver struct {
+	user     *model.User
+	readOnly bool
+}
+
+func (d *testDriver) Allowed(net.Addr) bool { return true }
+func (d *testDriver) ReadOnly() bool        { return d.readOnly }
+func (d *testDriver) GetFileSystem(addr net.Addr) (*ftp.AferoAdapter, error) {
+	ctx := context.WithValue(context.Background(), "user", d.user)
+	ctx = context.WithValue(ctx, "meta_pass", "")
+	ctx = context.WithValue(ctx, "client_ip", addr.String())
+	ctx = context.WithValue(ctx, "proxy_header", &http.Header{})
+	return ftp.NewAferoAdapter(ctx), nil
+}
+
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+	xid  uint32
+}
+
+// call sends a procedure call and returns the decoder of the result
+func (c *testClient) call(prog, vers, proc uint32, args func(w *xdrWriter)) *xdrReader {
+	c.t.Helper()
+	c.xid++
+	w := &xdrWriter{}
+	w.uint32(c.xid)
+	w.uint32(msgCall)
+	w.uint32(rpcVersion)
+	w.uint32(prog)
+	w.uint32(vers)
+	w.uint32(proc)
+	cred := &xdrWriter{}
+	cred.uint32(0)
+	cred.string("test")
+	cred.uint32(1000)
+	cred.uint32(1000)
+	cred.uint32(0)
+	w.uint32(authUnix)
+	w.opaque(cred.Bytes())
+	w.uint32(authNone)
+	w.uint32(0)
+	if args != nil {
+		args(w)
+	}
+	if err := writeRecord(c.conn, w.Bytes()); err != nil {
+		c.t.Fatal(err)
+	}
+	record, err := readRecord(c.r)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	r := &xdrReader{buf: record}
+	if xid := r.uint32(); xid != c.xid {
+		c.t.Fatalf("expected xid %d, got %d", c.xid, xid)
+	}
+	r.uint32() // reply
+	r.uint32() // accepted
+	r.uint32()
+	r.opaque(400)
+	if stat := r.uint32(); stat != acceptSuccess {
+		c.t.Fatalf("call %d of %d not accepted: %d", proc, prog, stat)
+	}
+	return r
+}
+
+func (c *testClient) nfs(proc uint32, args func(w *xdrWriter)) *xdrReader {
+	return c.call(progNFS, versionNFS, proc, args)
+}
+
+func dirArgs(handle []byte, name string) func(w *xdrWriter) {
+	return func(w *xdrWriter) {
+		w.opaque(handle)
+		w.string(name)
+	}
+}
+
+// size reads the size of fattr3
+func size(r *xdrReader) uint64 {
+	r.fixed(20)
+	s := r.uint64()
+	r.fixed(attrSize - 28)
+	return s
+}
+
+func TestServer(t *testing.T) {
+	testutil.InitDB()
+	conf.Conf.TempDir = t.TempDir()
+	stream.ClientUploadLimit = rate.NewLimiter(rate.Inf, 0)
+	stream.ClientDownloadLimit = rate.NewLimiter(rate.Inf, 0)
+	role := &model.Role{Name: "nfs", PermissionScopes: []model.PermissionEntry{{Path: "/", Permission: 0xFFFF}}}
+	err := op.CreateRole(role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &model.User{Username: "nfs", BasePath: "/", Role: model.Roles{int(role.ID)}}
+	_, dir := testutil.CreateLocal(t, "/local")
+
+	driver := &testDriver{user: user}
+	s, err := NewServer("", driver, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(l)
+	defer s.Close()
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	c := &testClient{t: t, conn: conn, r: bufio.NewReader(conn)}
+
+	r := c.call(progMount, versionMNT, mountProcMnt, func(w *xdrWriter) { w.string("/") })
+	if status := r.uint32(); status != mnt3OK {
+		t.Fatalf("mount failed: %d", status)
+	}
+	root := r.opaque(maxHandleLen)
+
+	r = c.nfs(nfsProcLookup, dirArgs(root, "local"))
+	if status := r.uint32(); status != nfs3OK {
+		t.Fatalf("lookup failed: %d", status)
+	}
+	local := r.opaque(maxHandleLen)
+
+	r = c.nfs(nfsProcCreate, func(w *xdrWriter) {
+		dirArgs(local, "a.txt")(w)
+		w.uint32(createGuarded)
+		for i := 0; i < 6; i++ {
+			w.uint32(0)
+		}
+	})
+	if status := r.uint32(); status != nfs3OK {
+		t.Fatalf("create failed: %d", status)
+	}
+	r.bool()
+	file := r.opaque(maxHandleLen)
+
+	r = c.nfs(nfsProcWrite, func(w *xdrWriter) {
+		w.opaque(file)
+		w.uint64(0)
+		w.uint32(5)
+		w.uint32(stableUnstable)
+		w.opaque([]byte("hello"))
+	})
+	if status := r.uint32(); status != nfs3OK {
+		t.Fatalf("write failed: %d", status)
+	}
+	r = c.nfs(nfsProcGetAttr, func(w *xdrWriter) { w.opaque(file) })
+	if status := r.uint32(); status != nfs3OK {
+		t.Fatalf("getattr failed: %d", status)
+	}
+	if got := size(r); got != 5 {
+		t.Fatalf("expected size 5 before commit, got %d", got)
+	}
+	r = c.nfs(nfsProcCommit, func(w *xdrWriter) {
+		w.opaque(file)
+		w.uint64(0)
+		w.uint32(0)
+	})
+	if status := r.uint32(); status != nfs3OK {
+		t.Fatalf("commit failed: %d", status)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "a.txt")); err != nil || string(data) != "hello" {
+		t.Fatalf("expected hello to be uploaded, got %q, %v", data, err)
+	}
+
+	r = c.nfs(nfsProcRead, func(w *xdrWriter) {
+		w.opaque(file)
+		w.uint64(1)
+		w.uint32(100)
+	})
+	if status := r.uint32(); status != nfs3OK {
+		t.Fatalf("read failed: %d", status)
+	}
+	if r.bool() {
+		size(r)
+	}
+	r.uint32()
+	eof := r.bool()
+	if data := r.opaque(maxData); string(data) != "ello" || !eof {
+		t.Fatalf("expected ello and eof, got %q %v", data, eof)
+	}
+
+	r = c.nfs(nfsProcReadDirPlus, func(w *xdrWriter) {
+		w.opaque(local)
+		w.uint64(0)
+		w.fixed(make([]byte, 8))
+		w.uint32(4096)
+		w.uint32(65536)
+	})
+	if status := r.uint32(); status != nfs3OK {
+		t.Fatalf("readdirplus failed: %d", status)
+	}
+	if r.bool() {
+		size(r)
+	}
+	r.fixed(8)
+	var names []string
+	for r.bool() {
+		r.uint64()
+		names = append(names, r.string(maxNameLen))
+		r.uint64()
+		if r.bool() {
+			size(r)
+		}
+		if r.bool() {
+			r.opaque(maxHandleLen)
+		}
+	}
+	if len(names) != 1 || names[0] != "a.txt" || !r.bool() {
+		t.Fatalf("unexpected entries %v", names)
+	}
+
+	r = c.nfs(nfsProcRename, func(w *xdrWriter) {
+		dirArgs(local, "a.txt")(w)
+		dirArgs(local, "b.txt")(w)
+	})
+	if status := r.uint32(); status != nfs3OK {
+		t.Fatalf("rename failed: %d", status)
+	}
+	// the handle follows the file
+	r = c.nfs(nfsProcGetAttr, func(w *xdrWriter) { w.opaque(file) })
+	if status := r.uint32(); status != nfs3OK {
+		t.Fatalf("getattr after rename failed: %d", status)
+	}
+	r = c.nfs(nfsProcRemove, dirArgs(local, "b.txt"))
+	if status := r.uint32(); status != nfs3OK {
+		t.Fatalf("remove failed: %d", status)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected b.txt to be removed, got %v", err)
+	}
+
+	write := func(handle []byte, offset uint64, data string) {
+		t.Helper()
+		r := c.nfs(nfsProcWrite, func(w *xdrWriter) {
+			w.opaque(handle)
+			w.uint64(offset)
+			w.uint32(uint32(len(data)))
+			w.uint32(stableFileSync)
+			w.opaque([]byte(data))
+		})
+		if status := r.uint32(); status != nfs3OK {
+			t.Fatalf("write at %d failed: %d", offset, status)
+		}
+		r.bool()
+		if r.bool() {
+			size(r)
+		}
+		r.uint32()
+		// stable writes are buffered as well, alist can only upload whole files
+		if committed := r.uint32(); committed != stableUnstable {
+			t.Fatalf("expected the write to be unstable, got %d", committed)
+		}
+	}
+	commit := func(handle []byte) uint32 {
+		return c.nfs(nfsProcCommit, func(w *xdrWriter) {
+			w.opaque(handle)
+			w.uint64(0)
+			w.uint32(0)
+		}).uint32()
+	}
+
+	r = c.nfs(nfsProcCreate, func(w *xdrWriter) {
+		dirArgs(local, "c.txt")(w)
+		w.uint32(createGuarded)
+		for i := 0; i < 6; i++ {
+			w.uint32(0)
+		}
+	})
+	if status := r.uint32(); status != nfs3OK {
+		t.Fatalf("create failed: %d", status)
+	}
+	r.bool()
+	file = r.opaque(maxHandleLen)
+	write(file, 0, "hello ")
+	write(file, 6, "world")
+	if _, err := os.Stat(filepath.Join(dir, "c.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected c.txt to be uploaded on commit, got %v", err)
+	}
+	if status := commit(file); status != nfs3OK {
+		t.Fatalf("commit failed: %d", status)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "c.txt")); err != nil || string(data) != "hello world" {
+		t.Fatalf("expected hello world to be uploaded, got %q, %v", data, err)
+	}
+
+	// a rewrite shorter than the file would truncate it
+	if err := os.WriteFile(filepath.Join(dir, "d.txt"), []byte("0123456789"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r = c.nfs(nfsProcLookup, dirArgs(local, "d.txt"))
+	if status := r.uint32(); status != nfs3OK {
+		t.Fatalf("lookup failed: %d", status)
+	}
+	file = r.opaque(maxHandleLen)
+	write(file, 0, "abc")
+	if status := commit(file); status != nfs3ErrIO {
+		t.Fatalf("expected partial rewrite to fail, got %d", status)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "d.txt")); string(data) != "0123456789" {
+		t.Fatalf("expected d.txt to be kept, got %q", data)
+	}
+	write(file, 0, "abcdefghijk")
+	if status := commit(file); status != nfs3OK {
+		t.Fatalf("commit failed: %d", status)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "d.txt")); string(data) != "abcdefghijk" {
+		t.Fatalf("expected d.txt to be rewritten, got %q", data)
+	}
+
+	driver.readOnly = true
+	r = c.nfs(nfsProcMkdir, func(w *xdrWriter) {
+		dirArgs(local, "sub")(w)
+		for i := 0; i < 6; i++ {
+			w.uint32(0)
+		}
+	})
+	if status := r.uint32(); status != nfs3ErrROFS {
+		t.Fatalf("expected read-only error, got %d", status)
+	}
+}
+
+func TestHandleCache(t *testing.T) {
+	c, err := newHandleCache(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := c.toHandle("/a")
+	child := c.toHandle("/a/b")
+	c.rename("/a", "/c")
+	if path, ok := c.toPath(child); !ok || path != "/c/b" {
+		t.Fatalf("expected /c/b, got %s", path)
+	}
+	c.toHandle("/d")
+	// the least recently used handle is evicted
+	if _, ok := c.toPath(a); ok {
+		t.Fatalf("expected the handle of /c to be evicted")
+	}
+	if path, ok := c.toPath(c.root()); !ok || path != "/" {
+		t.Fatalf("expected the root handle to be kept")
+	}
+}
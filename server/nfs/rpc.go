@@ -0,0 +1,301 @@
+package nfs
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/alist-org/alist/v3/server/ftp"
+	"github.com/pkg/errors"
+)
+
+// ONC RPC (RFC 5531) constants
+const (
+	rpcVersion = 2
+
+	msgCall  = 0
+	msgReply = 1
+
+	replyAccepted = 0
+
+	acceptSuccess      = 0
+	acceptProgUnavail  = 1
+	acceptProgMismatch = 2
+	acceptProcUnavail  = 3
+	acceptGarbageArgs  = 4
+	acceptSystemErr    = 5
+
+	authNone = 0
+	authUnix = 1
+
+	progNFS     = 100003
+	progMount   = 100005
+	versionNFS  = 3
+	versionMNT  = 3
+	maxRecord   = 4 << 20
+	maxRequests = 16
+)
+
+// Driver provides the file system and the access rules of the clients
+type Driver interface {
+	// Allowed reports whether the client at addr may connect
+	Allowed(addr net.Addr) bool
+	ReadOnly() bool
+	// GetFileSystem returns the file system of the user the client acts as
+	GetFileSystem(addr net.Addr) (*ftp.AferoAdapter, error)
+}
+
+type Server struct {
+	listen   string
+	driver   Driver
+	handles  *handleCache
+	files    *fileCache
+	verifier [8]byte
+
+	nfsProcs   map[uint32]procFunc
+	mountProcs map[uint32]procFunc
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+}
+
+func NewServer(listen string, driver Driver, handleCacheSize int) (*Server, error) {
+	handles, err := newHandleCache(handleCacheSize)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		listen:  listen,
+		driver:  driver,
+		handles: handles,
+		files:   newFileCache(),
+		conns:   make(map[net.Conn]struct{}),
+	}
+	s.initProcs()
+	// the verifier of WRITE and COMMIT changes when the server restarts,
+	// so that clients resend the data which isn't committed
+	binary.BigEndian.PutUint64(s.verifier[:], uint64(time.Now().UnixNano()))
+	return s, nil
+}
+
+func (s *Server) ListenAndServe() error {
+	l, err := net.Listen("tcp", s.listen)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = l.Close()
+		return net.ErrClosed
+	}
+	s.listener = l
+	s.mu.Unlock()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		if !s.driver.Allowed(conn.RemoteAddr()) {
+			utils.Log.Warnf("[nfs] reject connection from %s", conn.RemoteAddr())
+			_ = conn.Close()
+			continue
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+		go s.serveConn(conn)
+	}
+}
+
+// Close stops accepting clients, closes the connections
+// and uploads the files not committed yet
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+	s.files.close()
+	return err
+}
+
+type request struct {
+	xid  uint32
+	prog uint32
+	vers uint32
+	proc uint32
+	uid  uint32
+	gid  uint32
+	addr net.Addr
+	args *xdrReader
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		_ = conn.Close()
+	}()
+	reader := bufio.NewReader(conn)
+	var writeMu sync.Mutex
+	sem := make(chan struct{}, maxRequests)
+	for {
+		record, err := readRecord(reader)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				utils.Log.Debugf("[nfs] connection from %s closed: %+v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem }()
+			reply := s.handleRecord(record, conn.RemoteAddr())
+			if reply == nil {
+				return
+			}
+			writeMu.Lock()
+			defer writeMu.Unlock()
+			if err := writeRecord(conn, reply); err != nil {
+				_ = conn.Close()
+			}
+		}()
+	}
+}
+
+// readRecord reads a record made of fragments, the highest bit
+// of the header marks the last fragment
+func readRecord(r io.Reader) ([]byte, error) {
+	var record []byte
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return nil, err
+		}
+		h := binary.BigEndian.Uint32(header[:])
+		size := int(h & 0x7fffffff)
+		if len(record)+size > maxRecord {
+			return nil, errors.Errorf("record too large: %d", len(record)+size)
+		}
+		fragment := make([]byte, size)
+		if _, err := io.ReadFull(r, fragment); err != nil {
+			return nil, err
+		}
+		record = append(record, fragment...)
+		if h&0x80000000 != 0 {
+			return record, nil
+		}
+	}
+}
+
+func writeRecord(w io.Writer, data []byte) error {
+	buf := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(buf, uint32(len(data))|0x80000000)
+	copy(buf[4:], data)
+	_, err := w.Write(buf)
+	return err
+}
+
+func (s *Server) handleRecord(record []byte, addr net.Addr) []byte {
+	r := &xdrReader{buf: record}
+	req := &request{xid: r.uint32(), addr: addr, args: r}
+	if msgType := r.uint32(); r.err != nil || msgType != msgCall {
+		// replies and broken messages are ignored
+		return nil
+	}
+	if r.uint32() != rpcVersion {
+		w := &xdrWriter{}
+		w.uint32(req.xid)
+		w.uint32(msgReply)
+		w.uint32(1) // MSG_DENIED
+		w.uint32(0) // RPC_MISMATCH
+		w.uint32(rpcVersion)
+		w.uint32(rpcVersion)
+		return w.Bytes()
+	}
+	req.prog, req.vers, req.proc = r.uint32(), r.uint32(), r.uint32()
+	flavor, cred := r.uint32(), r.opaque(400)
+	r.uint32()
+	r.opaque(400)
+	if r.err != nil {
+		return nil
+	}
+	if flavor == authUnix {
+		c := &xdrReader{buf: cred}
+		c.uint32()    // stamp
+		c.string(255) // machine name
+		uid, gid := c.uint32(), c.uint32()
+		if c.err == nil {
+			req.uid, req.gid = uid, gid
+		}
+	}
+
+	w := &xdrWriter{}
+	w.uint32(req.xid)
+	w.uint32(msgReply)
+	w.uint32(replyAccepted)
+	w.uint32(authNone)
+	w.uint32(0)
+	var procs map[uint32]procFunc
+	var version uint32
+	switch req.prog {
+	case progNFS:
+		procs, version = s.nfsProcs, versionNFS
+	case progMount:
+		procs, version = s.mountProcs, versionMNT
+	default:
+		w.uint32(acceptProgUnavail)
+		return w.Bytes()
+	}
+	if req.vers != version {
+		w.uint32(acceptProgMismatch)
+		w.uint32(version)
+		w.uint32(version)
+		return w.Bytes()
+	}
+	proc, ok := procs[req.proc]
+	if !ok {
+		w.uint32(acceptProcUnavail)
+		return w.Bytes()
+	}
+	body := &xdrWriter{}
+	if err := proc(req, body); err != nil {
+		if errors.Is(err, errGarbageArgs) {
+			w.uint32(acceptGarbageArgs)
+			return w.Bytes()
+		}
+		utils.Log.Errorf("[nfs] failed to handle proc %d of program %d: %+v", req.proc, req.prog, err)
+		w.uint32(acceptSystemErr)
+		return w.Bytes()
+	}
+	w.uint32(acceptSuccess)
+	w.Write(body.Bytes())
+	return w.Bytes()
+}
+
+// procFunc writes the result of a procedure to w, errors other than
+// errGarbageArgs are replied as system errors
+type procFunc func(req *request, w *xdrWriter) error
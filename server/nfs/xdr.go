@@ -0,0 +1,105 @@
+package nfs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var errGarbageArgs = errors.New("garbage args")
+
+// xdrReader decodes XDR (RFC 4506) values, the first error is kept
+// and later reads return zero values
+type xdrReader struct {
+	buf []byte
+	err error
+}
+
+func (r *xdrReader) next(n int) []byte {
+	if r.err != nil || n < 0 || len(r.buf) < n {
+		r.err = errGarbageArgs
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *xdrReader) uint32() uint32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (r *xdrReader) uint64() uint64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+func (r *xdrReader) bool() bool {
+	return r.uint32() != 0
+}
+
+// fixed reads an opaque of a fixed length
+func (r *xdrReader) fixed(n int) []byte {
+	b := r.next(n)
+	r.next(pad(n))
+	return b
+}
+
+// opaque reads a variable length opaque of at most max bytes
+func (r *xdrReader) opaque(max int) []byte {
+	n := int(r.uint32())
+	if n > max {
+		r.err = errGarbageArgs
+		return nil
+	}
+	return r.fixed(n)
+}
+
+func (r *xdrReader) string(max int) string {
+	return string(r.opaque(max))
+}
+
+type xdrWriter struct {
+	bytes.Buffer
+}
+
+func (w *xdrWriter) uint32(v uint32) {
+	_ = binary.Write(&w.Buffer, binary.BigEndian, v)
+}
+
+func (w *xdrWriter) uint64(v uint64) {
+	_ = binary.Write(&w.Buffer, binary.BigEndian, v)
+}
+
+func (w *xdrWriter) bool(v bool) {
+	if v {
+		w.uint32(1)
+	} else {
+		w.uint32(0)
+	}
+}
+
+func (w *xdrWriter) fixed(b []byte) {
+	w.Write(b)
+	w.Write(make([]byte, pad(len(b))))
+}
+
+func (w *xdrWriter) opaque(b []byte) {
+	w.uint32(uint32(len(b)))
+	w.fixed(b)
+}
+
+func (w *xdrWriter) string(s string) {
+	w.opaque([]byte(s))
+}
+
+func pad(n int) int {
+	return (4 - n%4) % 4
+}
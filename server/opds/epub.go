@@ -0,0 +1,92 @@
+package opds
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	stdpath "path"
+	"strings"
+)
+
+// maxCoverSize limits the size of the cover read from an epub
+const maxCoverSize = 10 << 20
+
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type epubPackage struct {
+	Meta []struct {
+		Name    string `xml:"name,attr"`
+		Content string `xml:"content,attr"`
+	} `xml:"metadata>meta"`
+	Items []struct {
+		ID         string `xml:"id,attr"`
+		Href       string `xml:"href,attr"`
+		MediaType  string `xml:"media-type,attr"`
+		Properties string `xml:"properties,attr"`
+	} `xml:"manifest>item"`
+}
+
+func readZipXML(zr *zip.Reader, name string, v any) error {
+	f, err := zr.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return xml.NewDecoder(io.LimitReader(f, 1<<20)).Decode(v)
+}
+
+// epubCover returns the cover image of an epub: the manifest item with the
+// cover-image property (EPUB 3) or the one named by the cover meta (EPUB 2)
+func epubCover(r io.ReaderAt, size int64) ([]byte, string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, "", err
+	}
+	var container epubContainer
+	if err = readZipXML(zr, "META-INF/container.xml", &container); err != nil {
+		return nil, "", err
+	}
+	if len(container.Rootfiles) == 0 {
+		return nil, "", fmt.Errorf("no rootfile in container")
+	}
+	opfPath := container.Rootfiles[0].FullPath
+	var pkg epubPackage
+	if err = readZipXML(zr, opfPath, &pkg); err != nil {
+		return nil, "", err
+	}
+	var coverID string
+	for _, meta := range pkg.Meta {
+		if meta.Name == "cover" {
+			coverID = meta.Content
+		}
+	}
+	href, mediaType := "", ""
+	for _, item := range pkg.Items {
+		if strings.Contains(" "+item.Properties+" ", " cover-image ") || (coverID != "" && item.ID == coverID) {
+			href, mediaType = item.Href, item.MediaType
+			break
+		}
+	}
+	if href == "" {
+		return nil, "", fmt.Errorf("no cover in package")
+	}
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+	f, err := zr.Open(stdpath.Join(stdpath.Dir(opfPath), href))
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxCoverSize))
+	if err != nil {
+		return nil, "", err
+	}
+	return data, mediaType, nil
+}
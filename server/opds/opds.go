@@ -0,0 +1,456 @@
+// Package opds serves the folders of AList as an OPDS 1.2 catalog,
+// folders are navigation entries and ebooks acquisition entries.
+package opds
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	stdpath "path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/search"
+	"github.com/alist-org/alist/v3/internal/setting"
+	"github.com/alist-org/alist/v3/internal/sign"
+	"github.com/alist-org/alist/v3/internal/stream"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+const perPage = 50
+
+// bookTypes are the media types of the files listed as books
+var bookTypes = map[string]string{
+	"epub": "application/epub+zip",
+	"pdf":  "application/pdf",
+	"mobi": "application/x-mobipocket-ebook",
+	"azw3": "application/vnd.amazon.ebook",
+	"cbz":  "application/vnd.comicbook+zip",
+	"cbr":  "application/vnd.comicbook-rar",
+	"fb2":  "application/x-fictionbook+xml",
+	"djvu": "image/vnd.djvu",
+}
+
+func bookType(name string) string {
+	return bookTypes[strings.ToLower(utils.Ext(name))]
+}
+
+func Routes(g *gin.RouterGroup) {
+	g.GET("", Auth, Catalog)
+	g.GET("/*path", Auth, Catalog)
+}
+
+// errTwoFactor refuses the password of a user with two-factor authentication
+var errTwoFactor = errors.New("two-factor authentication is enabled, use an API token")
+
+// Auth authenticates the user with basic auth, a personal access token is
+// accepted as the password and required for users with two-factor
+// authentication. Requests without credentials are made as guest. Failures
+// count toward the sign-in limit of the client like the ones of the login page.
+func Auth(c *gin.Context) {
+	username, password, ok := c.Request.BasicAuth()
+	var user *model.User
+	var err error
+	if ok {
+		ip := c.ClientIP()
+		if common.LoginLocked(ip) {
+			c.AbortWithStatus(http.StatusTooManyRequests)
+			return
+		}
+		user, err = basicUser(username, password)
+		if err == nil {
+			common.LoginCache.Del(ip)
+		} else if !errors.Is(err, errTwoFactor) {
+			common.LoginFailed(ip)
+		}
+	} else {
+		user, err = op.GetGuest()
+	}
+	if err != nil || user.Disabled {
+		challenge(c)
+		return
+	}
+	if roles, err := op.GetRolesByUserID(user.ID); err == nil {
+		user.RolesDetail = roles
+	}
+	c.Set("user", user)
+	c.Next()
+}
+
+func basicUser(username, password string) (*model.User, error) {
+	if strings.HasPrefix(password, model.APITokenPrefix) {
+		user, err := op.GetUserByAPIToken(password)
+		if err != nil {
+			return nil, err
+		}
+		if user.Username != username {
+			return nil, errs.InvalidAPIToken
+		}
+		return user, nil
+	}
+	user, err := op.GetUserByName(username)
+	if err != nil {
+		return nil, err
+	}
+	if err := user.ValidateRawPassword(password); err != nil {
+		return nil, err
+	}
+	if user.OtpSecret != "" {
+		return nil, errTwoFactor
+	}
+	return user, nil
+}
+
+// challenge asks the client for credentials
+func challenge(c *gin.Context) {
+	c.Header("WWW-Authenticate", `Basic realm="AList OPDS"`)
+	c.AbortWithStatus(http.StatusUnauthorized)
+}
+
+// denied refuses a request, the client is asked to log in if it did not
+func denied(c *gin.Context) {
+	if _, _, ok := c.Request.BasicAuth(); !ok {
+		challenge(c)
+		return
+	}
+	c.AbortWithStatus(http.StatusForbidden)
+}
+
+// catalog builds the links of a request, the meta password given in the
+// query is kept in the links of the folders
+type catalog struct {
+	c        *gin.Context
+	user     *model.User
+	password string
+	base     string
+}
+
+// path returns the path of reqPath in the catalog
+func (ct *catalog) path(reqPath string) string {
+	base := utils.FixAndCleanPath(ct.user.BasePath)
+	if base == "/" {
+		return reqPath
+	}
+	return utils.FixAndCleanPath(strings.TrimPrefix(reqPath, base))
+}
+
+func (ct *catalog) url(reqPath string, query url.Values) string {
+	if ct.password != "" {
+		query.Set("password", ct.password)
+	}
+	u := ct.base + "/opds" + utils.EncodePath(ct.path(reqPath), true)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u
+}
+
+func (ct *catalog) downloadURL(reqPath string) string {
+	return fmt.Sprintf("%s/d%s?sign=%s", ct.base, utils.EncodePath(reqPath, true), sign.Sign(reqPath))
+}
+
+func (ct *catalog) canAccess(reqPath string) bool {
+	meta, err := op.GetNearestMeta(reqPath)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		return false
+	}
+	return common.CanAccessWithRoles(ct.user, meta, reqPath, ct.password)
+}
+
+// Catalog serves the feed of a folder, the search feed with q, the
+// OpenSearch description with osd and the cover of an epub with cover
+func Catalog(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+	ct := &catalog{c: c, user: user, password: c.Query("password"), base: common.GetApiUrl(c.Request)}
+	reqPath, err := user.JoinPath(utils.FixAndCleanPath(c.Param("path")))
+	if err != nil || !ct.canAccess(reqPath) {
+		denied(c)
+		return
+	}
+	ctx := context.WithValue(c.Request.Context(), "user", user)
+	obj, err := fs.Get(ctx, reqPath, &fs.GetArgs{})
+	if err != nil {
+		if errs.IsObjectNotFound(err) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	if !obj.IsDir() {
+		if c.Query("cover") == "" || strings.ToLower(utils.Ext(obj.GetName())) != "epub" {
+			c.Redirect(http.StatusFound, ct.downloadURL(reqPath))
+			return
+		}
+		ct.cover(ctx, reqPath, obj)
+		return
+	}
+	switch {
+	case c.Query("osd") != "":
+		ct.openSearch(reqPath)
+	case c.Query("q") != "":
+		ct.search(ctx, reqPath, c.Query("q"))
+	default:
+		ct.folder(ctx, reqPath, obj)
+	}
+}
+
+func page(c *gin.Context) int {
+	p, err := strconv.Atoi(c.Query("page"))
+	if err != nil || p < 1 {
+		return 1
+	}
+	return p
+}
+
+func (ct *catalog) newFeed(reqPath, title string, query url.Values, kind string) *feed {
+	f := &feed{
+		ID:      "urn:alist:opds:" + ct.path(reqPath),
+		Title:   title,
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Author:  author{Name: setting.GetStr(conf.SiteTitle)},
+		Links: []link{
+			{Rel: "self", Href: ct.url(reqPath, query), Type: kind},
+			{Rel: "start", Href: ct.url(ct.user.BasePath, url.Values{}), Type: navigationType},
+		},
+	}
+	if setting.GetStr(conf.SearchIndex) != "none" {
+		f.Links = append(f.Links, link{Rel: "search", Href: ct.url(reqPath, url.Values{"osd": {"1"}}), Type: openSearchType})
+	}
+	if ct.path(reqPath) != "/" {
+		f.Links = append(f.Links, link{Rel: "up", Href: ct.url(stdpath.Dir(reqPath), url.Values{}), Type: navigationType})
+	}
+	if f.Author.Name == "" {
+		f.Author.Name = "AList"
+	}
+	return f
+}
+
+// pageLinks adds the links to the previous and the next pages
+func (ct *catalog) pageLinks(f *feed, reqPath string, query url.Values, kind string, p int, more bool) {
+	if p > 1 {
+		prev := url.Values{}
+		for k, v := range query {
+			prev[k] = v
+		}
+		prev.Set("page", strconv.Itoa(p-1))
+		f.Links = append(f.Links, link{Rel: "previous", Href: ct.url(reqPath, prev), Type: kind})
+	}
+	if more {
+		next := url.Values{}
+		for k, v := range query {
+			next[k] = v
+		}
+		next.Set("page", strconv.Itoa(p+1))
+		f.Links = append(f.Links, link{Rel: "next", Href: ct.url(reqPath, next), Type: kind})
+	}
+}
+
+func updated(obj model.Obj) string {
+	t := obj.ModTime()
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func (ct *catalog) folderEntry(reqPath string, obj model.Obj) entry {
+	return entry{
+		Title:   obj.GetName(),
+		ID:      "urn:alist:opds:" + ct.path(reqPath),
+		Updated: updated(obj),
+		Links:   []link{{Rel: "subsection", Href: ct.url(reqPath, url.Values{}), Type: catalogType}},
+	}
+}
+
+func (ct *catalog) bookEntry(reqPath string, obj model.Obj) entry {
+	name := obj.GetName()
+	e := entry{
+		Title:   strings.TrimSuffix(name, stdpath.Ext(name)),
+		ID:      "urn:alist:opds:" + ct.path(reqPath),
+		Updated: updated(obj),
+		Content: &content{Type: "text", Text: name},
+		Links:   []link{{Rel: relAcquisition, Href: ct.downloadURL(reqPath), Type: bookType(name)}},
+	}
+	if strings.ToLower(utils.Ext(name)) == "epub" {
+		cover := ct.url(reqPath, url.Values{"cover": {"1"}})
+		e.Links = append(e.Links,
+			link{Rel: relImage, Href: cover},
+			link{Rel: relThumbnail, Href: cover})
+	}
+	return e
+}
+
+// folder serves the folders and the books of a folder, folders first
+func (ct *catalog) folder(ctx context.Context, reqPath string, dir model.Obj) {
+	objs, err := fs.List(ctx, reqPath, &fs.ListArgs{})
+	if err != nil {
+		common.ErrorResp(ct.c, err, 500)
+		return
+	}
+	var items []model.Obj
+	books := false
+	for _, obj := range objs {
+		if !obj.IsDir() && bookType(obj.GetName()) == "" {
+			continue
+		}
+		if !common.CanReadPathByRole(ct.user, stdpath.Join(reqPath, obj.GetName())) {
+			continue
+		}
+		books = books || !obj.IsDir()
+		items = append(items, obj)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].IsDir() != items[j].IsDir() {
+			return items[i].IsDir()
+		}
+		return strings.ToLower(items[i].GetName()) < strings.ToLower(items[j].GetName())
+	})
+	kind := navigationType
+	if books {
+		kind = acquisitionType
+	}
+	p := page(ct.c)
+	query := url.Values{}
+	if p > 1 {
+		query.Set("page", strconv.Itoa(p))
+	}
+	title := dir.GetName()
+	if ct.path(reqPath) == "/" {
+		title = setting.GetStr(conf.SiteTitle)
+	}
+	f := ct.newFeed(reqPath, title, query, kind)
+	start := (p - 1) * perPage
+	if start > len(items) {
+		start = len(items)
+	}
+	end := start + perPage
+	if end > len(items) {
+		end = len(items)
+	}
+	for _, obj := range items[start:end] {
+		childPath := stdpath.Join(reqPath, obj.GetName())
+		if obj.IsDir() {
+			f.Entries = append(f.Entries, ct.folderEntry(childPath, obj))
+		} else {
+			f.Entries = append(f.Entries, ct.bookEntry(childPath, obj))
+		}
+	}
+	ct.pageLinks(f, reqPath, url.Values{}, kind, p, end < len(items))
+	writeXML(ct.c, kind, f)
+}
+
+// search serves the books found by the search index under reqPath
+func (ct *catalog) search(ctx context.Context, reqPath, keywords string) {
+	p := page(ct.c)
+	req := model.SearchReq{
+		Parent:   reqPath,
+		Keywords: keywords,
+		Scope:    2,
+		PageReq:  model.PageReq{Page: 1, PerPage: perPage},
+	}
+	// the nodes are filtered after the search, pages of books are
+	// collected until the requested one is complete
+	var books []model.SearchNode
+	more := false
+	for {
+		nodes, _, err := search.Search(ctx, req)
+		if err != nil {
+			common.ErrorResp(ct.c, err, 500)
+			return
+		}
+		for _, node := range nodes {
+			if bookType(node.Name) == "" || !utils.IsSubPath(ct.user.BasePath, node.Parent) {
+				continue
+			}
+			if !ct.canAccess(stdpath.Join(node.Parent, node.Name)) {
+				continue
+			}
+			books = append(books, node)
+		}
+		if len(books) > p*perPage {
+			more = true
+			break
+		}
+		if len(nodes) < req.PerPage {
+			break
+		}
+		req.Page++
+	}
+	query := url.Values{"q": {keywords}}
+	f := ct.newFeed(reqPath, fmt.Sprintf("Search: %s", keywords), query, acquisitionType)
+	start := (p - 1) * perPage
+	if start > len(books) {
+		start = len(books)
+	}
+	end := start + perPage
+	if end > len(books) {
+		end = len(books)
+	}
+	for _, node := range books[start:end] {
+		obj := &model.Object{Name: node.Name, Size: node.Size}
+		f.Entries = append(f.Entries, ct.bookEntry(stdpath.Join(node.Parent, node.Name), obj))
+	}
+	ct.pageLinks(f, reqPath, query, acquisitionType, p, more)
+	writeXML(ct.c, acquisitionType, f)
+}
+
+func (ct *catalog) openSearch(reqPath string) {
+	template := ct.url(reqPath, url.Values{"q": {"SEARCH_TERMS"}})
+	writeXML(ct.c, openSearchType, &openSearchDescription{
+		ShortName:      "AList",
+		Description:    "Search the books",
+		InputEncoding:  "UTF-8",
+		OutputEncoding: "UTF-8",
+		URL: openSearchURL{
+			Type:     acquisitionType,
+			Template: strings.Replace(template, "SEARCH_TERMS", "{searchTerms}", 1),
+		},
+	})
+}
+
+// cover serves the cover image of an epub
+func (ct *catalog) cover(ctx context.Context, reqPath string, obj model.Obj) {
+	link, file, err := fs.Link(ctx, reqPath, model.LinkArgs{
+		IP:     ct.c.ClientIP(),
+		Header: ct.c.Request.Header,
+	})
+	if err != nil {
+		common.ErrorResp(ct.c, err, 500)
+		return
+	}
+	ss, err := stream.NewSeekableStream(stream.FileStream{Obj: file, Ctx: ctx}, link)
+	if err != nil {
+		common.ErrorResp(ct.c, err, 500)
+		return
+	}
+	r, err := stream.NewReadAtSeeker(ss, 0)
+	if err != nil {
+		_ = ss.Close()
+		common.ErrorResp(ct.c, err, 500)
+		return
+	}
+	defer r.Close()
+	data, mediaType, err := epubCover(r, obj.GetSize())
+	if err != nil {
+		utils.Log.Debugf("[opds] no cover in %s: %+v", reqPath, err)
+		ct.c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	if mediaType == "" {
+		mediaType = http.DetectContentType(data)
+	}
+	ct.c.Header("Cache-Control", "max-age=86400")
+	ct.c.Data(http.StatusOK, mediaType, data)
+}
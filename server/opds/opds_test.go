@@ -0,0 +1,154 @@
+package opds
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/alist-org/alist/v3/drivers"
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/stream"
+	"github.com/alist-org/alist/v3/internal/testutil"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+var coverData = []byte("\x89PNG\r\n\x1a\ncover")
+
+func epub(t *testing.T) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := []struct{ name, data string }{
+		{"mimetype", "application/epub+zip"},
+		{"META-INF/container.xml", `<?xml version="1.0"?><container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">` +
+			`<rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles></container>`},
+		{"OEBPS/content.opf", `<?xml version="1.0"?><package xmlns="http://www.idpf.org/2007/opf" version="3.0">` +
+			`<metadata><meta name="cover" content="cover"/></metadata>` +
+			`<manifest><item id="cover" href="images/cover%20art.png" media-type="image/png"/></manifest></package>`},
+		{"OEBPS/images/cover art.png", string(coverData)},
+	}
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(f.data))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCatalog(t *testing.T) {
+	testutil.InitDB()
+	conf.Conf.SiteURL = "http://alist.test"
+	stream.ClientDownloadLimit = rate.NewLimiter(rate.Inf, 0)
+	role := &model.Role{Name: "reader", PermissionScopes: []model.PermissionEntry{{Path: "/", Permission: 0}}}
+	err := op.CreateRole(role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &model.User{Username: "reader", BasePath: "/", Role: model.Roles{int(role.ID)}}
+	if err = op.CreateUser(user.SetPassword("secret")); err != nil {
+		t.Fatal(err)
+	}
+	_, dir := testutil.CreateLocal(t, "/books")
+	for _, sub := range []string{"Fiction", "Locked"} {
+		if err = os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = os.WriteFile(filepath.Join(dir, "Fiction", "The Book.epub"), epub(t), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "Fiction", "notes.txt"), []byte("text"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err = op.CreateMeta(&model.Meta{Path: "/books/Locked", Password: "pw", PSub: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	Routes(e.Group("/opds"))
+	get := func(target string, auth bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if auth {
+			req.SetBasicAuth("reader", "secret")
+		}
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("/opds/books", false); w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+		t.Fatalf("expected a challenge without credentials, got %d", w.Code)
+	}
+	w := get("/opds/books", true)
+	if w.Code != http.StatusOK || !strings.Contains(w.Header().Get("Content-Type"), navigationType) ||
+		!strings.Contains(w.Body.String(), `href="http://alist.test/opds/books/Fiction"`) {
+		t.Fatalf("unexpected navigation feed: %d %s", w.Code, w.Body.String())
+	}
+	w = get("/opds/books/Fiction", true)
+	body := w.Body.String()
+	if w.Code != http.StatusOK || !strings.Contains(w.Header().Get("Content-Type"), acquisitionType) ||
+		!strings.Contains(body, "<title>The Book</title>") ||
+		!strings.Contains(body, `href="http://alist.test/d/books/Fiction/The%20Book.epub?sign=`) ||
+		!strings.Contains(body, `rel="`+relImage+`" href="http://alist.test/opds/books/Fiction/The%20Book.epub?cover=1"`) {
+		t.Fatalf("unexpected acquisition feed: %d %s", w.Code, body)
+	}
+	if strings.Contains(body, "notes.txt") {
+		t.Fatalf("non book file listed: %s", body)
+	}
+	w = get("/opds/books/Fiction/The%20Book.epub?cover=1", true)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" || !bytes.Equal(w.Body.Bytes(), coverData) {
+		t.Fatalf("unexpected cover: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if w = get("/opds/books/Locked", true); w.Code != http.StatusForbidden {
+		t.Fatalf("expected the meta password to be required, got %d", w.Code)
+	}
+	if w = get("/opds/books/Locked?password=pw", true); w.Code != http.StatusOK {
+		t.Fatalf("expected access with the meta password, got %d", w.Code)
+	}
+
+	// the password alone doesn't pass two-factor authentication
+	guarded := &model.User{Username: "guarded", BasePath: "/", Role: model.Roles{int(role.ID)}, OtpSecret: "JBSWY3DPEHPK3PXP"}
+	if err = op.CreateUser(guarded.SetPassword("secret")); err != nil {
+		t.Fatal(err)
+	}
+	token, err := op.CreateAPIToken(&model.APIToken{UserID: guarded.ID, Name: "opds"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	getAs := func(username, password string) int {
+		req := httptest.NewRequest(http.MethodGet, "/opds/books", nil)
+		req.SetBasicAuth(username, password)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := getAs("guarded", "secret"); code != http.StatusUnauthorized {
+		t.Fatalf("expected the password of a 2FA user to be refused, got %d", code)
+	}
+	if code := getAs("guarded", token); code != http.StatusOK {
+		t.Fatalf("expected the API token of a 2FA user to be accepted, got %d", code)
+	}
+
+	// failures count toward the sign-in limit of the login page
+	defer common.LoginCache.Clear()
+	for i := 0; i < common.LoginMaxAttempts; i++ {
+		getAs("reader", "wrong")
+	}
+	if w = get("/opds/books", true); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the client to be locked out, got %d", w.Code)
+	}
+}
@@ -4,18 +4,26 @@ import (
 	"github.com/alist-org/alist/v3/cmd/flags"
 	"github.com/alist-org/alist/v3/internal/conf"
 	"github.com/alist-org/alist/v3/internal/message"
+	"github.com/alist-org/alist/v3/internal/metrics"
 	"github.com/alist-org/alist/v3/internal/sign"
 	"github.com/alist-org/alist/v3/internal/stream"
 	"github.com/alist-org/alist/v3/pkg/utils"
 	"github.com/alist-org/alist/v3/server/common"
 	"github.com/alist-org/alist/v3/server/handles"
 	"github.com/alist-org/alist/v3/server/middlewares"
+	"github.com/alist-org/alist/v3/server/opds"
 	"github.com/alist-org/alist/v3/server/static"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
 func Init(e *gin.Engine) {
+	if conf.Conf.Tracing.Enable {
+		// the handlers pass the gin context down to op, so it has to
+		// reach the span in the request context
+		e.ContextWithFallback = true
+		e.Use(middlewares.Tracing)
+	}
 	if !utils.SliceContains([]string{"", "/"}, conf.URL.Path) {
 		e.GET("/", func(c *gin.Context) {
 			c.Redirect(302, conf.URL.Path)
@@ -32,6 +40,9 @@ func Init(e *gin.Engine) {
 	})
 	g.GET("/favicon.ico", handles.Favicon)
 	g.GET("/robots.txt", handles.Robots)
+	if metricsOnMainListener() {
+		g.GET("/metrics", Metrics)
+	}
 	g.GET("/i/:link_name", handles.Plist)
 	common.SecretKey = []byte(conf.Conf.JwtSecret)
 	g.Use(middlewares.StoragesLoaded)
@@ -40,27 +51,30 @@ func Init(e *gin.Engine) {
 	}
 	WebDav(g.Group("/dav"))
 	S3(g.Group("/s3"))
+	Subsonic(g.Group("/rest"))
+	opds.Routes(g.Group("/opds"))
 
 	downloadLimiter := middlewares.DownloadRateLimiter(stream.ClientDownloadLimit)
+	served := middlewares.ServedBytes(metrics.HTTP)
 	signCheck := middlewares.Down(sign.Verify)
-	g.GET("/d/*path", signCheck, downloadLimiter, handles.Down)
-	g.GET("/p/*path", signCheck, downloadLimiter, handles.Proxy)
+	g.GET("/d/*path", signCheck, served, downloadLimiter, handles.Down)
+	g.GET("/p/*path", signCheck, served, downloadLimiter, handles.Proxy)
 	g.HEAD("/d/*path", signCheck, handles.Down)
 	g.HEAD("/p/*path", signCheck, handles.Proxy)
 	g.GET("/s/:share_id", handles.GetSharePage)
 	g.GET("/s/:share_id/*path", handles.GetSharePage)
-	g.GET("/sd/:share_id", downloadLimiter, handles.ShareDown)
-	g.GET("/sd/:share_id/*path", downloadLimiter, handles.ShareDown)
+	g.GET("/sd/:share_id", served, downloadLimiter, handles.ShareDown)
+	g.GET("/sd/:share_id/*path", served, downloadLimiter, handles.ShareDown)
 	g.HEAD("/sd/:share_id", handles.ShareDown)
 	g.HEAD("/sd/:share_id/*path", handles.ShareDown)
-	g.GET("/sp/:share_id", downloadLimiter, handles.ShareProxy)
-	g.GET("/sp/:share_id/*path", downloadLimiter, handles.ShareProxy)
+	g.GET("/sp/:share_id", served, downloadLimiter, handles.ShareProxy)
+	g.GET("/sp/:share_id/*path", served, downloadLimiter, handles.ShareProxy)
 	g.HEAD("/sp/:share_id", handles.ShareProxy)
 	g.HEAD("/sp/:share_id/*path", handles.ShareProxy)
 	archiveSignCheck := middlewares.Down(sign.VerifyArchive)
-	g.GET("/ad/*path", archiveSignCheck, downloadLimiter, handles.ArchiveDown)
-	g.GET("/ap/*path", archiveSignCheck, downloadLimiter, handles.ArchiveProxy)
-	g.GET("/ae/*path", archiveSignCheck, downloadLimiter, handles.ArchiveInternalExtract)
+	g.GET("/ad/*path", archiveSignCheck, served, downloadLimiter, handles.ArchiveDown)
+	g.GET("/ap/*path", archiveSignCheck, served, downloadLimiter, handles.ArchiveProxy)
+	g.GET("/ae/*path", archiveSignCheck, served, downloadLimiter, handles.ArchiveInternalExtract)
 	g.HEAD("/ad/*path", archiveSignCheck, handles.ArchiveDown)
 	g.HEAD("/ap/*path", archiveSignCheck, handles.ArchiveProxy)
 	g.HEAD("/ae/*path", archiveSignCheck, handles.ArchiveInternalExtract)
@@ -74,15 +88,24 @@ func Init(e *gin.Engine) {
 	api.POST("/auth/login/ldap", handles.LoginLdap)
 	api.POST("/auth/register", handles.Register)
 	auth.GET("/me", handles.CurrentUser)
-	auth.POST("/me/update", handles.UpdateCurrent)
+	auth.POST("/me/update", middlewares.AuthNotAPIToken, handles.UpdateCurrent)
 	auth.GET("/me/sshkey/list", handles.ListMyPublicKey)
-	auth.POST("/me/sshkey/add", handles.AddMyPublicKey)
-	auth.POST("/me/sshkey/delete", handles.DeleteMyPublicKey)
-	auth.POST("/auth/2fa/generate", handles.Generate2FA)
-	auth.POST("/auth/2fa/verify", handles.Verify2FA)
+	auth.POST("/me/sshkey/add", middlewares.AuthNotAPIToken, handles.AddMyPublicKey)
+	auth.POST("/me/sshkey/delete", middlewares.AuthNotAPIToken, handles.DeleteMyPublicKey)
+	apiToken := auth.Group("/me/token", middlewares.AuthNotAPIToken)
+	apiToken.GET("/list", handles.ListMyAPITokens)
+	apiToken.POST("/create", handles.CreateMyAPIToken)
+	apiToken.POST("/delete", handles.DeleteMyAPIToken)
+	s3Credential := auth.Group("/me/s3_credential", middlewares.AuthNotAPIToken)
+	s3Credential.GET("/list", handles.ListMyS3Credentials)
+	s3Credential.POST("/create", handles.CreateMyS3Credential)
+	s3Credential.POST("/update", handles.UpdateMyS3Credential)
+	s3Credential.POST("/delete", handles.DeleteMyS3Credential)
+	auth.POST("/auth/2fa/generate", middlewares.AuthNotAPIToken, handles.Generate2FA)
+	auth.POST("/auth/2fa/verify", middlewares.AuthNotAPIToken, handles.Verify2FA)
 	auth.GET("/auth/logout", handles.LogOut)
 	auth.GET("/me/sessions", handles.ListMySessions)
-	auth.POST("/me/sessions/evict", handles.EvictMySession)
+	auth.POST("/me/sessions/evict", middlewares.AuthNotAPIToken, handles.EvictMySession)
 
 	// auth
 	api.GET("/auth/sso", handles.SSOLoginRedirect)
@@ -116,6 +139,7 @@ func Init(e *gin.Engine) {
 	share.GET("/list", handles.ListShares)
 	share.POST("/delete", handles.DeleteShare)
 	_task(auth.Group("/task", middlewares.AuthNotGuest))
+	_rss(auth.Group("/rss", middlewares.AuthNotGuest))
 	_label(auth.Group("/label"))
 	_labelFileBinding(auth.Group("/label_file_binding"))
 	admin(auth.Group("/admin", middlewares.AuthAdmin))
@@ -145,6 +169,11 @@ func admin(g *gin.RouterGroup) {
 	user.POST("/del_cache", handles.DelUserCache)
 	user.GET("/sshkey/list", handles.ListPublicKeys)
 	user.POST("/sshkey/delete", handles.DeletePublicKey)
+	user.GET("/token/list", handles.ListAPITokens)
+	user.POST("/token/delete", handles.DeleteAPIToken)
+	user.GET("/s3_credential/list", handles.ListS3Credentials)
+	user.POST("/s3_credential/create", handles.CreateS3Credential)
+	user.POST("/s3_credential/delete", handles.DeleteS3Credential)
 
 	role := g.Group("/role")
 	role.GET("/list", handles.ListRoles)
@@ -162,6 +191,8 @@ func admin(g *gin.RouterGroup) {
 	storage.POST("/enable", handles.EnableStorage)
 	storage.POST("/disable", handles.DisableStorage)
 	storage.POST("/load_all", handles.LoadAllStorages)
+	storage.POST("/crypt_rotate", handles.CryptRotate)
+	storage.POST("/chunker_check", handles.ChunkerCheck)
 
 	driver := g.Group("/driver")
 	driver.GET("/list", handles.ListDriverInfo)
@@ -178,6 +209,7 @@ func admin(g *gin.RouterGroup) {
 	setting.POST("/set_aria2", handles.SetAria2)
 	setting.POST("/set_qbit", handles.SetQbittorrent)
 	setting.POST("/set_transmission", handles.SetTransmission)
+	setting.POST("/set_bittorrent", handles.SetBitTorrent)
 	setting.POST("/set_115", handles.Set115)
 	setting.POST("/set_pikpak", handles.SetPikPak)
 	setting.POST("/set_thunder", handles.SetThunder)
@@ -186,6 +218,13 @@ func admin(g *gin.RouterGroup) {
 	setting.POST("/stop_frp", handles.StopFRP)
 	setting.GET("/frp_runtime", handles.GetFRPRuntime)
 
+	rss := g.Group("/rss")
+	rss.GET("/list", handles.ListRSSSubscriptions)
+	rss.POST("/update", handles.UpdateRSSSubscription)
+	rss.POST("/delete", handles.DeleteRSSSubscription)
+	rss.GET("/items", handles.ListRSSItems)
+	rss.POST("/check", handles.CheckRSSSubscription)
+
 	// retain /admin/task API to ensure compatibility with legacy automation scripts
 	_task(g.Group("/task"))
 
@@ -234,6 +273,8 @@ func _fs(g *gin.RouterGroup) {
 	g.POST("/copy", handles.FsCopy)
 	g.POST("/remove", handles.FsRemove)
 	g.POST("/remove_empty_directory", handles.FsRemoveEmptyDirectory)
+	g.Any("/versions", handles.FsVersions)
+	g.POST("/versions/restore", handles.FsRestoreVersion)
 	uploadLimiter := middlewares.UploadRateLimiter(stream.ClientUploadLimit)
 	g.PUT("/put", middlewares.FsUp, uploadLimiter, handles.FsStream)
 	g.PUT("/form", middlewares.FsUp, uploadLimiter, handles.FsForm)
@@ -248,6 +289,15 @@ func _fs(g *gin.RouterGroup) {
 	a.POST("/decompress", handles.FsArchiveDecompress)
 }
 
+func _rss(g *gin.RouterGroup) {
+	g.GET("/list", handles.ListMyRSSSubscriptions)
+	g.POST("/create", handles.CreateMyRSSSubscription)
+	g.POST("/update", handles.UpdateMyRSSSubscription)
+	g.POST("/delete", handles.DeleteMyRSSSubscription)
+	g.GET("/items", handles.ListMyRSSItems)
+	g.POST("/check", handles.CheckMyRSSSubscription)
+}
+
 func _task(g *gin.RouterGroup) {
 	handles.SetupTaskRoute(g)
 }
@@ -6,7 +6,9 @@ import (
 	"strings"
 
 	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/metrics"
 	"github.com/alist-org/alist/v3/server/common"
+	"github.com/alist-org/alist/v3/server/middlewares"
 	"github.com/alist-org/alist/v3/server/s3"
 	"github.com/gin-gonic/gin"
 )
@@ -25,7 +27,7 @@ func S3(g *gin.RouterGroup) {
 		return
 	}
 	h, _ := s3.NewServer(context.Background())
-
+	g.Use(middlewares.ServedBytes(metrics.S3))
 	g.Any("/*path", func(c *gin.Context) {
 		adjustedPath := strings.TrimPrefix(c.Request.URL.Path, path.Join(conf.URL.Path, "/s3"))
 		c.Request.URL.Path = adjustedPath
@@ -35,5 +37,6 @@ func S3(g *gin.RouterGroup) {
 
 func S3Server(g *gin.RouterGroup) {
 	h, _ := s3.NewServer(context.Background())
+	g.Use(middlewares.ServedBytes(metrics.S3))
 	g.Any("/*path", gin.WrapH(h))
 }
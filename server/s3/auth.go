@@ -0,0 +1,188 @@
+package s3
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/setting"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/alist-org/gofakes3"
+	"github.com/alist-org/gofakes3/signature"
+	log "github.com/sirupsen/logrus"
+)
+
+const accessDenied gofakes3.ErrorCode = "AccessDenied"
+
+var errAccessDenied = gofakes3.ErrorMessage(accessDenied, "Access Denied")
+
+// requestAccessKey extracts the access key id of a signed request.
+func requestAccessKey(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	switch {
+	case strings.HasPrefix(auth, "AWS4-HMAC-SHA256"):
+		_, cred, found := strings.Cut(auth, "Credential=")
+		if !found {
+			return ""
+		}
+		ak, _, _ := strings.Cut(strings.TrimSpace(cred), "/")
+		return ak
+	case strings.HasPrefix(auth, "AWS "):
+		ak, _, _ := strings.Cut(strings.TrimPrefix(auth, "AWS "), ":")
+		return ak
+	}
+	q := r.URL.Query()
+	if cred := q.Get("X-Amz-Credential"); cred != "" {
+		ak, _, _ := strings.Cut(cred, "/")
+		return ak
+	}
+	return q.Get("AWSAccessKeyId")
+}
+
+func writeAuthError(w http.ResponseWriter, apiErr signature.APIError) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(apiErr.HTTPStatusCode)
+	_, _ = w.Write(signature.EncodeAPIErrorToResponse(apiErr))
+}
+
+// verifySignature checks the V4 or V2 signature of r against the keys known
+// to the signature package and writes the error response on failure.
+func verifySignature(w http.ResponseWriter, r *http.Request) bool {
+	result := signature.V4SignVerify(r)
+	if result == signature.ErrUnsupportAlgorithm {
+		result = signature.V2SignVerify(r)
+	}
+	if result != signature.ErrNone {
+		writeAuthError(w, signature.GetAPIError(result))
+		return false
+	}
+	return true
+}
+
+// bucketsCtxKey carries the buckets resolved for the credential of a request
+type bucketsCtxKey struct{}
+
+// tokenBucket is the only bucket of a personal access token, it maps to the
+// base path of the token's user.
+const tokenBucket = "home"
+
+// resolveAccessKey looks up the user, secret and buckets of a per-user S3
+// credential or of a personal access token. Bucket paths are relative to the
+// user's base path, a credential without buckets sees none and a token sees
+// its user's base path as tokenBucket.
+func resolveAccessKey(accessKey string) (*model.User, string, []Bucket, error) {
+	user, cred, err := op.GetUserByS3AccessKey(accessKey)
+	if err == nil {
+		buckets := make([]Bucket, 0, len(cred.Buckets))
+		for _, b := range cred.Buckets {
+			buckets = append(buckets, Bucket{Name: b.Name, Path: b.Path})
+		}
+		return user, cred.SecretAccessKey, joinBuckets(user, buckets), nil
+	}
+	user, err = op.GetUserByAPITokenAccessKey(accessKey)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return user, op.APITokenS3Secret(user.Scope), joinBuckets(user, []Bucket{{Name: tokenBucket, Path: "/"}}), nil
+}
+
+// joinBuckets moves buckets below the base path of user, dropping the ones
+// the user can't reach. The result is never nil.
+func joinBuckets(user *model.User, buckets []Bucket) []Bucket {
+	res := make([]Bucket, 0, len(buckets))
+	for _, b := range buckets {
+		bucketPath, err := user.JoinPath(path.Join(utils.FixAndCleanPath(user.BasePath), utils.FixAndCleanPath(b.Path)))
+		if err != nil {
+			log.Warnf("[s3] skip bucket %s of user %s: %v", b.Name, user.Username, err)
+			continue
+		}
+		res = append(res, Bucket{Name: b.Name, Path: bucketPath})
+	}
+	return res
+}
+
+// authHandler verifies requests signed with a per-user credential or with the
+// access key of a personal access token, and injects the user and its buckets
+// into the request context. Requests using the global key pair are passed
+// through unchanged.
+func authHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accessKey := requestAccessKey(r)
+		if accessKey == "" || accessKey == setting.GetStr(conf.S3AccessKeyId) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		user, secret, buckets, err := resolveAccessKey(accessKey)
+		if err != nil || user.Disabled {
+			log.Debugf("[s3] access key %s rejected: %v", accessKey, err)
+			writeAuthError(w, signature.APIError{
+				Code:           "InvalidAccessKeyId",
+				Description:    "The access key ID you provided does not exist in our records.",
+				HTTPStatusCode: http.StatusForbidden,
+			})
+			return
+		}
+		signature.StoreKeys(map[string]string{accessKey: secret})
+		if !verifySignature(w, r) {
+			return
+		}
+		if len(user.Role) > 0 {
+			roles, err := op.GetRolesByUserID(user.ID)
+			if err != nil {
+				writeAuthError(w, signature.APIError{
+					Code:           string(gofakes3.ErrInternal),
+					Description:    err.Error(),
+					HTTPStatusCode: http.StatusInternalServerError,
+				})
+				return
+			}
+			user.RolesDetail = roles
+		}
+		ctx := context.WithValue(r.Context(), "user", user)
+		ctx = context.WithValue(ctx, bucketsCtxKey{}, buckets)
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requestUser returns the user authenticated by authHandler, nil means the
+// request was signed with the global key pair and is not restricted.
+func requestUser(ctx context.Context) *model.User {
+	user, _ := ctx.Value("user").(*model.User)
+	return user
+}
+
+// canRead reports whether the request user may see reqPath.
+func canRead(ctx context.Context, reqPath string) bool {
+	user := requestUser(ctx)
+	if user == nil {
+		return true
+	}
+	meta, _ := op.GetNearestMeta(reqPath)
+	return common.CanAccessWithRoles(user, meta, reqPath, "")
+}
+
+// checkRead returns errAccessDenied unless the request user may read reqPath.
+func checkRead(ctx context.Context, reqPath string) error {
+	if !canRead(ctx, reqPath) {
+		return errAccessDenied
+	}
+	return nil
+}
+
+// checkManage returns errAccessDenied unless the request user holds the
+// permission bit on reqPath.
+func checkManage(ctx context.Context, reqPath string, bit uint) error {
+	user := requestUser(ctx)
+	if user == nil {
+		return nil
+	}
+	if !canRead(ctx, reqPath) || !common.HasPermission(common.MergeRolePermissions(user, reqPath), bit) {
+		return errAccessDenied
+	}
+	return nil
+}
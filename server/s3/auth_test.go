@@ -0,0 +1,62 @@
+package s3
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/testutil"
+)
+
+func init() {
+	testutil.InitDB()
+}
+
+func TestResolveAccessKeyBuckets(t *testing.T) {
+	if err := op.SaveSettingItem(&model.SettingItem{Key: conf.S3Buckets, Value: `[{"name":"pub","path":"/pub"}]`, Type: conf.TypeString, Group: model.S3, Flag: model.PRIVATE}); err != nil {
+		t.Fatal(err)
+	}
+	user := &model.User{Username: "s3_bucket_user", BasePath: "/team"}
+	if err := db.CreateUser(user); err != nil {
+		t.Fatal(err)
+	}
+
+	// a credential without buckets doesn't see the global ones
+	cred := &model.S3Credential{UserID: user.ID}
+	if err := op.CreateS3Credential(cred); err != nil {
+		t.Fatal(err)
+	}
+	_, _, buckets, err := resolveAccessKey(cred.AccessKeyID)
+	if err != nil || buckets == nil || len(buckets) != 0 {
+		t.Fatalf("expected no buckets, got %v %v", buckets, err)
+	}
+	cred = &model.S3Credential{UserID: user.ID, Buckets: model.S3Buckets{{Name: "docs", Path: "/docs"}}}
+	if err = op.CreateS3Credential(cred); err != nil {
+		t.Fatal(err)
+	}
+	_, _, buckets, err = resolveAccessKey(cred.AccessKeyID)
+	if err != nil || len(buckets) != 1 || buckets[0].Path != "/team/docs" {
+		t.Fatalf("expected the bucket below the base path, got %v %v", buckets, err)
+	}
+
+	// a token sees the base path of its user and not the global buckets
+	tk := &model.APIToken{UserID: user.ID, Name: "s3"}
+	if _, err = op.CreateAPIToken(tk); err != nil {
+		t.Fatal(err)
+	}
+	_, _, buckets, err = resolveAccessKey(tk.AccessKey)
+	if err != nil || len(buckets) != 1 || buckets[0].Name != tokenBucket || buckets[0].Path != "/team" {
+		t.Fatalf("expected the base path bucket, got %v %v", buckets, err)
+	}
+
+	ctx := context.WithValue(context.Background(), "user", user)
+	if buckets, err = getAndParseBuckets(ctx); err != nil || len(buckets) != 0 {
+		t.Fatalf("expected a user without buckets to get none, got %v %v", buckets, err)
+	}
+	if buckets, err = getAndParseBuckets(context.Background()); err != nil || len(buckets) != 1 || buckets[0].Path != "/pub" {
+		t.Fatalf("expected the global key pair to get the global buckets, got %v %v", buckets, err)
+	}
+}
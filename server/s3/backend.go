@@ -9,7 +9,6 @@ import (
 	"io"
 	"path"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -21,6 +20,7 @@ import (
 	"github.com/alist-org/alist/v3/internal/stream"
 	"github.com/alist-org/alist/v3/pkg/http_range"
 	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/alist-org/alist/v3/server/common"
 	"github.com/alist-org/gofakes3"
 	"github.com/ncw/swift/v2"
 	log "github.com/sirupsen/logrus"
@@ -33,26 +33,29 @@ var (
 
 // s3Backend implements the gofacess3.Backend interface to make an S3
 // backend for gofakes3
-type s3Backend struct {
-	meta *sync.Map
-}
+type s3Backend struct{}
 
 // newBackend creates a new SimpleBucketBackend.
 func newBackend() gofakes3.Backend {
-	return &s3Backend{
-		meta: new(sync.Map),
-	}
+	return &s3Backend{}
 }
 
-// ListBuckets always returns the default bucket.
+// ListBuckets returns the buckets visible to the request credential.
 func (b *s3Backend) ListBuckets(ctx context.Context) ([]gofakes3.BucketInfo, error) {
-	buckets, err := getAndParseBuckets()
+	buckets, err := getAndParseBuckets(ctx)
 	if err != nil {
 		return nil, err
 	}
 	var response []gofakes3.BucketInfo
 	for _, b := range buckets {
-		node, _ := fs.Get(ctx, b.Path, &fs.GetArgs{})
+		if !canRead(ctx, b.Path) {
+			continue
+		}
+		node, err := fs.Get(ctx, b.Path, &fs.GetArgs{})
+		if err != nil {
+			log.Warnf("[s3] skip bucket %s: %v", b.Name, err)
+			continue
+		}
 		response = append(response, gofakes3.BucketInfo{
 			// Name:         gofakes3.URLEncode(b.Name),
 			Name:         b.Name,
@@ -64,11 +67,14 @@ func (b *s3Backend) ListBuckets(ctx context.Context) ([]gofakes3.BucketInfo, err
 
 // ListBucket lists the objects in the given bucket.
 func (b *s3Backend) ListBucket(ctx context.Context, bucketName string, prefix *gofakes3.Prefix, page gofakes3.ListBucketPage) (*gofakes3.ObjectList, error) {
-	bucket, err := getBucketByName(bucketName)
+	bucket, err := getBucketByName(ctx, bucketName)
 	if err != nil {
 		return nil, err
 	}
 	bucketPath := bucket.Path
+	if err := checkRead(ctx, bucketPath); err != nil {
+		return nil, err
+	}
 
 	if prefix == nil {
 		prefix = emptyPrefix
@@ -85,7 +91,7 @@ func (b *s3Backend) ListBucket(ctx context.Context, bucketName string, prefix *g
 	response := gofakes3.NewObjectList()
 	path, remaining := prefixParser(prefix)
 
-	err = b.entryListR(bucketPath, path, remaining, prefix.HasDelimiter, response)
+	err = b.entryListR(ctx, bucketPath, path, remaining, prefix.HasDelimiter, response)
 	if err == gofakes3.ErrNoSuchKey {
 		// AWS just returns an empty list
 		response = gofakes3.NewObjectList()
@@ -97,16 +103,17 @@ func (b *s3Backend) ListBucket(ctx context.Context, bucketName string, prefix *g
 }
 
 // HeadObject returns the fileinfo for the given object name.
-//
-// Note that the metadata is not supported yet.
 func (b *s3Backend) HeadObject(ctx context.Context, bucketName, objectName string) (*gofakes3.Object, error) {
-	bucket, err := getBucketByName(bucketName)
+	bucket, err := getBucketByName(ctx, bucketName)
 	if err != nil {
 		return nil, err
 	}
 	bucketPath := bucket.Path
 
 	fp := path.Join(bucketPath, objectName)
+	if err := checkRead(ctx, fp); err != nil {
+		return nil, err
+	}
 	fmeta, _ := op.GetNearestMeta(fp)
 	node, err := fs.Get(context.WithValue(ctx, "meta", fmeta), fp, &fs.GetArgs{})
 	if err != nil {
@@ -118,23 +125,11 @@ func (b *s3Backend) HeadObject(ctx context.Context, bucketName, objectName strin
 	}
 
 	size := node.GetSize()
-	// hash := getFileHashByte(fobj)
-
-	meta := map[string]string{
-		"Last-Modified": node.ModTime().Format(timeFormat),
-		"Content-Type":  utils.GetMimeType(fp),
-	}
-
-	if val, ok := b.meta.Load(fp); ok {
-		metaMap := val.(map[string]string)
-		for k, v := range metaMap {
-			meta[k] = v
-		}
-	}
+	meta, hash := objectInfo(fp, node)
 
 	return &gofakes3.Object{
-		Name: objectName,
-		// Hash:     hash,
+		Name:     objectName,
+		Hash:     hash,
 		Metadata: meta,
 		Size:     size,
 		Contents: noOpReadCloser{},
@@ -143,13 +138,16 @@ func (b *s3Backend) HeadObject(ctx context.Context, bucketName, objectName strin
 
 // GetObject fetchs the object from the filesystem.
 func (b *s3Backend) GetObject(ctx context.Context, bucketName, objectName string, rangeRequest *gofakes3.ObjectRangeRequest) (obj *gofakes3.Object, err error) {
-	bucket, err := getBucketByName(bucketName)
+	bucket, err := getBucketByName(ctx, bucketName)
 	if err != nil {
 		return nil, err
 	}
 	bucketPath := bucket.Path
 
 	fp := path.Join(bucketPath, objectName)
+	if err := checkRead(ctx, fp); err != nil {
+		return nil, err
+	}
 	fmeta, _ := op.GetNearestMeta(fp)
 	node, err := fs.Get(context.WithValue(ctx, "meta", fmeta), fp, &fs.GetArgs{})
 	if err != nil {
@@ -212,22 +210,12 @@ func (b *s3Backend) GetObject(ctx context.Context, bucketName, objectName string
 		}
 	}
 
-	meta := map[string]string{
-		"Last-Modified": node.ModTime().Format(timeFormat),
-		"Content-Type":  utils.GetMimeType(fp),
-	}
-
-	if val, ok := b.meta.Load(fp); ok {
-		metaMap := val.(map[string]string)
-		for k, v := range metaMap {
-			meta[k] = v
-		}
-	}
+	meta, hash := objectInfo(fp, node)
 
 	return &gofakes3.Object{
 		// Name: gofakes3.URLEncode(objectName),
-		Name: objectName,
-		// Hash:     "",
+		Name:     objectName,
+		Hash:     hash,
 		Metadata: meta,
 		Size:     size,
 		Range:    rnge,
@@ -235,10 +223,29 @@ func (b *s3Backend) GetObject(ctx context.Context, bucketName, objectName string
 	}, nil
 }
 
-// TouchObject creates or updates meta on specified object.
+// TouchObject replaces the metadata of the object at fp, the content is left
+// untouched.
 func (b *s3Backend) TouchObject(ctx context.Context, fp string, meta map[string]string) (result gofakes3.PutObjectResult, err error) {
-	//TODO: implement
-	return result, gofakes3.ErrNotImplemented
+	if err := checkManage(ctx, fp, common.PermWrite); err != nil {
+		return result, err
+	}
+	tags, err := parseTags(meta["X-Amz-Tagging"])
+	if err != nil {
+		return result, err
+	}
+	fmeta, _ := op.GetNearestMeta(fp)
+	node, err := fs.Get(context.WithValue(ctx, "meta", fmeta), fp, &fs.GetArgs{})
+	if err != nil || node.IsDir() {
+		return result, gofakes3.KeyNotFound(fp)
+	}
+	err = op.UpdateS3ObjectMeta(fp, func(m *model.S3ObjectMeta) {
+		m.ContentType = meta["Content-Type"]
+		m.Headers = objectHeaders(meta)
+		if _, ok := meta["X-Amz-Tagging"]; ok {
+			m.Tags = tags
+		}
+	})
+	return result, err
 }
 
 // PutObject creates or overwrites the object with the given name.
@@ -247,7 +254,7 @@ func (b *s3Backend) PutObject(
 	meta map[string]string,
 	input io.Reader, size int64,
 ) (result gofakes3.PutObjectResult, err error) {
-	bucket, err := getBucketByName(bucketName)
+	bucket, err := getBucketByName(ctx, bucketName)
 	if err != nil {
 		return result, err
 	}
@@ -266,6 +273,9 @@ func (b *s3Backend) PutObject(
 		reqPath = path.Dir(fp)
 	}
 	log.Debugf("reqPath: %s", reqPath)
+	if err := checkManage(ctx, fp, common.PermWrite); err != nil {
+		return result, err
+	}
 	fmeta, _ := op.GetNearestMeta(fp)
 	ctx = context.WithValue(ctx, "meta", fmeta)
 
@@ -286,6 +296,11 @@ func (b *s3Backend) PutObject(
 		return result, nil
 	}
 
+	tags, err := parseTags(meta["X-Amz-Tagging"])
+	if err != nil {
+		return result, err
+	}
+
 	var ti time.Time
 
 	if val, ok := meta["X-Amz-Meta-Mtime"]; ok {
@@ -302,9 +317,10 @@ func (b *s3Backend) PutObject(
 		Modified: ti,
 		Ctime:    time.Now(),
 	}
+	hashReader := newMD5Reader(input)
 	stream := &stream.FileStream{
 		Obj:      &obj,
-		Reader:   input,
+		Reader:   hashReader,
 		Mimetype: meta["Content-Type"],
 	}
 
@@ -319,7 +335,16 @@ func (b *s3Backend) PutObject(
 		return result, err
 	}
 
-	b.meta.Store(fp, meta)
+	err = op.UpdateS3ObjectMeta(fp, func(m *model.S3ObjectMeta) {
+		m.ContentType = meta["Content-Type"]
+		m.Headers = objectHeaders(meta)
+		m.Tags = tags
+		m.MD5 = hashReader.sum(size)
+		m.Size = size
+	})
+	if err != nil {
+		return result, errors.WithMessage(err, "failed to save object meta")
+	}
 
 	return result, nil
 }
@@ -328,7 +353,7 @@ func (b *s3Backend) PutObject(
 func (b *s3Backend) DeleteMulti(ctx context.Context, bucketName string, objects ...string) (result gofakes3.MultiDeleteResult, rerr error) {
 	for _, object := range objects {
 		if err := b.deleteObject(ctx, bucketName, object); err != nil {
-			utils.Log.Errorf("serve s3", "delete object failed: %v", err)
+			utils.Log.Errorf("serve s3: delete object failed: %v", err)
 			result.Error = append(result.Error, gofakes3.ErrorResult{
 				Code:    gofakes3.ErrInternal,
 				Message: gofakes3.ErrInternal.Message(),
@@ -351,13 +376,16 @@ func (b *s3Backend) DeleteObject(ctx context.Context, bucketName, objectName str
 
 // deleteObject deletes the object from the filesystem.
 func (b *s3Backend) deleteObject(ctx context.Context, bucketName, objectName string) error {
-	bucket, err := getBucketByName(bucketName)
+	bucket, err := getBucketByName(ctx, bucketName)
 	if err != nil {
 		return err
 	}
 	bucketPath := bucket.Path
 
 	fp := path.Join(bucketPath, objectName)
+	if err := checkManage(ctx, fp, common.PermRemove); err != nil {
+		return err
+	}
 	fmeta, _ := op.GetNearestMeta(fp)
 	// S3 does not report an error when attemping to delete a key that does not exist, so
 	// we need to skip IsNotExist errors.
@@ -366,6 +394,9 @@ func (b *s3Backend) deleteObject(ctx context.Context, bucketName, objectName str
 	}
 
 	fs.Remove(ctx, fp)
+	if err := op.DeleteS3ObjectMeta(fp); err != nil {
+		log.Warnf("[s3] failed delete object meta of %s: %+v", fp, err)
+	}
 	return nil
 }
 
@@ -381,7 +412,7 @@ func (b *s3Backend) DeleteBucket(ctx context.Context, name string) error {
 
 // BucketExists checks if the bucket exists.
 func (b *s3Backend) BucketExists(ctx context.Context, name string) (exists bool, err error) {
-	buckets, err := getAndParseBuckets()
+	buckets, err := getAndParseBuckets(ctx)
 	if err != nil {
 		return false, err
 	}
@@ -395,20 +426,34 @@ func (b *s3Backend) BucketExists(ctx context.Context, name string) (exists bool,
 
 // CopyObject copy specified object from srcKey to dstKey.
 func (b *s3Backend) CopyObject(ctx context.Context, srcBucket, srcKey, dstBucket, dstKey string, meta map[string]string) (result gofakes3.CopyObjectResult, err error) {
-	if srcBucket == dstBucket && srcKey == dstKey {
-		//TODO: update meta
-		return result, nil
-	}
-
-	srcB, err := getBucketByName(srcBucket)
+	srcB, err := getBucketByName(ctx, srcBucket)
 	if err != nil {
 		return result, err
 	}
 	srcBucketPath := srcB.Path
 
 	srcFp := path.Join(srcBucketPath, srcKey)
+	if err := checkRead(ctx, srcFp); err != nil {
+		return result, err
+	}
 	fmeta, _ := op.GetNearestMeta(srcFp)
 	srcNode, err := fs.Get(context.WithValue(ctx, "meta", fmeta), srcFp, &fs.GetArgs{})
+	if err != nil {
+		return result, gofakes3.KeyNotFound(srcKey)
+	}
+
+	if srcBucket == dstBucket && srcKey == dstKey {
+		if meta["X-Amz-Metadata-Directive"] == "REPLACE" {
+			if _, err := b.TouchObject(ctx, srcFp, meta); err != nil {
+				return result, err
+			}
+		}
+		_, hash := objectInfo(srcFp, srcNode)
+		return gofakes3.CopyObjectResult{
+			ETag:         `"` + hex.EncodeToString(hash) + `"`,
+			LastModified: gofakes3.NewContentTime(srcNode.ModTime()),
+		}, nil
+	}
 
 	c, err := b.GetObject(ctx, srcBucket, srcKey, nil)
 	if err != nil {
@@ -418,9 +463,17 @@ func (b *s3Backend) CopyObject(ctx context.Context, srcBucket, srcKey, dstBucket
 		_ = c.Contents.Close()
 	}()
 
-	for k, v := range c.Metadata {
-		if _, found := meta[k]; !found && k != "X-Amz-Acl" {
-			meta[k] = v
+	if meta["X-Amz-Metadata-Directive"] != "REPLACE" {
+		for k, v := range c.Metadata {
+			if k != "X-Amz-Acl" {
+				meta[k] = v
+			}
+		}
+	}
+	if meta["X-Amz-Tagging-Directive"] != "REPLACE" {
+		delete(meta, "X-Amz-Tagging")
+		if stored := getObjectMeta(srcFp); stored != nil && len(stored.Tags) > 0 {
+			meta["X-Amz-Tagging"] = encodeTags(stored.Tags)
 		}
 	}
 	if _, ok := meta["mtime"]; !ok {
@@ -432,8 +485,17 @@ func (b *s3Backend) CopyObject(ctx context.Context, srcBucket, srcKey, dstBucket
 		return
 	}
 
+	hash := c.Hash
+	if len(hash) == 0 {
+		dstB, err := getBucketByName(ctx, dstBucket)
+		if err != nil {
+			return result, err
+		}
+		hash, _ = hex.DecodeString(getObjectMeta(path.Join(dstB.Path, dstKey)).ContentMD5(c.Size))
+	}
+
 	return gofakes3.CopyObjectResult{
-		ETag:         `"` + hex.EncodeToString(c.Hash) + `"`,
+		ETag:         `"` + hex.EncodeToString(hash) + `"`,
 		LastModified: gofakes3.NewContentTime(srcNode.ModTime()),
 	}, nil
 }
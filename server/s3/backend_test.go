@@ -0,0 +1,19 @@
+package s3
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/model"
+)
+
+func TestCopyObjectSelfChecksRead(t *testing.T) {
+	// the user has no role and can't read anything
+	user := &model.User{Username: "s3_copy_user", BasePath: "/"}
+	ctx := context.WithValue(context.Background(), "user", user)
+	ctx = context.WithValue(ctx, bucketsCtxKey{}, []Bucket{{Name: "data", Path: "/data"}})
+	meta := map[string]string{"X-Amz-Metadata-Directive": "REPLACE", "Content-Type": "text/plain"}
+	if _, err := (&s3Backend{}).CopyObject(ctx, "data", "a.txt", "data", "a.txt", meta); err != errAccessDenied {
+		t.Fatalf("expected the self copy to be denied, got %v", err)
+	}
+}
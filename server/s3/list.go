@@ -3,13 +3,14 @@
 package s3
 
 import (
+	"context"
 	"path"
 	"strings"
 
 	"github.com/alist-org/gofakes3"
 )
 
-func (b *s3Backend) entryListR(bucket, fdPath, name string, addPrefix bool, response *gofakes3.ObjectList) error {
+func (b *s3Backend) entryListR(ctx context.Context, bucket, fdPath, name string, addPrefix bool, response *gofakes3.ObjectList) error {
 	fp := path.Join(bucket, fdPath)
 
 	dirEntries, err := getDirEntries(fp)
@@ -26,6 +27,9 @@ func (b *s3Backend) entryListR(bucket, fdPath, name string, addPrefix bool, resp
 		if !strings.HasPrefix(object, name) {
 			continue
 		}
+		if !canRead(ctx, path.Join(fp, object)) {
+			continue
+		}
 
 		if entry.IsDir() {
 			if addPrefix {
@@ -33,7 +37,7 @@ func (b *s3Backend) entryListR(bucket, fdPath, name string, addPrefix bool, resp
 				response.AddPrefix(objectPath)
 				continue
 			}
-			err := b.entryListR(bucket, path.Join(fdPath, object), "", false, response)
+			err := b.entryListR(ctx, bucket, path.Join(fdPath, object), "", false, response)
 			if err != nil {
 				return err
 			}
@@ -42,7 +46,7 @@ func (b *s3Backend) entryListR(bucket, fdPath, name string, addPrefix bool, resp
 				// Key:          gofakes3.URLEncode(objectPath),
 				Key:          objectPath,
 				LastModified: gofakes3.NewContentTime(entry.ModTime()),
-				ETag:         getFileHash(entry),
+				ETag:         getFileHash(path.Join(fp, object), entry),
 				Size:         entry.GetSize(),
 				StorageClass: gofakes3.StorageStandard,
 			}
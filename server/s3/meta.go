@@ -0,0 +1,158 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/alist-org/gofakes3"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	maxObjectTags     = 10
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+)
+
+// storedHeaders are the request headers kept with an object besides its user
+// metadata, Content-Type is stored on its own.
+var storedHeaders = []string{
+	"Cache-Control",
+	"Content-Disposition",
+	"Content-Encoding",
+	"Content-Language",
+}
+
+// objectHeaders picks the headers of a PUT request which are returned when
+// the object is read.
+func objectHeaders(meta map[string]string) model.StringMap {
+	headers := make(model.StringMap)
+	for k, v := range meta {
+		if strings.HasPrefix(k, "X-Amz-Meta-") || slices.Contains(storedHeaders, k) {
+			headers[k] = v
+		}
+	}
+	return headers
+}
+
+// getObjectMeta returns the S3 attributes recorded for fp, nil if there are
+// none or they can not be loaded.
+func getObjectMeta(fp string) *model.S3ObjectMeta {
+	m, err := op.GetS3ObjectMeta(fp)
+	if err != nil {
+		log.Warnf("[s3] failed get object meta of %s: %+v", fp, err)
+		return nil
+	}
+	return m
+}
+
+// objectMD5 returns the md5 of node reported by its storage, falling back to
+// the one recorded when it was uploaded through the S3 server.
+func objectMD5(node model.Obj, stored *model.S3ObjectMeta) string {
+	if h := node.GetHash().GetHash(utils.MD5); h != "" {
+		return h
+	}
+	return stored.ContentMD5(node.GetSize())
+}
+
+// objectInfo returns the response headers and the hash of the object at fp.
+func objectInfo(fp string, node model.Obj) (map[string]string, []byte) {
+	meta := map[string]string{
+		"Last-Modified": node.ModTime().Format(timeFormat),
+		"Content-Type":  utils.GetMimeType(fp),
+	}
+	stored := getObjectMeta(fp)
+	if stored != nil {
+		for k, v := range stored.Headers {
+			meta[k] = v
+		}
+		if stored.ContentType != "" {
+			meta["Content-Type"] = stored.ContentType
+		}
+		if len(stored.Tags) > 0 {
+			meta["X-Amz-Tagging-Count"] = strconv.Itoa(len(stored.Tags))
+		}
+	}
+	hash, _ := hex.DecodeString(objectMD5(node, stored))
+	return meta, hash
+}
+
+// parseTags parses tags in the query string form of the x-amz-tagging header.
+func parseTags(s string) (model.StringMap, error) {
+	if s == "" {
+		return nil, nil
+	}
+	values, err := url.ParseQuery(s)
+	if err != nil {
+		return nil, gofakes3.ErrorMessage(gofakes3.ErrInvalidArgument, "The tag string is malformed")
+	}
+	tags := make(model.StringMap, len(values))
+	for k, v := range values {
+		if len(v) != 1 {
+			return nil, gofakes3.ErrorMessage(gofakes3.ErrInvalidArgument, "Cannot provide multiple Tags with the same key")
+		}
+		tags[k] = v[0]
+	}
+	return tags, validateTags(tags)
+}
+
+func validateTags(tags model.StringMap) error {
+	if len(tags) > maxObjectTags {
+		return gofakes3.ErrorMessage(gofakes3.ErrInvalidArgument, "Object tags cannot be greater than 10")
+	}
+	for k, v := range tags {
+		if k == "" || utf8.RuneCountInString(k) > maxTagKeyLength {
+			return gofakes3.ErrorMessage(gofakes3.ErrInvalidArgument, "The TagKey you have provided is invalid")
+		}
+		if utf8.RuneCountInString(v) > maxTagValueLength {
+			return gofakes3.ErrorMessage(gofakes3.ErrInvalidArgument, "The TagValue you have provided is invalid")
+		}
+	}
+	return nil
+}
+
+// encodeTags is the reverse of parseTags.
+func encodeTags(tags model.StringMap) string {
+	values := make(url.Values, len(tags))
+	for k, v := range tags {
+		values.Set(k, v)
+	}
+	return values.Encode()
+}
+
+// md5Reader hashes the content read through it.
+type md5Reader struct {
+	io.Reader
+	hash hash.Hash
+	n    int64
+}
+
+func newMD5Reader(r io.Reader) *md5Reader {
+	return &md5Reader{Reader: r, hash: md5.New()}
+}
+
+func (r *md5Reader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.hash.Write(p[:n])
+	r.n += int64(n)
+	return n, err
+}
+
+// sum returns the md5 of the content, empty if less than size bytes were read
+// since the storage did not consume the whole stream.
+func (r *md5Reader) sum(size int64) string {
+	if r.n != size {
+		return ""
+	}
+	return hex.EncodeToString(r.hash.Sum(nil))
+}
@@ -0,0 +1,174 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/alist-org/gofakes3"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const maxTaggingBodySize = 1 << 20
+
+type tagging struct {
+	XMLName xml.Name `xml:"Tagging"`
+	Xmlns   string   `xml:"xmlns,attr,omitempty"`
+	TagSet  struct {
+		Tags []tag `xml:"Tag"`
+	} `xml:"TagSet"`
+}
+
+type tag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+// taggingHandler serves the tagging sub-resource of objects, which is not
+// routed by gofakes3.
+func taggingHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bucketName, objectName, _ := strings.Cut(strings.Trim(r.URL.Path, "/"), "/")
+		if _, ok := r.URL.Query()["tagging"]; !ok || objectName == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		// authHandler only verifies per-user keys, the global key pair is
+		// verified by gofakes3 which is bypassed here
+		if requestUser(r.Context()) == nil && len(authlistResolver()) > 0 && !verifySignature(w, r) {
+			return
+		}
+		var err error
+		switch r.Method {
+		case http.MethodGet:
+			err = getObjectTagging(w, r, bucketName, objectName)
+		case http.MethodPut:
+			err = putObjectTagging(w, r, bucketName, objectName)
+		case http.MethodDelete:
+			err = deleteObjectTagging(w, r, bucketName, objectName)
+		default:
+			err = gofakes3.ErrMethodNotAllowed
+		}
+		if err != nil {
+			writeError(w, r, err)
+		}
+	})
+}
+
+func checkWrite(ctx context.Context, reqPath string) error {
+	return checkManage(ctx, reqPath, common.PermWrite)
+}
+
+// taggedObjectPath resolves the path of the existing object of a tagging
+// request once check allows the request user to access it.
+func taggedObjectPath(ctx context.Context, bucketName, objectName string, check func(context.Context, string) error) (string, error) {
+	bucket, err := getBucketByName(ctx, bucketName)
+	if err != nil {
+		return "", err
+	}
+	fp := path.Join(bucket.Path, objectName)
+	if err := check(ctx, fp); err != nil {
+		return "", err
+	}
+	fmeta, _ := op.GetNearestMeta(fp)
+	node, err := fs.Get(context.WithValue(ctx, "meta", fmeta), fp, &fs.GetArgs{})
+	if err != nil || node.IsDir() {
+		return "", gofakes3.KeyNotFound(objectName)
+	}
+	return fp, nil
+}
+
+func getObjectTagging(w http.ResponseWriter, r *http.Request, bucketName, objectName string) error {
+	fp, err := taggedObjectPath(r.Context(), bucketName, objectName, checkRead)
+	if err != nil {
+		return err
+	}
+	resp := tagging{Xmlns: "http://s3.amazonaws.com/doc/2006-03-01/"}
+	if stored := getObjectMeta(fp); stored != nil {
+		for k, v := range stored.Tags {
+			resp.TagSet.Tags = append(resp.TagSet.Tags, tag{Key: k, Value: v})
+		}
+	}
+	writeXML(w, http.StatusOK, resp)
+	return nil
+}
+
+func putObjectTagging(w http.ResponseWriter, r *http.Request, bucketName, objectName string) error {
+	fp, err := taggedObjectPath(r.Context(), bucketName, objectName, checkWrite)
+	if err != nil {
+		return err
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxTaggingBodySize))
+	if err != nil {
+		return err
+	}
+	var req tagging
+	if err := xml.Unmarshal(body, &req); err != nil {
+		return gofakes3.ErrorMessage(gofakes3.ErrMalformedXML, err.Error())
+	}
+	tags := make(model.StringMap, len(req.TagSet.Tags))
+	for _, t := range req.TagSet.Tags {
+		if _, ok := tags[t.Key]; ok {
+			return gofakes3.ErrorMessage(gofakes3.ErrInvalidArgument, "Cannot provide multiple Tags with the same key")
+		}
+		tags[t.Key] = t.Value
+	}
+	if err := validateTags(tags); err != nil {
+		return err
+	}
+	if err := op.UpdateS3ObjectMeta(fp, func(m *model.S3ObjectMeta) {
+		m.Tags = tags
+	}); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func deleteObjectTagging(w http.ResponseWriter, r *http.Request, bucketName, objectName string) error {
+	fp, err := taggedObjectPath(r.Context(), bucketName, objectName, checkWrite)
+	if err != nil {
+		return err
+	}
+	if err := op.UpdateS3ObjectMeta(fp, func(m *model.S3ObjectMeta) {
+		m.Tags = nil
+	}); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func writeXML(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(xml.Header))
+	if err := xml.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("[s3] failed encode response: %+v", err)
+	}
+}
+
+// writeError writes err in the error format of S3.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var resp gofakes3.Error
+	if !errors.As(err, &resp) {
+		log.Errorf("[s3] %s %s: %+v", r.Method, r.URL.Path, err)
+		resp = &gofakes3.ErrorResponse{Code: gofakes3.ErrInternal, Message: "Internal Error"}
+	}
+	if code, ok := resp.(gofakes3.ErrorCode); ok {
+		resp = &gofakes3.ErrorResponse{Code: code, Message: code.Message()}
+	}
+	status := resp.ErrorCode().Status()
+	if resp.ErrorCode() == accessDenied {
+		status = http.StatusForbidden
+	}
+	writeXML(w, status, resp)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/alist-org/alist/v3/internal/model"
 	"github.com/alist-org/alist/v3/internal/op"
 	"github.com/alist-org/alist/v3/internal/setting"
+	"github.com/alist-org/alist/v3/pkg/utils"
 	"github.com/alist-org/gofakes3"
 )
 
@@ -21,14 +22,26 @@ type Bucket struct {
 	Path string `json:"path"`
 }
 
-func getAndParseBuckets() ([]Bucket, error) {
+// getAndParseBuckets returns the buckets of the credential used by the
+// request, the global buckets setting only applies to the global key pair.
+func getAndParseBuckets(ctx context.Context) ([]Bucket, error) {
+	if buckets, ok := ctx.Value(bucketsCtxKey{}).([]Bucket); ok {
+		return buckets, nil
+	}
+	if requestUser(ctx) != nil {
+		return []Bucket{}, nil
+	}
+	return globalBuckets()
+}
+
+func globalBuckets() ([]Bucket, error) {
 	var res []Bucket
 	err := json.Unmarshal([]byte(setting.GetStr(conf.S3Buckets)), &res)
 	return res, err
 }
 
-func getBucketByName(name string) (Bucket, error) {
-	buckets, err := getAndParseBuckets()
+func getBucketByName(ctx context.Context, name string) (Bucket, error) {
+	buckets, err := getAndParseBuckets(ctx)
 	if err != nil {
 		return Bucket{}, err
 	}
@@ -62,50 +75,17 @@ func getDirEntries(path string) ([]model.Obj, error) {
 	return dirEntries, nil
 }
 
-// func getFileHashByte(node interface{}) []byte {
-// 	b, err := hex.DecodeString(getFileHash(node))
-// 	if err != nil {
-// 		return nil
-// 	}
-// 	return b
-// }
-
-func getFileHash(node interface{}) string {
-	// var o fs.Object
-
-	// switch b := node.(type) {
-	// case vfs.Node:
-	// 	fsObj, ok := b.DirEntry().(fs.Object)
-	// 	if !ok {
-	// 		fs.Debugf("serve s3", "File uploading - reading hash from VFS cache")
-	// 		in, err := b.Open(os.O_RDONLY)
-	// 		if err != nil {
-	// 			return ""
-	// 		}
-	// 		defer func() {
-	// 			_ = in.Close()
-	// 		}()
-	// 		h, err := hash.NewMultiHasherTypes(hash.NewHashSet(hash.MD5))
-	// 		if err != nil {
-	// 			return ""
-	// 		}
-	// 		_, err = io.Copy(h, in)
-	// 		if err != nil {
-	// 			return ""
-	// 		}
-	// 		return h.Sums()[hash.MD5]
-	// 	}
-	// 	o = fsObj
-	// case fs.Object:
-	// 	o = b
-	// }
-
-	// hash, err := o.Hash(context.Background(), hash.MD5)
-	// if err != nil {
-	// 	return ""
-	// }
-	// return hash
-	return ""
+// getFileHash returns the quoted md5 of the object at fp to be used as its
+// ETag, empty if it is unknown.
+func getFileHash(fp string, node model.Obj) string {
+	h := node.GetHash().GetHash(utils.MD5)
+	if h == "" {
+		h = getObjectMeta(fp).ContentMD5(node.GetSize())
+	}
+	if h == "" {
+		return ""
+	}
+	return `"` + h + `"`
 }
 
 func prefixParser(p *gofakes3.Prefix) (path, remaining string) {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/KirCute/sftpd-alist"
 	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/metrics"
 	"github.com/alist-org/alist/v3/internal/model"
 	"github.com/alist-org/alist/v3/internal/op"
 	"github.com/alist-org/alist/v3/internal/setting"
@@ -65,6 +66,12 @@ func (d *SftpDriver) GetFileSystem(sc *ssh.ServerConn) (sftpd.FileSystem, error)
 	ctx = context.WithValue(ctx, "meta_pass", "")
 	ctx = context.WithValue(ctx, "client_ip", sc.RemoteAddr().String())
 	ctx = context.WithValue(ctx, "proxy_header", d.proxyHeader)
+	ctx = metrics.WithProtocol(ctx, metrics.SFTP)
+	metrics.SessionStarted(metrics.SFTP)
+	go func() {
+		_ = sc.Wait()
+		metrics.SessionEnded(metrics.SFTP)
+	}()
 	return &sftp.DriverAdapter{FtpDriver: ftp.NewAferoAdapter(ctx)}, nil
 }
 
@@ -0,0 +1,18 @@
+package server
+
+import (
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/alist-org/alist/v3/server/subsonic"
+	"github.com/gin-gonic/gin"
+)
+
+func Subsonic(g *gin.RouterGroup) {
+	if !conf.Conf.Subsonic.Enable {
+		g.Any("/*path", func(c *gin.Context) {
+			common.ErrorStrResp(c, "Subsonic API is not enabled", 403)
+		})
+		return
+	}
+	subsonic.Routes(g)
+}
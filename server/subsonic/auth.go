@@ -0,0 +1,103 @@
+package subsonic
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// param returns a parameter from the query or the posted form
+func param(c *gin.Context, name string) string {
+	return c.Request.FormValue(name)
+}
+
+// Auth authenticates the user of a request. Only the password (plain or
+// hex encoded with "enc:") or an API token are accepted: the token
+// authentication of Subsonic needs the clear password, which is not kept.
+// Users with two-factor authentication have to use an API token.
+func Auth(c *gin.Context) {
+	user, code, message := authenticate(c)
+	if user == nil {
+		writeError(c, code, message)
+		c.Abort()
+		return
+	}
+	if roles, err := op.GetRolesByUserID(user.ID); err == nil {
+		user.RolesDetail = roles
+	}
+	c.Set("user", user)
+	c.Next()
+}
+
+// authenticate checks the credentials of a request, failures count toward
+// the sign-in limit of the client like the ones of the login page
+func authenticate(c *gin.Context) (*model.User, int, string) {
+	ip := c.ClientIP()
+	if common.LoginLocked(ip) {
+		return nil, errWrongAuth, "too many failed attempts, try again later"
+	}
+	user, code, message := credentials(c)
+	switch {
+	case code == errWrongAuth || code == errInvalidAPIKey:
+		common.LoginFailed(ip)
+	case user != nil:
+		common.LoginCache.Del(ip)
+	}
+	return user, code, message
+}
+
+func credentials(c *gin.Context) (*model.User, int, string) {
+	if apiKey := param(c, "apiKey"); apiKey != "" {
+		if param(c, "u") != "" {
+			return nil, errConflictAuth, "multiple authentication mechanisms provided"
+		}
+		user, err := op.GetUserByAPIToken(apiKey)
+		if err != nil || user.Disabled {
+			return nil, errInvalidAPIKey, "invalid API key"
+		}
+		return user, 0, ""
+	}
+	username := param(c, "u")
+	if username == "" {
+		return nil, errMissingParam, "required parameter is missing: u"
+	}
+	password := param(c, "p")
+	if password == "" {
+		if param(c, "t") != "" {
+			return nil, errTokenAuth, "token authentication not supported, use the password or an API token"
+		}
+		return nil, errMissingParam, "required parameter is missing: p"
+	}
+	if strings.HasPrefix(password, "enc:") {
+		decoded, err := hex.DecodeString(strings.TrimPrefix(password, "enc:"))
+		if err != nil {
+			return nil, errWrongAuth, "wrong username or password"
+		}
+		password = string(decoded)
+	}
+	var user *model.User
+	var err error
+	if strings.HasPrefix(password, model.APITokenPrefix) {
+		user, err = op.GetUserByAPIToken(password)
+		if err == nil && user.Username != username {
+			user = nil
+		}
+	} else {
+		user, err = op.GetUserByName(username)
+		if err == nil && user.ValidateRawPassword(password) != nil {
+			user = nil
+		}
+	}
+	if err != nil || user == nil || user.Disabled {
+		return nil, errWrongAuth, "wrong username or password"
+	}
+	// the password alone would bypass the second factor
+	if user.OtpSecret != "" && user.Scope == nil {
+		return nil, errNotAuthorized, "two-factor authentication is enabled, use an API token"
+	}
+	return user, 0, ""
+}
@@ -0,0 +1,275 @@
+package subsonic
+
+import (
+	"fmt"
+	"net/http"
+	stdpath "path"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/sign"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+var handlers = map[string]gin.HandlerFunc{
+	"ping":              ping,
+	"getLicense":        getLicense,
+	"getMusicFolders":   getMusicFolders,
+	"getIndexes":        getIndexes,
+	"getMusicDirectory": getMusicDirectory,
+	"stream":            download,
+	"download":          download,
+	"search3":           search3,
+	"getCoverArt":       getCoverArt,
+}
+
+// Routes registers the endpoints, with and without the .view suffix
+func Routes(g *gin.RouterGroup) {
+	g.Any("/:method", Auth, func(c *gin.Context) {
+		h, ok := handlers[strings.TrimSuffix(c.Param("method"), ".view")]
+		if !ok {
+			writeError(c, errNotFound, "unknown method")
+			return
+		}
+		h(c)
+	})
+}
+
+func ping(c *gin.Context) {
+	write(c, newResponse())
+}
+
+func getLicense(c *gin.Context) {
+	resp := newResponse()
+	resp.License = &license{Valid: true}
+	write(c, resp)
+}
+
+func getMusicFolders(c *gin.Context) {
+	l := newLibrary(c)
+	res := &musicFolders{MusicFolder: []musicFolder{}}
+	for _, f := range l.folders {
+		res.MusicFolder = append(res.MusicFolder, musicFolder{ID: f.ID, Name: f.Name})
+	}
+	resp := newResponse()
+	resp.MusicFolders = res
+	write(c, resp)
+}
+
+// indexName returns the letter a folder is listed under
+func indexName(name string) string {
+	for _, r := range name {
+		if unicode.IsLetter(r) {
+			return strings.ToUpper(string(r))
+		}
+		break
+	}
+	return "#"
+}
+
+// getIndexes lists the folders of the music folders, as the artists,
+// and the songs at their root
+func getIndexes(c *gin.Context) {
+	l := newLibrary(c)
+	folders := l.selected(param(c, "musicFolderId"))
+	if folders == nil {
+		writeError(c, errNotFound, "music folder not found")
+		return
+	}
+	res := &indexes{}
+	byName := map[string]int{}
+	for _, f := range folders {
+		objs, err := l.list(f.Path)
+		if err != nil {
+			utils.Log.Warnf("[subsonic] failed to list %s: %+v", f.Path, err)
+			continue
+		}
+		songs := l.tags(f.Path, objs)
+		for _, obj := range objs {
+			if modified := obj.ModTime().UnixMilli(); modified > res.LastModified {
+				res.LastModified = modified
+			}
+			if !obj.IsDir() {
+				res.Child = append(res.Child, l.song(f.Path, obj, songs[obj.GetName()]))
+				continue
+			}
+			name := indexName(obj.GetName())
+			i, ok := byName[name]
+			if !ok {
+				i = len(res.Index)
+				byName[name] = i
+				res.Index = append(res.Index, index{Name: name})
+			}
+			path := stdpath.Join(f.Path, obj.GetName())
+			res.Index[i].Artist = append(res.Index[i].Artist, artist{ID: encodeID(path), Name: obj.GetName()})
+		}
+	}
+	if since, _ := strconv.ParseInt(param(c, "ifModifiedSince"), 10, 64); since > 0 && since >= res.LastModified {
+		res.Index, res.Child = nil, nil
+	}
+	resp := newResponse()
+	resp.Indexes = res
+	write(c, resp)
+}
+
+func getMusicDirectory(c *gin.Context) {
+	l := newLibrary(c)
+	path, obj, err := l.resolve(param(c, "id"))
+	if err != nil || !obj.IsDir() {
+		writeError(c, errNotFound, "directory not found")
+		return
+	}
+	objs, err := l.list(path)
+	if err != nil {
+		writeError(c, errGeneric, err.Error())
+		return
+	}
+	songs := l.tags(path, objs)
+	dir := &directory{ID: encodeID(path), Parent: l.parentID(path), Name: obj.GetName()}
+	if f, _ := l.folderOf(path); f.Path == path {
+		dir.Name = f.Name
+	}
+	for _, obj := range objs {
+		if obj.IsDir() {
+			dir.Child = append(dir.Child, l.folderChild(path, obj))
+		} else {
+			dir.Child = append(dir.Child, l.song(path, obj, songs[obj.GetName()]))
+		}
+	}
+	resp := newResponse()
+	resp.Directory = dir
+	write(c, resp)
+}
+
+// redirect sends the client to the download link of path
+func redirect(c *gin.Context, path string) {
+	link := fmt.Sprintf("%s/d%s?sign=%s", common.GetApiUrl(c.Request), utils.EncodePath(path, true), sign.Sign(path))
+	c.Redirect(http.StatusFound, link)
+}
+
+// download streams a song as is, there is no transcoding
+func download(c *gin.Context) {
+	l := newLibrary(c)
+	path, obj, err := l.resolve(param(c, "id"))
+	if err != nil || obj.IsDir() {
+		writeError(c, errNotFound, "song not found")
+		return
+	}
+	redirect(c, path)
+}
+
+func intParam(c *gin.Context, name string, def int) int {
+	n, err := strconv.Atoi(param(c, name))
+	if err != nil || n < 0 {
+		return def
+	}
+	return n
+}
+
+// search3 searches the tags of the songs already listed, an empty query
+// returns all of them
+func search3(c *gin.Context) {
+	l := newLibrary(c)
+	folders := l.selected(param(c, "musicFolderId"))
+	if folders == nil {
+		writeError(c, errNotFound, "music folder not found")
+		return
+	}
+	query := strings.Trim(param(c, "query"), `"*`)
+	res := &searchResult3{}
+	if count := intParam(c, "artistCount", 20); count > 0 {
+		artists, err := db.SearchMusicArtists(roots(folders), query, intParam(c, "artistOffset", 0), count)
+		if err != nil {
+			writeError(c, errGeneric, err.Error())
+			return
+		}
+		for _, a := range artists {
+			// the folders are laid out as artist/album/song
+			dir := stdpath.Dir(a.Dir)
+			if _, ok := l.folderOf(dir); !ok || !l.canAccess(dir) {
+				continue
+			}
+			res.Artist = append(res.Artist, artist{ID: encodeID(dir), Name: a.Artist, AlbumCount: a.AlbumCount})
+		}
+	}
+	if count := intParam(c, "albumCount", 20); count > 0 {
+		albums, err := db.SearchMusicAlbums(roots(folders), query, intParam(c, "albumOffset", 0), count)
+		if err != nil {
+			writeError(c, errGeneric, err.Error())
+			return
+		}
+		for _, a := range albums {
+			if !l.canAccess(a.Dir) {
+				continue
+			}
+			res.Album = append(res.Album, album{
+				ID:        encodeID(a.Dir),
+				Name:      a.Album,
+				Artist:    a.Artist,
+				CoverArt:  encodeID(a.Dir),
+				SongCount: a.SongCount,
+			})
+		}
+	}
+	if count := intParam(c, "songCount", 20); count > 0 {
+		tags, err := db.SearchMusicTags(roots(folders), query, intParam(c, "songOffset", 0), count)
+		if err != nil {
+			writeError(c, errGeneric, err.Error())
+			return
+		}
+		for i := range tags {
+			t := &tags[i]
+			path := stdpath.Join(t.Dir, t.Name)
+			if !l.canAccess(path) {
+				continue
+			}
+			obj := &model.Object{Name: t.Name, Size: t.Size, Modified: t.Modified}
+			res.Song = append(res.Song, l.song(t.Dir, obj, t))
+		}
+	}
+	resp := newResponse()
+	resp.SearchResult3 = res
+	write(c, resp)
+}
+
+// getCoverArt returns the picture embedded in a song or the cover image
+// of a folder, the picture is not resized
+func getCoverArt(c *gin.Context) {
+	l := newLibrary(c)
+	path, obj, err := l.resolve(param(c, "id"))
+	if err != nil {
+		writeError(c, errNotFound, "cover art not found")
+		return
+	}
+	if !obj.IsDir() {
+		if utils.GetFileType(obj.GetName()) == conf.IMAGE {
+			redirect(c, path)
+			return
+		}
+		if m, err := l.readMetadata(path); err == nil && m.Picture() != nil {
+			p := m.Picture()
+			mimeType := p.MIMEType
+			if mimeType == "" {
+				mimeType = "image/jpeg"
+			}
+			c.Data(http.StatusOK, mimeType, p.Data)
+			return
+		}
+		path = stdpath.Dir(path)
+		if !l.canAccess(path) {
+			writeError(c, errNotFound, "cover art not found")
+			return
+		}
+	}
+	if cover := l.cover(path); cover != "" {
+		redirect(c, cover)
+		return
+	}
+	writeError(c, errNotFound, "cover art not found")
+}
@@ -0,0 +1,353 @@
+package subsonic
+
+import (
+	"context"
+	"encoding/base64"
+	stdpath "path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/alist-org/alist/v3/server/ftp"
+	"github.com/dhowden/tag"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// tagReaders limits the files read at once when the tags of a folder are not cached
+const tagReaders = 4
+
+// coverNames are the names of the images used as the cover of a folder
+var coverNames = []string{"cover", "folder", "front", "album", "albumart"}
+
+// folder is a music folder, Path is the actual path
+type folder struct {
+	ID   int
+	Name string
+	Path string
+}
+
+// library is the music of the user of a request
+type library struct {
+	ctx     context.Context
+	user    *model.User
+	folders []folder
+}
+
+func newLibrary(c *gin.Context) *library {
+	user := c.MustGet("user").(*model.User)
+	ctx := context.WithValue(c.Request.Context(), "user", user)
+	ctx = context.WithValue(ctx, "meta_pass", "")
+	ctx = context.WithValue(ctx, "client_ip", c.ClientIP())
+	ctx = context.WithValue(ctx, "proxy_header", &c.Request.Header)
+	l := &library{ctx: ctx, user: user}
+	paths := conf.Conf.Subsonic.Paths
+	if len(paths) == 0 {
+		paths = []string{"/"}
+	}
+	for i, p := range paths {
+		reqPath, err := user.JoinPath(utils.FixAndCleanPath(p))
+		if err != nil {
+			continue
+		}
+		name := stdpath.Base(reqPath)
+		if reqPath == "/" {
+			name = "Music"
+		}
+		l.folders = append(l.folders, folder{ID: i + 1, Name: name, Path: reqPath})
+	}
+	return l
+}
+
+// selected returns the folder of id, all the folders if id is empty
+func (l *library) selected(id string) []folder {
+	if id == "" {
+		return l.folders
+	}
+	n, _ := strconv.Atoi(id)
+	for _, f := range l.folders {
+		if f.ID == n {
+			return []folder{f}
+		}
+	}
+	return nil
+}
+
+func roots(folders []folder) []string {
+	res := make([]string, len(folders))
+	for i, f := range folders {
+		res[i] = f.Path
+	}
+	return res
+}
+
+// folderOf returns the music folder containing path
+func (l *library) folderOf(path string) (folder, bool) {
+	for _, f := range l.folders {
+		if utils.IsSubPath(f.Path, path) {
+			return f, true
+		}
+	}
+	return folder{}, false
+}
+
+func encodeID(path string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(path))
+}
+
+func decodeID(id string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(id)
+	if err != nil {
+		return "", errs.ObjectNotFound
+	}
+	return utils.FixAndCleanPath(string(data)), nil
+}
+
+// parentID returns the id of the folder containing path, empty for a music folder
+func (l *library) parentID(path string) string {
+	for _, f := range l.folders {
+		if f.Path == path {
+			return ""
+		}
+	}
+	return encodeID(stdpath.Dir(path))
+}
+
+// canAccess reports whether the user may read path
+func (l *library) canAccess(path string) bool {
+	if _, ok := l.folderOf(path); !ok {
+		return false
+	}
+	meta, err := op.GetNearestMeta(path)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		return false
+	}
+	return common.CanAccessWithRoles(l.user, meta, path, "")
+}
+
+// resolve returns the path and the object of id
+func (l *library) resolve(id string) (string, model.Obj, error) {
+	path, err := decodeID(id)
+	if err != nil {
+		return "", nil, err
+	}
+	if !l.canAccess(path) {
+		return "", nil, errs.ObjectNotFound
+	}
+	obj, err := fs.Get(l.ctx, path, &fs.GetArgs{})
+	if err != nil {
+		return "", nil, err
+	}
+	return path, obj, nil
+}
+
+// list returns the folders and the songs in path, sorted by name
+func (l *library) list(path string) ([]model.Obj, error) {
+	objs, err := fs.List(l.ctx, path, &fs.ListArgs{})
+	if err != nil {
+		return nil, err
+	}
+	meta, _ := op.GetNearestMeta(path)
+	res := make([]model.Obj, 0, len(objs))
+	for _, obj := range objs {
+		if !obj.IsDir() && utils.GetFileType(obj.GetName()) != conf.AUDIO {
+			continue
+		}
+		if !common.CanAccessWithRoles(l.user, meta, stdpath.Join(path, obj.GetName()), "") {
+			continue
+		}
+		res = append(res, obj)
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].IsDir() != res[j].IsDir() {
+			return res[i].IsDir()
+		}
+		return strings.ToLower(res[i].GetName()) < strings.ToLower(res[j].GetName())
+	})
+	return res, nil
+}
+
+// tags returns the tags of the songs of dir by name, reading the files
+// whose tags are not cached yet
+func (l *library) tags(dir string, objs []model.Obj) map[string]*model.MusicTag {
+	cached, err := db.GetMusicTagsByDir(dir)
+	if err != nil {
+		utils.Log.Warnf("[subsonic] failed to get tags of %s: %+v", dir, err)
+	}
+	res := make(map[string]*model.MusicTag, len(objs))
+	for i := range cached {
+		res[cached[i].Name] = &cached[i]
+	}
+	var names []string
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sem := make(chan struct{}, tagReaders)
+	for _, obj := range objs {
+		if obj.IsDir() {
+			continue
+		}
+		names = append(names, obj.GetName())
+		if t, ok := res[obj.GetName()]; ok && t.Valid(obj.GetSize(), obj.ModTime()) {
+			continue
+		}
+		wg.Add(1)
+		go func(obj model.Obj, old *model.MusicTag) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			t := l.readTag(dir, obj)
+			if old != nil {
+				t.ID = old.ID
+			}
+			if err := db.SaveMusicTag(t); err != nil {
+				utils.Log.Warnf("[subsonic] failed to save tags of %s: %+v", t.Name, err)
+			}
+			mu.Lock()
+			res[obj.GetName()] = t
+			mu.Unlock()
+		}(obj, res[obj.GetName()])
+	}
+	wg.Wait()
+	if err := db.DeleteMusicTagsExcept(dir, names); err != nil {
+		utils.Log.Warnf("[subsonic] failed to delete tags in %s: %+v", dir, err)
+	}
+	return res
+}
+
+// readTag reads the tags of a song, the title is the file name if
+// the file has no tags
+func (l *library) readTag(dir string, obj model.Obj) *model.MusicTag {
+	t := &model.MusicTag{
+		Dir:      dir,
+		Name:     obj.GetName(),
+		Size:     obj.GetSize(),
+		Modified: obj.ModTime(),
+		Title:    strings.TrimSuffix(obj.GetName(), stdpath.Ext(obj.GetName())),
+	}
+	m, err := l.readMetadata(stdpath.Join(dir, obj.GetName()))
+	if err != nil {
+		utils.Log.Debugf("[subsonic] failed to read tags of %s: %+v", obj.GetName(), err)
+		return t
+	}
+	if m.Title() != "" {
+		t.Title = m.Title()
+	}
+	t.Artist = m.Artist()
+	t.Album = m.Album()
+	t.AlbumArtist = m.AlbumArtist()
+	t.Genre = m.Genre()
+	t.Year = m.Year()
+	t.Track, _ = m.Track()
+	t.Disc, _ = m.Disc()
+	t.HasPicture = m.Picture() != nil
+	return t
+}
+
+func (l *library) readMetadata(path string) (tag.Metadata, error) {
+	f, err := ftp.OpenDownload(l.ctx, path, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return tag.ReadFrom(f)
+}
+
+// song converts a song of dir to a child
+func (l *library) song(dir string, obj model.Obj, t *model.MusicTag) child {
+	path := stdpath.Join(dir, obj.GetName())
+	s := child{
+		ID:          encodeID(path),
+		Parent:      encodeID(dir),
+		Title:       obj.GetName(),
+		CoverArt:    encodeID(dir),
+		Size:        obj.GetSize(),
+		ContentType: utils.GetMimeType(obj.GetName()),
+		Suffix:      strings.ToLower(utils.Ext(obj.GetName())),
+		Path:        l.relative(path),
+		Type:        "music",
+	}
+	if !obj.ModTime().IsZero() {
+		s.Created = obj.ModTime().UTC().Format("2006-01-02T15:04:05Z")
+	}
+	if t != nil {
+		s.Title = t.Title
+		s.Album = t.Album
+		s.Artist = t.Artist
+		if s.Artist == "" {
+			s.Artist = t.AlbumArtist
+		}
+		s.Genre = t.Genre
+		s.Year = t.Year
+		s.Track = t.Track
+		s.DiscNumber = t.Disc
+		if t.HasPicture {
+			s.CoverArt = s.ID
+		}
+	}
+	return s
+}
+
+func (l *library) folderChild(dir string, obj model.Obj) child {
+	path := stdpath.Join(dir, obj.GetName())
+	d := child{
+		ID:       encodeID(path),
+		Parent:   l.parentID(path),
+		IsDir:    true,
+		Title:    obj.GetName(),
+		CoverArt: encodeID(path),
+		Path:     l.relative(path),
+	}
+	if !obj.ModTime().IsZero() {
+		d.Created = obj.ModTime().UTC().Format("2006-01-02T15:04:05Z")
+	}
+	return d
+}
+
+// relative returns path relative to its music folder
+func (l *library) relative(path string) string {
+	f, ok := l.folderOf(path)
+	if !ok || f.Path == "/" {
+		return strings.TrimPrefix(path, "/")
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(path, f.Path), "/")
+}
+
+// cover returns the path of the cover image of dir, empty if there is none
+func (l *library) cover(dir string) string {
+	objs, err := l.listImages(dir)
+	if err != nil || len(objs) == 0 {
+		return ""
+	}
+	for _, name := range coverNames {
+		for _, obj := range objs {
+			base := strings.TrimSuffix(obj.GetName(), stdpath.Ext(obj.GetName()))
+			if strings.EqualFold(base, name) {
+				return stdpath.Join(dir, obj.GetName())
+			}
+		}
+	}
+	return stdpath.Join(dir, objs[0].GetName())
+}
+
+func (l *library) listImages(dir string) ([]model.Obj, error) {
+	objs, err := fs.List(l.ctx, dir, &fs.ListArgs{})
+	if err != nil {
+		return nil, err
+	}
+	var res []model.Obj
+	for _, obj := range objs {
+		if !obj.IsDir() && utils.GetFileType(obj.GetName()) == conf.IMAGE && l.canAccess(stdpath.Join(dir, obj.GetName())) {
+			res = append(res, obj)
+		}
+	}
+	return res, nil
+}
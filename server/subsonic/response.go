@@ -0,0 +1,145 @@
+package subsonic
+
+import (
+	"encoding/xml"
+	"net/http"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/gin-gonic/gin"
+)
+
+const apiVersion = "1.16.1"
+
+// error codes of the Subsonic API
+const (
+	errGeneric       = 0
+	errMissingParam  = 10
+	errWrongAuth     = 40
+	errTokenAuth     = 41
+	errConflictAuth  = 43
+	errInvalidAPIKey = 44
+	errNotAuthorized = 50
+	errNotFound      = 70
+)
+
+type apiError struct {
+	Code    int    `xml:"code,attr" json:"code"`
+	Message string `xml:"message,attr" json:"message"`
+}
+
+type response struct {
+	XMLName       xml.Name       `xml:"http://subsonic.org/restapi subsonic-response" json:"-"`
+	Status        string         `xml:"status,attr" json:"status"`
+	Version       string         `xml:"version,attr" json:"version"`
+	Type          string         `xml:"type,attr" json:"type"`
+	ServerVersion string         `xml:"serverVersion,attr" json:"serverVersion"`
+	OpenSubsonic  bool           `xml:"openSubsonic,attr" json:"openSubsonic"`
+	Error         *apiError      `xml:"error,omitempty" json:"error,omitempty"`
+	License       *license       `xml:"license,omitempty" json:"license,omitempty"`
+	MusicFolders  *musicFolders  `xml:"musicFolders,omitempty" json:"musicFolders,omitempty"`
+	Indexes       *indexes       `xml:"indexes,omitempty" json:"indexes,omitempty"`
+	Directory     *directory     `xml:"directory,omitempty" json:"directory,omitempty"`
+	SearchResult3 *searchResult3 `xml:"searchResult3,omitempty" json:"searchResult3,omitempty"`
+}
+
+type license struct {
+	Valid bool `xml:"valid,attr" json:"valid"`
+}
+
+type musicFolder struct {
+	ID   int    `xml:"id,attr" json:"id"`
+	Name string `xml:"name,attr" json:"name"`
+}
+
+type musicFolders struct {
+	MusicFolder []musicFolder `xml:"musicFolder" json:"musicFolder"`
+}
+
+type artist struct {
+	ID         string `xml:"id,attr" json:"id"`
+	Name       string `xml:"name,attr" json:"name"`
+	CoverArt   string `xml:"coverArt,attr,omitempty" json:"coverArt,omitempty"`
+	AlbumCount int    `xml:"albumCount,attr,omitempty" json:"albumCount,omitempty"`
+}
+
+type index struct {
+	Name   string   `xml:"name,attr" json:"name"`
+	Artist []artist `xml:"artist" json:"artist"`
+}
+
+type indexes struct {
+	LastModified    int64   `xml:"lastModified,attr" json:"lastModified"`
+	IgnoredArticles string  `xml:"ignoredArticles,attr" json:"ignoredArticles"`
+	Index           []index `xml:"index" json:"index,omitempty"`
+	Child           []child `xml:"child" json:"child,omitempty"`
+}
+
+// child is a folder or a song of a directory
+type child struct {
+	ID          string `xml:"id,attr" json:"id"`
+	Parent      string `xml:"parent,attr,omitempty" json:"parent,omitempty"`
+	IsDir       bool   `xml:"isDir,attr" json:"isDir"`
+	Title       string `xml:"title,attr" json:"title"`
+	Album       string `xml:"album,attr,omitempty" json:"album,omitempty"`
+	Artist      string `xml:"artist,attr,omitempty" json:"artist,omitempty"`
+	Track       int    `xml:"track,attr,omitempty" json:"track,omitempty"`
+	Year        int    `xml:"year,attr,omitempty" json:"year,omitempty"`
+	Genre       string `xml:"genre,attr,omitempty" json:"genre,omitempty"`
+	DiscNumber  int    `xml:"discNumber,attr,omitempty" json:"discNumber,omitempty"`
+	CoverArt    string `xml:"coverArt,attr,omitempty" json:"coverArt,omitempty"`
+	Size        int64  `xml:"size,attr,omitempty" json:"size,omitempty"`
+	ContentType string `xml:"contentType,attr,omitempty" json:"contentType,omitempty"`
+	Suffix      string `xml:"suffix,attr,omitempty" json:"suffix,omitempty"`
+	Path        string `xml:"path,attr,omitempty" json:"path,omitempty"`
+	Created     string `xml:"created,attr,omitempty" json:"created,omitempty"`
+	Type        string `xml:"type,attr,omitempty" json:"type,omitempty"`
+}
+
+type directory struct {
+	ID     string  `xml:"id,attr" json:"id"`
+	Parent string  `xml:"parent,attr,omitempty" json:"parent,omitempty"`
+	Name   string  `xml:"name,attr" json:"name"`
+	Child  []child `xml:"child" json:"child,omitempty"`
+}
+
+type album struct {
+	ID        string `xml:"id,attr" json:"id"`
+	Name      string `xml:"name,attr" json:"name"`
+	Artist    string `xml:"artist,attr,omitempty" json:"artist,omitempty"`
+	CoverArt  string `xml:"coverArt,attr,omitempty" json:"coverArt,omitempty"`
+	SongCount int    `xml:"songCount,attr" json:"songCount"`
+}
+
+type searchResult3 struct {
+	Artist []artist `xml:"artist" json:"artist,omitempty"`
+	Album  []album  `xml:"album" json:"album,omitempty"`
+	Song   []child  `xml:"song" json:"song,omitempty"`
+}
+
+func newResponse() *response {
+	return &response{
+		Status:        "ok",
+		Version:       apiVersion,
+		Type:          "alist",
+		ServerVersion: conf.Version,
+		OpenSubsonic:  true,
+	}
+}
+
+// write sends resp in the format asked by the client, xml by default
+func write(c *gin.Context, resp *response) {
+	switch param(c, "f") {
+	case "json":
+		c.JSON(http.StatusOK, gin.H{"subsonic-response": resp})
+	default:
+		c.XML(http.StatusOK, resp)
+	}
+}
+
+// writeError sends a failed response, the Subsonic API reports errors with status 200
+func writeError(c *gin.Context, code int, message string) {
+	resp := newResponse()
+	resp.Status = "failed"
+	resp.Error = &apiError{Code: code, Message: message}
+	write(c, resp)
+}
@@ -0,0 +1,163 @@
+package subsonic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/alist-org/alist/v3/drivers"
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/stream"
+	"github.com/alist-org/alist/v3/internal/testutil"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// id3 returns an mp3 file holding only an ID3v2.3 tag with frames
+func id3(frames map[string]string) []byte {
+	var body bytes.Buffer
+	for id, text := range frames {
+		body.WriteString(id)
+		_ = binary.Write(&body, binary.BigEndian, uint32(len(text)+1))
+		body.Write([]byte{0, 0, 0})
+		body.WriteString(text)
+	}
+	size := body.Len()
+	header := []byte{'I', 'D', '3', 3, 0, 0,
+		byte(size >> 21 & 0x7f), byte(size >> 14 & 0x7f), byte(size >> 7 & 0x7f), byte(size & 0x7f)}
+	return append(header, body.Bytes()...)
+}
+
+type testResponse struct {
+	Response struct {
+		Status        string         `json:"status"`
+		Error         *apiError      `json:"error"`
+		Directory     *directory     `json:"directory"`
+		SearchResult3 *searchResult3 `json:"searchResult3"`
+	} `json:"subsonic-response"`
+}
+
+func TestSubsonic(t *testing.T) {
+	testutil.InitDB()
+	conf.Conf.SiteURL = "http://alist.test"
+	conf.Conf.Subsonic.Paths = []string{"/music"}
+	conf.SlicesMap[conf.AudioTypes] = []string{"mp3"}
+	stream.ClientDownloadLimit = rate.NewLimiter(rate.Inf, 0)
+	role := &model.Role{Name: "subsonic", PermissionScopes: []model.PermissionEntry{{Path: "/", Permission: 0xFFFF}}}
+	err := op.CreateRole(role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &model.User{Username: "listener", BasePath: "/", Role: model.Roles{int(role.ID)}}
+	if err = op.CreateUser(user.SetPassword("secret")); err != nil {
+		t.Fatal(err)
+	}
+	_, dir := testutil.CreateLocal(t, "/music")
+	if err = os.MkdirAll(filepath.Join(dir, "Artist", "Album"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	song := id3(map[string]string{"TIT2": "First Song", "TPE1": "The Artist", "TALB": "The Album", "TRCK": "1"})
+	if err = os.WriteFile(filepath.Join(dir, "Artist", "Album", "01.mp3"), song, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "Artist", "Album", "notes.txt"), []byte("text"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	Routes(e.Group("/rest"))
+	call := func(method string, params url.Values) (*httptest.ResponseRecorder, testResponse) {
+		t.Helper()
+		if params.Get("u") == "" && params.Get("apiKey") == "" {
+			params.Set("u", "listener")
+			params.Set("p", "enc:736563726574")
+		}
+		params.Set("f", "json")
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/rest/"+method+"?"+params.Encode(), nil))
+		var resp testResponse
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid response %s: %v", w.Body.String(), err)
+			}
+		}
+		return w, resp
+	}
+
+	if _, resp := call("ping.view", url.Values{"u": {"listener"}, "p": {"wrong"}}); resp.Response.Error == nil || resp.Response.Error.Code != errWrongAuth {
+		t.Fatalf("expected wrong credentials, got %+v", resp.Response)
+	}
+	if _, resp := call("ping", url.Values{"u": {"listener"}, "t": {"token"}, "s": {"salt"}}); resp.Response.Error == nil || resp.Response.Error.Code != errTokenAuth {
+		t.Fatalf("expected token authentication to be refused, got %+v", resp.Response)
+	}
+	if _, resp := call("ping.view", url.Values{}); resp.Response.Status != "ok" {
+		t.Fatalf("ping failed: %+v", resp.Response)
+	}
+
+	_, resp := call("getMusicDirectory", url.Values{"id": {encodeID("/music/Artist/Album")}})
+	d := resp.Response.Directory
+	if d == nil || len(d.Child) != 1 {
+		t.Fatalf("unexpected directory: %+v", resp.Response)
+	}
+	s := d.Child[0]
+	if s.Title != "First Song" || s.Artist != "The Artist" || s.Album != "The Album" || s.Track != 1 || s.Parent != d.ID {
+		t.Fatalf("unexpected song: %+v", s)
+	}
+	if tag, err := db.GetMusicTag("/music/Artist/Album", "01.mp3"); err != nil || tag.Title != "First Song" {
+		t.Fatalf("tags not cached: %+v %v", tag, err)
+	}
+
+	_, resp = call("search3", url.Values{"query": {"the"}})
+	r := resp.Response.SearchResult3
+	if r == nil || len(r.Song) != 1 || len(r.Album) != 1 || len(r.Artist) != 1 {
+		t.Fatalf("unexpected search result: %+v", resp.Response)
+	}
+	if r.Album[0].ID != d.ID || r.Artist[0].ID != encodeID("/music/Artist") {
+		t.Fatalf("unexpected album or artist: %+v", r)
+	}
+
+	w, _ := call("stream", url.Values{"id": {s.ID}})
+	if w.Code != http.StatusFound || !strings.HasPrefix(w.Header().Get("Location"), "http://alist.test/d/music/Artist/Album/01.mp3?sign=") {
+		t.Fatalf("unexpected stream response: %d %s", w.Code, w.Header().Get("Location"))
+	}
+	if _, resp = call("stream", url.Values{"id": {encodeID("/other/01.mp3")}}); resp.Response.Error == nil || resp.Response.Error.Code != errNotFound {
+		t.Fatalf("expected not found outside the music folders, got %+v", resp.Response)
+	}
+
+	// the password alone doesn't pass two-factor authentication
+	guarded := &model.User{Username: "guarded", BasePath: "/", Role: model.Roles{int(role.ID)}, OtpSecret: "JBSWY3DPEHPK3PXP"}
+	if err = op.CreateUser(guarded.SetPassword("secret")); err != nil {
+		t.Fatal(err)
+	}
+	if _, resp = call("ping", url.Values{"u": {"guarded"}, "p": {"secret"}}); resp.Response.Error == nil || resp.Response.Error.Code != errNotAuthorized {
+		t.Fatalf("expected the password of a 2FA user to be refused, got %+v", resp.Response)
+	}
+	token, err := op.CreateAPIToken(&model.APIToken{UserID: guarded.ID, Name: "subsonic", Permission: 0xFFFF})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, resp = call("ping", url.Values{"u": {"guarded"}, "p": {token}}); resp.Response.Status != "ok" {
+		t.Fatalf("expected the API token of a 2FA user to be accepted, got %+v", resp.Response)
+	}
+
+	// failures count toward the sign-in limit of the login page
+	defer common.LoginCache.Clear()
+	for i := 0; i < common.LoginMaxAttempts; i++ {
+		call("ping", url.Values{"u": {"listener"}, "p": {"wrong"}})
+	}
+	if _, resp = call("ping", url.Values{}); resp.Response.Error == nil || resp.Response.Error.Code != errWrongAuth {
+		t.Fatalf("expected the client to be locked out, got %+v", resp.Response)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/metrics"
 	"github.com/alist-org/alist/v3/internal/stream"
 	"github.com/alist-org/alist/v3/server/middlewares"
 
@@ -41,7 +42,7 @@ func WebDav(dav *gin.RouterGroup) {
 			log.Errorf("%s %s %+v", request.Method, request.URL.Path, err)
 		},
 	}
-	dav.Use(WebDAVAuth)
+	dav.Use(WebDAVAuth, middlewares.ServedBytes(metrics.WebDAV))
 	uploadLimiter := middlewares.UploadRateLimiter(stream.ClientUploadLimit)
 	downloadLimiter := middlewares.DownloadRateLimiter(stream.ClientDownloadLimit)
 	dav.Any("/*path", uploadLimiter, downloadLimiter, ServeWebDAV)
@@ -57,6 +58,9 @@ func WebDav(dav *gin.RouterGroup) {
 }
 
 func ServeWebDAV(c *gin.Context) {
+	// webdav has no session, the requests in flight are counted instead
+	metrics.SessionStarted(metrics.WebDAV)
+	defer metrics.SessionEnded(metrics.WebDAV)
 	user := c.MustGet("user").(*model.User)
 	ctx := context.WithValue(c.Request.Context(), "user", user)
 	handler.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
@@ -90,7 +94,14 @@ func WebDAVAuth(c *gin.Context) {
 				c.Next()
 				return
 			}
+			if strings.HasPrefix(bt, model.APITokenPrefix) {
+				if u, err := op.GetUserByAPIToken(bt); err == nil {
+					username, password, ok = u.Username, bt, true
+				}
+			}
 		}
+	}
+	if !ok {
 		if c.Request.Method == "OPTIONS" {
 			c.Set("user", guest)
 			c.Next()
@@ -101,8 +112,8 @@ func WebDAVAuth(c *gin.Context) {
 		c.Abort()
 		return
 	}
-	user, err := op.GetUserByName(username)
-	if err != nil || user.ValidateRawPassword(password) != nil {
+	user, err := webdavUser(username, password)
+	if err != nil {
 		if c.Request.Method == "OPTIONS" {
 			c.Set("user", guest)
 			c.Next()
@@ -173,3 +184,26 @@ func WebDAVAuth(c *gin.Context) {
 	c.Set("user", user)
 	c.Next()
 }
+
+// webdavUser authenticates basic auth credentials, a personal access token of
+// the user is accepted in place of the password.
+func webdavUser(username, password string) (*model.User, error) {
+	if strings.HasPrefix(password, model.APITokenPrefix) {
+		user, err := op.GetUserByAPIToken(password)
+		if err != nil {
+			return nil, err
+		}
+		if user.Username != username {
+			return nil, errs.InvalidAPIToken
+		}
+		return user, nil
+	}
+	user, err := op.GetUserByName(username)
+	if err != nil {
+		return nil, err
+	}
+	if err := user.ValidateRawPassword(password); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -342,6 +342,9 @@ func (h *Handler) handlePut(w http.ResponseWriter, r *http.Request) (status int,
 
 	_ = r.Body.Close()
 	_ = fsStream.Close()
+	if errors.Is(err, errs.UploadNotAllowed) {
+		return http.StatusForbidden, err
+	}
 	// TODO(rost): Returning 405 Method Not Allowed might not be appropriate.
 	if err != nil {
 		return http.StatusMethodNotAllowed, err